@@ -0,0 +1,335 @@
+// internal/config/config.go
+
+package config
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"ricambi-manager/internal/domain"
+)
+
+const DefaultPath = "configs/config.yaml"
+
+type Config struct {
+	App       AppConfig       `yaml:"app"`
+	MongoDB   MongoDBConfig   `yaml:"mongodb"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Business  BusinessConfig  `yaml:"business"`
+	Barcode   BarcodeConfig   `yaml:"barcode"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	UI        UIConfig        `yaml:"ui"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+}
+
+type AppConfig struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Environment string `yaml:"environment"`
+}
+
+type MongoDBConfig struct {
+	URI            string `yaml:"uri"`
+	Database       string `yaml:"database"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+}
+
+// AuthConfig: SessionStore "mongo" condivide le sessioni fra le istanze, "memory" le tiene nel solo processo.
+// Dopo MaxFailedAttempts password errate l'account resta bloccato LockoutDurationMinutes (0 = fino allo
+// sblocco manuale); TerminalMaxAttempts limita i tentativi da un terminale su qualsiasi username.
+// Per i profili in TwoFactorProfiles il 2FA è obbligatorio e si attiva al primo accesso
+type AuthConfig struct {
+	SessionTimeoutMinutes  int                  `yaml:"session_timeout_minutes"`
+	SessionStore           string               `yaml:"session_store"`
+	PasswordCost           int                  `yaml:"password_cost"`
+	MaxFailedAttempts      int                  `yaml:"max_failed_attempts"`
+	LockoutDurationMinutes int                  `yaml:"lockout_duration_minutes"`
+	TerminalMaxAttempts    int                  `yaml:"terminal_max_attempts"`
+	TwoFactorProfiles      []string             `yaml:"two_factor_profiles"`
+	PasswordPolicy         PasswordPolicyConfig `yaml:"password_policy"`
+}
+
+// PasswordPolicyConfig: MaxAgeDays 0 disattiva la scadenza, HistorySize 0 lo storico delle password
+type PasswordPolicyConfig struct {
+	MinLength        int  `yaml:"min_length"`
+	RequireUppercase bool `yaml:"require_uppercase"`
+	RequireLowercase bool `yaml:"require_lowercase"`
+	RequireDigit     bool `yaml:"require_digit"`
+	RequireSpecial   bool `yaml:"require_special"`
+	MaxAgeDays       int  `yaml:"max_age_days"`
+	HistorySize      int  `yaml:"history_size"`
+}
+
+// PasswordRules converte policy e costo di hashing di configurazione nella policy password del dominio
+func (c AuthConfig) PasswordRules() domain.PasswordPolicy {
+	return domain.PasswordPolicy{
+		MinLength:        c.PasswordPolicy.MinLength,
+		RequireUppercase: c.PasswordPolicy.RequireUppercase,
+		RequireLowercase: c.PasswordPolicy.RequireLowercase,
+		RequireDigit:     c.PasswordPolicy.RequireDigit,
+		RequireSpecial:   c.PasswordPolicy.RequireSpecial,
+		MaxAge:           time.Duration(c.PasswordPolicy.MaxAgeDays) * 24 * time.Hour,
+		HistorySize:      c.PasswordPolicy.HistorySize,
+		Cost:             c.PasswordCost,
+	}
+}
+
+type BusinessConfig struct {
+	Fido           FidoConfig            `yaml:"fido"`
+	Margin         MarginConfig          `yaml:"margin"`
+	CreditVoucher  CreditVoucherConfig   `yaml:"credit_voucher"`
+	Pricing        PricingConfig         `yaml:"pricing"`
+	DiscountLimits []DiscountLimitConfig `yaml:"discount_limits"`
+	NetPrices      NetPricesConfig       `yaml:"net_prices"`
+	Budget         BudgetConfig          `yaml:"budget"`
+}
+
+type FidoConfig struct {
+	WarningThresholdPercent float64 `yaml:"warning_threshold_percent"`
+	BlockThresholdPercent   float64 `yaml:"block_threshold_percent"`
+}
+
+type MarginConfig struct {
+	SottocostoThresholdPercent    float64 `yaml:"sottocosto_threshold_percent"`
+	SottoguadagnoThresholdPercent float64 `yaml:"sottoguadagno_threshold_percent"`
+}
+
+type CreditVoucherConfig struct {
+	DefaultExpiryDays int `yaml:"default_expiry_days"`
+}
+
+type PricingConfig struct {
+	StackOnCustomerDiscount bool    `yaml:"stack_on_customer_discount"`
+	StackOnNetPrice         bool    `yaml:"stack_on_net_price"`
+	MaxTotalDiscountPercent float64 `yaml:"max_total_discount_percent"`
+}
+
+type NetPricesConfig struct {
+	ReminderDays int `yaml:"reminder_days"`
+}
+
+// BudgetConfig è lo schema incentivi di default: pesi dei KPI e soglie minime di accesso
+type BudgetConfig struct {
+	Weights BudgetWeightsConfig `yaml:"weights"`
+	Gates   []BudgetGateConfig  `yaml:"gates"`
+}
+
+type BudgetWeightsConfig struct {
+	Revenue  float64            `yaml:"revenue"`
+	Margin   float64            `yaml:"margin"`
+	Orders   float64            `yaml:"orders"`
+	Families map[string]float64 `yaml:"families"`
+}
+
+type BudgetGateConfig struct {
+	KPI            string  `yaml:"kpi"`
+	Family         string  `yaml:"family"`
+	MinAchievement float64 `yaml:"min_achievement"`
+}
+
+// IncentiveScheme converte pesi e soglie di configurazione nello schema incentivi del dominio
+func (c BudgetConfig) IncentiveScheme() domain.IncentiveScheme {
+	scheme := domain.IncentiveScheme{
+		Weights: domain.BudgetWeights{
+			Revenue:  c.Weights.Revenue,
+			Margin:   c.Weights.Margin,
+			Orders:   c.Weights.Orders,
+			Families: map[string]float64{},
+		},
+	}
+	for family, weight := range c.Weights.Families {
+		scheme.Weights.Families[domain.FamilyKey(family)] = weight
+	}
+	for _, gate := range c.Gates {
+		scheme.Gates = append(scheme.Gates, domain.BudgetGate{
+			KPI:            domain.BudgetKPI(gate.KPI),
+			Family:         domain.FamilyKey(gate.Family),
+			MinAchievement: gate.MinAchievement,
+		})
+	}
+	return scheme
+}
+
+// DiscountLimitConfig è un limite di sconto di default: per profilo oppure per username
+type DiscountLimitConfig struct {
+	Profile            string   `yaml:"profile"`
+	Username           string   `yaml:"username"`
+	Family             string   `yaml:"family"`
+	Brand              string   `yaml:"brand"`
+	MaxDiscountPercent float64  `yaml:"max_discount_percent"`
+	MinMarginPercent   *float64 `yaml:"min_margin_percent"`
+}
+
+// DefaultDiscountLimits converte i limiti di sconto di configurazione in quelli del dominio
+func (c BusinessConfig) DefaultDiscountLimits() domain.DiscountLimits {
+	limits := domain.DiscountLimits{}
+	for _, limit := range c.DiscountLimits {
+		limits = append(limits, domain.DiscountLimit{
+			Profile:            domain.ProfileType(limit.Profile),
+			Username:           limit.Username,
+			Family:             limit.Family,
+			Brand:              limit.Brand,
+			MaxDiscountPercent: limit.MaxDiscountPercent,
+			MinMarginPercent:   limit.MinMarginPercent,
+		})
+	}
+	return limits
+}
+
+type BarcodeConfig struct {
+	DefaultFormat string `yaml:"default_format"`
+	PrinterFormat string `yaml:"printer_format"`
+}
+
+// LoggingConfig: AuditLog è la copia su file del registro di audit, vuoto per disattivarla
+type LoggingConfig struct {
+	Level    string `yaml:"level"`
+	Output   string `yaml:"output"`
+	AuditLog string `yaml:"audit_log"`
+}
+
+// SchedulerConfig pianifica i job di manutenzione: cron a 5 campi, @hourly/@daily o "@every 5m";
+// "off" disattiva il job. Instance vuoto usa host:pid
+type SchedulerConfig struct {
+	Enabled        bool              `yaml:"enabled"`
+	Instance       string            `yaml:"instance"`
+	LockTTLMinutes int               `yaml:"lock_ttl_minutes"`
+	HistoryDays    int               `yaml:"history_days"`
+	Jobs           map[string]string `yaml:"jobs"`
+}
+
+type UIConfig struct {
+	RefreshRateMs int `yaml:"refresh_rate_ms"`
+	PageSize      int `yaml:"page_size"`
+}
+
+func Default() *Config {
+	return &Config{
+		App: AppConfig{
+			Name:        "Ricambi Manager",
+			Environment: "production",
+		},
+		MongoDB: MongoDBConfig{
+			URI:            "mongodb://localhost:27017",
+			Database:       "ricambi_db",
+			TimeoutSeconds: 10,
+		},
+		Auth: AuthConfig{
+			SessionTimeoutMinutes:  480,
+			SessionStore:           "mongo",
+			PasswordCost:           12,
+			MaxFailedAttempts:      5,
+			LockoutDurationMinutes: 30,
+			TerminalMaxAttempts:    15,
+			TwoFactorProfiles:      []string{"admin", "accounting"},
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:        8,
+				RequireUppercase: true,
+				RequireLowercase: true,
+				RequireDigit:     true,
+				RequireSpecial:   false,
+				MaxAgeDays:       90,
+				HistorySize:      5,
+			},
+		},
+		Business: BusinessConfig{
+			Fido: FidoConfig{
+				WarningThresholdPercent: 80,
+				BlockThresholdPercent:   100,
+			},
+			Margin: MarginConfig{
+				SottocostoThresholdPercent:    0,
+				SottoguadagnoThresholdPercent: 15,
+			},
+			CreditVoucher: CreditVoucherConfig{
+				DefaultExpiryDays: 365,
+			},
+			Pricing: PricingConfig{
+				StackOnCustomerDiscount: false,
+				StackOnNetPrice:         true,
+			},
+			DiscountLimits: []DiscountLimitConfig{
+				{Profile: "admin", MaxDiscountPercent: 100},
+				{Profile: "sales", MaxDiscountPercent: 20},
+				{Profile: "warehouse", MaxDiscountPercent: 0},
+				{Profile: "accounting", MaxDiscountPercent: 0},
+			},
+			NetPrices: NetPricesConfig{
+				ReminderDays: 30,
+			},
+			Budget: BudgetConfig{
+				Weights: BudgetWeightsConfig{Revenue: 1, Margin: 1, Orders: 1},
+			},
+		},
+		Barcode: BarcodeConfig{
+			DefaultFormat: "EAN13",
+			PrinterFormat: "ZPL",
+		},
+		Logging: LoggingConfig{
+			Level:    "info",
+			Output:   "logs/app.log",
+			AuditLog: "logs/audit.log",
+		},
+		UI: UIConfig{
+			RefreshRateMs: 100,
+			PageSize:      20,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:        true,
+			LockTTLMinutes: 10,
+			HistoryDays:    90,
+			Jobs: map[string]string{
+				"price_changes":       "* * * * *",
+				"promotions":          "0 * * * *",
+				"net_price_reminders": "15 * * * *",
+				"vouchers":            "5 0 * * *",
+				"budgets":             "10 0 * * *",
+				"sessions":            "*/15 * * * *",
+				"job_history":         "0 3 * * 0",
+			},
+		},
+	}
+}
+
+// Load legge il file YAML sopra i valori di default; se il file non esiste restituisce i default
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal([]byte(expandEnv(string(data))), cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?\}`)
+
+// expandEnv sostituisce ${VAR:default} con la variabile d'ambiente o il default
+func expandEnv(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := envPattern.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(parts[1]); ok {
+			return value
+		}
+		return parts[2]
+	})
+}
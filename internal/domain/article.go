@@ -17,6 +17,9 @@ var (
 	ErrInsufficientStock    = errors.New("insufficient stock")
 	ErrDuplicateBarcode     = errors.New("duplicate barcode")
 	ErrInvalidApplicability = errors.New("invalid applicability")
+	ErrNetPriceNotFound     = errors.New("net price not found")
+	ErrNetPriceNoExpiry     = errors.New("net price has no expiry date")
+	ErrInvalidNetPrice      = errors.New("invalid net price")
 )
 
 type Article struct {
@@ -252,6 +255,25 @@ func (a *Article) ReleaseReservedStock(quantity float64) error {
 	return nil
 }
 
+func (a *Article) ApplyPriceChange(change *PriceChange, updatedBy string) error {
+	if change.ArticleID != a.ID {
+		return errors.New("price change refers to a different article")
+	}
+
+	previousListPrice := a.Pricing.ListPrice
+	previousCost := a.Pricing.LastPurchaseCost
+
+	if err := change.MarkApplied(previousListPrice, previousCost); err != nil {
+		return err
+	}
+
+	a.Pricing.ListPrice = change.ListPrice
+	a.Pricing.LastPurchaseCost = change.Cost
+	a.UpdatedAt = time.Now()
+	a.UpdatedBy = updatedBy
+	return nil
+}
+
 func (a *Article) IsLowStock() bool {
 	return a.Stock.Available <= a.Stock.ReorderPoint
 }
@@ -264,6 +286,13 @@ func (a *Article) CalculateMargin(sellingPrice float64) float64 {
 }
 
 func (a *Article) IsSottocosto(sellingPrice float64, threshold float64) bool {
+	// senza costo d'acquisto il margine non è valutabile
+	if a.Pricing.LastPurchaseCost == 0 {
+		return false
+	}
+	if sellingPrice <= 0 {
+		return true
+	}
 	margin := a.CalculateMargin(sellingPrice)
 	return margin < threshold
 }
@@ -338,30 +367,139 @@ func (a *Article) GetAvailableForKitProduction() float64 {
 	return 0
 }
 
+// AddNetPrice sostituisce i periodi dello stesso cliente che si sovrappongono al nuovo; quelli iniziati
+// prima vengono chiusi alla nuova decorrenza, così lo storico resta consultabile
 func (a *Article) AddNetPrice(netPrice NetPrice) {
-	for i, np := range a.Pricing.NetPrices {
-		if np.CustomerID == netPrice.CustomerID {
-			a.Pricing.NetPrices[i] = netPrice
-			a.UpdatedAt = time.Now()
-			return
+	prices := make([]NetPrice, 0, len(a.Pricing.NetPrices)+1)
+	for _, np := range a.Pricing.NetPrices {
+		if np.CustomerID != netPrice.CustomerID || !np.Overlaps(netPrice) {
+			prices = append(prices, np)
+			continue
+		}
+		if np.ValidFrom.Before(netPrice.ValidFrom) {
+			np.ValidTo = netPrice.ValidFrom
+			prices = append(prices, np)
 		}
 	}
-	a.Pricing.NetPrices = append(a.Pricing.NetPrices, netPrice)
+	a.Pricing.NetPrices = append(prices, netPrice)
 	a.UpdatedAt = time.Now()
 }
 
+// LatestNetPrice restituisce il periodo più recente del cliente, valido o scaduto
+func (a *Article) LatestNetPrice(customerID primitive.ObjectID) *NetPrice {
+	var latest *NetPrice
+	for i, np := range a.Pricing.NetPrices {
+		if np.CustomerID != customerID {
+			continue
+		}
+		if latest == nil || np.ValidFrom.After(latest.ValidFrom) {
+			latest = &a.Pricing.NetPrices[i]
+		}
+	}
+	return latest
+}
+
+// RenewNetPrice apre un nuovo periodo dalla scadenza dell'ultimo, con il prezzo aumentato di upliftPercent
+func (a *Article) RenewNetPrice(customerID primitive.ObjectID, upliftPercent float64, validTo time.Time, renewedBy string) (NetPrice, error) {
+	latest := a.LatestNetPrice(customerID)
+	if latest == nil {
+		return NetPrice{}, ErrNetPriceNotFound
+	}
+	if latest.ValidTo.IsZero() {
+		return NetPrice{}, ErrNetPriceNoExpiry
+	}
+	if upliftPercent <= -100 || !validTo.IsZero() && !validTo.After(latest.ValidTo) {
+		return NetPrice{}, ErrInvalidNetPrice
+	}
+
+	renewed := NetPrice{
+		CustomerID: customerID,
+		Price:      roundCents(latest.Price * (1 + upliftPercent/100)),
+		ValidFrom:  latest.ValidTo,
+		ValidTo:    validTo,
+		CreatedBy:  renewedBy,
+	}
+	a.AddNetPrice(renewed)
+	return renewed, nil
+}
+
 func (a *Article) GetNetPrice(customerID primitive.ObjectID) *NetPrice {
-	now := time.Now()
+	return a.GetNetPriceAt(customerID, time.Now())
+}
+
+func (a *Article) GetNetPriceAt(customerID primitive.ObjectID, date time.Time) *NetPrice {
+	for _, np := range a.Pricing.NetPrices {
+		if np.CustomerID == customerID && np.IsValidAt(date) {
+			return &np
+		}
+	}
+	return nil
+}
+
+func (a *Article) NetPricesFor(customerID primitive.ObjectID) []NetPrice {
+	var prices []NetPrice
 	for _, np := range a.Pricing.NetPrices {
 		if np.CustomerID == customerID {
-			if now.After(np.ValidFrom) && (np.ValidTo.IsZero() || now.Before(np.ValidTo)) {
-				return &np
-			}
+			prices = append(prices, np)
 		}
 	}
+	return prices
+}
+
+func (np NetPrice) Validate() error {
+	if np.CustomerID.IsZero() || np.Price <= 0 {
+		return ErrInvalidNetPrice
+	}
+	if !np.ValidTo.IsZero() && !np.ValidTo.After(np.ValidFrom) {
+		return ErrInvalidNetPrice
+	}
 	return nil
 }
 
+// Overlaps confronta i periodi di validità; una scadenza vuota vale come illimitata
+func (np NetPrice) Overlaps(other NetPrice) bool {
+	if !np.ValidTo.IsZero() && !np.ValidTo.After(other.ValidFrom) {
+		return false
+	}
+	if !other.ValidTo.IsZero() && !other.ValidTo.After(np.ValidFrom) {
+		return false
+	}
+	return true
+}
+
+func (np NetPrice) ExpiresBetween(from, to time.Time) bool {
+	return !np.ValidTo.IsZero() && !np.ValidTo.Before(from) && !np.ValidTo.After(to)
+}
+
+func (np NetPrice) IsValidAt(date time.Time) bool {
+	return np.InvalidReason(date) == ""
+}
+
+// InvalidReason spiega perché il prezzo netto non vale alla data, stringa vuota se vale
+func (np NetPrice) InvalidReason(date time.Time) string {
+	if !date.After(np.ValidFrom) {
+		return "not yet valid on " + date.Format("02/01/2006")
+	}
+	if !np.ValidTo.IsZero() && !date.Before(np.ValidTo) {
+		return "expired on " + np.ValidTo.Format("02/01/2006")
+	}
+	return ""
+}
+
+// ExpiringNetPrices restituisce i prezzi netti in scadenza nell'intervallo non ancora rinnovati
+func (a *Article) ExpiringNetPrices(from, to time.Time) []NetPrice {
+	var expiring []NetPrice
+	for _, np := range a.Pricing.NetPrices {
+		if !np.ExpiresBetween(from, to) {
+			continue
+		}
+		if latest := a.LatestNetPrice(np.CustomerID); latest != nil && latest.ValidFrom.Equal(np.ValidFrom) {
+			expiring = append(expiring, np)
+		}
+	}
+	return expiring
+}
+
 func (a *Article) GetExpiredNetPrices() []NetPrice {
 	now := time.Now()
 	var expired []NetPrice
@@ -0,0 +1,117 @@
+// internal/domain/audit_record.go
+
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// AuditRecord è una voce del registro di audit, separato dai documenti operatore e in sola aggiunta.
+// Ogni voce riporta l'impronta della precedente: modificare o cancellare una voce rompe la catena
+// da quel punto in poi
+type AuditRecord struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Sequence   int64              `bson:"sequence" json:"sequence"`
+	OperatorID primitive.ObjectID `bson:"operator_id" json:"operator_id"`
+	Username   string             `bson:"username" json:"username"`
+	Action     string             `bson:"action" json:"action"`
+	Area       string             `bson:"area" json:"area"`
+	ResourceID string             `bson:"resource_id" json:"resource_id"`
+	Details    string             `bson:"details" json:"details"`
+	IPAddress  string             `bson:"ip_address" json:"ip_address"`
+	Denied     bool               `bson:"denied" json:"denied"`
+	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
+	PrevHash   string             `bson:"prev_hash" json:"prev_hash"`
+	Hash       string             `bson:"hash" json:"hash"`
+}
+
+// AuditFilter seleziona le voci per area, risorsa e operatore; i campi vuoti non filtrano
+type AuditFilter struct {
+	Area       string
+	ResourceID string
+	Username   string
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int64
+}
+
+// NewAuditRecord tronca l'orario al millisecondo, la precisione con cui Mongo lo conserva, così
+// l'impronta calcolata ora coincide con quella ricalcolata dal documento letto
+func NewAuditRecord(operator *Operator, action, area, resourceID, details, ipAddress string) *AuditRecord {
+	record := &AuditRecord{
+		ID:         primitive.NewObjectID(),
+		Action:     action,
+		Area:       area,
+		ResourceID: resourceID,
+		Details:    details,
+		IPAddress:  ipAddress,
+		Timestamp:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if operator != nil {
+		record.OperatorID = operator.ID
+		record.Username = operator.Username
+	}
+	return record
+}
+
+// ComputeHash copre tutti i campi e l'impronta precedente; la codifica JSON di una lista rende
+// l'input non ambiguo anche con separatori nei dettagli
+func (r *AuditRecord) ComputeHash() string {
+	payload, _ := json.Marshal([]interface{}{
+		r.Sequence,
+		r.PrevHash,
+		r.ID.Hex(),
+		r.OperatorID.Hex(),
+		r.Username,
+		r.Action,
+		r.Area,
+		r.ResourceID,
+		r.Details,
+		r.IPAddress,
+		r.Denied,
+		r.Timestamp.UnixMilli(),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal accoda la voce a prev (nil per la prima del registro) e ne calcola l'impronta
+func (r *AuditRecord) Seal(prev *AuditRecord) {
+	r.Sequence = 1
+	r.PrevHash = ""
+	if prev != nil {
+		r.Sequence = prev.Sequence + 1
+		r.PrevHash = prev.Hash
+	}
+	r.Hash = r.ComputeHash()
+}
+
+// VerifyAfter controlla che la voce sia integra e segua prev senza buchi
+func (r *AuditRecord) VerifyAfter(prev *AuditRecord) error {
+	if r.ComputeHash() != r.Hash {
+		return fmt.Errorf("%w at sequence %d: record altered", ErrAuditChainBroken, r.Sequence)
+	}
+
+	wantSequence, wantPrev := int64(1), ""
+	if prev != nil {
+		wantSequence, wantPrev = prev.Sequence+1, prev.Hash
+	}
+	if r.Sequence != wantSequence {
+		return fmt.Errorf("%w at sequence %d: expected sequence %d", ErrAuditChainBroken, r.Sequence, wantSequence)
+	}
+	if r.PrevHash != wantPrev {
+		return fmt.Errorf("%w at sequence %d: previous hash mismatch", ErrAuditChainBroken, r.Sequence)
+	}
+
+	return nil
+}
@@ -18,6 +18,15 @@ const (
 	BudgetTypeGlobal   BudgetType = "global"
 )
 
+// BudgetStatus è lo stato salvato, aggiornato dal job pianificato; GetStatus resta calcolato sulle date
+type BudgetStatus string
+
+const (
+	BudgetStatusPlanned BudgetStatus = "planned"
+	BudgetStatusActive  BudgetStatus = "active"
+	BudgetStatusClosed  BudgetStatus = "closed"
+)
+
 type BudgetIncentive struct {
 	ID               primitive.ObjectID `bson:"id"`
 	MinRevenue       float64            `bson:"min_revenue"`
@@ -25,6 +34,8 @@ type BudgetIncentive struct {
 	IncentivePercent float64            `bson:"incentive_percent"`
 	IncentiveAmount  float64            `bson:"incentive_amount"`
 	Description      string             `bson:"description"`
+	// Basis indica su cosa si misurano Min/MaxRevenue: fatturato (default) o raggiungimento ponderato in %
+	Basis IncentiveBasis `bson:"basis,omitempty"`
 }
 
 type Budget struct {
@@ -45,7 +56,18 @@ type Budget struct {
 	TargetOrders int `bson:"target_orders"`
 	ActualOrders int `bson:"actual_orders"`
 
+	// sotto-obiettivi di fatturato per famiglia
+	FamilyTargets map[string]float64 `bson:"family_targets,omitempty"`
+	FamilyActuals map[string]float64 `bson:"family_actuals,omitempty"`
+
 	Incentives []BudgetIncentive `bson:"incentives"`
+	// Scheme sostituisce pesi e soglie di configurazione per questo budget
+	Scheme *IncentiveScheme `bson:"scheme,omitempty"`
+
+	// alla chiusura l'incentivo maturato viene congelato insieme allo schema usato per calcolarlo
+	Status         BudgetStatus `bson:"status,omitempty"`
+	ClosedAt       time.Time    `bson:"closed_at,omitempty"`
+	FinalIncentive float64      `bson:"final_incentive"`
 
 	Notes     string    `bson:"notes"`
 	CreatedBy string    `bson:"created_by"`
@@ -54,10 +76,12 @@ type Budget struct {
 }
 
 var (
-	ErrBudgetNotFound        = errors.New("budget not found")
-	ErrInvalidBudgetPeriod   = errors.New("invalid budget period")
-	ErrBudgetAlreadyExists   = errors.New("budget already exists for this period")
-	ErrInvalidIncentiveRange = errors.New("invalid incentive range")
+	ErrBudgetNotFound         = errors.New("budget not found")
+	ErrInvalidBudgetPeriod    = errors.New("invalid budget period")
+	ErrBudgetAlreadyExists    = errors.New("budget already exists for this period")
+	ErrInvalidIncentiveRange  = errors.New("invalid incentive range")
+	ErrInvalidFamilyTarget    = errors.New("invalid family target")
+	ErrInvalidIncentiveScheme = errors.New("invalid incentive scheme")
 )
 
 func NewBudget(
@@ -93,6 +117,7 @@ func NewBudget(
 		ActualMargin:  0,
 		ActualOrders:  0,
 		Incentives:    []BudgetIncentive{},
+		Status:        BudgetStatusPlanned,
 		CreatedBy:     createdBy,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
@@ -138,6 +163,94 @@ func (b *Budget) AddOrder() {
 	b.UpdatedAt = time.Now()
 }
 
+// BudgetContribution è l'apporto di un documento registrato ai consuntivi
+type BudgetContribution struct {
+	Revenue  float64
+	Margin   float64
+	Orders   int
+	Families map[string]float64
+}
+
+// DocumentBudgetContribution: le note di credito stornano fatturato e margine ma non contano come ordini;
+// sugli acquisti il fatturato è il volume acquistato e il margine non si applica. La spedizione è esclusa
+func DocumentBudgetContribution(d *Document) BudgetContribution {
+	sign := d.Sign()
+
+	contribution := BudgetContribution{
+		Revenue: roundCents(sign * (d.Totals.Net - d.Totals.Shipping)),
+	}
+	if d.IsSale() {
+		contribution.Margin = roundCents(sign * d.Totals.Margin)
+
+		contribution.Families = map[string]float64{}
+		for _, line := range d.Lines {
+			if line.Family != "" {
+				key := FamilyKey(line.Family)
+				contribution.Families[key] = roundCents(contribution.Families[key] + sign*line.Total)
+			}
+		}
+	}
+	if d.Type != DocumentTypeCreditNote {
+		contribution.Orders = 1
+	}
+
+	return contribution
+}
+
+func (c BudgetContribution) Negate() BudgetContribution {
+	negated := BudgetContribution{Revenue: -c.Revenue, Margin: -c.Margin, Orders: -c.Orders}
+	if c.Families != nil {
+		negated.Families = make(map[string]float64, len(c.Families))
+		for family, revenue := range c.Families {
+			negated.Families[family] = -revenue
+		}
+	}
+	return negated
+}
+
+func (b *Budget) Covers(date time.Time) bool {
+	return !date.Before(b.StartDate) && !date.After(b.EndDate)
+}
+
+// AppliesTo indica se il documento alimenta il budget: cliente, operatore e globale sulle vendite, fornitore sugli acquisti
+func (b *Budget) AppliesTo(d *Document) bool {
+	if !b.Covers(d.Date) {
+		return false
+	}
+
+	switch b.Type {
+	case BudgetTypeCustomer:
+		return d.IsSale() && b.EntityID == d.CustomerID
+	case BudgetTypeOperator:
+		return d.IsSale() && b.EntityID == d.OperatorID
+	case BudgetTypeSupplier:
+		return d.IsPurchase() && b.EntityID == d.SupplierID
+	case BudgetTypeGlobal:
+		return d.IsSale()
+	default:
+		return false
+	}
+}
+
+func (b *Budget) ApplyContribution(c BudgetContribution) {
+	b.AddRevenue(c.Revenue)
+	b.AddMargin(c.Margin)
+	b.ActualOrders += c.Orders
+
+	for family, revenue := range c.Families {
+		if b.FamilyActuals == nil {
+			b.FamilyActuals = map[string]float64{}
+		}
+		b.FamilyActuals[family] = roundCents(b.FamilyActuals[family] + revenue)
+	}
+}
+
+// ResetActuals azzera i consuntivi, comprese le famiglie, prima di un ricalcolo
+func (b *Budget) ResetActuals() {
+	b.UpdateActuals(0, 0, 0)
+	b.FamilyActuals = map[string]float64{}
+}
+
 func (b *Budget) GetRevenueAchievement() float64 {
 	if b.TargetRevenue == 0 {
 		return 0
@@ -159,12 +272,14 @@ func (b *Budget) GetOrdersAchievement() float64 {
 	return (float64(b.ActualOrders) / float64(b.TargetOrders)) * 100
 }
 
+// GetOverallAchievement è la media dei raggiungimenti ponderata con i pesi dello schema (uguali se assente)
 func (b *Budget) GetOverallAchievement() float64 {
-	revenueAch := b.GetRevenueAchievement()
-	marginAch := b.GetMarginAchievement()
-	ordersAch := b.GetOrdersAchievement()
+	weights := DefaultIncentiveScheme().Weights
+	if b.Scheme != nil {
+		weights = b.Scheme.Weights
+	}
 
-	return (revenueAch + marginAch + ordersAch) / 3
+	return b.weightedAchievement(weights)
 }
 
 func (b *Budget) GetRemainingRevenue() float64 {
@@ -264,25 +379,14 @@ func (b *Budget) RemoveIncentive(incentiveID primitive.ObjectID) {
 	}
 }
 
+// CalculateIncentive restituisce l'incentivo maturato; è zero se una soglia dello schema non è superata
 func (b *Budget) CalculateIncentive() float64 {
-	for _, inc := range b.Incentives {
-		if b.ActualRevenue >= inc.MinRevenue && b.ActualRevenue < inc.MaxRevenue {
-			if inc.IncentiveAmount > 0 {
-				return inc.IncentiveAmount
-			}
-			return b.ActualRevenue * (inc.IncentivePercent / 100)
-		}
-	}
-	return 0
+	return b.EvaluateIncentive().Amount
 }
 
 func (b *Budget) GetApplicableIncentive() *BudgetIncentive {
-	for _, inc := range b.Incentives {
-		if b.ActualRevenue >= inc.MinRevenue && b.ActualRevenue < inc.MaxRevenue {
-			return &inc
-		}
-	}
-	return nil
+	current, _ := b.incentiveTiers()
+	return current
 }
 
 func (b *Budget) IsActive() bool {
@@ -311,6 +415,9 @@ func (b *Budget) IsFuture() bool {
 }
 
 func (b *Budget) GetStatus() string {
+	if b.Status == BudgetStatusClosed {
+		return "closed"
+	}
 	if b.IsFuture() {
 		return "future"
 	}
@@ -323,6 +430,31 @@ func (b *Budget) GetStatus() string {
 	return "unknown"
 }
 
+// UpdateStatus allinea lo stato salvato alle date: attiva il budget all'inizio del periodo e lo chiude
+// alla fine, congelando l'incentivo. Restituisce true se lo stato è cambiato
+func (b *Budget) UpdateStatus(now time.Time) bool {
+	previous := b.Status
+
+	switch {
+	case b.Status == BudgetStatusClosed:
+	case now.After(b.EndDate):
+		b.Status = BudgetStatusClosed
+		b.ClosedAt = now
+		b.FinalIncentive = b.EvaluateIncentive().Amount
+	case !now.Before(b.StartDate):
+		b.Status = BudgetStatusActive
+	default:
+		b.Status = BudgetStatusPlanned
+	}
+
+	if b.Status == previous {
+		return false
+	}
+
+	b.UpdatedAt = now
+	return true
+}
+
 func (b *Budget) Validate() error {
 	if b.EntityID.IsZero() {
 		return errors.New("entity ID is required")
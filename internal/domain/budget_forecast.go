@@ -0,0 +1,145 @@
+// internal/domain/budget_forecast.go
+
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+type ForecastMethod string
+
+const (
+	ForecastMethodNone     ForecastMethod = "none"
+	ForecastMethodFinal    ForecastMethod = "final"
+	ForecastMethodRunRate  ForecastMethod = "run_rate"
+	ForecastMethodSeasonal ForecastMethod = "seasonal"
+)
+
+// sotto questa quota dell'anno precedente la stagionalità è poco affidabile e si usa il run-rate
+const minSeasonalShare = 0.05
+
+// BudgetSeasonality è l'andamento dello stesso periodo dell'anno precedente: quanto era stato fatturato
+// alla data corrispondente a oggi e quanto sull'intero periodo
+type BudgetSeasonality struct {
+	RevenueToDate float64
+	RevenueTotal  float64
+}
+
+// Share è la quota del fatturato dell'anno precedente già realizzata alla data corrispondente
+func (s BudgetSeasonality) Share() float64 {
+	if s.RevenueTotal <= 0 || s.RevenueToDate <= 0 {
+		return 0
+	}
+	return math.Min(s.RevenueToDate/s.RevenueTotal, 1)
+}
+
+type BudgetForecast struct {
+	Date   time.Time
+	Method ForecastMethod
+
+	ElapsedDays   float64
+	RemainingDays float64
+
+	RunRatePerDay    float64
+	SeasonalShare    float64
+	ProjectedRevenue float64
+	ProjectedMargin  float64
+	ProjectedOrders  int
+
+	ProjectedAchievement         float64
+	ProjectedWeightedAchievement float64
+
+	// scaglione raggiunto con i consuntivi proiettati e prossimo scaglione; il gap è nell'unità dello scaglione
+	ExpectedIncentive       *BudgetIncentive
+	ExpectedIncentiveAmount float64
+	NextIncentive           *BudgetIncentive
+	GapToNextTier           float64
+
+	// fatturato giornaliero necessario da qui a fine periodo
+	NeededPerDay         float64
+	NeededPerDayNextTier float64
+}
+
+// Forecast proietta i consuntivi a fine periodo. Con uno storico dell'anno precedente significativo
+// il fatturato si proietta in base alla quota stagionale già realizzata, altrimenti sul run-rate giornaliero
+func (b *Budget) Forecast(now time.Time, seasonality BudgetSeasonality) *BudgetForecast {
+	total := b.EndDate.Sub(b.StartDate).Hours() / 24
+	elapsed := math.Max(0, math.Min(now.Sub(b.StartDate).Hours()/24, total))
+
+	f := &BudgetForecast{
+		Date:          now,
+		Method:        ForecastMethodNone,
+		ElapsedDays:   elapsed,
+		RemainingDays: total - elapsed,
+	}
+
+	if elapsed > 0 {
+		f.RunRatePerDay = roundCents(b.ActualRevenue / elapsed)
+	}
+
+	factor := 0.0
+	switch {
+	case elapsed >= total:
+		f.Method = ForecastMethodFinal
+		factor = 1
+	case seasonality.Share() >= minSeasonalShare:
+		f.Method = ForecastMethodSeasonal
+		f.SeasonalShare = seasonality.Share()
+		factor = 1 / f.SeasonalShare
+	case elapsed > 0:
+		f.Method = ForecastMethodRunRate
+		factor = total / elapsed
+	}
+
+	f.ProjectedRevenue = roundCents(b.ActualRevenue * factor)
+	f.ProjectedMargin = roundCents(b.ActualMargin * factor)
+	f.ProjectedOrders = int(math.Round(float64(b.ActualOrders) * factor))
+
+	if b.TargetRevenue > 0 {
+		f.ProjectedAchievement = f.ProjectedRevenue / b.TargetRevenue * 100
+	}
+
+	projected := b.projected(factor)
+	f.ProjectedWeightedAchievement = projected.GetOverallAchievement()
+
+	f.ExpectedIncentive, f.NextIncentive = projected.incentiveTiers()
+	if f.ExpectedIncentive != nil {
+		f.ExpectedIncentiveAmount = f.ExpectedIncentive.Amount(f.ProjectedRevenue)
+	}
+	if f.NextIncentive != nil {
+		f.GapToNextTier = roundCents(f.NextIncentive.MinRevenue - projected.tierValue(f.NextIncentive))
+	}
+
+	if f.RemainingDays > 0 {
+		days := math.Ceil(f.RemainingDays)
+		f.NeededPerDay = roundCents(b.GetRemainingRevenue() / days)
+		if f.NextIncentive != nil && f.NextIncentive.Basis != IncentiveBasisWeighted {
+			f.NeededPerDayNextTier = roundCents(math.Max(0, f.NextIncentive.MinRevenue-b.ActualRevenue) / days)
+		}
+	}
+
+	return f
+}
+
+// projected restituisce una copia del budget con i consuntivi scalati a fine periodo
+func (b *Budget) projected(factor float64) *Budget {
+	p := *b
+	p.ActualRevenue = roundCents(b.ActualRevenue * factor)
+	p.ActualMargin = roundCents(b.ActualMargin * factor)
+	p.ActualOrders = int(math.Round(float64(b.ActualOrders) * factor))
+	p.FamilyActuals = make(map[string]float64, len(b.FamilyActuals))
+	for family, revenue := range b.FamilyActuals {
+		p.FamilyActuals[family] = roundCents(revenue * factor)
+	}
+	return &p
+}
+
+// LastYear restituisce lo stesso budget spostato all'anno precedente, per confrontarne i documenti
+func (b *Budget) LastYear() *Budget {
+	last := *b
+	last.Year--
+	last.StartDate = b.StartDate.AddDate(-1, 0, 0)
+	last.EndDate = b.EndDate.AddDate(-1, 0, 0)
+	return &last
+}
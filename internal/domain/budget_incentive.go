@@ -0,0 +1,268 @@
+// internal/domain/budget_incentive.go
+
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type BudgetKPI string
+
+const (
+	BudgetKPIRevenue BudgetKPI = "revenue"
+	BudgetKPIMargin  BudgetKPI = "margin"
+	BudgetKPIOrders  BudgetKPI = "orders"
+	BudgetKPIFamily  BudgetKPI = "family"
+)
+
+type IncentiveBasis string
+
+const (
+	IncentiveBasisRevenue  IncentiveBasis = "revenue"
+	IncentiveBasisWeighted IncentiveBasis = "weighted"
+)
+
+// BudgetWeights sono i pesi dei KPI nel raggiungimento complessivo; vengono normalizzati sulla somma
+type BudgetWeights struct {
+	Revenue  float64            `bson:"revenue" json:"revenue"`
+	Margin   float64            `bson:"margin" json:"margin"`
+	Orders   float64            `bson:"orders" json:"orders"`
+	Families map[string]float64 `bson:"families,omitempty" json:"families,omitempty"`
+}
+
+func (w BudgetWeights) total() float64 {
+	total := w.Revenue + w.Margin + w.Orders
+	for _, weight := range w.Families {
+		total += weight
+	}
+	return total
+}
+
+// BudgetGate è una condizione di accesso all'incentivo, es. margine raggiunto almeno al 90%
+type BudgetGate struct {
+	KPI            BudgetKPI `bson:"kpi" json:"kpi"`
+	Family         string    `bson:"family,omitempty" json:"family,omitempty"`
+	MinAchievement float64   `bson:"min_achievement" json:"min_achievement"`
+}
+
+func (g BudgetGate) Label() string {
+	if g.KPI == BudgetKPIFamily {
+		return fmt.Sprintf("famiglia %s >= %.0f%%", g.Family, g.MinAchievement)
+	}
+	return fmt.Sprintf("%s >= %.0f%%", g.KPI, g.MinAchievement)
+}
+
+func (g BudgetGate) Validate() error {
+	switch g.KPI {
+	case BudgetKPIRevenue, BudgetKPIMargin, BudgetKPIOrders:
+	case BudgetKPIFamily:
+		if g.Family == "" {
+			return ErrInvalidIncentiveScheme
+		}
+	default:
+		return ErrInvalidIncentiveScheme
+	}
+	if g.MinAchievement < 0 {
+		return ErrInvalidIncentiveScheme
+	}
+	return nil
+}
+
+type IncentiveScheme struct {
+	Weights BudgetWeights `bson:"weights" json:"weights"`
+	Gates   []BudgetGate  `bson:"gates" json:"gates"`
+}
+
+// DefaultIncentiveScheme pesa allo stesso modo fatturato, margine e ordini, senza soglie
+func DefaultIncentiveScheme() IncentiveScheme {
+	return IncentiveScheme{Weights: BudgetWeights{Revenue: 1, Margin: 1, Orders: 1}}
+}
+
+func (s IncentiveScheme) Validate() error {
+	if s.Weights.Revenue < 0 || s.Weights.Margin < 0 || s.Weights.Orders < 0 || s.Weights.total() <= 0 {
+		return ErrInvalidIncentiveScheme
+	}
+	for _, weight := range s.Weights.Families {
+		if weight < 0 {
+			return ErrInvalidIncentiveScheme
+		}
+	}
+	for _, gate := range s.Gates {
+		if err := gate.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FamilyKey normalizza il nome famiglia per usarlo come chiave dei sotto-obiettivi
+func FamilyKey(family string) string {
+	return strings.NewReplacer(".", "_", "$", "_").Replace(strings.ToUpper(strings.TrimSpace(family)))
+}
+
+func (b *Budget) SetFamilyTarget(family string, targetRevenue float64) error {
+	if targetRevenue < 0 || strings.TrimSpace(family) == "" {
+		return ErrInvalidFamilyTarget
+	}
+	if b.FamilyTargets == nil {
+		b.FamilyTargets = map[string]float64{}
+	}
+	b.FamilyTargets[FamilyKey(family)] = targetRevenue
+	return nil
+}
+
+func (b *Budget) GetFamilyAchievement(family string) float64 {
+	key := FamilyKey(family)
+	target := b.FamilyTargets[key]
+	if target == 0 {
+		return 0
+	}
+	return b.FamilyActuals[key] / target * 100
+}
+
+func (b *Budget) kpiAchievement(kpi BudgetKPI, family string) float64 {
+	switch kpi {
+	case BudgetKPIRevenue:
+		return b.GetRevenueAchievement()
+	case BudgetKPIMargin:
+		return b.GetMarginAchievement()
+	case BudgetKPIOrders:
+		return b.GetOrdersAchievement()
+	case BudgetKPIFamily:
+		return b.GetFamilyAchievement(family)
+	default:
+		return 0
+	}
+}
+
+func (b *Budget) weightedAchievement(weights BudgetWeights) float64 {
+	total := weights.total()
+	if total <= 0 {
+		weights = DefaultIncentiveScheme().Weights
+		total = weights.total()
+	}
+
+	sum := weights.Revenue*b.GetRevenueAchievement() +
+		weights.Margin*b.GetMarginAchievement() +
+		weights.Orders*b.GetOrdersAchievement()
+	for family, weight := range weights.Families {
+		sum += weight * b.GetFamilyAchievement(family)
+	}
+
+	return sum / total
+}
+
+// tierValue è il valore su cui si misura lo scaglione: fatturato o raggiungimento ponderato
+func (b *Budget) tierValue(inc *BudgetIncentive) float64 {
+	if inc.Basis == IncentiveBasisWeighted {
+		return b.GetOverallAchievement()
+	}
+	return b.ActualRevenue
+}
+
+// incentiveTiers restituisce lo scaglione raggiunto e il primo scaglione superiore ancora da raggiungere
+func (b *Budget) incentiveTiers() (current, next *BudgetIncentive) {
+	for i := range b.Incentives {
+		inc := &b.Incentives[i]
+		value := b.tierValue(inc)
+		if value >= inc.MinRevenue && value < inc.MaxRevenue {
+			current = inc
+			continue
+		}
+		if inc.MinRevenue > value && (next == nil || inc.MinRevenue < next.MinRevenue) {
+			next = inc
+		}
+	}
+	return current, next
+}
+
+// Amount è l'incentivo dello scaglione per il fatturato dato: importo fisso se previsto, altrimenti percentuale
+func (i *BudgetIncentive) Amount(revenue float64) float64 {
+	if i.IncentiveAmount > 0 {
+		return i.IncentiveAmount
+	}
+	return roundCents(revenue * (i.IncentivePercent / 100))
+}
+
+type KPIResult struct {
+	KPI         BudgetKPI
+	Family      string
+	Target      float64
+	Actual      float64
+	Achievement float64
+	Weight      float64
+}
+
+type GateResult struct {
+	Gate        BudgetGate
+	Achievement float64
+	Passed      bool
+}
+
+// IncentiveEvaluation è il prospetto di calcolo dell'incentivo di un budget
+type IncentiveEvaluation struct {
+	KPIs                []KPIResult
+	WeightedAchievement float64
+	Gates               []GateResult
+	GatesPassed         bool
+	Tier                *BudgetIncentive
+	Amount              float64
+}
+
+// EvaluateIncentive calcola raggiungimento ponderato, soglie e incentivo con lo schema del budget
+func (b *Budget) EvaluateIncentive() *IncentiveEvaluation {
+	scheme := DefaultIncentiveScheme()
+	if b.Scheme != nil {
+		scheme = *b.Scheme
+	}
+
+	total := scheme.Weights.total()
+	if total <= 0 {
+		total = 1
+	}
+
+	e := &IncentiveEvaluation{
+		KPIs: []KPIResult{
+			{KPI: BudgetKPIRevenue, Target: b.TargetRevenue, Actual: b.ActualRevenue,
+				Achievement: b.GetRevenueAchievement(), Weight: scheme.Weights.Revenue / total},
+			{KPI: BudgetKPIMargin, Target: b.TargetMargin, Actual: b.ActualMargin,
+				Achievement: b.GetMarginAchievement(), Weight: scheme.Weights.Margin / total},
+			{KPI: BudgetKPIOrders, Target: float64(b.TargetOrders), Actual: float64(b.ActualOrders),
+				Achievement: b.GetOrdersAchievement(), Weight: scheme.Weights.Orders / total},
+		},
+		WeightedAchievement: b.GetOverallAchievement(),
+		GatesPassed:         true,
+	}
+
+	for family, target := range b.FamilyTargets {
+		e.KPIs = append(e.KPIs, KPIResult{
+			KPI:         BudgetKPIFamily,
+			Family:      family,
+			Target:      target,
+			Actual:      b.FamilyActuals[family],
+			Achievement: b.GetFamilyAchievement(family),
+			Weight:      scheme.Weights.Families[family] / total,
+		})
+	}
+	// i KPI principali restano in testa, le famiglie in ordine di nome
+	families := e.KPIs[3:]
+	sort.Slice(families, func(i, j int) bool { return families[i].Family < families[j].Family })
+
+	for _, gate := range scheme.Gates {
+		achievement := b.kpiAchievement(gate.KPI, gate.Family)
+		passed := achievement >= gate.MinAchievement
+		e.Gates = append(e.Gates, GateResult{Gate: gate, Achievement: achievement, Passed: passed})
+		if !passed {
+			e.GatesPassed = false
+		}
+	}
+
+	e.Tier, _ = b.incentiveTiers()
+	if e.Tier != nil && e.GatesPassed {
+		e.Amount = e.Tier.Amount(b.ActualRevenue)
+	}
+
+	return e
+}
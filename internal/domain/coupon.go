@@ -0,0 +1,264 @@
+// internal/domain/coupon.go
+
+package domain
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrCouponNotFound     = errors.New("coupon not found")
+	ErrCouponAlreadyUsed  = errors.New("coupon already used")
+	ErrCouponExpired      = errors.New("coupon expired")
+	ErrCouponNotActive    = errors.New("coupon not active")
+	ErrCouponWrongOwner   = errors.New("coupon is bound to another customer")
+	ErrInvalidCouponBatch = errors.New("invalid coupon batch")
+)
+
+type CouponUsage string
+
+const (
+	CouponSingleUse CouponUsage = "single_use"
+	CouponMultiUse  CouponUsage = "multi_use"
+)
+
+// senza caratteri ambigui (0/O, 1/I/L)
+const couponAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+const couponCodeLength = 10
+
+type CouponBatch struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromotionID    primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	PromotionCode  string             `bson:"promotion_code" json:"promotion_code"`
+	Name           string             `bson:"name" json:"name"`
+	Prefix         string             `bson:"prefix" json:"prefix"`
+	Quantity       int                `bson:"quantity" json:"quantity"`
+	Usage          CouponUsage        `bson:"usage" json:"usage"`
+	MaxRedemptions int                `bson:"max_redemptions" json:"max_redemptions"`
+	CustomerID     primitive.ObjectID `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	ValidTo        time.Time          `bson:"valid_to" json:"valid_to"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+}
+
+type Coupon struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code            string             `bson:"code" json:"code"`
+	BatchID         primitive.ObjectID `bson:"batch_id,omitempty" json:"batch_id,omitempty"`
+	PromotionID     primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	PromotionCode   string             `bson:"promotion_code" json:"promotion_code"`
+	Usage           CouponUsage        `bson:"usage" json:"usage"`
+	MaxRedemptions  int                `bson:"max_redemptions" json:"max_redemptions"`
+	RedemptionCount int                `bson:"redemption_count" json:"redemption_count"`
+	Redemptions     []CouponRedemption `bson:"redemptions" json:"redemptions"`
+	CustomerID      primitive.ObjectID `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	ValidTo         time.Time          `bson:"valid_to" json:"valid_to"`
+	IsActive        bool               `bson:"is_active" json:"is_active"`
+	IsStatic        bool               `bson:"-" json:"is_static"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CouponRedemption struct {
+	DocumentID     primitive.ObjectID `bson:"document_id" json:"document_id"`
+	DocumentNumber string             `bson:"document_number" json:"document_number"`
+	CustomerID     primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Discount       float64            `bson:"discount" json:"discount"`
+	RedeemedAt     time.Time          `bson:"redeemed_at" json:"redeemed_at"`
+	RedeemedBy     string             `bson:"redeemed_by" json:"redeemed_by"`
+}
+
+func NewCouponBatch(promo *Promotion, name, prefix string, quantity int, usage CouponUsage, maxRedemptions int, createdBy string) (*CouponBatch, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidCouponBatch
+	}
+
+	switch usage {
+	case CouponSingleUse:
+		maxRedemptions = 1
+	case CouponMultiUse:
+		if maxRedemptions < 0 {
+			return nil, ErrInvalidCouponBatch
+		}
+	default:
+		return nil, ErrInvalidCouponBatch
+	}
+
+	return &CouponBatch{
+		ID:             primitive.NewObjectID(),
+		PromotionID:    promo.ID,
+		PromotionCode:  promo.Code,
+		Name:           strings.TrimSpace(name),
+		Prefix:         strings.ToUpper(strings.TrimSpace(prefix)),
+		Quantity:       quantity,
+		Usage:          usage,
+		MaxRedemptions: maxRedemptions,
+		ValidTo:        promo.ValidTo,
+		CreatedAt:      time.Now(),
+		CreatedBy:      createdBy,
+	}, nil
+}
+
+func (b *CouponBatch) BindToCustomer(customerID primitive.ObjectID) {
+	b.CustomerID = customerID
+}
+
+func (b *CouponBatch) GenerateCoupons() ([]*Coupon, error) {
+	now := time.Now()
+	seen := make(map[string]bool, b.Quantity)
+	coupons := make([]*Coupon, 0, b.Quantity)
+
+	for len(coupons) < b.Quantity {
+		code, err := GenerateCouponCode(b.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		coupons = append(coupons, &Coupon{
+			ID:             primitive.NewObjectID(),
+			Code:           code,
+			BatchID:        b.ID,
+			PromotionID:    b.PromotionID,
+			PromotionCode:  b.PromotionCode,
+			Usage:          b.Usage,
+			MaxRedemptions: b.MaxRedemptions,
+			Redemptions:    []CouponRedemption{},
+			CustomerID:     b.CustomerID,
+			ValidTo:        b.ValidTo,
+			IsActive:       true,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	return coupons, nil
+}
+
+// RegenerateCodes assegna un nuovo codice ai coupon il cui codice è già in uso
+func (b *CouponBatch) RegenerateCodes(coupons []*Coupon, taken map[string]bool) error {
+	seen := make(map[string]bool, len(coupons))
+	for _, coupon := range coupons {
+		seen[coupon.Code] = true
+	}
+
+	for _, coupon := range coupons {
+		if !taken[coupon.Code] {
+			continue
+		}
+		for {
+			code, err := GenerateCouponCode(b.Prefix)
+			if err != nil {
+				return err
+			}
+			if seen[code] || taken[code] {
+				continue
+			}
+			seen[code] = true
+			coupon.Code = code
+			break
+		}
+	}
+
+	return nil
+}
+
+func GenerateCouponCode(prefix string) (string, error) {
+	max := big.NewInt(int64(len(couponAlphabet)))
+
+	var sb strings.Builder
+	if prefix != "" {
+		sb.WriteString(prefix)
+		sb.WriteString("-")
+	}
+
+	for i := 0; i < couponCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(couponAlphabet[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// StaticCoupon rappresenta il vecchio codice unico PromotionConditions.CouponCode, riutilizzabile senza limiti
+func StaticCoupon(promo *Promotion) *Coupon {
+	return &Coupon{
+		Code:          strings.ToUpper(strings.TrimSpace(promo.Conditions.CouponCode)),
+		PromotionID:   promo.ID,
+		PromotionCode: promo.Code,
+		Usage:         CouponMultiUse,
+		ValidTo:       promo.ValidTo,
+		IsActive:      true,
+		IsStatic:      true,
+	}
+}
+
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *Coupon) IsExhausted() bool {
+	return c.MaxRedemptions > 0 && c.RedemptionCount >= c.MaxRedemptions
+}
+
+func (c *Coupon) CanBeRedeemedBy(customerID primitive.ObjectID, now time.Time) error {
+	if !c.IsActive {
+		return ErrCouponNotActive
+	}
+	if !c.ValidTo.IsZero() && now.After(c.ValidTo) {
+		return ErrCouponExpired
+	}
+	if c.IsExhausted() {
+		return ErrCouponAlreadyUsed
+	}
+	if !c.CustomerID.IsZero() && c.CustomerID != customerID {
+		return ErrCouponWrongOwner
+	}
+	return nil
+}
+
+func (c *Coupon) IsForPromotion(promo *Promotion) bool {
+	return c.PromotionID == promo.ID
+}
+
+func (c *Coupon) Redeem(redemption CouponRedemption) error {
+	if err := c.CanBeRedeemedBy(redemption.CustomerID, redemption.RedeemedAt); err != nil {
+		return err
+	}
+
+	c.Redemptions = append(c.Redemptions, redemption)
+	c.RedemptionCount++
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// AsOf restituisce il coupon com'era alla data, contando solo i riscatti precedenti
+func (c *Coupon) AsOf(date time.Time) *Coupon {
+	past := *c
+	past.Redemptions = nil
+	for _, redemption := range c.Redemptions {
+		if redemption.RedeemedAt.Before(date) {
+			past.Redemptions = append(past.Redemptions, redemption)
+		}
+	}
+	past.RedemptionCount = len(past.Redemptions)
+	return &past
+}
+
+func (c *Coupon) Deactivate() {
+	c.IsActive = false
+	c.UpdatedAt = time.Now()
+}
@@ -3,8 +3,11 @@
 package domain
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -48,12 +51,23 @@ type CreditVoucher struct {
 }
 
 var (
-	ErrVoucherNotFound      = errors.New("credit voucher not found")
-	ErrVoucherExpired       = errors.New("credit voucher has expired")
-	ErrVoucherUsed          = errors.New("credit voucher is already fully used")
-	ErrVoucherCancelled     = errors.New("credit voucher has been cancelled")
-	ErrInsufficientBalance  = errors.New("insufficient voucher balance")
-	ErrInvalidVoucherAmount = errors.New("invalid voucher amount")
+	ErrVoucherNotFound         = errors.New("credit voucher not found")
+	ErrVoucherExpired          = errors.New("credit voucher has expired")
+	ErrVoucherUsed             = errors.New("credit voucher is already fully used")
+	ErrVoucherCancelled        = errors.New("credit voucher has been cancelled")
+	ErrInsufficientBalance     = errors.New("insufficient voucher balance")
+	ErrInvalidVoucherAmount    = errors.New("invalid voucher amount")
+	ErrVoucherNotUsed          = errors.New("credit voucher was not used by this document")
+	ErrInvalidVoucherCode      = errors.New("invalid credit voucher code")
+	ErrDuplicateVoucherCode    = errors.New("credit voucher code already exists")
+	ErrInvalidVoucherExtension = errors.New("credit voucher cannot be extended")
+)
+
+// i codici hanno la forma VC-XXXX-XXXX-C, con l'alfabeto dei coupon e un carattere di controllo finale
+const (
+	voucherCodePrefix    = "VC"
+	voucherCodeGroups    = 2
+	voucherCodeGroupSize = 4
 )
 
 func NewCreditVoucher(
@@ -67,7 +81,11 @@ func NewCreditVoucher(
 		return nil, ErrInvalidVoucherAmount
 	}
 
-	code := generateVoucherCode()
+	code, err := GenerateVoucherCode()
+	if err != nil {
+		return nil, err
+	}
+
 	issuedDate := time.Now()
 	var expiryDate time.Time
 	if expiryDays > 0 {
@@ -93,9 +111,118 @@ func NewCreditVoucher(
 	return voucher, nil
 }
 
-func generateVoucherCode() string {
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("VC-%d", timestamp)
+// GenerateVoucherCode genera un codice casuale non prevedibile; l'unicità è garantita dall'indice
+// sul repository, che in caso di collisione ne chiede uno nuovo
+func GenerateVoucherCode() (string, error) {
+	max := big.NewInt(int64(len(couponAlphabet)))
+
+	body := make([]byte, 0, voucherCodeGroups*voucherCodeGroupSize)
+	for i := 0; i < cap(body); i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		body = append(body, couponAlphabet[n.Int64()])
+	}
+
+	return formatVoucherCode(string(body)), nil
+}
+
+func formatVoucherCode(body string) string {
+	var sb strings.Builder
+	sb.WriteString(voucherCodePrefix)
+	for i := 0; i < len(body); i += voucherCodeGroupSize {
+		sb.WriteString("-")
+		sb.WriteString(body[i : i+voucherCodeGroupSize])
+	}
+	sb.WriteString("-")
+	sb.WriteByte(voucherCheckChar(body))
+	return sb.String()
+}
+
+// voucherCheckChar calcola il carattere di controllo con l'algoritmo di Luhn mod N sull'alfabeto dei
+// coupon: intercetta gli errori su un singolo carattere e quasi tutti gli scambi tra caratteri adiacenti
+func voucherCheckChar(body string) byte {
+	n := len(couponAlphabet)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(couponAlphabet, body[i])
+		addend = addend/n + addend%n
+		sum += addend
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return couponAlphabet[(n-sum%n)%n]
+}
+
+// NormalizeVoucherCode porta in maiuscolo il codice letto o digitato e ricompone i trattini,
+// così un codice inserito senza separatori viene comunque trovato
+func NormalizeVoucherCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	compact := strings.NewReplacer("-", "", " ", "").Replace(code)
+	size := len(voucherCodePrefix) + voucherCodeGroups*voucherCodeGroupSize + 1
+	if len(compact) != size || !strings.HasPrefix(compact, voucherCodePrefix) || isLegacyVoucherCode(code) {
+		return code
+	}
+
+	// il carattere di controllo letto resta quello originale, così ValidateVoucherCode lo può verificare
+	var sb strings.Builder
+	sb.WriteString(voucherCodePrefix)
+	for i := len(voucherCodePrefix); i < size-1; i += voucherCodeGroupSize {
+		sb.WriteString("-")
+		sb.WriteString(compact[i : i+voucherCodeGroupSize])
+	}
+	sb.WriteString("-")
+	sb.WriteString(compact[size-1:])
+	return sb.String()
+}
+
+// ValidateVoucherCode verifica formato e carattere di controllo prima di cercare il buono. I codici
+// VC-<timestamp> emessi in passato non hanno controllo e restano validi
+func ValidateVoucherCode(code string) error {
+	code = NormalizeVoucherCode(code)
+	if isLegacyVoucherCode(code) {
+		return nil
+	}
+
+	parts := strings.Split(code, "-")
+	if len(parts) != voucherCodeGroups+2 || parts[0] != voucherCodePrefix || len(parts[len(parts)-1]) != 1 {
+		return ErrInvalidVoucherCode
+	}
+
+	body := strings.Join(parts[1:len(parts)-1], "")
+	if len(body) != voucherCodeGroups*voucherCodeGroupSize {
+		return ErrInvalidVoucherCode
+	}
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(couponAlphabet, body[i]) < 0 {
+			return ErrInvalidVoucherCode
+		}
+	}
+
+	if parts[len(parts)-1][0] != voucherCheckChar(body) {
+		return ErrInvalidVoucherCode
+	}
+
+	return nil
+}
+
+func isLegacyVoucherCode(code string) bool {
+	digits := strings.TrimPrefix(code, voucherCodePrefix+"-")
+	if digits == code || digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (v *CreditVoucher) Use(amount float64, documentID, documentType, usedBy, notes string) error {
@@ -152,6 +279,53 @@ func (v *CreditVoucher) Use(amount float64, documentID, documentType, usedBy, no
 	return nil
 }
 
+// UsedByDocument è l'importo netto usato dal documento, al netto di eventuali riaccrediti
+func (v *CreditVoucher) UsedByDocument(documentID string) float64 {
+	total := 0.0
+	for _, usage := range v.UsageHistory {
+		if usage.DocumentID == documentID {
+			total += usage.Amount
+		}
+	}
+	return roundCents(total)
+}
+
+// Restore riaccredita quanto usato dal documento stornato, con un utilizzo di segno opposto collegato
+func (v *CreditVoucher) Restore(documentID, documentType, restoredBy, notes string) (float64, error) {
+	amount := v.UsedByDocument(documentID)
+	if amount <= 0 {
+		return 0, ErrVoucherNotUsed
+	}
+
+	timestamp := time.Now()
+	v.UsageHistory = append(v.UsageHistory, VoucherUsage{
+		ID:           primitive.NewObjectID(),
+		DocumentID:   documentID,
+		DocumentType: documentType,
+		Amount:       -amount,
+		UsedBy:       restoredBy,
+		UsedAt:       timestamp,
+		Notes:        notes,
+	})
+	v.RemainingAmount = roundCents(v.RemainingAmount + amount)
+
+	// un buono annullato resta annullato, ma il saldo torna coerente con lo storico
+	if v.Status != VoucherStatusCancelled {
+		switch {
+		case v.IsExpired():
+			v.Status = VoucherStatusExpired
+		case v.RemainingAmount >= v.OriginalAmount:
+			v.Status = VoucherStatusIssued
+		default:
+			v.Status = VoucherStatusPartiallyUsed
+		}
+	}
+
+	v.UpdatedAt = timestamp
+
+	return amount, nil
+}
+
 func (v *CreditVoucher) Cancel(reason string) error {
 	if v.Status == VoucherStatusUsed {
 		return errors.New("cannot cancel a fully used voucher")
@@ -168,6 +342,21 @@ func (v *CreditVoucher) Cancel(reason string) error {
 	return nil
 }
 
+// Expire segna come scaduto un buono con saldo residuo oltre la data di scadenza; i buoni annullati
+// o esauriti restano come sono
+func (v *CreditVoucher) Expire(now time.Time) bool {
+	if v.ExpiryDate.IsZero() || !now.After(v.ExpiryDate) {
+		return false
+	}
+	if v.Status == VoucherStatusCancelled || v.Status == VoucherStatusUsed || v.Status == VoucherStatusExpired {
+		return false
+	}
+
+	v.Status = VoucherStatusExpired
+	v.UpdatedAt = now
+	return true
+}
+
 func (v *CreditVoucher) IsExpired() bool {
 	if v.ExpiryDate.IsZero() {
 		return false
@@ -4,6 +4,7 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -226,8 +227,8 @@ func (c *Customer) UnblockSales() {
 }
 
 func (c *Customer) AddDiscountRule(rule DiscountRule) error {
-	if rule.DiscountPercent < 0 || rule.DiscountPercent > 100 {
-		return ErrInvalidDiscountRule
+	if err := rule.Validate(); err != nil {
+		return err
 	}
 
 	rule.ID = primitive.NewObjectID()
@@ -249,72 +250,86 @@ func (c *Customer) RemoveDiscountRule(ruleID primitive.ObjectID) {
 }
 
 func (c *Customer) GetApplicableDiscount(article *Article, quantity float64) *DiscountRule {
-	now := time.Now()
+	return c.GetApplicableDiscountAt(article, quantity, time.Now())
+}
+
+func (c *Customer) GetApplicableDiscountAt(article *Article, quantity float64, date time.Time) *DiscountRule {
 	var bestRule *DiscountRule
 
 	for i := range c.DiscountGrid {
 		rule := &c.DiscountGrid[i]
 
-		if !rule.IsActive {
+		if rule.RejectionReason(article, quantity, date) != "" {
 			continue
 		}
 
-		if !rule.ValidFrom.IsZero() && now.Before(rule.ValidFrom) {
-			continue
+		if bestRule == nil || rule.Priority > bestRule.Priority {
+			bestRule = rule
 		}
+	}
 
-		if !rule.ValidTo.IsZero() && now.After(rule.ValidTo) {
-			continue
-		}
+	return bestRule
+}
 
-		if rule.MinQuantity > 0 && quantity < rule.MinQuantity {
-			continue
-		}
+// RejectionReason spiega perché la regola non si applica, stringa vuota se si applica
+func (r *DiscountRule) RejectionReason(article *Article, quantity float64, date time.Time) string {
+	if !r.IsActive {
+		return "rule not active"
+	}
 
-		matches := false
-
-		if rule.ArticleCode != "" && rule.ArticleCode == article.Code {
-			matches = true
-		} else if rule.Precodice != "" && rule.Precodice == article.Precodice {
-			matches = true
-		} else if rule.Family != "" && rule.Family == article.Family {
-			matches = true
-		} else if rule.Classification != "" {
-			for _, cls := range article.Classification {
-				if cls == rule.Classification {
-					matches = true
-					break
-				}
-			}
-		}
+	if !r.ValidFrom.IsZero() && date.Before(r.ValidFrom) {
+		return "not yet valid, starts " + r.ValidFrom.Format("02/01/2006")
+	}
 
-		if matches {
-			if bestRule == nil || rule.Priority > bestRule.Priority {
-				bestRule = rule
-			}
-		}
+	if !r.ValidTo.IsZero() && date.After(r.ValidTo) {
+		return "expired on " + r.ValidTo.Format("02/01/2006")
 	}
 
-	return bestRule
-}
+	if r.MinQuantity > 0 && quantity < r.MinQuantity {
+		return fmt.Sprintf("minimum quantity %.0f not met", r.MinQuantity)
+	}
 
-func (c *Customer) CalculateFinalPrice(article *Article, quantity float64, basePrice float64) float64 {
-	rule := c.GetApplicableDiscount(article, quantity)
-	if rule == nil {
-		return basePrice
+	if !r.MatchesArticle(article) {
+		return "does not match article"
 	}
 
-	finalPrice := basePrice
+	return ""
+}
 
-	if len(rule.DiscountCascade) > 0 {
-		for _, discount := range rule.DiscountCascade {
-			finalPrice = finalPrice * (1 - discount/100)
+func (r *DiscountRule) MatchesArticle(article *Article) bool {
+	if r.ArticleCode != "" && r.ArticleCode == article.Code {
+		return true
+	} else if r.Precodice != "" && r.Precodice == article.Precodice {
+		return true
+	} else if r.Family != "" && r.Family == article.Family {
+		return true
+	} else if r.Classification != "" {
+		for _, cls := range article.Classification {
+			if cls == r.Classification {
+				return true
+			}
 		}
-	} else {
-		finalPrice = basePrice * (1 - rule.DiscountPercent/100)
 	}
+	return false
+}
+
+func (c *Customer) CalculateFinalPrice(article *Article, quantity float64, basePrice float64) float64 {
+	breakdown := NewPriceBreakdown(basePrice)
+	breakdown.ApplyDiscountRule(c.GetApplicableDiscount(article, quantity))
+	return breakdown.FinalPrice
+}
+
+func (c *Customer) AssignPriceList(code string) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		code = PriceListStandard
+	}
+	c.PriceList = code
+	c.UpdatedAt = time.Now()
+}
 
-	return finalPrice
+func (c *Customer) HasCustomPriceList() bool {
+	return c.PriceList != "" && c.PriceList != PriceListStandard
 }
 
 func (c *Customer) GetAvailableFido() float64 {
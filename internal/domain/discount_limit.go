@@ -0,0 +1,221 @@
+// internal/domain/discount_limit.go
+
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrDiscountLimitNotFound = errors.New("discount limit not found")
+	ErrInvalidDiscountLimit  = errors.New("invalid discount limit")
+	ErrDiscountNotAuthorized = errors.New("discount exceeds authorized limit")
+	ErrInvalidDiscount       = errors.New("invalid discount percentage")
+)
+
+// DiscountLimit fissa lo sconto massimo concedibile a mano e il margine minimo da preservare,
+// per profilo o per singolo operatore, eventualmente ristretto a una famiglia o a una marca
+type DiscountLimit struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Profile            ProfileType        `bson:"profile,omitempty" json:"profile,omitempty"`
+	OperatorID         primitive.ObjectID `bson:"operator_id,omitempty" json:"operator_id,omitempty"`
+	Username           string             `bson:"username,omitempty" json:"username,omitempty"`
+	Family             string             `bson:"family,omitempty" json:"family,omitempty"`
+	Brand              string             `bson:"brand,omitempty" json:"brand,omitempty"`
+	MaxDiscountPercent float64            `bson:"max_discount_percent" json:"max_discount_percent"`
+	MinMarginPercent   *float64           `bson:"min_margin_percent,omitempty" json:"min_margin_percent,omitempty"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+	UpdatedBy          string             `bson:"updated_by" json:"updated_by"`
+}
+
+func (l *DiscountLimit) Validate() error {
+	if (l.Profile == "") == !l.IsOperatorLimit() {
+		return fmt.Errorf("%w: set either a profile or an operator", ErrInvalidDiscountLimit)
+	}
+	if l.MaxDiscountPercent < 0 || l.MaxDiscountPercent > 100 {
+		return fmt.Errorf("%w: max discount must be between 0 and 100", ErrInvalidDiscountLimit)
+	}
+	if l.MinMarginPercent != nil && *l.MinMarginPercent >= 100 {
+		return fmt.Errorf("%w: min margin must be below 100", ErrInvalidDiscountLimit)
+	}
+	return nil
+}
+
+func (l *DiscountLimit) IsOperatorLimit() bool {
+	return !l.OperatorID.IsZero() || l.Username != ""
+}
+
+func (l *DiscountLimit) Matches(operator *Operator, family, brand string) bool {
+	if l.IsOperatorLimit() {
+		if !l.OperatorID.IsZero() && l.OperatorID != operator.ID {
+			return false
+		}
+		if l.Username != "" && !strings.EqualFold(l.Username, operator.Username) {
+			return false
+		}
+	} else if l.Profile != operator.Profile {
+		return false
+	}
+
+	if l.Family != "" && !strings.EqualFold(l.Family, family) {
+		return false
+	}
+	if l.Brand != "" && !strings.EqualFold(l.Brand, brand) {
+		return false
+	}
+	return true
+}
+
+// specificity: il limite del singolo operatore prevale su quello di profilo, famiglia e marca affinano
+func (l *DiscountLimit) specificity() int {
+	score := 0
+	if l.IsOperatorLimit() {
+		score += 4
+	}
+	if l.Family != "" {
+		score += 2
+	}
+	if l.Brand != "" {
+		score++
+	}
+	return score
+}
+
+func (l *DiscountLimit) Scope() string {
+	var parts []string
+	if l.IsOperatorLimit() {
+		name := l.Username
+		if name == "" {
+			name = l.OperatorID.Hex()
+		}
+		parts = append(parts, "operator "+name)
+	} else {
+		parts = append(parts, "profile "+string(l.Profile))
+	}
+	if l.Family != "" {
+		parts = append(parts, "family "+l.Family)
+	}
+	if l.Brand != "" {
+		parts = append(parts, "brand "+l.Brand)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type DiscountLimits []DiscountLimit
+
+// DefaultDiscountLimits riproduce la regola storica: l'admin non ha limiti, il venditore arriva al 20%,
+// magazzino e contabilità non concedono sconti
+func DefaultDiscountLimits() DiscountLimits {
+	return DiscountLimits{
+		{Profile: ProfileAdmin, MaxDiscountPercent: 100},
+		{Profile: ProfileSales, MaxDiscountPercent: 20},
+		{Profile: ProfileWarehouse, MaxDiscountPercent: 0},
+		{Profile: ProfileAccounting, MaxDiscountPercent: 0},
+	}
+}
+
+// Resolve sceglie il limite più specifico; a parità vince quello salvato in archivio
+// rispetto al default di configurazione, poi il più restrittivo
+func (limits DiscountLimits) Resolve(operator *Operator, family, brand string) *DiscountLimit {
+	var best *DiscountLimit
+
+	for i := range limits {
+		limit := &limits[i]
+		if !limit.Matches(operator, family, brand) {
+			continue
+		}
+		if best == nil || limit.specificity() > best.specificity() {
+			best = limit
+			continue
+		}
+		if limit.specificity() < best.specificity() {
+			continue
+		}
+		if limit.ID.IsZero() != best.ID.IsZero() {
+			if !limit.ID.IsZero() {
+				best = limit
+			}
+			continue
+		}
+		if limit.MaxDiscountPercent < best.MaxDiscountPercent {
+			best = limit
+		}
+	}
+
+	return best
+}
+
+// DiscountRequest descrive lo sconto manuale da autorizzare; UnitPrice è il prezzo risultante
+// e va lasciato a 0 quando non è noto, nel qual caso il margine minimo non viene verificato
+type DiscountRequest struct {
+	Family          string
+	Brand           string
+	DiscountPercent float64
+	UnitPrice       float64
+	UnitCost        float64
+}
+
+func (r DiscountRequest) MarginPercent() (float64, bool) {
+	if r.UnitPrice <= 0 || r.UnitCost <= 0 {
+		return 0, false
+	}
+	return (r.UnitPrice - r.UnitCost) / r.UnitPrice * 100, true
+}
+
+type DiscountAuthorization struct {
+	Allowed         bool
+	Limit           *DiscountLimit
+	DiscountPercent float64
+	MarginPercent   float64
+	Reason          string
+}
+
+func (a *DiscountAuthorization) Err() error {
+	if a.Allowed {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrDiscountNotAuthorized, a.Reason)
+}
+
+func (limits DiscountLimits) Authorize(operator *Operator, request DiscountRequest) *DiscountAuthorization {
+	auth := &DiscountAuthorization{DiscountPercent: request.DiscountPercent}
+
+	if request.DiscountPercent < 0 || request.DiscountPercent > 100 {
+		auth.Reason = ErrInvalidDiscount.Error()
+		return auth
+	}
+
+	if request.DiscountPercent == 0 {
+		auth.Allowed = true
+		return auth
+	}
+
+	limit := limits.Resolve(operator, request.Family, request.Brand)
+	if limit == nil {
+		auth.Reason = fmt.Sprintf("no discount limit configured for %s", operator.Username)
+		return auth
+	}
+	auth.Limit = limit
+
+	if request.DiscountPercent > limit.MaxDiscountPercent+0.005 {
+		auth.Reason = fmt.Sprintf("discount %.2f%% exceeds %.2f%% (%s)",
+			request.DiscountPercent, limit.MaxDiscountPercent, limit.Scope())
+		return auth
+	}
+
+	margin, known := request.MarginPercent()
+	auth.MarginPercent = margin
+	if limit.MinMarginPercent != nil && known && margin < *limit.MinMarginPercent-0.005 {
+		auth.Reason = fmt.Sprintf("margin %.2f%% below minimum %.2f%% (%s)",
+			margin, *limit.MinMarginPercent, limit.Scope())
+		return auth
+	}
+
+	auth.Allowed = true
+	return auth
+}
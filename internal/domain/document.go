@@ -0,0 +1,628 @@
+// internal/domain/document.go
+
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrDocumentNotFound     = errors.New("document not found")
+	ErrDocumentNotDraft     = errors.New("document is not a draft")
+	ErrDocumentNotPosted    = errors.New("document is not posted")
+	ErrDocumentEmpty        = errors.New("document has no lines")
+	ErrInvalidDocumentLine  = errors.New("invalid document line")
+	ErrVoucherNotApplicable = errors.New("credit voucher cannot be applied to this document")
+	ErrVoucherExceedsTotal  = errors.New("voucher amount exceeds the amount due")
+)
+
+const ShippingVATPercent = 22.0
+
+type DocumentType string
+
+const (
+	DocumentTypeQuote           DocumentType = "quote"
+	DocumentTypeOrder           DocumentType = "order"
+	DocumentTypeInvoice         DocumentType = "invoice"
+	DocumentTypeCreditNote      DocumentType = "credit_note"
+	DocumentTypePurchaseInvoice DocumentType = "purchase_invoice"
+)
+
+type DocumentStatus string
+
+const (
+	DocumentStatusDraft     DocumentStatus = "draft"
+	DocumentStatusPosted    DocumentStatus = "posted"
+	DocumentStatusCancelled DocumentStatus = "cancelled"
+	DocumentStatusReversed  DocumentStatus = "reversed"
+)
+
+type Document struct {
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Number           string              `bson:"number" json:"number"`
+	Type             DocumentType        `bson:"type" json:"type"`
+	Status           DocumentStatus      `bson:"status" json:"status"`
+	Date             time.Time           `bson:"date" json:"date"`
+	CustomerID       primitive.ObjectID  `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	CustomerCode     string              `bson:"customer_code" json:"customer_code"`
+	SupplierID       primitive.ObjectID  `bson:"supplier_id,omitempty" json:"supplier_id,omitempty"`
+	SupplierCode     string              `bson:"supplier_code" json:"supplier_code"`
+	OperatorID       primitive.ObjectID  `bson:"operator_id,omitempty" json:"operator_id,omitempty"`
+	OperatorUsername string              `bson:"operator_username" json:"operator_username"`
+	Lines            []DocumentLine      `bson:"lines" json:"lines"`
+	Promotions       []DocumentPromotion `bson:"promotions" json:"promotions"`
+	CouponCode       string              `bson:"coupon_code" json:"coupon_code"`
+	Vouchers         []DocumentVoucher   `bson:"vouchers" json:"vouchers"`
+	ShippingCharge   float64             `bson:"shipping_charge" json:"shipping_charge"`
+	ShippingDiscount float64             `bson:"shipping_discount" json:"shipping_discount"`
+	Totals           DocumentTotals      `bson:"totals" json:"totals"`
+	Notes            string              `bson:"notes" json:"notes"`
+	PostedAt         time.Time           `bson:"posted_at,omitempty" json:"posted_at,omitempty"`
+	PostedBy         string              `bson:"posted_by" json:"posted_by"`
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time           `bson:"updated_at" json:"updated_at"`
+	CreatedBy        string              `bson:"created_by" json:"created_by"`
+	UpdatedBy        string              `bson:"updated_by" json:"updated_by"`
+}
+
+type DocumentLine struct {
+	ArticleID   primitive.ObjectID   `bson:"article_id" json:"article_id"`
+	ArticleCode string               `bson:"article_code" json:"article_code"`
+	Description string               `bson:"description" json:"description"`
+	Family      string               `bson:"family" json:"family"`
+	Brand       string               `bson:"brand" json:"brand"`
+	Quantity    float64              `bson:"quantity" json:"quantity"`
+	ListPrice   float64              `bson:"list_price" json:"list_price"`
+	UnitPrice   float64              `bson:"unit_price" json:"unit_price"`
+	UnitCost    float64              `bson:"unit_cost" json:"unit_cost"`
+	VAT         float64              `bson:"vat" json:"vat"`
+	Pricing     []PricingStep        `bson:"pricing" json:"pricing"`
+	Adjustments []DocumentAdjustment `bson:"adjustments" json:"adjustments"`
+	Total       float64              `bson:"total" json:"total"`
+	Margin      float64              `bson:"margin" json:"margin"`
+	Approval    *LineApproval        `bson:"approval,omitempty" json:"approval,omitempty"`
+
+	ManualDiscount   float64 `bson:"manual_discount" json:"manual_discount"`
+	ManualDiscountBy string  `bson:"manual_discount_by" json:"manual_discount_by"`
+}
+
+// LineApproval collega la riga alla richiesta di approvazione per vendita sotto soglia di margine
+type LineApproval struct {
+	ApprovalID primitive.ObjectID `bson:"approval_id" json:"approval_id"`
+	Violation  MarginViolation    `bson:"violation" json:"violation"`
+	Status     ApprovalStatus     `bson:"status" json:"status"`
+	DecidedBy  string             `bson:"decided_by" json:"decided_by"`
+}
+
+// DocumentAdjustment è uno sconto sull'importo di riga calcolato a livello di documento (es. bundle)
+type DocumentAdjustment struct {
+	Type          PricingStepType    `bson:"type" json:"type"`
+	PromotionID   primitive.ObjectID `bson:"promotion_id,omitempty" json:"promotion_id,omitempty"`
+	PromotionCode string             `bson:"promotion_code" json:"promotion_code"`
+	Description   string             `bson:"description" json:"description"`
+	Amount        float64            `bson:"amount" json:"amount"`
+}
+
+type DocumentPromotion struct {
+	PromotionID   primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	PromotionCode string             `bson:"promotion_code" json:"promotion_code"`
+	Type          PromotionType      `bson:"type" json:"type"`
+	Description   string             `bson:"description" json:"description"`
+	Discount      float64            `bson:"discount" json:"discount"`
+}
+
+// DocumentVoucher è un buono a credito usato come mezzo di pagamento: riduce il dovuto, non il fatturato
+type DocumentVoucher struct {
+	VoucherID primitive.ObjectID `bson:"voucher_id" json:"voucher_id"`
+	Code      string             `bson:"code" json:"code"`
+	Amount    float64            `bson:"amount" json:"amount"`
+}
+
+type DocumentTotals struct {
+	Gross         float64 `bson:"gross" json:"gross"`
+	LineDiscounts float64 `bson:"line_discounts" json:"line_discounts"`
+	Adjustments   float64 `bson:"adjustments" json:"adjustments"`
+	Shipping      float64 `bson:"shipping" json:"shipping"`
+	Net           float64 `bson:"net" json:"net"`
+	VAT           float64 `bson:"vat" json:"vat"`
+	Total         float64 `bson:"total" json:"total"`
+	Cost          float64 `bson:"cost" json:"cost"`
+	Margin        float64 `bson:"margin" json:"margin"`
+	Vouchers      float64 `bson:"vouchers" json:"vouchers"`
+	Due           float64 `bson:"due" json:"due"`
+}
+
+func NewDocument(docType DocumentType, createdBy string) (*Document, error) {
+	switch docType {
+	case DocumentTypeQuote, DocumentTypeOrder, DocumentTypeInvoice, DocumentTypeCreditNote, DocumentTypePurchaseInvoice:
+	default:
+		return nil, errors.New("invalid document type")
+	}
+
+	now := time.Now()
+	return &Document{
+		ID:         primitive.NewObjectID(),
+		Type:       docType,
+		Status:     DocumentStatusDraft,
+		Date:       now,
+		Lines:      []DocumentLine{},
+		Promotions: []DocumentPromotion{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		CreatedBy:  createdBy,
+		UpdatedBy:  createdBy,
+	}, nil
+}
+
+func (d *Document) SetCustomer(customer *Customer) {
+	d.CustomerID = customer.ID
+	d.CustomerCode = customer.Code
+	d.UpdatedAt = time.Now()
+}
+
+func (d *Document) SetSupplier(supplier *Supplier) {
+	d.SupplierID = supplier.ID
+	d.SupplierCode = supplier.Code
+	d.UpdatedAt = time.Now()
+}
+
+func (d *Document) SetOperator(operator *Operator) {
+	d.OperatorID = operator.ID
+	d.OperatorUsername = operator.Username
+	d.UpdatedAt = time.Now()
+}
+
+func (d *Document) AddLine(article *Article, quantity float64) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+	if quantity <= 0 {
+		return ErrInvalidDocumentLine
+	}
+
+	d.Lines = append(d.Lines, DocumentLine{
+		ArticleID:   article.ID,
+		ArticleCode: article.Code,
+		Description: article.Description,
+		Family:      article.Family,
+		Brand:       article.Brand,
+		Quantity:    quantity,
+		ListPrice:   article.Pricing.ListPrice,
+		UnitPrice:   article.Pricing.ListPrice,
+		UnitCost:    article.Pricing.LastPurchaseCost,
+		VAT:         article.Pricing.VAT,
+		Pricing:     []PricingStep{},
+		Adjustments: []DocumentAdjustment{},
+	})
+	d.Recalculate()
+	return nil
+}
+
+func (d *Document) RemoveLine(index int) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+	if index < 0 || index >= len(d.Lines) {
+		return ErrInvalidDocumentLine
+	}
+
+	d.Lines = append(d.Lines[:index], d.Lines[index+1:]...)
+	d.Recalculate()
+	return nil
+}
+
+func (d *Document) SetShippingCharge(amount float64) error {
+	if amount < 0 {
+		return ErrInvalidPrice
+	}
+	d.ShippingCharge = amount
+	d.Recalculate()
+	return nil
+}
+
+// SetLinePricing riporta il calcolo automatico sulla riga e vi riapplica lo sconto manuale già concesso
+func (d *Document) SetLinePricing(index int, breakdown *PriceBreakdown) {
+	line := &d.Lines[index]
+	breakdown.ApplyPercent(PricingStepManualDiscount, "Manual discount", line.ManualDiscountBy, line.ManualDiscount)
+	line.ListPrice = breakdown.ListPrice
+	line.UnitPrice = breakdown.FinalPrice
+	line.Pricing = breakdown.Steps
+}
+
+// SetLineDiscount imposta lo sconto manuale dell'operatore sopra il prezzo calcolato; l'autorizzazione
+// spetta al chiamante (DiscountAuthorizationUseCase)
+func (d *Document) SetLineDiscount(index int, percent float64, by string) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+	if index < 0 || index >= len(d.Lines) {
+		return ErrInvalidDocumentLine
+	}
+	if percent < 0 || percent > 100 {
+		return ErrInvalidDiscount
+	}
+
+	line := &d.Lines[index]
+	breakdown := line.automaticPricing()
+
+	line.ManualDiscount = percent
+	line.ManualDiscountBy = by
+	if percent == 0 {
+		line.ManualDiscountBy = ""
+	}
+
+	d.SetLinePricing(index, breakdown)
+	d.Recalculate()
+	return nil
+}
+
+// automaticPricing ricostruisce il calcolo della riga senza lo sconto manuale
+func (l *DocumentLine) automaticPricing() *PriceBreakdown {
+	if len(l.Pricing) == 0 {
+		return NewPriceBreakdown(l.ListPrice)
+	}
+
+	breakdown := &PriceBreakdown{ListPrice: l.ListPrice, BasePrice: l.ListPrice}
+	for _, step := range l.Pricing {
+		if step.Type == PricingStepManualDiscount {
+			continue
+		}
+		if step.Type == PricingStepNetPrice || step.Type == PricingStepPriceList {
+			breakdown.BasePrice = step.PriceAfter
+		}
+		breakdown.Steps = append(breakdown.Steps, step)
+		breakdown.FinalPrice = step.PriceAfter
+	}
+	return breakdown
+}
+
+// SetLineApproval riporta sulla riga lo stato della richiesta di approvazione, nil la rimuove
+func (d *Document) SetLineApproval(index int, approval *MarginApproval) {
+	if approval == nil {
+		d.Lines[index].Approval = nil
+		return
+	}
+	d.Lines[index].Approval = &LineApproval{
+		ApprovalID: approval.ID,
+		Violation:  approval.Violation,
+		Status:     approval.Status,
+		DecidedBy:  approval.DecidedBy,
+	}
+}
+
+// UnapprovedLines restituisce gli indici delle righe sotto soglia non ancora approvate
+func (d *Document) UnapprovedLines() []int {
+	var indexes []int
+	for i, line := range d.Lines {
+		if line.Approval != nil && line.Approval.Status != ApprovalStatusApproved {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// RequiresMarginControl vale per i documenti di vendita che impegnano un prezzo (ordini e fatture)
+func (d *Document) RequiresMarginControl() bool {
+	return d.Type == DocumentTypeOrder || d.Type == DocumentTypeInvoice
+}
+
+// ClearDocumentPromotions azzera bundle e spedizione gratuita prima di un nuovo calcolo del carrello
+func (d *Document) ClearDocumentPromotions() {
+	for i := range d.Lines {
+		d.Lines[i].Adjustments = []DocumentAdjustment{}
+	}
+	d.Promotions = []DocumentPromotion{}
+	d.ShippingDiscount = 0
+}
+
+func (d *Document) AddLineAdjustment(index int, adjustment DocumentAdjustment) {
+	d.Lines[index].Adjustments = append(d.Lines[index].Adjustments, adjustment)
+}
+
+func (d *Document) AddPromotion(promo *Promotion, discount float64) {
+	d.Promotions = append(d.Promotions, DocumentPromotion{
+		PromotionID:   promo.ID,
+		PromotionCode: promo.Code,
+		Type:          promo.Type,
+		Description:   promo.Name,
+		Discount:      discount,
+	})
+}
+
+func (d *Document) Recalculate() {
+	totals := DocumentTotals{}
+
+	for i := range d.Lines {
+		line := &d.Lines[i]
+
+		adjustments := 0.0
+		for _, adj := range line.Adjustments {
+			adjustments += adj.Amount
+		}
+
+		line.Total = roundCents(line.UnitPrice*line.Quantity - adjustments)
+		line.Margin = line.Total - line.UnitCost*line.Quantity
+
+		totals.Gross += line.ListPrice * line.Quantity
+		totals.LineDiscounts += (line.ListPrice - line.UnitPrice) * line.Quantity
+		totals.Adjustments += adjustments
+		totals.Net += line.Total
+		totals.VAT += line.Total * line.VAT / 100
+		totals.Cost += line.UnitCost * line.Quantity
+	}
+
+	if d.ShippingDiscount > d.ShippingCharge {
+		d.ShippingDiscount = d.ShippingCharge
+	}
+	totals.Shipping = d.ShippingCharge - d.ShippingDiscount
+	totals.Net += totals.Shipping
+	totals.VAT += totals.Shipping * ShippingVATPercent / 100
+
+	totals.Net = roundCents(totals.Net)
+	totals.VAT = roundCents(totals.VAT)
+	totals.Total = totals.Net + totals.VAT
+	totals.Margin = roundCents(totals.Net - totals.Shipping - totals.Cost)
+
+	// i buoni non possono superare il totale: se il documento si è ridotto si tagliano gli ultimi applicati
+	available := totals.Total
+	for i := range d.Vouchers {
+		if d.Vouchers[i].Amount > available {
+			d.Vouchers[i].Amount = roundCents(math.Max(available, 0))
+		}
+		available -= d.Vouchers[i].Amount
+		totals.Vouchers += d.Vouchers[i].Amount
+	}
+	totals.Vouchers = roundCents(totals.Vouchers)
+	totals.Due = roundCents(totals.Total - totals.Vouchers)
+
+	d.Totals = totals
+	d.UpdatedAt = time.Now()
+}
+
+// PromotionDiscount somma lo sconto attribuito a una promozione su righe, bundle e spedizione
+func (d *Document) PromotionDiscount(promotionCode string) float64 {
+	total := 0.0
+	for _, line := range d.Lines {
+		for _, step := range line.Pricing {
+			if step.Type == PricingStepPromotion && step.Reference == promotionCode {
+				total += step.Amount * line.Quantity
+			}
+		}
+	}
+	for _, promo := range d.Promotions {
+		if promo.PromotionCode == promotionCode {
+			total += promo.Discount
+		}
+	}
+	return roundCents(total)
+}
+
+func (d *Document) AppliedPromotionCodes() []string {
+	seen := make(map[string]bool)
+	var codes []string
+
+	add := func(code string) {
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	for _, line := range d.Lines {
+		for _, step := range line.Pricing {
+			if step.Type == PricingStepPromotion {
+				add(step.Reference)
+			}
+		}
+		for _, adj := range line.Adjustments {
+			add(adj.PromotionCode)
+		}
+	}
+	for _, promo := range d.Promotions {
+		add(promo.PromotionCode)
+	}
+
+	return codes
+}
+
+// PromotionRevenue è il fatturato delle righe su cui la promozione ha agito; per la spedizione gratuita è l'intero documento
+func (d *Document) PromotionRevenue(promotionCode string) float64 {
+	total := 0.0
+	for _, line := range d.Lines {
+		if line.hasPromotion(promotionCode) {
+			total += line.Total
+		}
+	}
+	if total == 0 {
+		for _, promo := range d.Promotions {
+			if promo.PromotionCode == promotionCode {
+				return d.Totals.Net
+			}
+		}
+	}
+	return roundCents(total)
+}
+
+func (l DocumentLine) hasPromotion(promotionCode string) bool {
+	for _, step := range l.Pricing {
+		if step.Type == PricingStepPromotion && step.Reference == promotionCode {
+			return true
+		}
+	}
+	for _, adj := range l.Adjustments {
+		if adj.PromotionCode == promotionCode {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyVoucher usa il buono del cliente sulla fattura in bozza; amount 0 usa il massimo possibile.
+// Riapplicare lo stesso buono ne sostituisce l'importo
+func (d *Document) ApplyVoucher(voucher *CreditVoucher, amount float64) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+	if d.Type != DocumentTypeInvoice || voucher.CustomerID != d.CustomerID {
+		return ErrVoucherNotApplicable
+	}
+	if !voucher.IsValid() {
+		return ErrVoucherNotApplicable
+	}
+
+	_ = d.RemoveVoucher(voucher.Code)
+	d.Recalculate()
+
+	if amount == 0 {
+		amount = math.Min(voucher.RemainingAmount, d.Totals.Due)
+	}
+	amount = roundCents(amount)
+	if amount <= 0 {
+		return ErrInvalidVoucherAmount
+	}
+	if amount > voucher.RemainingAmount {
+		return ErrInsufficientBalance
+	}
+	if amount > d.Totals.Due {
+		return ErrVoucherExceedsTotal
+	}
+
+	d.Vouchers = append(d.Vouchers, DocumentVoucher{
+		VoucherID: voucher.ID,
+		Code:      voucher.Code,
+		Amount:    amount,
+	})
+	d.Recalculate()
+	return nil
+}
+
+func (d *Document) RemoveVoucher(code string) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+
+	for i, v := range d.Vouchers {
+		if v.Code == code {
+			d.Vouchers = append(d.Vouchers[:i], d.Vouchers[i+1:]...)
+			d.Recalculate()
+			return nil
+		}
+	}
+	return ErrVoucherNotFound
+}
+
+func (d *Document) TotalQuantity() float64 {
+	total := 0.0
+	for _, line := range d.Lines {
+		total += line.Quantity
+	}
+	return total
+}
+
+func (d *Document) MerchandiseTotal() float64 {
+	total := 0.0
+	for _, line := range d.Lines {
+		total += line.UnitPrice * line.Quantity
+	}
+	return total
+}
+
+func (d *Document) IsDraft() bool {
+	return d.Status == DocumentStatusDraft
+}
+
+func (d *Document) IsPosted() bool {
+	return d.Status == DocumentStatusPosted
+}
+
+func (d *Document) IsSale() bool {
+	return d.Type == DocumentTypeInvoice || d.Type == DocumentTypeCreditNote
+}
+
+func (d *Document) IsPurchase() bool {
+	return d.Type == DocumentTypePurchaseInvoice
+}
+
+// Sign restituisce -1 per le note di credito, così gli importi stornano i consuntivi
+func (d *Document) Sign() float64 {
+	if d.Type == DocumentTypeCreditNote {
+		return -1
+	}
+	return 1
+}
+
+func (d *Document) Post(number, postedBy string) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+	if len(d.Lines) == 0 {
+		return ErrDocumentEmpty
+	}
+	if len(d.UnapprovedLines()) > 0 {
+		return ErrMarginApprovalRequired
+	}
+
+	d.Recalculate()
+
+	now := time.Now()
+	d.Number = number
+	d.Status = DocumentStatusPosted
+	d.PostedAt = now
+	d.PostedBy = postedBy
+	d.UpdatedAt = now
+	d.UpdatedBy = postedBy
+	return nil
+}
+
+// Reverse annulla un documento registrato; i consuntivi e gli utilizzi collegati vanno stornati dal chiamante
+func (d *Document) Reverse(reversedBy string) error {
+	if !d.IsPosted() {
+		return ErrDocumentNotPosted
+	}
+
+	d.Status = DocumentStatusReversed
+	d.UpdatedAt = time.Now()
+	d.UpdatedBy = reversedBy
+	return nil
+}
+
+func (d *Document) Cancel(cancelledBy string) error {
+	if !d.IsDraft() {
+		return ErrDocumentNotDraft
+	}
+
+	d.Status = DocumentStatusCancelled
+	d.UpdatedAt = time.Now()
+	d.UpdatedBy = cancelledBy
+	return nil
+}
+
+func DocumentNumberPrefix(docType DocumentType) string {
+	switch docType {
+	case DocumentTypeQuote:
+		return "PRV"
+	case DocumentTypeOrder:
+		return "ORD"
+	case DocumentTypeInvoice:
+		return "FT"
+	case DocumentTypeCreditNote:
+		return "NC"
+	case DocumentTypePurchaseInvoice:
+		return "FA"
+	default:
+		return "DOC"
+	}
+}
+
+func FormatDocumentNumber(docType DocumentType, year int, sequence int64) string {
+	return fmt.Sprintf("%s-%d-%06d", DocumentNumberPrefix(docType), year, sequence)
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
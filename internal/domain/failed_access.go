@@ -0,0 +1,32 @@
+// internal/domain/failed_access.go
+
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FailedAccess è un tentativo di accesso respinto; Username è quello digitato, anche se non esiste
+type FailedAccess struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username  string             `bson:"username" json:"username"`
+	Action    string             `bson:"action" json:"action"`
+	Area      string             `bson:"area" json:"area"`
+	Reason    string             `bson:"reason" json:"reason"`
+	IPAddress string             `bson:"ip_address" json:"ip_address"`
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+func NewFailedAccess(username, action, area, reason, ipAddress string) *FailedAccess {
+	return &FailedAccess{
+		ID:        primitive.NewObjectID(),
+		Username:  username,
+		Action:    action,
+		Area:      area,
+		Reason:    reason,
+		IPAddress: ipAddress,
+		Timestamp: time.Now(),
+	}
+}
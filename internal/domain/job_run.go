@@ -0,0 +1,63 @@
+// internal/domain/job_run.go
+
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	JobRunStatusFailed    JobRunStatus = "failed"
+)
+
+// JobRun è una esecuzione di un job pianificato, con l'istanza che l'ha eseguita e l'esito
+type JobRun struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Job        string             `bson:"job" json:"job"`
+	Instance   string             `bson:"instance" json:"instance"`
+	Slot       time.Time          `bson:"slot" json:"slot"`
+	Status     JobRunStatus       `bson:"status" json:"status"`
+	Result     string             `bson:"result" json:"result"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt time.Time          `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	DurationMs int64              `bson:"duration_ms" json:"duration_ms"`
+}
+
+// JobLock assegna un job a una sola istanza: Slot è l'ultima esecuzione pianificata già presa in carico,
+// così un'altra istanza non la ripete dopo il rilascio
+type JobLock struct {
+	Job         string    `bson:"_id" json:"job"`
+	Owner       string    `bson:"owner" json:"owner"`
+	Slot        time.Time `bson:"slot" json:"slot"`
+	LockedUntil time.Time `bson:"locked_until" json:"locked_until"`
+	AcquiredAt  time.Time `bson:"acquired_at" json:"acquired_at"`
+}
+
+func NewJobRun(job, instance string, slot time.Time) *JobRun {
+	return &JobRun{
+		ID:        primitive.NewObjectID(),
+		Job:       job,
+		Instance:  instance,
+		Slot:      slot,
+		Status:    JobRunStatusRunning,
+		StartedAt: time.Now(),
+	}
+}
+
+func (r *JobRun) Finish(result string, err error) {
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	r.Result = result
+	r.Status = JobRunStatusSucceeded
+	if err != nil {
+		r.Status = JobRunStatusFailed
+		r.Error = err.Error()
+	}
+}
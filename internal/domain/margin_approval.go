@@ -0,0 +1,192 @@
+// internal/domain/margin_approval.go
+
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrApprovalNotFound       = errors.New("margin approval not found")
+	ErrApprovalNotPending     = errors.New("margin approval is not pending")
+	ErrMarginApprovalRequired = errors.New("sale below margin threshold requires approval")
+	ErrSelfApproval           = errors.New("approval cannot be granted by the requesting operator")
+)
+
+type MarginViolation string
+
+const (
+	MarginViolationNone          MarginViolation = ""
+	MarginViolationSottocosto    MarginViolation = "sottocosto"
+	MarginViolationSottoguadagno MarginViolation = "sottoguadagno"
+)
+
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending   ApprovalStatus = "pending"
+	ApprovalStatusApproved  ApprovalStatus = "approved"
+	ApprovalStatusRejected  ApprovalStatus = "rejected"
+	ApprovalStatusCancelled ApprovalStatus = "cancelled"
+)
+
+type ApprovalMethod string
+
+const (
+	ApprovalMethodQueue ApprovalMethod = "queue"
+	ApprovalMethodPIN   ApprovalMethod = "supervisor_pin"
+)
+
+// MarginPolicy riporta le soglie di margine della configurazione (business.margin)
+type MarginPolicy struct {
+	SottocostoThresholdPercent    float64
+	SottoguadagnoThresholdPercent float64
+}
+
+// Classify usa Article.IsSottocosto con entrambe le soglie: prima il sottocosto, poi il sottoguadagno
+func (p MarginPolicy) Classify(article *Article, sellingPrice float64) MarginViolation {
+	if article.IsSottocosto(sellingPrice, p.SottocostoThresholdPercent) {
+		return MarginViolationSottocosto
+	}
+	if article.IsSottocosto(sellingPrice, p.SottoguadagnoThresholdPercent) {
+		return MarginViolationSottoguadagno
+	}
+	return MarginViolationNone
+}
+
+func (p MarginPolicy) Threshold(violation MarginViolation) float64 {
+	if violation == MarginViolationSottocosto {
+		return p.SottocostoThresholdPercent
+	}
+	return p.SottoguadagnoThresholdPercent
+}
+
+type MarginApproval struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DocumentID       primitive.ObjectID `bson:"document_id" json:"document_id"`
+	DocumentType     DocumentType       `bson:"document_type" json:"document_type"`
+	LineIndex        int                `bson:"line_index" json:"line_index"`
+	CustomerID       primitive.ObjectID `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	CustomerCode     string             `bson:"customer_code" json:"customer_code"`
+	ArticleID        primitive.ObjectID `bson:"article_id" json:"article_id"`
+	ArticleCode      string             `bson:"article_code" json:"article_code"`
+	Quantity         float64            `bson:"quantity" json:"quantity"`
+	UnitPrice        float64            `bson:"unit_price" json:"unit_price"`
+	UnitCost         float64            `bson:"unit_cost" json:"unit_cost"`
+	MarginPercent    float64            `bson:"margin_percent" json:"margin_percent"`
+	ThresholdPercent float64            `bson:"threshold_percent" json:"threshold_percent"`
+	Violation        MarginViolation    `bson:"violation" json:"violation"`
+	Status           ApprovalStatus     `bson:"status" json:"status"`
+	RequestedBy      string             `bson:"requested_by" json:"requested_by"`
+	RequestedAt      time.Time          `bson:"requested_at" json:"requested_at"`
+	DecidedBy        string             `bson:"decided_by" json:"decided_by"`
+	DecidedAt        time.Time          `bson:"decided_at,omitempty" json:"decided_at,omitempty"`
+	Method           ApprovalMethod     `bson:"method" json:"method"`
+	Note             string             `bson:"note" json:"note"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func NewMarginApproval(
+	document *Document,
+	lineIndex int,
+	article *Article,
+	unitPrice float64,
+	violation MarginViolation,
+	policy MarginPolicy,
+	requestedBy string,
+) *MarginApproval {
+	line := document.Lines[lineIndex]
+	now := time.Now()
+
+	return &MarginApproval{
+		ID:               primitive.NewObjectID(),
+		DocumentID:       document.ID,
+		DocumentType:     document.Type,
+		LineIndex:        lineIndex,
+		CustomerID:       document.CustomerID,
+		CustomerCode:     document.CustomerCode,
+		ArticleID:        article.ID,
+		ArticleCode:      article.Code,
+		Quantity:         line.Quantity,
+		UnitPrice:        unitPrice,
+		UnitCost:         article.Pricing.LastPurchaseCost,
+		MarginPercent:    article.CalculateMargin(unitPrice),
+		ThresholdPercent: policy.Threshold(violation),
+		Violation:        violation,
+		Status:           ApprovalStatusPending,
+		RequestedBy:      requestedBy,
+		RequestedAt:      now,
+		UpdatedAt:        now,
+	}
+}
+
+func (a *MarginApproval) IsPending() bool {
+	return a.Status == ApprovalStatusPending
+}
+
+// Covers indica se l'approvazione vale ancora per la riga: stesso articolo e prezzo non più basso di quello approvato
+func (a *MarginApproval) Covers(articleID primitive.ObjectID, unitPrice float64) bool {
+	return a.ArticleID == articleID && roundCents(unitPrice) >= roundCents(a.UnitPrice)
+}
+
+// AppliesTo indica se la richiesta esistente vale ancora per la riga. Una richiesta respinta resta valida
+// finché il prezzo non cambia, così la riga non torna in coda senza una modifica
+func (a *MarginApproval) AppliesTo(articleID primitive.ObjectID, unitPrice float64) bool {
+	switch a.Status {
+	case ApprovalStatusPending, ApprovalStatusApproved:
+		return a.Covers(articleID, unitPrice)
+	case ApprovalStatusRejected:
+		return a.ArticleID == articleID && roundCents(unitPrice) == roundCents(a.UnitPrice)
+	default:
+		return false
+	}
+}
+
+func (a *MarginApproval) Approve(approver *Operator, method ApprovalMethod, note string) error {
+	if !a.IsPending() {
+		return ErrApprovalNotPending
+	}
+	if !approver.CanApproveSottocosto() {
+		return ErrInsufficientPermissions
+	}
+	if approver.Username == a.RequestedBy && !approver.IsAdmin() {
+		return ErrSelfApproval
+	}
+
+	a.decide(ApprovalStatusApproved, approver.Username, method, note)
+	return nil
+}
+
+func (a *MarginApproval) Reject(approver *Operator, note string) error {
+	if !a.IsPending() {
+		return ErrApprovalNotPending
+	}
+	if !approver.CanApproveSottocosto() {
+		return ErrInsufficientPermissions
+	}
+
+	a.decide(ApprovalStatusRejected, approver.Username, ApprovalMethodQueue, note)
+	return nil
+}
+
+// Cancel chiude una richiesta non più necessaria (riga rimossa o prezzo cambiato)
+func (a *MarginApproval) Cancel(by string) {
+	if !a.IsPending() {
+		return
+	}
+	a.decide(ApprovalStatusCancelled, by, "", "superseded")
+}
+
+func (a *MarginApproval) decide(status ApprovalStatus, by string, method ApprovalMethod, note string) {
+	now := time.Now()
+	a.Status = status
+	a.DecidedBy = by
+	a.DecidedAt = now
+	a.Method = method
+	a.Note = strings.TrimSpace(note)
+	a.UpdatedAt = now
+}
@@ -0,0 +1,55 @@
+// internal/domain/net_price_reminder.go
+
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrReminderNotFound = errors.New("net price reminder not found")
+
+type ReminderStatus string
+
+const (
+	ReminderStatusOpen      ReminderStatus = "open"
+	ReminderStatusRenewed   ReminderStatus = "renewed"
+	ReminderStatusDismissed ReminderStatus = "dismissed"
+)
+
+// NetPriceReminder segnala un prezzo netto in scadenza finché non viene rinnovato o ignorato
+type NetPriceReminder struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID    primitive.ObjectID `bson:"article_id" json:"article_id"`
+	ArticleCode  string             `bson:"article_code" json:"article_code"`
+	CustomerID   primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	CustomerCode string             `bson:"customer_code" json:"customer_code"`
+	CustomerName string             `bson:"customer_name" json:"customer_name"`
+	Price        float64            `bson:"price" json:"price"`
+	ValidTo      time.Time          `bson:"valid_to" json:"valid_to"`
+	Status       ReminderStatus     `bson:"status" json:"status"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ClosedAt     time.Time          `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	ClosedBy     string             `bson:"closed_by" json:"closed_by"`
+}
+
+func NewNetPriceReminder(article *Article, customer *Customer, netPrice NetPrice) *NetPriceReminder {
+	return &NetPriceReminder{
+		ID:           primitive.NewObjectID(),
+		ArticleID:    article.ID,
+		ArticleCode:  article.Code,
+		CustomerID:   customer.ID,
+		CustomerCode: customer.Code,
+		CustomerName: customer.CompanyName,
+		Price:        netPrice.Price,
+		ValidTo:      netPrice.ValidTo,
+		Status:       ReminderStatusOpen,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func (r *NetPriceReminder) DaysLeft(now time.Time) int {
+	return int(r.ValidTo.Sub(now).Hours() / 24)
+}
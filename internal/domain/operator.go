@@ -17,6 +17,11 @@ var (
 	ErrOperatorLocked          = errors.New("operator account is locked")
 	ErrInsufficientPermissions = errors.New("insufficient permissions")
 	ErrInvalidPassword         = errors.New("invalid password format")
+	ErrInvalidSupervisorPIN    = errors.New("invalid supervisor PIN")
+	ErrOperatorInactive        = errors.New("operator is not active")
+	ErrLoginThrottled          = errors.New("too many login attempts")
+	ErrInvalidTOTPCode         = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled         = errors.New("two-factor enrollment not started")
 )
 
 type ProfileType string
@@ -65,13 +70,18 @@ type Operator struct {
 	Permissions        []Permission       `bson:"permissions" json:"permissions"`
 	IsActive           bool               `bson:"is_active" json:"is_active"`
 	IsLocked           bool               `bson:"is_locked" json:"is_locked"`
+	LockedUntil        time.Time          `bson:"locked_until" json:"locked_until"`
 	FailedAttempts     int                `bson:"failed_attempts" json:"failed_attempts"`
 	LastFailedAttempt  time.Time          `bson:"last_failed_attempt" json:"last_failed_attempt"`
 	LastLogin          time.Time          `bson:"last_login" json:"last_login"`
 	LastPasswordChange time.Time          `bson:"last_password_change" json:"last_password_change"`
-	SessionToken       string             `bson:"session_token" json:"-"`
-	SessionExpiry      time.Time          `bson:"session_expiry" json:"session_expiry"`
-	AuditLog           []AuditEntry       `bson:"audit_log" json:"audit_log"`
+	PasswordHistory    []string           `bson:"password_history" json:"-"`
+	MustChangePassword bool               `bson:"must_change_password" json:"must_change_password"`
+	SupervisorPINHash  string             `bson:"supervisor_pin_hash,omitempty" json:"-"`
+	TOTPSecret         string             `bson:"totp_secret" json:"-"`
+	TOTPEnabled        bool               `bson:"totp_enabled" json:"totp_enabled"`
+	TOTPLastCounter    int64              `bson:"totp_last_counter" json:"-"`
+	RecoveryCodeHashes []string           `bson:"recovery_code_hashes" json:"-"`
 	Settings           OperatorSettings   `bson:"settings" json:"settings"`
 	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
@@ -83,6 +93,8 @@ type Permission struct {
 	Actions []PermissionAction `bson:"actions" json:"actions"`
 }
 
+// AuditEntry è il formato del vecchio array audit_log sul documento operatore, letto solo per la
+// migrazione verso il registro di audit (AuditRecord)
 type AuditEntry struct {
 	ID         primitive.ObjectID `bson:"id" json:"id"`
 	Action     string             `bson:"action" json:"action"`
@@ -100,15 +112,17 @@ type OperatorSettings struct {
 	NotificationsEmail bool   `bson:"notifications_email" json:"notifications_email"`
 }
 
-func NewOperator(username, fullName, email, password string, profile ProfileType, createdBy string) (*Operator, error) {
+// NewOperator crea l'operatore con la password iniziale scelta dall'amministratore, da cambiare al
+// primo accesso
+func NewOperator(username, fullName, email, password string, profile ProfileType, createdBy string, policy PasswordPolicy) (*Operator, error) {
 	if strings.TrimSpace(username) == "" {
 		return nil, errors.New("username cannot be empty")
 	}
-	if strings.TrimSpace(password) == "" || len(password) < 8 {
-		return nil, ErrInvalidPassword
+	if err := policy.Validate(password); err != nil {
+		return nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hashedPassword, err := policy.hash(password)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +131,7 @@ func NewOperator(username, fullName, email, password string, profile ProfileType
 	operator := &Operator{
 		ID:             primitive.NewObjectID(),
 		Username:       strings.ToLower(strings.TrimSpace(username)),
-		PasswordHash:   string(hashedPassword),
+		PasswordHash:   hashedPassword,
 		FullName:       strings.TrimSpace(fullName),
 		Email:          strings.ToLower(strings.TrimSpace(email)),
 		Profile:        profile,
@@ -125,7 +139,6 @@ func NewOperator(username, fullName, email, password string, profile ProfileType
 		IsActive:       true,
 		IsLocked:       false,
 		FailedAttempts: 0,
-		AuditLog:       []AuditEntry{},
 		Settings: OperatorSettings{
 			Theme:              "default",
 			Language:           "it",
@@ -135,6 +148,7 @@ func NewOperator(username, fullName, email, password string, profile ProfileType
 		CreatedAt:          now,
 		UpdatedAt:          now,
 		LastPasswordChange: now,
+		MustChangePassword: true,
 		CreatedBy:          createdBy,
 	}
 
@@ -197,65 +211,96 @@ func (o *Operator) Validate() error {
 	return nil
 }
 
+// CheckPassword verifica la password senza toccare il conteggio dei tentativi falliti, che è
+// aggiornato in modo atomico sul repository da LoginUseCase
 func (o *Operator) CheckPassword(password string) error {
-	if o.IsLocked {
+	if o.IsLockedAt(time.Now()) {
 		return ErrOperatorLocked
 	}
 	if !o.IsActive {
-		return errors.New("operator is not active")
+		return ErrOperatorInactive
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(o.PasswordHash), []byte(password))
-	if err != nil {
-		o.FailedAttempts++
-		o.LastFailedAttempt = time.Now()
-		if o.FailedAttempts >= 5 {
-			o.IsLocked = true
-		}
-		o.UpdatedAt = time.Now()
+	if err := bcrypt.CompareHashAndPassword([]byte(o.PasswordHash), []byte(password)); err != nil {
 		return ErrInvalidCredentials
 	}
 
-	o.FailedAttempts = 0
-	o.LastLogin = time.Now()
-	o.UpdatedAt = time.Now()
 	return nil
 }
 
-func (o *Operator) ChangePassword(oldPassword, newPassword string) error {
-	if err := o.CheckPassword(oldPassword); err != nil {
+// PasswordChangeRequired dice se al login va imposta una nuova password: dopo un reset, per i nuovi
+// operatori o per scadenza secondo la policy
+func (o *Operator) PasswordChangeRequired(policy PasswordPolicy, now time.Time) bool {
+	return o.MustChangePassword || policy.Expired(o.LastPasswordChange, now)
+}
+
+// setPassword rifiuta la password attuale e quelle nello storico, che conserva le ultime
+// policy.HistorySize impronte precedenti
+func (o *Operator) setPassword(newPassword string, policy PasswordPolicy) error {
+	if err := policy.Validate(newPassword); err != nil {
 		return err
 	}
 
-	if len(newPassword) < 8 {
-		return ErrInvalidPassword
+	for _, hash := range append([]string{o.PasswordHash}, o.PasswordHistory...) {
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+			return ErrPasswordReused
+		}
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	hashedPassword, err := policy.hash(newPassword)
 	if err != nil {
 		return err
 	}
 
-	o.PasswordHash = string(hashedPassword)
+	if policy.HistorySize > 0 {
+		history := append([]string{o.PasswordHash}, o.PasswordHistory...)
+		if len(history) > policy.HistorySize {
+			history = history[:policy.HistorySize]
+		}
+		o.PasswordHistory = history
+	} else {
+		o.PasswordHistory = nil
+	}
+
+	o.PasswordHash = hashedPassword
 	o.LastPasswordChange = time.Now()
 	o.UpdatedAt = time.Now()
 	return nil
 }
 
-func (o *Operator) ResetPassword(newPassword string) error {
-	if len(newPassword) < 8 {
-		return ErrInvalidPassword
+// IsLockedAt dice se l'account è bloccato alla data: un blocco senza LockedUntil è manuale e resta
+// fino allo sblocco, quello per tentativi falliti decade da solo
+func (o *Operator) IsLockedAt(now time.Time) bool {
+	if !o.IsLocked {
+		return false
 	}
+	return o.LockedUntil.IsZero() || now.Before(o.LockedUntil)
+}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
-	if err != nil {
+func (o *Operator) ChangePassword(oldPassword, newPassword string, policy PasswordPolicy) error {
+	if err := o.CheckPassword(oldPassword); err != nil {
 		return err
 	}
 
-	o.PasswordHash = string(hashedPassword)
-	o.LastPasswordChange = time.Now()
+	if err := o.setPassword(newPassword, policy); err != nil {
+		return err
+	}
+
+	o.MustChangePassword = false
+	return nil
+}
+
+// ResetPassword imposta una password temporanea scelta dall'amministratore: l'operatore dovrà
+// cambiarla al prossimo accesso
+func (o *Operator) ResetPassword(newPassword string, policy PasswordPolicy) error {
+	if err := o.setPassword(newPassword, policy); err != nil {
+		return err
+	}
+
+	o.MustChangePassword = true
 	o.FailedAttempts = 0
 	o.IsLocked = false
+	o.LockedUntil = time.Time{}
 	o.UpdatedAt = time.Now()
 	return nil
 }
@@ -308,54 +353,21 @@ func (o *Operator) RevokePermission(area PermissionArea, action PermissionAction
 	}
 }
 
-func (o *Operator) AddAuditEntry(action, area, resourceID, details, ipAddress string) {
-	entry := AuditEntry{
-		ID:         primitive.NewObjectID(),
-		Action:     action,
-		Area:       area,
-		ResourceID: resourceID,
-		Details:    details,
-		IPAddress:  ipAddress,
-		Timestamp:  time.Now(),
-	}
-
-	o.AuditLog = append(o.AuditLog, entry)
-
-	if len(o.AuditLog) > 1000 {
-		o.AuditLog = o.AuditLog[len(o.AuditLog)-1000:]
-	}
-}
-
-func (o *Operator) GetRecentAuditLog(limit int) []AuditEntry {
-	if limit <= 0 || limit > len(o.AuditLog) {
-		limit = len(o.AuditLog)
-	}
-
-	result := make([]AuditEntry, limit)
-	copy(result, o.AuditLog[len(o.AuditLog)-limit:])
-
-	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-		result[i], result[j] = result[j], result[i]
-	}
-
-	return result
-}
-
 func (o *Operator) Lock() {
 	o.IsLocked = true
+	o.LockedUntil = time.Time{}
 	o.UpdatedAt = time.Now()
 }
 
 func (o *Operator) Unlock() {
 	o.IsLocked = false
+	o.LockedUntil = time.Time{}
 	o.FailedAttempts = 0
 	o.UpdatedAt = time.Now()
 }
 
 func (o *Operator) Deactivate() {
 	o.IsActive = false
-	o.SessionToken = ""
-	o.SessionExpiry = time.Time{}
 	o.UpdatedAt = time.Now()
 }
 
@@ -364,26 +376,12 @@ func (o *Operator) Activate() {
 	o.UpdatedAt = time.Now()
 }
 
-func (o *Operator) CreateSession(token string, duration time.Duration) {
-	o.SessionToken = token
-	o.SessionExpiry = time.Now().Add(duration)
+// RecordLogin registra l'accesso; le sessioni sono nello SessionStore di auth
+func (o *Operator) RecordLogin() {
 	o.LastLogin = time.Now()
 	o.UpdatedAt = time.Now()
 }
 
-func (o *Operator) IsSessionValid() bool {
-	if o.SessionToken == "" {
-		return false
-	}
-	return time.Now().Before(o.SessionExpiry)
-}
-
-func (o *Operator) InvalidateSession() {
-	o.SessionToken = ""
-	o.SessionExpiry = time.Time{}
-	o.UpdatedAt = time.Now()
-}
-
 func (o *Operator) IsAdmin() bool {
 	return o.Profile == ProfileAdmin
 }
@@ -395,3 +393,34 @@ func (o *Operator) CanOverrideFido() bool {
 func (o *Operator) CanApproveSottocosto() bool {
 	return o.Profile == ProfileAdmin || o.Profile == ProfileSales
 }
+
+func (o *Operator) SetSupervisorPIN(pin string) error {
+	pin = strings.TrimSpace(pin)
+	if len(pin) < 4 || len(pin) > 8 {
+		return errors.New("supervisor PIN must be 4 to 8 digits")
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return errors.New("supervisor PIN must be 4 to 8 digits")
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), 12)
+	if err != nil {
+		return err
+	}
+
+	o.SupervisorPINHash = string(hash)
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (o *Operator) CheckSupervisorPIN(pin string) error {
+	if o.SupervisorPINHash == "" || !o.IsActive || o.IsLockedAt(time.Now()) {
+		return ErrInvalidSupervisorPIN
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(o.SupervisorPINHash), []byte(strings.TrimSpace(pin))); err != nil {
+		return ErrInvalidSupervisorPIN
+	}
+	return nil
+}
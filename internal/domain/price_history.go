@@ -0,0 +1,112 @@
+// internal/domain/price_history.go
+
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPriceChangeNotFound   = errors.New("price change not found")
+	ErrPriceChangeNotPending = errors.New("price change is not scheduled")
+)
+
+type PriceChangeStatus string
+
+const (
+	PriceChangeScheduled PriceChangeStatus = "scheduled"
+	PriceChangeApplied   PriceChangeStatus = "applied"
+	PriceChangeCancelled PriceChangeStatus = "cancelled"
+)
+
+type PriceChange struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID         primitive.ObjectID `bson:"article_id" json:"article_id"`
+	ArticleCode       string             `bson:"article_code" json:"article_code"`
+	ListPrice         float64            `bson:"list_price" json:"list_price"`
+	Cost              float64            `bson:"cost" json:"cost"`
+	PreviousListPrice float64            `bson:"previous_list_price" json:"previous_list_price"`
+	PreviousCost      float64            `bson:"previous_cost" json:"previous_cost"`
+	Currency          string             `bson:"currency" json:"currency"`
+	EffectiveFrom     time.Time          `bson:"effective_from" json:"effective_from"`
+	Status            PriceChangeStatus  `bson:"status" json:"status"`
+	Reason            string             `bson:"reason" json:"reason"`
+	ChangedBy         string             `bson:"changed_by" json:"changed_by"`
+	ChangedAt         time.Time          `bson:"changed_at" json:"changed_at"`
+	AppliedAt         time.Time          `bson:"applied_at" json:"applied_at"`
+	CancelledBy       string             `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func NewPriceChange(article *Article, listPrice, cost float64, effectiveFrom time.Time, reason, changedBy string) (*PriceChange, error) {
+	if article == nil {
+		return nil, ErrArticleNotFound
+	}
+	if listPrice < 0 || cost < 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	// una data passata vale come "da subito": retrodatare la variazione renderebbe errati i prezzi
+	// già praticati secondo lo storico
+	now := time.Now()
+	if effectiveFrom.IsZero() || effectiveFrom.Before(now) {
+		effectiveFrom = now
+	}
+
+	return &PriceChange{
+		ID:            primitive.NewObjectID(),
+		ArticleID:     article.ID,
+		ArticleCode:   article.Code,
+		ListPrice:     listPrice,
+		Cost:          cost,
+		Currency:      article.Pricing.Currency,
+		EffectiveFrom: effectiveFrom,
+		Status:        PriceChangeScheduled,
+		Reason:        reason,
+		ChangedBy:     changedBy,
+		ChangedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+func (pc *PriceChange) IsDue(now time.Time) bool {
+	return pc.Status == PriceChangeScheduled && !now.Before(pc.EffectiveFrom)
+}
+
+func (pc *PriceChange) IsEffectiveAt(date time.Time) bool {
+	return pc.Status == PriceChangeApplied && !date.Before(pc.EffectiveFrom)
+}
+
+func (pc *PriceChange) MarkApplied(previousListPrice, previousCost float64) error {
+	if pc.Status != PriceChangeScheduled {
+		return ErrPriceChangeNotPending
+	}
+
+	pc.PreviousListPrice = previousListPrice
+	pc.PreviousCost = previousCost
+	pc.Status = PriceChangeApplied
+	pc.AppliedAt = time.Now()
+	pc.UpdatedAt = pc.AppliedAt
+	return nil
+}
+
+func (pc *PriceChange) Cancel(cancelledBy string) error {
+	if pc.Status != PriceChangeScheduled {
+		return ErrPriceChangeNotPending
+	}
+
+	pc.Status = PriceChangeCancelled
+	pc.CancelledBy = cancelledBy
+	pc.UpdatedAt = time.Now()
+	return nil
+}
+
+func (pc *PriceChange) GetVariationPercent() float64 {
+	if pc.PreviousListPrice == 0 {
+		return 0
+	}
+	return ((pc.ListPrice - pc.PreviousListPrice) / pc.PreviousListPrice) * 100
+}
@@ -0,0 +1,209 @@
+// internal/domain/price_list.go
+
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrPriceListNotFound = errors.New("price list not found")
+	ErrInvalidPriceList  = errors.New("invalid price list")
+)
+
+const PriceListStandard = "standard"
+
+type PriceListType string
+
+const (
+	PriceListTypeTable   PriceListType = "table"
+	PriceListTypeDerived PriceListType = "derived"
+)
+
+type PriceList struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code               string             `bson:"code" json:"code"`
+	Name               string             `bson:"name" json:"name"`
+	Description        string             `bson:"description" json:"description"`
+	Type               PriceListType      `bson:"type" json:"type"`
+	Currency           string             `bson:"currency" json:"currency"`
+	DerivedPercent     float64            `bson:"derived_percent" json:"derived_percent"`
+	Entries            []PriceListEntry   `bson:"entries" json:"entries"`
+	CustomerCategories []CustomerCategory `bson:"customer_categories" json:"customer_categories"`
+	ValidFrom          time.Time          `bson:"valid_from" json:"valid_from"`
+	ValidTo            time.Time          `bson:"valid_to" json:"valid_to"`
+	IsActive           bool               `bson:"is_active" json:"is_active"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+	CreatedBy          string             `bson:"created_by" json:"created_by"`
+	UpdatedBy          string             `bson:"updated_by" json:"updated_by"`
+}
+
+type PriceListEntry struct {
+	ArticleID   primitive.ObjectID `bson:"article_id" json:"article_id"`
+	ArticleCode string             `bson:"article_code" json:"article_code"`
+	Price       float64            `bson:"price" json:"price"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func NewPriceList(code, name string, listType PriceListType, createdBy string) (*PriceList, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" || code == PriceListStandard {
+		return nil, errors.New("price list code cannot be empty or reserved")
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("price list name cannot be empty")
+	}
+	if listType != PriceListTypeTable && listType != PriceListTypeDerived {
+		return nil, ErrInvalidPriceList
+	}
+
+	now := time.Now()
+	return &PriceList{
+		ID:                 primitive.NewObjectID(),
+		Code:               code,
+		Name:               strings.TrimSpace(name),
+		Type:               listType,
+		Currency:           "EUR",
+		Entries:            []PriceListEntry{},
+		CustomerCategories: []CustomerCategory{},
+		IsActive:           true,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		CreatedBy:          createdBy,
+		UpdatedBy:          createdBy,
+	}, nil
+}
+
+func (pl *PriceList) Validate() error {
+	if strings.TrimSpace(pl.Code) == "" {
+		return errors.New("price list code cannot be empty")
+	}
+	if strings.TrimSpace(pl.Name) == "" {
+		return errors.New("price list name cannot be empty")
+	}
+	if !pl.ValidTo.IsZero() && pl.ValidFrom.After(pl.ValidTo) {
+		return errors.New("valid_from must be before valid_to")
+	}
+
+	switch pl.Type {
+	case PriceListTypeTable:
+		for _, entry := range pl.Entries {
+			if entry.Price < 0 {
+				return ErrInvalidPrice
+			}
+		}
+	case PriceListTypeDerived:
+		if pl.DerivedPercent <= -100 {
+			return ErrInvalidPriceList
+		}
+	default:
+		return ErrInvalidPriceList
+	}
+
+	return nil
+}
+
+func (pl *PriceList) IsValid(now time.Time) bool {
+	if !pl.IsActive {
+		return false
+	}
+	if !pl.ValidFrom.IsZero() && now.Before(pl.ValidFrom) {
+		return false
+	}
+	if !pl.ValidTo.IsZero() && now.After(pl.ValidTo) {
+		return false
+	}
+	return true
+}
+
+func (pl *PriceList) SetEntry(article *Article, price float64) error {
+	if pl.Type != PriceListTypeTable {
+		return errors.New("entries can only be set on table price lists")
+	}
+	if price < 0 {
+		return ErrInvalidPrice
+	}
+
+	now := time.Now()
+	for i, entry := range pl.Entries {
+		if entry.ArticleID == article.ID {
+			pl.Entries[i].ArticleCode = article.Code
+			pl.Entries[i].Price = price
+			pl.Entries[i].UpdatedAt = now
+			pl.UpdatedAt = now
+			return nil
+		}
+	}
+
+	pl.Entries = append(pl.Entries, PriceListEntry{
+		ArticleID:   article.ID,
+		ArticleCode: article.Code,
+		Price:       price,
+		UpdatedAt:   now,
+	})
+	pl.UpdatedAt = now
+	return nil
+}
+
+func (pl *PriceList) RemoveEntry(articleID primitive.ObjectID) {
+	for i, entry := range pl.Entries {
+		if entry.ArticleID == articleID {
+			pl.Entries = append(pl.Entries[:i], pl.Entries[i+1:]...)
+			pl.UpdatedAt = time.Now()
+			return
+		}
+	}
+}
+
+func (pl *PriceList) PriceFor(article *Article) (float64, bool) {
+	switch pl.Type {
+	case PriceListTypeTable:
+		for _, entry := range pl.Entries {
+			if entry.ArticleID == article.ID || (entry.ArticleID.IsZero() && entry.ArticleCode == article.Code) {
+				return entry.Price, true
+			}
+		}
+		return 0, false
+
+	case PriceListTypeDerived:
+		if article.Pricing.ListPrice <= 0 {
+			return 0, false
+		}
+		return article.Pricing.ListPrice * (1 + pl.DerivedPercent/100), true
+
+	default:
+		return 0, false
+	}
+}
+
+func (pl *PriceList) AppliesToCategory(category CustomerCategory) bool {
+	for _, c := range pl.CustomerCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func (pl *PriceList) AssignCategory(category CustomerCategory) {
+	if pl.AppliesToCategory(category) {
+		return
+	}
+	pl.CustomerCategories = append(pl.CustomerCategories, category)
+	pl.UpdatedAt = time.Now()
+}
+
+func (pl *PriceList) UnassignCategory(category CustomerCategory) {
+	for i, c := range pl.CustomerCategories {
+		if c == category {
+			pl.CustomerCategories = append(pl.CustomerCategories[:i], pl.CustomerCategories[i+1:]...)
+			pl.UpdatedAt = time.Now()
+			return
+		}
+	}
+}
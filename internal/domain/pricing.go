@@ -0,0 +1,230 @@
+// internal/domain/pricing.go
+
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+type PricingStepType string
+
+const (
+	PricingStepListPrice        PricingStepType = "list_price"
+	PricingStepNetPrice         PricingStepType = "net_price"
+	PricingStepPriceList        PricingStepType = "price_list"
+	PricingStepCustomerDiscount PricingStepType = "customer_discount"
+	PricingStepPromotion        PricingStepType = "promotion"
+	PricingStepDiscountCap      PricingStepType = "discount_cap"
+	PricingStepBundle           PricingStepType = "bundle"
+	PricingStepManualDiscount   PricingStepType = "manual_discount"
+)
+
+type PricingStep struct {
+	Type        PricingStepType `bson:"type" json:"type"`
+	Description string          `bson:"description" json:"description"`
+	Reference   string          `bson:"reference" json:"reference"`
+	Percent     float64         `bson:"percent" json:"percent"`
+	PriceBefore float64         `bson:"price_before" json:"price_before"`
+	Amount      float64         `bson:"amount" json:"amount"`
+	PriceAfter  float64         `bson:"price_after" json:"price_after"`
+}
+
+func (s PricingStep) String() string {
+	switch {
+	case s.Percent > 0:
+		return fmt.Sprintf("%s -%.2f%%: %.2f → %.2f", s.Description, s.Percent, s.PriceBefore, s.PriceAfter)
+	case s.Amount != 0:
+		return fmt.Sprintf("%s -%.2f: %.2f → %.2f", s.Description, s.Amount, s.PriceBefore, s.PriceAfter)
+	default:
+		return fmt.Sprintf("%s: %.2f", s.Description, s.PriceAfter)
+	}
+}
+
+// PriceBreakdown traccia ogni passaggio del prezzo unitario, dal listino al netto finale
+type PriceBreakdown struct {
+	Steps      []PricingStep `bson:"steps" json:"steps"`
+	ListPrice  float64       `bson:"list_price" json:"list_price"`
+	BasePrice  float64       `bson:"base_price" json:"base_price"`
+	FinalPrice float64       `bson:"final_price" json:"final_price"`
+}
+
+func NewPriceBreakdown(listPrice float64) *PriceBreakdown {
+	return &PriceBreakdown{
+		Steps: []PricingStep{{
+			Type:        PricingStepListPrice,
+			Description: "List price",
+			PriceBefore: listPrice,
+			PriceAfter:  listPrice,
+		}},
+		ListPrice:  listPrice,
+		BasePrice:  listPrice,
+		FinalPrice: listPrice,
+	}
+}
+
+// SetBasePrice sostituisce il prezzo di partenza (netto cliente o listino dedicato) prima degli sconti
+func (b *PriceBreakdown) SetBasePrice(stepType PricingStepType, description, reference string, price float64) {
+	b.Steps = append(b.Steps, PricingStep{
+		Type:        stepType,
+		Description: description,
+		Reference:   reference,
+		PriceBefore: b.FinalPrice,
+		Amount:      b.FinalPrice - price,
+		PriceAfter:  price,
+	})
+	b.BasePrice = price
+	b.FinalPrice = price
+}
+
+func (b *PriceBreakdown) ApplyPercent(stepType PricingStepType, description, reference string, percent float64) {
+	if percent <= 0 {
+		return
+	}
+
+	before := b.FinalPrice
+	after := before * (1 - percent/100)
+
+	b.Steps = append(b.Steps, PricingStep{
+		Type:        stepType,
+		Description: description,
+		Reference:   reference,
+		Percent:     percent,
+		PriceBefore: before,
+		Amount:      before - after,
+		PriceAfter:  after,
+	})
+	b.FinalPrice = after
+}
+
+func (b *PriceBreakdown) ApplyAmount(stepType PricingStepType, description, reference string, amount float64) {
+	if amount <= 0 {
+		return
+	}
+
+	before := b.FinalPrice
+	if amount > before {
+		amount = before
+	}
+
+	b.Steps = append(b.Steps, PricingStep{
+		Type:        stepType,
+		Description: description,
+		Reference:   reference,
+		PriceBefore: before,
+		Amount:      amount,
+		PriceAfter:  before - amount,
+	})
+	b.FinalPrice = before - amount
+}
+
+func (b *PriceBreakdown) ApplyDiscountRule(rule *DiscountRule) {
+	if rule == nil {
+		return
+	}
+
+	for i, percent := range rule.Cascade() {
+		b.ApplyPercent(
+			PricingStepCustomerDiscount,
+			fmt.Sprintf("Customer discount %d", i+1),
+			rule.ID.Hex(),
+			percent,
+		)
+	}
+}
+
+// ApplyPromotion calcola lo sconto sul prezzo corrente, così le promozioni cumulabili si applicano in sequenza
+func (b *PriceBreakdown) ApplyPromotion(promo *Promotion, quantity float64) {
+	if promo == nil || quantity <= 0 {
+		return
+	}
+
+	if promo.Type == PromotionTypePercentDiscount {
+		b.ApplyPercent(PricingStepPromotion, promo.Name, promo.Code, promo.Rules.DiscountPercent)
+		return
+	}
+
+	b.ApplyAmount(PricingStepPromotion, promo.Name, promo.Code, promo.CalculateDiscount(b.FinalPrice, quantity)/quantity)
+}
+
+func (b *PriceBreakdown) ApplyDiscountCap(maxPercent float64) bool {
+	if maxPercent <= 0 || b.BasePrice <= 0 || b.DiscountPercent() <= maxPercent {
+		return false
+	}
+
+	before := b.FinalPrice
+	after := b.BasePrice * (1 - maxPercent/100)
+
+	b.Steps = append(b.Steps, PricingStep{
+		Type:        PricingStepDiscountCap,
+		Description: fmt.Sprintf("Max total discount %.2f%%", maxPercent),
+		PriceBefore: before,
+		Amount:      before - after,
+		PriceAfter:  after,
+	})
+	b.FinalPrice = after
+	return true
+}
+
+func (b *PriceBreakdown) DiscountFor(stepType PricingStepType) float64 {
+	total := 0.0
+	for _, step := range b.Steps {
+		if step.Type == stepType {
+			total += step.Amount
+		}
+	}
+	return total
+}
+
+func (b *PriceBreakdown) TotalDiscount() float64 {
+	return b.BasePrice - b.FinalPrice
+}
+
+func (b *PriceBreakdown) DiscountPercent() float64 {
+	if b.BasePrice <= 0 {
+		return 0
+	}
+	return b.TotalDiscount() / b.BasePrice * 100
+}
+
+func (b *PriceBreakdown) Lines() []string {
+	lines := make([]string, 0, len(b.Steps))
+	for _, step := range b.Steps {
+		lines = append(lines, step.String())
+	}
+	return lines
+}
+
+// Cascade restituisce gli sconti in cascata della regola (es. 30+10+5), oppure il solo sconto semplice
+func (r *DiscountRule) Cascade() []float64 {
+	if len(r.DiscountCascade) > 0 {
+		return r.DiscountCascade
+	}
+	if r.DiscountPercent > 0 {
+		return []float64{r.DiscountPercent}
+	}
+	return nil
+}
+
+func (r *DiscountRule) Apply(price float64) float64 {
+	for _, percent := range r.Cascade() {
+		price = price * (1 - percent/100)
+	}
+	return price
+}
+
+func (r *DiscountRule) EffectivePercent() float64 {
+	return (1 - r.Apply(1)) * 100
+}
+
+func (r *DiscountRule) Validate() error {
+	if r.DiscountPercent < 0 || r.DiscountPercent > 100 {
+		return ErrInvalidDiscountRule
+	}
+	for _, percent := range r.DiscountCascade {
+		if percent < 0 || percent > 100 || math.IsNaN(percent) {
+			return ErrInvalidDiscountRule
+		}
+	}
+	return nil
+}
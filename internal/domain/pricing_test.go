@@ -0,0 +1,44 @@
+// internal/domain/pricing_test.go
+
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceBreakdownApplyPromotionNxM(t *testing.T) {
+	// prendi 3 paghi 2: ogni 3 pezzi uno è gratis
+	promo := &Promotion{
+		Code:  "3X2",
+		Name:  "Prendi 3 paghi 2",
+		Type:  PromotionTypeNxM,
+		Rules: PromotionRules{BuyQuantity: 3, GetQuantity: 1},
+	}
+
+	tests := []struct {
+		name      string
+		quantity  float64
+		wantUnit  float64
+		wantTotal float64
+	}{
+		{name: "below threshold", quantity: 2, wantUnit: 30, wantTotal: 60},
+		{name: "one set", quantity: 3, wantUnit: 20, wantTotal: 60},
+		{name: "two sets", quantity: 6, wantUnit: 20, wantTotal: 120},
+		{name: "two sets and a remainder", quantity: 7, wantUnit: 30 - 60.0/7, wantTotal: 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := NewPriceBreakdown(30)
+			breakdown.ApplyPromotion(promo, tt.quantity)
+
+			if math.Abs(breakdown.FinalPrice-tt.wantUnit) > 1e-9 {
+				t.Errorf("unit price = %.4f, want %.4f", breakdown.FinalPrice, tt.wantUnit)
+			}
+			if total := breakdown.FinalPrice * tt.quantity; math.Abs(total-tt.wantTotal) > 1e-9 {
+				t.Errorf("line total = %.4f, want %.4f", total, tt.wantTotal)
+			}
+		})
+	}
+}
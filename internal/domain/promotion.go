@@ -4,6 +4,7 @@ package domain
 
 import (
 	"errors"
+	"math"
 	"strings"
 	"time"
 
@@ -28,24 +29,25 @@ const (
 )
 
 type Promotion struct {
-	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	Code          string              `bson:"code" json:"code"`
-	Name          string              `bson:"name" json:"name"`
-	Description   string              `bson:"description" json:"description"`
-	Type          PromotionType       `bson:"type" json:"type"`
-	IsActive      bool                `bson:"is_active" json:"is_active"`
-	ValidFrom     time.Time           `bson:"valid_from" json:"valid_from"`
-	ValidTo       time.Time           `bson:"valid_to" json:"valid_to"`
-	Priority      int                 `bson:"priority" json:"priority"`
-	Rules         PromotionRules      `bson:"rules" json:"rules"`
-	Applicability ApplicabilityRules  `bson:"applicability" json:"applicability"`
-	Conditions    PromotionConditions `bson:"conditions" json:"conditions"`
-	Limits        PromotionLimits     `bson:"limits" json:"limits"`
-	Statistics    PromotionStats      `bson:"statistics" json:"statistics"`
-	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
-	CreatedBy     string              `bson:"created_by" json:"created_by"`
-	UpdatedBy     string              `bson:"updated_by" json:"updated_by"`
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Code          string               `bson:"code" json:"code"`
+	Name          string               `bson:"name" json:"name"`
+	Description   string               `bson:"description" json:"description"`
+	Type          PromotionType        `bson:"type" json:"type"`
+	IsActive      bool                 `bson:"is_active" json:"is_active"`
+	ValidFrom     time.Time            `bson:"valid_from" json:"valid_from"`
+	ValidTo       time.Time            `bson:"valid_to" json:"valid_to"`
+	Priority      int                  `bson:"priority" json:"priority"`
+	Rules         PromotionRules       `bson:"rules" json:"rules"`
+	Applicability ApplicabilityRules   `bson:"applicability" json:"applicability"`
+	Conditions    PromotionConditions  `bson:"conditions" json:"conditions"`
+	Combination   PromotionCombination `bson:"combination" json:"combination"`
+	Limits        PromotionLimits      `bson:"limits" json:"limits"`
+	Statistics    PromotionStats       `bson:"statistics" json:"statistics"`
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+	CreatedBy     string               `bson:"created_by" json:"created_by"`
+	UpdatedBy     string               `bson:"updated_by" json:"updated_by"`
 }
 
 type PromotionRules struct {
@@ -93,7 +95,7 @@ type PromotionLimits struct {
 
 type PromotionStats struct {
 	TotalUsages    int            `bson:"total_usages" json:"total_usages"`
-	UsagesToday    int            `bson:"usages_today" json:"usages_today"`
+	UsagesToday    int            `bson:"-" json:"usages_today"`
 	LastUsageDate  time.Time      `bson:"last_usage_date" json:"last_usage_date"`
 	CustomerUsages map[string]int `bson:"customer_usages" json:"customer_usages"`
 	TotalRevenue   float64        `bson:"total_revenue" json:"total_revenue"`
@@ -174,6 +176,15 @@ func (p *Promotion) Validate() error {
 		if len(p.Rules.BundleArticles) < 2 {
 			return errors.New("bundle must contain at least 2 articles")
 		}
+		if p.Rules.BundlePrice <= 0 && p.Rules.DiscountPercent <= 0 {
+			return ErrInvalidPromotionRule
+		}
+	}
+
+	switch p.Combination.Mode {
+	case "", CombinationExclusive, CombinationStackable, CombinationBestOf:
+	default:
+		return errors.New("invalid promotion combination mode")
 	}
 
 	return nil
@@ -284,7 +295,24 @@ func (p *Promotion) IsApplicableToCustomer(customer *Customer) bool {
 	return true
 }
 
-func (p *Promotion) CanBeUsed(customerID string, quantity float64, amount float64) (bool, string) {
+func (p *Promotion) CanBeUsed(customerID string, quantity float64, amount float64, coupon *Coupon) (bool, string) {
+	return p.CanBeUsedAt(customerID, quantity, amount, coupon, time.Now())
+}
+
+func (p *Promotion) CanBeUsedAt(customerID string, quantity float64, amount float64, coupon *Coupon, date time.Time) (bool, string) {
+	if p.Conditions.RequiresCoupon {
+		if coupon == nil {
+			return false, "coupon required"
+		}
+		if !coupon.IsForPromotion(p) {
+			return false, "coupon not valid for this promotion"
+		}
+		customerOID, _ := primitive.ObjectIDFromHex(customerID)
+		if err := coupon.CanBeRedeemedBy(customerOID, date); err != nil {
+			return false, err.Error()
+		}
+	}
+
 	if p.Limits.MaxUsageTotal > 0 && p.Statistics.TotalUsages >= p.Limits.MaxUsageTotal {
 		return false, "promotion usage limit reached"
 	}
@@ -336,10 +364,166 @@ func (p *Promotion) CalculateDiscount(basePrice float64, quantity float64) float
 		return basePrice * float64(freeItems)
 
 	default:
+		// bundle e spedizione gratuita si calcolano sul documento intero
+		return 0
+	}
+}
+
+func (p *Promotion) IsDocumentLevel() bool {
+	return p.Type == PromotionTypeBundle || p.Type == PromotionTypeFreeShipping
+}
+
+type BundleAllocation struct {
+	LineIndex int
+	Quantity  float64
+	Required  bool
+}
+
+type BundleMatch struct {
+	Sets        int
+	Allocations []BundleAllocation
+}
+
+func (ba BundleArticle) Matches(line DocumentLine) bool {
+	if !ba.ArticleID.IsZero() {
+		return ba.ArticleID == line.ArticleID
+	}
+	return ba.ArticleCode != "" && ba.ArticleCode == line.ArticleCode
+}
+
+func (ba BundleArticle) required(bundle []BundleArticle) bool {
+	if ba.IsRequired {
+		return true
+	}
+	// senza articoli obbligatori dichiarati, tutti gli articoli del bundle sono obbligatori
+	for _, other := range bundle {
+		if other.IsRequired {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchBundle cerca quanti bundle completi sono presenti nelle righe, consumando le quantità ancora disponibili
+func (p *Promotion) MatchBundle(lines []DocumentLine, available []float64) *BundleMatch {
+	if p.Type != PromotionTypeBundle || len(p.Rules.BundleArticles) == 0 {
+		return nil
+	}
+
+	sets := -1
+	for _, ba := range p.Rules.BundleArticles {
+		if !ba.required(p.Rules.BundleArticles) {
+			continue
+		}
+
+		qty := ba.Quantity
+		if qty <= 0 {
+			qty = 1
+		}
+
+		found := 0.0
+		for i, line := range lines {
+			if ba.Matches(line) {
+				found += available[i]
+			}
+		}
+
+		n := int(found / qty)
+		if sets == -1 || n < sets {
+			sets = n
+		}
+	}
+
+	if sets <= 0 {
+		return nil
+	}
+
+	match := &BundleMatch{Sets: sets}
+	for _, ba := range p.Rules.BundleArticles {
+		qty := ba.Quantity
+		if qty <= 0 {
+			qty = 1
+		}
+		needed := qty * float64(sets)
+		required := ba.required(p.Rules.BundleArticles)
+
+		for i, line := range lines {
+			if needed <= 0 {
+				break
+			}
+			if !ba.Matches(line) || available[i] <= 0 {
+				continue
+			}
+
+			take := available[i]
+			if take > needed {
+				take = needed
+			}
+			available[i] -= take
+			needed -= take
+
+			match.Allocations = append(match.Allocations, BundleAllocation{
+				LineIndex: i,
+				Quantity:  take,
+				Required:  required,
+			})
+		}
+	}
+
+	return match
+}
+
+// CalculateBundleDiscount ripartisce lo sconto del bundle sulle righe: il gruppo obbligatorio costa BundlePrice
+// per ogni set, gli articoli opzionali ricevono DiscountPercent
+func (p *Promotion) CalculateBundleDiscount(lines []DocumentLine, match *BundleMatch) map[int]float64 {
+	discounts := make(map[int]float64)
+	if match == nil {
+		return discounts
+	}
+
+	requiredValue := 0.0
+	for _, alloc := range match.Allocations {
+		if alloc.Required {
+			requiredValue += alloc.Quantity * lines[alloc.LineIndex].UnitPrice
+		}
+	}
+
+	requiredDiscount := 0.0
+	if p.Rules.BundlePrice > 0 {
+		requiredDiscount = requiredValue - p.Rules.BundlePrice*float64(match.Sets)
+	} else {
+		requiredDiscount = requiredValue * p.Rules.DiscountPercent / 100
+	}
+	if requiredDiscount < 0 {
+		requiredDiscount = 0
+	}
+
+	for _, alloc := range match.Allocations {
+		value := alloc.Quantity * lines[alloc.LineIndex].UnitPrice
+		if alloc.Required {
+			if requiredValue > 0 {
+				discounts[alloc.LineIndex] += requiredDiscount * value / requiredValue
+			}
+			continue
+		}
+		discounts[alloc.LineIndex] += value * p.Rules.DiscountPercent / 100
+	}
+
+	for i, amount := range discounts {
+		discounts[i] = math.Round(amount*100) / 100
+	}
+
+	return discounts
+}
+
+func (p *Promotion) CalculateShippingDiscount(shippingCharge float64) float64 {
+	if p.Type != PromotionTypeFreeShipping || shippingCharge <= 0 {
 		return 0
 	}
+	return shippingCharge
 }
 
+// RecordUsage aggiorna solo la copia in memoria: in archivio l'utilizzo si registra con PromotionRepository.RecordUsage
 func (p *Promotion) RecordUsage(customerID string, revenue, discount float64) {
 	p.Statistics.TotalUsages++
 	p.Statistics.UsagesToday++
@@ -355,6 +539,11 @@ func (p *Promotion) RecordUsage(customerID string, revenue, discount float64) {
 	p.UpdatedAt = time.Now()
 }
 
+// SetDailyUsage imposta gli utilizzi odierni calcolati dal registro utilizzi
+func (p *Promotion) SetDailyUsage(count int) {
+	p.Statistics.UsagesToday = count
+}
+
 func (p *Promotion) ResetDailyUsage() {
 	p.Statistics.UsagesToday = 0
 	p.UpdatedAt = time.Now()
@@ -0,0 +1,128 @@
+// internal/domain/promotion_combination.go
+
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+type CombinationMode string
+
+const (
+	CombinationExclusive CombinationMode = "exclusive"
+	CombinationStackable CombinationMode = "stackable"
+	CombinationBestOf    CombinationMode = "best_of"
+)
+
+type PromotionCombination struct {
+	Group string          `bson:"group" json:"group"`
+	Mode  CombinationMode `bson:"mode" json:"mode"`
+}
+
+type CombinationPolicy struct {
+	StackOnCustomerDiscount bool
+	StackOnNetPrice         bool
+	MaxTotalDiscountPercent float64
+}
+
+type PricingDecision struct {
+	Subject  string `bson:"subject" json:"subject"`
+	Accepted bool   `bson:"accepted" json:"accepted"`
+	Reason   string `bson:"reason" json:"reason"`
+}
+
+type PromotionCandidate struct {
+	Promotion *Promotion
+	Discount  float64
+}
+
+func (p *Promotion) CombinationMode() CombinationMode {
+	if p.Combination.Mode != "" {
+		return p.Combination.Mode
+	}
+	// promozioni precedenti al motore di combinazione
+	if p.Conditions.CombineWithOthers {
+		return CombinationStackable
+	}
+	return CombinationExclusive
+}
+
+func (p *Promotion) CombinationGroup() string {
+	if p.Combination.Group != "" {
+		return p.Combination.Group
+	}
+	return p.Code
+}
+
+func AcceptDecision(subject, reason string) PricingDecision {
+	return PricingDecision{Subject: subject, Accepted: true, Reason: reason}
+}
+
+func RejectDecision(subject, reason string) PricingDecision {
+	return PricingDecision{Subject: subject, Accepted: false, Reason: reason}
+}
+
+// SelectPromotions sceglie le promozioni da applicare: priorità decrescente, migliore per ogni gruppo best_of,
+// una promozione esclusiva non si combina con nessun'altra
+func SelectPromotions(candidates []PromotionCandidate) ([]PromotionCandidate, []PricingDecision) {
+	sorted := make([]PromotionCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Promotion.Priority != sorted[j].Promotion.Priority {
+			return sorted[i].Promotion.Priority > sorted[j].Promotion.Priority
+		}
+		return sorted[i].Discount > sorted[j].Discount
+	})
+
+	bestOfGroup := make(map[string]PromotionCandidate)
+	for _, c := range sorted {
+		if c.Promotion.CombinationMode() != CombinationBestOf {
+			continue
+		}
+		group := c.Promotion.CombinationGroup()
+		if best, exists := bestOfGroup[group]; !exists || c.Discount > best.Discount {
+			bestOfGroup[group] = c
+		}
+	}
+
+	var selected []PromotionCandidate
+	var decisions []PricingDecision
+	var exclusive *Promotion
+
+	for _, c := range sorted {
+		promo := c.Promotion
+		mode := promo.CombinationMode()
+
+		if mode == CombinationBestOf {
+			best := bestOfGroup[promo.CombinationGroup()]
+			if best.Promotion != promo {
+				decisions = append(decisions, RejectDecision(promo.Code,
+					fmt.Sprintf("group %s: %s gives a better discount", promo.CombinationGroup(), best.Promotion.Code)))
+				continue
+			}
+		}
+
+		if exclusive != nil {
+			decisions = append(decisions, RejectDecision(promo.Code,
+				fmt.Sprintf("exclusive promotion %s has higher priority", exclusive.Code)))
+			continue
+		}
+
+		if mode == CombinationExclusive && len(selected) > 0 {
+			decisions = append(decisions, RejectDecision(promo.Code,
+				fmt.Sprintf("exclusive, but %s already applied with higher priority", selected[0].Promotion.Code)))
+			continue
+		}
+
+		if mode == CombinationExclusive {
+			exclusive = promo
+		}
+
+		selected = append(selected, c)
+		decisions = append(decisions, AcceptDecision(promo.Code,
+			fmt.Sprintf("%s, priority %d, discount %.2f", mode, promo.Priority, c.Discount)))
+	}
+
+	return selected, decisions
+}
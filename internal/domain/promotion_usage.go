@@ -0,0 +1,48 @@
+// internal/domain/promotion_usage.go
+
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrPromotionUsageLimit = errors.New("promotion usage limit reached")
+
+type PromotionUsage struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromotionID    primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	PromotionCode  string             `bson:"promotion_code" json:"promotion_code"`
+	CustomerID     primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	DocumentID     primitive.ObjectID `bson:"document_id" json:"document_id"`
+	DocumentNumber string             `bson:"document_number" json:"document_number"`
+	CouponCode     string             `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	Revenue        float64            `bson:"revenue" json:"revenue"`
+	Discount       float64            `bson:"discount" json:"discount"`
+	UsedAt         time.Time          `bson:"used_at" json:"used_at"`
+	RecordedBy     string             `bson:"recorded_by" json:"recorded_by"`
+}
+
+func NewPromotionUsage(promo *Promotion, document *Document, revenue, discount float64, recordedBy string) *PromotionUsage {
+	return &PromotionUsage{
+		ID:             primitive.NewObjectID(),
+		PromotionID:    promo.ID,
+		PromotionCode:  promo.Code,
+		CustomerID:     document.CustomerID,
+		DocumentID:     document.ID,
+		DocumentNumber: document.Number,
+		CouponCode:     document.CouponCode,
+		Revenue:        revenue,
+		Discount:       discount,
+		UsedAt:         time.Now(),
+		RecordedBy:     recordedBy,
+	}
+}
+
+// StartOfDay è l'inizio della finestra giornaliera usata per MaxUsagePerDay
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,27 @@
+// internal/domain/session.go
+
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session è la sessione di login di un operatore; la scadenza scorre a ogni attività
+type Session struct {
+	Token        string      `bson:"_id" json:"-"`
+	OperatorID   string      `bson:"operator_id" json:"operator_id"`
+	Username     string      `bson:"username" json:"username"`
+	Profile      ProfileType `bson:"profile" json:"profile"`
+	CreatedAt    time.Time   `bson:"created_at" json:"created_at"`
+	LastActivity time.Time   `bson:"last_activity" json:"last_activity"`
+	ExpiresAt    time.Time   `bson:"expires_at" json:"expires_at"`
+	IPAddress    string      `bson:"ip_address" json:"ip_address"`
+	UserAgent    string      `bson:"user_agent" json:"user_agent"`
+}
+
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
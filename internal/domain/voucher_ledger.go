@@ -0,0 +1,175 @@
+// internal/domain/voucher_ledger.go
+
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type VoucherEventType string
+
+const (
+	VoucherEventIssue   VoucherEventType = "issue"
+	VoucherEventUse     VoucherEventType = "use"
+	VoucherEventRestore VoucherEventType = "restore"
+	VoucherEventCancel  VoucherEventType = "cancel"
+	VoucherEventExtend  VoucherEventType = "extend"
+	VoucherEventExpire  VoucherEventType = "expire"
+)
+
+// VoucherLedgerEntry è un movimento del registro buoni: Amount è la variazione del saldo spendibile
+// (positiva per emissione, riaccredito e proroga di un buono scaduto) ed ExpiryDate la scadenza dopo il movimento
+type VoucherLedgerEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	VoucherID    primitive.ObjectID `bson:"voucher_id" json:"voucher_id"`
+	VoucherCode  string             `bson:"voucher_code" json:"voucher_code"`
+	CustomerID   primitive.ObjectID `bson:"customer_id" json:"customer_id"`
+	Type         VoucherEventType   `bson:"type" json:"type"`
+	Amount       float64            `bson:"amount" json:"amount"`
+	ExpiryDate   time.Time          `bson:"expiry_date" json:"expiry_date"`
+	DocumentID   string             `bson:"document_id,omitempty" json:"document_id,omitempty"`
+	DocumentType string             `bson:"document_type,omitempty" json:"document_type,omitempty"`
+	Date         time.Time          `bson:"date" json:"date"`
+	By           string             `bson:"by" json:"by"`
+	Notes        string             `bson:"notes" json:"notes"`
+}
+
+func NewVoucherLedgerEntry(v *CreditVoucher, eventType VoucherEventType, amount float64, by, notes string) *VoucherLedgerEntry {
+	return &VoucherLedgerEntry{
+		ID:          primitive.NewObjectID(),
+		VoucherID:   v.ID,
+		VoucherCode: v.Code,
+		CustomerID:  v.CustomerID,
+		Type:        eventType,
+		Amount:      roundCents(amount),
+		ExpiryDate:  v.ExpiryDate,
+		Date:        time.Now(),
+		By:          by,
+		Notes:       notes,
+	}
+}
+
+// LedgerFromHistory ricostruisce i movimenti di un buono creato prima del registro, dallo storico utilizzi
+// e dallo stato attuale. Le date di annullamento e proroga non sono note e si usa l'ultimo aggiornamento
+func (v *CreditVoucher) LedgerFromHistory() []*VoucherLedgerEntry {
+	entry := func(eventType VoucherEventType, amount float64, date time.Time, by, notes string) *VoucherLedgerEntry {
+		e := NewVoucherLedgerEntry(v, eventType, amount, by, notes)
+		e.Date = date
+		return e
+	}
+
+	issue := entry(VoucherEventIssue, v.OriginalAmount, v.IssuedDate, v.CreatedBy, v.Reason)
+	entries := []*VoucherLedgerEntry{issue}
+
+	balance := v.OriginalAmount
+	for _, usage := range v.UsageHistory {
+		eventType := VoucherEventUse
+		if usage.Amount < 0 {
+			eventType = VoucherEventRestore
+		}
+		e := entry(eventType, -usage.Amount, usage.UsedAt, usage.UsedBy, usage.Notes)
+		e.DocumentID = usage.DocumentID
+		e.DocumentType = usage.DocumentType
+		entries = append(entries, e)
+		balance -= usage.Amount
+	}
+
+	switch {
+	case v.Status == VoucherStatusCancelled && balance > 0:
+		entries = append(entries, entry(VoucherEventCancel, -balance, v.UpdatedAt, "", v.Reason))
+	case v.Status == VoucherStatusExpired && balance > 0:
+		entries = append(entries, entry(VoucherEventExpire, -balance, v.ExpiryDate, "system", ""))
+	}
+
+	return entries
+}
+
+// VoucherState è la situazione di un buono a una data, ricostruita dal registro
+type VoucherState struct {
+	VoucherID     primitive.ObjectID
+	Code          string
+	CustomerID    primitive.ObjectID
+	IssuedDate    time.Time
+	IssuedAmount  float64
+	ExpiryDate    time.Time
+	Balance       float64
+	Cancelled     bool
+	ExpiredAmount float64
+	ExpiredAt     time.Time
+}
+
+// ReplayVoucherLedger applica i movimenti fino ad asOf compreso. Un buono oltre la scadenza con saldo
+// residuo è considerato scaduto anche se il job di scadenza non l'ha ancora registrato
+func ReplayVoucherLedger(entries []*VoucherLedgerEntry, asOf time.Time) []*VoucherState {
+	sorted := make([]*VoucherLedgerEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Date.After(asOf) {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	states := map[primitive.ObjectID]*VoucherState{}
+	var order []*VoucherState
+	for _, e := range sorted {
+		state, ok := states[e.VoucherID]
+		if !ok {
+			state = &VoucherState{VoucherID: e.VoucherID, Code: e.VoucherCode, CustomerID: e.CustomerID}
+			states[e.VoucherID] = state
+			order = append(order, state)
+		}
+
+		state.Balance = roundCents(state.Balance + e.Amount)
+		state.ExpiryDate = e.ExpiryDate
+
+		switch e.Type {
+		case VoucherEventIssue:
+			state.IssuedDate = e.Date
+			state.IssuedAmount = e.Amount
+		case VoucherEventCancel:
+			state.Cancelled = true
+		case VoucherEventExpire:
+			state.ExpiredAmount = -e.Amount
+			state.ExpiredAt = e.ExpiryDate
+		case VoucherEventExtend:
+			state.ExpiredAmount = 0
+			state.ExpiredAt = time.Time{}
+		}
+	}
+
+	for _, state := range order {
+		if !state.Cancelled && state.Balance > 0 && !state.ExpiryDate.IsZero() && state.ExpiryDate.Before(asOf) {
+			state.ExpiredAmount = roundCents(state.ExpiredAmount + state.Balance)
+			state.ExpiredAt = state.ExpiryDate
+			state.Balance = 0
+		}
+	}
+
+	return order
+}
+
+// VoucherAgeBuckets sono le fasce di anzianità dall'emissione, in giorni
+var VoucherAgeBuckets = []struct {
+	Label   string
+	MaxDays int
+}{
+	{"0-30", 30},
+	{"31-90", 90},
+	{"91-180", 180},
+	{"181-365", 365},
+	{"oltre 365", 0},
+}
+
+// VoucherAgeBucket restituisce l'indice della fascia di anzianità del buono alla data
+func VoucherAgeBucket(issued, asOf time.Time) int {
+	days := int(asOf.Sub(issued).Hours() / 24)
+	for i, bucket := range VoucherAgeBuckets {
+		if bucket.MaxDays > 0 && days <= bucket.MaxDays {
+			return i
+		}
+	}
+	return len(VoucherAgeBuckets) - 1
+}
@@ -348,6 +348,51 @@ func (r *ArticleRepository) FindWithExpiredNetPrices(ctx context.Context, date t
 	return articles, nil
 }
 
+// FindWithNetPricesExpiring restituisce gli articoli con almeno un prezzo netto che scade nell'intervallo
+func (r *ArticleRepository) FindWithNetPricesExpiring(ctx context.Context, from, to time.Time) ([]*domain.Article, error) {
+	filter := bson.M{
+		"pricing.net_prices": bson.M{
+			"$elemMatch": bson.M{
+				"valid_to": bson.M{"$gte": from, "$lte": to},
+			},
+		},
+		"is_active": true,
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "code", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var articles []*domain.Article
+	if err = cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func (r *ArticleRepository) FindWithNetPricesForCustomer(ctx context.Context, customerID primitive.ObjectID) ([]*domain.Article, error) {
+	filter := bson.M{"pricing.net_prices.customer_id": customerID}
+	opts := options.Find().SetSort(bson.D{{Key: "code", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var articles []*domain.Article
+	if err = cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
 func (r *ArticleRepository) FindReplacementChain(ctx context.Context, code string) ([]*domain.Article, error) {
 	var articles []*domain.Article
 	visited := make(map[string]bool)
@@ -445,28 +490,31 @@ func (r *ArticleRepository) UpdateStock(ctx context.Context, articleID primitive
 	return nil
 }
 
-func (r *ArticleRepository) BulkUpdatePrices(ctx context.Context, updates map[primitive.ObjectID]float64) error {
-	var models []mongo.WriteModel
+// UpdatePricing scrive solo prezzo di listino e costo: le variazioni passano da ManagePricesUseCase,
+// che le registra nello storico prezzi nella stessa transazione
+func (r *ArticleRepository) UpdatePricing(ctx context.Context, article *domain.Article) error {
+	article.UpdatedAt = time.Now()
 
-	for id, price := range updates {
-		model := mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"_id": id}).
-			SetUpdate(bson.M{
-				"$set": bson.M{
-					"pricing.list_price": price,
-					"updated_at":         time.Now(),
-				},
-			})
-		models = append(models, model)
+	filter := bson.M{"_id": article.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"pricing.list_price":         article.Pricing.ListPrice,
+			"pricing.last_purchase_cost": article.Pricing.LastPurchaseCost,
+			"updated_at":                 article.UpdatedAt,
+			"updated_by":                 article.UpdatedBy,
+		},
 	}
 
-	if len(models) == 0 {
-		return nil
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
 	}
 
-	opts := options.BulkWrite().SetOrdered(false)
-	_, err := r.collection.BulkWrite(ctx, models, opts)
-	return err
+	if result.MatchedCount == 0 {
+		return domain.ErrArticleNotFound
+	}
+
+	return nil
 }
 
 func (r *ArticleRepository) CreateIndexes(ctx context.Context) error {
@@ -0,0 +1,181 @@
+// internal/repository/audit_repo.go
+
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+// le istanze che scrivono insieme si contendono il numero di sequenza: chi perde rilegge la coda
+const maxAuditAppendAttempts = 10
+
+var errAuditAppendContention = errors.New("audit append: too many concurrent writers")
+
+// AuditRepository è il registro di audit in sola aggiunta: non espone modifiche né cancellazioni.
+// L'indice univoco su sequence garantisce una sola catena anche con più istanze. Implementa auth.AuditStore
+type AuditRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewAuditRepository(db *mongo.Database) *AuditRepository {
+	return &AuditRepository{
+		collection: db.Collection("audit_log"),
+		db:         db,
+	}
+}
+
+// Append accoda la voce all'ultima registrata e ne calcola l'impronta
+func (r *AuditRepository) Append(ctx context.Context, record *domain.AuditRecord) error {
+	for attempt := 0; attempt < maxAuditAppendAttempts; attempt++ {
+		last, err := r.Last(ctx)
+		if err != nil {
+			return err
+		}
+
+		record.Seal(last)
+		_, err = r.collection.InsertOne(ctx, record)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return errAuditAppendContention
+}
+
+// Last restituisce la coda della catena, nil se il registro è vuoto
+func (r *AuditRepository) Last(ctx context.Context) (*domain.AuditRecord, error) {
+	var record domain.AuditRecord
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (r *AuditRepository) FindBySequence(ctx context.Context, sequence int64) (*domain.AuditRecord, error) {
+	var record domain.AuditRecord
+
+	err := r.collection.FindOne(ctx, bson.M{"sequence": sequence}).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// Search restituisce le voci più recenti che soddisfano il filtro
+func (r *AuditRepository) Search(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditRecord, error) {
+	query := bson.M{}
+	if filter.Area != "" {
+		query["area"] = filter.Area
+	}
+	if filter.ResourceID != "" {
+		query["resource_id"] = filter.ResourceID
+	}
+	if filter.Username != "" {
+		query["username"] = filter.Username
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		period := bson.M{}
+		if !filter.From.IsZero() {
+			period["$gte"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			period["$lt"] = filter.To
+		}
+		query["timestamp"] = period
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*domain.AuditRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// VerifyChain ricalcola la catena dall'inizio e restituisce le voci verificate; l'errore indica la
+// prima voce alterata, mancante o fuori ordine
+func (r *AuditRepository) VerifyChain(ctx context.Context) (int64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var prev *domain.AuditRecord
+	var verified int64
+	for cursor.Next(ctx) {
+		var record domain.AuditRecord
+		if err := cursor.Decode(&record); err != nil {
+			return verified, err
+		}
+		if err := record.VerifyAfter(prev); err != nil {
+			return verified, err
+		}
+		prev = &record
+		verified++
+	}
+
+	return verified, cursor.Err()
+}
+
+func (r *AuditRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "area", Value: 1}, {Key: "sequence", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "resource_id", Value: 1}, {Key: "sequence", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "username", Value: 1}, {Key: "sequence", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "timestamp", Value: -1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -144,6 +144,56 @@ func (r *BudgetRepository) FindActive(ctx context.Context, date time.Time) ([]*d
 	return budgets, nil
 }
 
+// FindOverlapping restituisce i budget il cui periodo interseca l'intervallo
+func (r *BudgetRepository) FindOverlapping(ctx context.Context, from, to time.Time) ([]*domain.Budget, error) {
+	filter := bson.M{
+		"start_date": bson.M{"$lte": to},
+		"end_date":   bson.M{"$gte": from},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var budgets []*domain.Budget
+	if err = cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+// IncrementActuals somma l'apporto in modo atomico, così registrazioni concorrenti non si sovrascrivono
+func (r *BudgetRepository) IncrementActuals(ctx context.Context, id primitive.ObjectID, contribution domain.BudgetContribution) error {
+	inc := bson.M{
+		"actual_revenue": contribution.Revenue,
+		"actual_margin":  contribution.Margin,
+		"actual_orders":  contribution.Orders,
+	}
+	for family, revenue := range contribution.Families {
+		inc["family_actuals."+family] = revenue
+	}
+
+	filter := bson.M{"_id": id}
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrBudgetNotFound
+	}
+
+	return nil
+}
+
 func (r *BudgetRepository) FindByType(ctx context.Context, budgetType domain.BudgetType) ([]*domain.Budget, error) {
 	filter := bson.M{
 		"type": budgetType,
@@ -184,6 +234,60 @@ func (r *BudgetRepository) FindExpired(ctx context.Context, date time.Time) ([]*
 	return budgets, nil
 }
 
+// FindStatusChanges trova i budget da attivare o da chiudere alla data
+func (r *BudgetRepository) FindStatusChanges(ctx context.Context, date time.Time) ([]*domain.Budget, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{
+				"status":     bson.M{"$nin": []domain.BudgetStatus{domain.BudgetStatusActive, domain.BudgetStatusClosed}},
+				"start_date": bson.M{"$lte": date},
+			},
+			{
+				"status":   bson.M{"$ne": domain.BudgetStatusClosed},
+				"end_date": bson.M{"$lt": date},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var budgets []*domain.Budget
+	if err = cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+// UpdateStatus salva solo stato e dati di chiusura, senza sovrascrivere i consuntivi aggiornati in parallelo
+func (r *BudgetRepository) UpdateStatus(ctx context.Context, budget *domain.Budget) error {
+	filter := bson.M{"_id": budget.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          budget.Status,
+			"closed_at":       budget.ClosedAt,
+			"final_incentive": budget.FinalIncentive,
+			"scheme":          budget.Scheme,
+			"updated_at":      budget.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrBudgetNotFound
+	}
+
+	return nil
+}
+
 func (r *BudgetRepository) FindAll(ctx context.Context, skip, limit int) ([]*domain.Budget, error) {
 	opts := options.Find().
 		SetSkip(int64(skip)).
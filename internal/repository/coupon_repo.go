@@ -0,0 +1,263 @@
+// internal/repository/coupon_repo.go
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type CouponRepository struct {
+	collection *mongo.Collection
+	batches    *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewCouponRepository(db *mongo.Database) *CouponRepository {
+	return &CouponRepository{
+		collection: db.Collection("coupons"),
+		batches:    db.Collection("coupon_batches"),
+		db:         db,
+	}
+}
+
+// maxCouponBatchAttempts limita i tentativi di creazione quando un codice casuale collide con uno esistente
+const maxCouponBatchAttempts = 5
+
+// CreateBatch registra lotto e coupon in una transazione: su codice duplicato nulla resta scritto,
+// i codici in collisione vengono rigenerati e l'inserimento riprova
+func (r *CouponRepository) CreateBatch(ctx context.Context, batch *domain.CouponBatch, coupons []*domain.Coupon) error {
+	if batch.ID.IsZero() {
+		batch.ID = primitive.NewObjectID()
+	}
+
+	docs := make([]interface{}, 0, len(coupons))
+	for _, coupon := range coupons {
+		coupon.BatchID = batch.ID
+		docs = append(docs, coupon)
+	}
+
+	for attempt := 0; attempt < maxCouponBatchAttempts; attempt++ {
+		err := RunInTransaction(ctx, r.db, func(ctx context.Context) error {
+			if _, err := r.batches.InsertOne(ctx, batch); err != nil {
+				return err
+			}
+			_, err := r.collection.InsertMany(ctx, docs)
+			return err
+		})
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		taken, err := r.existingCodes(ctx, coupons)
+		if err != nil {
+			return err
+		}
+		if len(taken) == 0 {
+			return errors.New("coupon batch already exists")
+		}
+		if err := batch.RegenerateCodes(coupons, taken); err != nil {
+			return err
+		}
+	}
+
+	return errors.New("coupon codes still duplicated after regeneration")
+}
+
+func (r *CouponRepository) existingCodes(ctx context.Context, coupons []*domain.Coupon) (map[string]bool, error) {
+	codes := make([]string, 0, len(coupons))
+	for _, coupon := range coupons {
+		codes = append(codes, coupon.Code)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"code": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"code": bson.M{"$in": codes}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	taken := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var coupon domain.Coupon
+		if err := cursor.Decode(&coupon); err != nil {
+			return nil, err
+		}
+		taken[coupon.Code] = true
+	}
+
+	return taken, cursor.Err()
+}
+
+func (r *CouponRepository) Create(ctx context.Context, coupon *domain.Coupon) error {
+	if coupon.ID.IsZero() {
+		coupon.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, coupon)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("coupon with this code already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *CouponRepository) FindByCode(ctx context.Context, code string) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	filter := bson.M{"code": domain.NormalizeCouponCode(code)}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&coupon)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrCouponNotFound
+		}
+		return nil, err
+	}
+
+	return &coupon, nil
+}
+
+func (r *CouponRepository) FindByBatch(ctx context.Context, batchID primitive.ObjectID) ([]*domain.Coupon, error) {
+	filter := bson.M{"batch_id": batchID}
+	opts := options.Find().SetSort(bson.D{{Key: "code", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var coupons []*domain.Coupon
+	if err = cursor.All(ctx, &coupons); err != nil {
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+func (r *CouponRepository) FindBatchesByPromotion(ctx context.Context, promotionID primitive.ObjectID) ([]*domain.CouponBatch, error) {
+	filter := bson.M{"promotion_id": promotionID}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.batches.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var batches []*domain.CouponBatch
+	if err = cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+
+	return batches, nil
+}
+
+// Redeem registra l'utilizzo in modo atomico: il filtro impedisce di superare il numero massimo di utilizzi
+func (r *CouponRepository) Redeem(ctx context.Context, coupon *domain.Coupon, redemption domain.CouponRedemption) error {
+	filter := bson.M{
+		"_id":                     coupon.ID,
+		"is_active":               true,
+		"redemptions.document_id": bson.M{"$ne": redemption.DocumentID},
+	}
+	if coupon.MaxRedemptions > 0 {
+		filter["redemption_count"] = bson.M{"$lt": coupon.MaxRedemptions}
+	}
+
+	update := bson.M{
+		"$inc":  bson.M{"redemption_count": 1},
+		"$push": bson.M{"redemptions": redemption},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrCouponAlreadyUsed
+	}
+
+	return nil
+}
+
+func (r *CouponRepository) ReleaseRedemption(ctx context.Context, couponID, documentID primitive.ObjectID) error {
+	filter := bson.M{
+		"_id":                     couponID,
+		"redemptions.document_id": documentID,
+	}
+	update := bson.M{
+		"$inc":  bson.M{"redemption_count": -1},
+		"$pull": bson.M{"redemptions": bson.M{"document_id": documentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrCouponNotFound
+	}
+
+	return nil
+}
+
+func (r *CouponRepository) Deactivate(ctx context.Context, couponID primitive.ObjectID) error {
+	filter := bson.M{"_id": couponID}
+	update := bson.M{"$set": bson.M{"is_active": false, "updated_at": time.Now()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrCouponNotFound
+	}
+
+	return nil
+}
+
+func (r *CouponRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "batch_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "promotion_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "customer_id", Value: 1}},
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return err
+	}
+
+	_, err := r.batches.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "promotion_id", Value: 1}},
+	})
+	return err
+}
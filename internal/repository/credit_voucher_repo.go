@@ -4,7 +4,6 @@ package repository
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,6 +14,8 @@ import (
 	"ricambi-manager/internal/domain"
 )
 
+const maxVoucherCodeAttempts = 5
+
 type CreditVoucherRepository struct {
 	collection *mongo.Collection
 	db         *mongo.Database
@@ -32,8 +33,30 @@ func (r *CreditVoucherRepository) Create(ctx context.Context, voucher *domain.Cr
 		voucher.ID = primitive.NewObjectID()
 	}
 
-	_, err := r.collection.InsertOne(ctx, voucher)
-	return err
+	// il codice è casuale: in caso di collisione con l'indice univoco se ne genera uno nuovo. Dentro una
+	// transazione la collisione la annulla, quindi si restituisce l'errore e il chiamante riprova da capo
+	for attempt := 0; attempt < maxVoucherCodeAttempts; attempt++ {
+		_, err := r.collection.InsertOne(ctx, voucher)
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if mongo.SessionFromContext(ctx) != nil {
+			return domain.ErrDuplicateVoucherCode
+		}
+
+		code, err := domain.GenerateVoucherCode()
+		if err != nil {
+			return err
+		}
+		voucher.Code = code
+	}
+
+	return domain.ErrDuplicateVoucherCode
+}
+
+// RunInTransaction esegue fn in una transazione, per salvare il buono insieme al movimento di registro
+func (r *CreditVoucherRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTransaction(ctx, r.db, fn)
 }
 
 func (r *CreditVoucherRepository) Update(ctx context.Context, voucher *domain.CreditVoucher) error {
@@ -71,7 +94,7 @@ func (r *CreditVoucherRepository) FindByID(ctx context.Context, id primitive.Obj
 
 func (r *CreditVoucherRepository) FindByCode(ctx context.Context, code string) (*domain.CreditVoucher, error) {
 	var voucher domain.CreditVoucher
-	filter := bson.M{"code": strings.ToUpper(strings.TrimSpace(code))}
+	filter := bson.M{"code": domain.NormalizeVoucherCode(code)}
 
 	err := r.collection.FindOne(ctx, filter).Decode(&voucher)
 	if err != nil {
@@ -174,10 +197,76 @@ func (r *CreditVoucherRepository) FindExpiringSoon(ctx context.Context, days int
 	return vouchers, nil
 }
 
+func (r *CreditVoucherRepository) FindAll(ctx context.Context) ([]*domain.CreditVoucher, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "issued_date", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var vouchers []*domain.CreditVoucher
+	if err = cursor.All(ctx, &vouchers); err != nil {
+		return nil, err
+	}
+
+	return vouchers, nil
+}
+
+// FindDuplicateCodes restituisce i buoni il cui codice è già usato da un buono creato prima, come
+// i VC-<timestamp> emessi nello stesso secondo
+func (r *CreditVoucherRepository) FindDuplicateCodes(ctx context.Context) ([]*domain.CreditVoucher, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$code",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	var duplicates []primitive.ObjectID
+	for _, group := range groups {
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if len(duplicates) == 0 {
+		return nil, nil
+	}
+
+	cursor, err = r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var vouchers []*domain.CreditVoucher
+	if err = cursor.All(ctx, &vouchers); err != nil {
+		return nil, err
+	}
+
+	return vouchers, nil
+}
+
 func (r *CreditVoucherRepository) CreateIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "code", Value: 1}},
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
 		},
 		{
 			Keys: bson.D{{Key: "customer_id", Value: 1}},
@@ -0,0 +1,119 @@
+// internal/repository/discount_limit_repo.go
+
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type DiscountLimitRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewDiscountLimitRepository(db *mongo.Database) *DiscountLimitRepository {
+	return &DiscountLimitRepository{
+		collection: db.Collection("discount_limits"),
+		db:         db,
+	}
+}
+
+// Save inserisce o sostituisce il limite
+func (r *DiscountLimitRepository) Save(ctx context.Context, limit *domain.DiscountLimit) error {
+	if limit.ID.IsZero() {
+		limit.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": limit.ID}, limit, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *DiscountLimitRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrDiscountLimitNotFound
+	}
+
+	return nil
+}
+
+func (r *DiscountLimitRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.DiscountLimit, error) {
+	var limit domain.DiscountLimit
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&limit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrDiscountLimitNotFound
+		}
+		return nil, err
+	}
+
+	return &limit, nil
+}
+
+func (r *DiscountLimitRepository) FindAll(ctx context.Context) (domain.DiscountLimits, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "profile", Value: 1},
+		{Key: "username", Value: 1},
+		{Key: "family", Value: 1},
+		{Key: "brand", Value: 1},
+	})
+
+	return r.find(ctx, bson.M{}, opts)
+}
+
+// FindForOperator restituisce i limiti del profilo e quelli intestati all'operatore
+func (r *DiscountLimitRepository) FindForOperator(ctx context.Context, operator *domain.Operator) (domain.DiscountLimits, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"profile": operator.Profile},
+			{"operator_id": operator.ID},
+			{"username": operator.Username},
+		},
+	}
+
+	return r.find(ctx, filter, options.Find())
+}
+
+func (r *DiscountLimitRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) (domain.DiscountLimits, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var limits domain.DiscountLimits
+	if err = cursor.All(ctx, &limits); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+func (r *DiscountLimitRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "profile", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "operator_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "username", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,211 @@
+// internal/repository/document_repo.go
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type DocumentRepository struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewDocumentRepository(db *mongo.Database) *DocumentRepository {
+	return &DocumentRepository{
+		collection: db.Collection("documents"),
+		counters:   db.Collection("document_counters"),
+		db:         db,
+	}
+}
+
+func (r *DocumentRepository) Create(ctx context.Context, document *domain.Document) error {
+	if document.ID.IsZero() {
+		document.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, document)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("document with this number already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *DocumentRepository) Update(ctx context.Context, document *domain.Document) error {
+	document.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": document.ID}
+	update := bson.M{"$set": document}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// RunInTransaction esegue fn in una transazione sul database dei documenti
+func (r *DocumentRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTransaction(ctx, r.db, fn)
+}
+
+func (r *DocumentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.Document, error) {
+	var document domain.Document
+	filter := bson.M{"_id": id}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrDocumentNotFound
+		}
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+func (r *DocumentRepository) FindByNumber(ctx context.Context, number string) (*domain.Document, error) {
+	var document domain.Document
+	filter := bson.M{"number": strings.ToUpper(strings.TrimSpace(number))}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrDocumentNotFound
+		}
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+func (r *DocumentRepository) FindByCustomer(ctx context.Context, customerID primitive.ObjectID, limit int) ([]*domain.Document, error) {
+	filter := bson.M{"customer_id": customerID}
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *DocumentRepository) FindPosted(ctx context.Context, types []domain.DocumentType, from, to time.Time) ([]*domain.Document, error) {
+	filter := bson.M{
+		"status": domain.DocumentStatusPosted,
+		"type":   bson.M{"$in": types},
+		"date":   bson.M{"$gte": from, "$lte": to},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *DocumentRepository) FindPostedByArticle(ctx context.Context, articleID primitive.ObjectID, from, to time.Time) ([]*domain.Document, error) {
+	filter := bson.M{
+		"status":           domain.DocumentStatusPosted,
+		"type":             bson.M{"$in": []domain.DocumentType{domain.DocumentTypeInvoice, domain.DocumentTypeCreditNote}},
+		"lines.article_id": articleID,
+		"date":             bson.M{"$gte": from, "$lte": to},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *DocumentRepository) FindDrafts(ctx context.Context, operatorUsername string) ([]*domain.Document, error) {
+	filter := bson.M{
+		"status":            domain.DocumentStatusDraft,
+		"operator_username": operatorUsername,
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+// NextNumber restituisce il progressivo annuale per tipo documento
+func (r *DocumentRepository) NextNumber(ctx context.Context, docType domain.DocumentType, year int) (string, error) {
+	filter := bson.M{"type": docType, "year": year}
+	update := bson.M{"$inc": bson.M{"sequence": int64(1)}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	if err := r.counters.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter); err != nil {
+		return "", err
+	}
+
+	return domain.FormatDocumentNumber(docType, year, counter.Sequence), nil
+}
+
+func (r *DocumentRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.Document, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*domain.Document
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (r *DocumentRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"number": bson.M{"$gt": ""},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "date", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "supplier_id", Value: 1}, {Key: "date", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "type", Value: 1}, {Key: "date", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "lines.article_id", Value: 1}},
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return err
+	}
+
+	_, err := r.counters.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "type", Value: 1}, {Key: "year", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+// internal/repository/failed_access_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+// FailedAccessRepository registra gli accessi respinti. Implementa auth.FailedAccessStore
+type FailedAccessRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewFailedAccessRepository(db *mongo.Database) *FailedAccessRepository {
+	return &FailedAccessRepository{
+		collection: db.Collection("failed_access"),
+		db:         db,
+	}
+}
+
+func (r *FailedAccessRepository) Create(ctx context.Context, attempt *domain.FailedAccess) error {
+	if attempt.ID.IsZero() {
+		attempt.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, attempt)
+	return err
+}
+
+func (r *FailedAccessRepository) FindSince(ctx context.Context, since time.Time, limit int64) ([]*domain.FailedAccess, error) {
+	filter := bson.M{"timestamp": bson.M{"$gte": since}}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*domain.FailedAccess
+	if err = cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (r *FailedAccessRepository) FindByUsername(ctx context.Context, username string, limit int64) ([]*domain.FailedAccess, error) {
+	filter := bson.M{"username": username}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*domain.FailedAccess
+	if err = cursor.All(ctx, &attempts); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (r *FailedAccessRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "username", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "timestamp", Value: -1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
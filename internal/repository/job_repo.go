@@ -0,0 +1,139 @@
+// internal/repository/job_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type JobRepository struct {
+	locks *mongo.Collection
+	runs  *mongo.Collection
+	db    *mongo.Database
+}
+
+func NewJobRepository(db *mongo.Database) *JobRepository {
+	return &JobRepository{
+		locks: db.Collection("job_locks"),
+		runs:  db.Collection("job_runs"),
+		db:    db,
+	}
+}
+
+// AcquireLock prende il lock del job per l'esecuzione slot. Fallisce se un'altra istanza lo tiene
+// ancora o se lo slot è già stato eseguito: in quel caso l'upsert collide sull'_id e si restituisce false
+func (r *JobRepository) AcquireLock(ctx context.Context, job, owner string, slot time.Time, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":          job,
+		"locked_until": bson.M{"$lt": now},
+		"slot":         bson.M{"$lt": slot},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":        owner,
+			"slot":         slot,
+			"locked_until": now.Add(ttl),
+			"acquired_at":  now,
+		},
+	}
+
+	_, err := r.locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *JobRepository) ReleaseLock(ctx context.Context, job, owner string) error {
+	filter := bson.M{"_id": job, "owner": owner}
+	update := bson.M{"$set": bson.M{"locked_until": time.Time{}}}
+
+	_, err := r.locks.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *JobRepository) FindLocks(ctx context.Context) ([]*domain.JobLock, error) {
+	cursor, err := r.locks.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var locks []*domain.JobLock
+	if err = cursor.All(ctx, &locks); err != nil {
+		return nil, err
+	}
+
+	return locks, nil
+}
+
+func (r *JobRepository) CreateRun(ctx context.Context, run *domain.JobRun) error {
+	_, err := r.runs.InsertOne(ctx, run)
+	return err
+}
+
+func (r *JobRepository) UpdateRun(ctx context.Context, run *domain.JobRun) error {
+	_, err := r.runs.ReplaceOne(ctx, bson.M{"_id": run.ID}, run)
+	return err
+}
+
+// FindRuns restituisce le ultime esecuzioni, di un job o di tutti se job è vuoto
+func (r *JobRepository) FindRuns(ctx context.Context, job string, limit int) ([]*domain.JobRun, error) {
+	filter := bson.M{}
+	if job != "" {
+		filter["job"] = job
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.runs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var runs []*domain.JobRun
+	if err = cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (r *JobRepository) DeleteRunsBefore(ctx context.Context, date time.Time) (int64, error) {
+	result, err := r.runs.DeleteMany(ctx, bson.M{"started_at": bson.M{"$lt": date}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (r *JobRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "job", Value: 1}, {Key: "started_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "started_at", Value: 1}},
+		},
+	}
+
+	_, err := r.runs.Indexes().CreateMany(ctx, indexes)
+	return err
+}
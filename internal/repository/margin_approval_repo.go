@@ -0,0 +1,120 @@
+// internal/repository/margin_approval_repo.go
+
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type MarginApprovalRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewMarginApprovalRepository(db *mongo.Database) *MarginApprovalRepository {
+	return &MarginApprovalRepository{
+		collection: db.Collection("margin_approvals"),
+		db:         db,
+	}
+}
+
+func (r *MarginApprovalRepository) Create(ctx context.Context, approval *domain.MarginApproval) error {
+	if approval.ID.IsZero() {
+		approval.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, approval)
+	return err
+}
+
+// SaveDecision salva la decisione solo se la richiesta è ancora in attesa, così due approvatori non decidono la stessa riga
+func (r *MarginApprovalRepository) SaveDecision(ctx context.Context, approval *domain.MarginApproval) error {
+	filter := bson.M{"_id": approval.ID, "status": domain.ApprovalStatusPending}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     approval.Status,
+			"decided_by": approval.DecidedBy,
+			"decided_at": approval.DecidedAt,
+			"method":     approval.Method,
+			"note":       approval.Note,
+			"updated_at": approval.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrApprovalNotPending
+	}
+
+	return nil
+}
+
+func (r *MarginApprovalRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.MarginApproval, error) {
+	var approval domain.MarginApproval
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&approval)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrApprovalNotFound
+		}
+		return nil, err
+	}
+
+	return &approval, nil
+}
+
+func (r *MarginApprovalRepository) FindPending(ctx context.Context, limit int) ([]*domain.MarginApproval, error) {
+	filter := bson.M{"status": domain.ApprovalStatusPending}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "requested_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *MarginApprovalRepository) FindByDocument(ctx context.Context, documentID primitive.ObjectID) ([]*domain.MarginApproval, error) {
+	filter := bson.M{"document_id": documentID}
+	opts := options.Find().SetSort(bson.D{{Key: "requested_at", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *MarginApprovalRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.MarginApproval, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var approvals []*domain.MarginApproval
+	if err = cursor.All(ctx, &approvals); err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+func (r *MarginApprovalRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "requested_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "document_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
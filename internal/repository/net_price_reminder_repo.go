@@ -0,0 +1,185 @@
+// internal/repository/net_price_reminder_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type NetPriceReminderRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewNetPriceReminderRepository(db *mongo.Database) *NetPriceReminderRepository {
+	return &NetPriceReminderRepository{
+		collection: db.Collection("net_price_reminders"),
+		db:         db,
+	}
+}
+
+// CreateIfMissing inserisce il promemoria solo se non ne esiste già uno per lo stesso articolo, cliente e scadenza,
+// così il job può girare più volte senza duplicati né riaprire quelli chiusi
+func (r *NetPriceReminderRepository) CreateIfMissing(ctx context.Context, reminder *domain.NetPriceReminder) (bool, error) {
+	filter := bson.M{
+		"article_id":  reminder.ArticleID,
+		"customer_id": reminder.CustomerID,
+		"valid_to":    reminder.ValidTo,
+	}
+	update := bson.M{"$setOnInsert": reminder}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+func (r *NetPriceReminderRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.NetPriceReminder, error) {
+	var reminder domain.NetPriceReminder
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&reminder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrReminderNotFound
+		}
+		return nil, err
+	}
+
+	return &reminder, nil
+}
+
+func (r *NetPriceReminderRepository) FindOpen(ctx context.Context, limit int) ([]*domain.NetPriceReminder, error) {
+	filter := bson.M{"status": domain.ReminderStatusOpen}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "valid_to", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reminders []*domain.NetPriceReminder
+	if err = cursor.All(ctx, &reminders); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+func (r *NetPriceReminderRepository) CountOpen(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"status": domain.ReminderStatusOpen})
+}
+
+func (r *NetPriceReminderRepository) Close(ctx context.Context, id primitive.ObjectID, status domain.ReminderStatus, closedBy string) error {
+	filter := bson.M{"_id": id, "status": domain.ReminderStatusOpen}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    status,
+			"closed_at": time.Now(),
+			"closed_by": closedBy,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrReminderNotFound
+	}
+
+	return nil
+}
+
+// CloseFor chiude i promemoria aperti della coppia articolo/cliente, per esempio dopo un rinnovo
+func (r *NetPriceReminderRepository) CloseFor(
+	ctx context.Context,
+	articleID, customerID primitive.ObjectID,
+	status domain.ReminderStatus,
+	closedBy string,
+) error {
+	filter := bson.M{
+		"article_id":  articleID,
+		"customer_id": customerID,
+		"status":      domain.ReminderStatusOpen,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    status,
+			"closed_at": time.Now(),
+			"closed_by": closedBy,
+		},
+	}
+
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// RemoveDuplicates elimina i promemoria doppi creati prima dell'indice univoco, tenendo il più vecchio
+func (r *NetPriceReminderRepository) RemoveDuplicates(ctx context.Context) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"article_id": "$article_id", "customer_id": "$customer_id", "valid_to": "$valid_to"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	var duplicates []primitive.ObjectID
+	for _, group := range groups {
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if len(duplicates) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (r *NetPriceReminderRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "article_id", Value: 1}, {Key: "customer_id", Value: 1}, {Key: "valid_to", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "valid_to", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
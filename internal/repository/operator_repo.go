@@ -121,24 +121,6 @@ func (r *OperatorRepository) FindByEmail(ctx context.Context, email string) (*do
 	return &operator, nil
 }
 
-func (r *OperatorRepository) FindBySessionToken(ctx context.Context, token string) (*domain.Operator, error) {
-	var operator domain.Operator
-	filter := bson.M{
-		"session_token":  token,
-		"session_expiry": bson.M{"$gt": time.Now()},
-	}
-
-	err := r.collection.FindOne(ctx, filter).Decode(&operator)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, domain.ErrOperatorNotFound
-		}
-		return nil, err
-	}
-
-	return &operator, nil
-}
-
 func (r *OperatorRepository) FindByProfile(ctx context.Context, profile domain.ProfileType) ([]*domain.Operator, error) {
 	filter := bson.M{
 		"profile":   profile,
@@ -242,12 +224,16 @@ func (r *OperatorRepository) UpdateLastLogin(ctx context.Context, operatorID pri
 	return nil
 }
 
-func (r *OperatorRepository) UpdatePassword(ctx context.Context, operatorID primitive.ObjectID, passwordHash string) error {
-	filter := bson.M{"_id": operatorID}
+// UpdatePassword salva la password impostata da Operator.ChangePassword o ResetPassword insieme
+// allo storico e all'obbligo di cambio
+func (r *OperatorRepository) UpdatePassword(ctx context.Context, operator *domain.Operator) error {
+	filter := bson.M{"_id": operator.ID}
 	update := bson.M{
 		"$set": bson.M{
-			"password_hash":        passwordHash,
-			"last_password_change": time.Now(),
+			"password_hash":        operator.PasswordHash,
+			"password_history":     operator.PasswordHistory,
+			"last_password_change": operator.LastPasswordChange,
+			"must_change_password": operator.MustChangePassword,
 			"updated_at":           time.Now(),
 		},
 	}
@@ -264,12 +250,12 @@ func (r *OperatorRepository) UpdatePassword(ctx context.Context, operatorID prim
 	return nil
 }
 
-func (r *OperatorRepository) IncrementFailedAttempts(ctx context.Context, operatorID primitive.ObjectID) error {
-	filter := bson.M{"_id": operatorID}
+// UpdateSupervisorPIN scrive solo il PIN, senza riportare in archivio il resto dell'operatore in memoria
+func (r *OperatorRepository) UpdateSupervisorPIN(ctx context.Context, operator *domain.Operator) error {
+	filter := bson.M{"_id": operator.ID}
 	update := bson.M{
-		"$inc": bson.M{"failed_attempts": 1},
 		"$set": bson.M{
-			"last_failed_attempt": time.Now(),
+			"supervisor_pin_hash": operator.SupervisorPINHash,
 			"updated_at":          time.Now(),
 		},
 	}
@@ -286,6 +272,33 @@ func (r *OperatorRepository) IncrementFailedAttempts(ctx context.Context, operat
 	return nil
 }
 
+// IncrementFailedAttempts conta un tentativo fallito e restituisce il totale aggiornato, così più
+// terminali che sbagliano insieme non perdono incrementi
+func (r *OperatorRepository) IncrementFailedAttempts(ctx context.Context, operatorID primitive.ObjectID) (int, error) {
+	filter := bson.M{"_id": operatorID}
+	update := bson.M{
+		"$inc": bson.M{"failed_attempts": 1},
+		"$set": bson.M{
+			"last_failed_attempt": time.Now(),
+			"updated_at":          time.Now(),
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"failed_attempts": 1})
+
+	var operator domain.Operator
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&operator)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, domain.ErrOperatorNotFound
+		}
+		return 0, err
+	}
+
+	return operator.FailedAttempts, nil
+}
+
 func (r *OperatorRepository) ResetFailedAttempts(ctx context.Context, operatorID primitive.ObjectID) error {
 	filter := bson.M{"_id": operatorID}
 	update := bson.M{
@@ -307,12 +320,19 @@ func (r *OperatorRepository) ResetFailedAttempts(ctx context.Context, operatorID
 	return nil
 }
 
+// Lock blocca l'account fino allo sblocco manuale
 func (r *OperatorRepository) Lock(ctx context.Context, operatorID primitive.ObjectID) error {
+	return r.LockUntil(ctx, operatorID, time.Time{})
+}
+
+// LockUntil blocca l'account fino a until; con until zero il blocco è manuale
+func (r *OperatorRepository) LockUntil(ctx context.Context, operatorID primitive.ObjectID, until time.Time) error {
 	filter := bson.M{"_id": operatorID}
 	update := bson.M{
 		"$set": bson.M{
-			"is_locked":  true,
-			"updated_at": time.Now(),
+			"is_locked":    true,
+			"locked_until": until,
+			"updated_at":   time.Now(),
 		},
 	}
 
@@ -333,6 +353,7 @@ func (r *OperatorRepository) Unlock(ctx context.Context, operatorID primitive.Ob
 	update := bson.M{
 		"$set": bson.M{
 			"is_locked":       false,
+			"locked_until":    time.Time{},
 			"failed_attempts": 0,
 			"updated_at":      time.Now(),
 		},
@@ -350,40 +371,85 @@ func (r *OperatorRepository) Unlock(ctx context.Context, operatorID primitive.Ob
 	return nil
 }
 
-func (r *OperatorRepository) UpdateSession(ctx context.Context, operatorID primitive.ObjectID, token string, expiry time.Time) error {
-	filter := bson.M{"_id": operatorID}
+// UnlockExpired sblocca gli account il cui blocco per tentativi falliti è scaduto
+func (r *OperatorRepository) UnlockExpired(ctx context.Context, now time.Time) (int64, error) {
+	filter := bson.M{
+		"is_locked":    true,
+		"locked_until": bson.M{"$lte": now, "$gt": time.Time{}},
+	}
 	update := bson.M{
 		"$set": bson.M{
-			"session_token":  token,
-			"session_expiry": expiry,
-			"last_login":     time.Now(),
-			"updated_at":     time.Now(),
+			"is_locked":       false,
+			"locked_until":    time.Time{},
+			"failed_attempts": 0,
+			"updated_at":      now,
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, filter, update)
+	result, err := r.collection.UpdateMany(ctx, filter, update)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if result.MatchedCount == 0 {
-		return domain.ErrOperatorNotFound
-	}
+	return result.ModifiedCount, nil
+}
 
-	return nil
+// EnableTOTP attiva il 2FA con il segreto confermato; counter è il passo del codice di conferma
+func (r *OperatorRepository) EnableTOTP(ctx context.Context, operatorID primitive.ObjectID, secret string, counter int64, recoveryHashes []string) error {
+	return r.updateTOTP(ctx, bson.M{"_id": operatorID}, bson.M{
+		"totp_secret":          secret,
+		"totp_enabled":         true,
+		"totp_last_counter":    counter,
+		"recovery_code_hashes": recoveryHashes,
+	})
 }
 
-func (r *OperatorRepository) ClearSession(ctx context.Context, operatorID primitive.ObjectID) error {
-	filter := bson.M{"_id": operatorID}
+func (r *OperatorRepository) DisableTOTP(ctx context.Context, operatorID primitive.ObjectID) error {
+	return r.updateTOTP(ctx, bson.M{"_id": operatorID}, bson.M{
+		"totp_secret":          "",
+		"totp_enabled":         false,
+		"totp_last_counter":    0,
+		"recovery_code_hashes": []string{},
+	})
+}
+
+func (r *OperatorRepository) SetRecoveryCodes(ctx context.Context, operatorID primitive.ObjectID, recoveryHashes []string) error {
+	return r.updateTOTP(ctx, bson.M{"_id": operatorID}, bson.M{"recovery_code_hashes": recoveryHashes})
+}
+
+// UseTOTPCounter registra il passo del codice appena usato; false se quel passo o uno successivo
+// è già stato usato, così lo stesso codice non vale due volte nemmeno da due terminali
+func (r *OperatorRepository) UseTOTPCounter(ctx context.Context, operatorID primitive.ObjectID, counter int64) (bool, error) {
+	err := r.updateTOTP(ctx, bson.M{
+		"_id":               operatorID,
+		"totp_last_counter": bson.M{"$lt": counter},
+	}, bson.M{"totp_last_counter": counter})
+	if err == domain.ErrOperatorNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UseRecoveryCode consuma un codice di recupero; false se non esiste o è già stato usato
+func (r *OperatorRepository) UseRecoveryCode(ctx context.Context, operatorID primitive.ObjectID, hash string) (bool, error) {
+	filter := bson.M{"_id": operatorID, "recovery_code_hashes": hash}
 	update := bson.M{
-		"$set": bson.M{
-			"session_token":  "",
-			"session_expiry": time.Time{},
-			"updated_at":     time.Now(),
-		},
+		"$pull": bson.M{"recovery_code_hashes": hash},
+		"$set":  bson.M{"updated_at": time.Now()},
 	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *OperatorRepository) updateTOTP(ctx context.Context, filter, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": fields})
 	if err != nil {
 		return err
 	}
@@ -395,23 +461,35 @@ func (r *OperatorRepository) ClearSession(ctx context.Context, operatorID primit
 	return nil
 }
 
-func (r *OperatorRepository) AddAuditEntry(ctx context.Context, operatorID primitive.ObjectID, entry domain.AuditEntry) error {
-	filter := bson.M{"_id": operatorID}
-	update := bson.M{
-		"$push": bson.M{"audit_log": entry},
-		"$set":  bson.M{"updated_at": time.Now()},
-	}
+// LegacyAudit sono le voci ancora nell'array audit_log di un documento operatore
+type LegacyAudit struct {
+	OperatorID primitive.ObjectID  `bson:"_id"`
+	Username   string              `bson:"username"`
+	Entries    []domain.AuditEntry `bson:"audit_log"`
+}
 
-	result, err := r.collection.UpdateOne(ctx, filter, update)
+// FindLegacyAuditLogs restituisce gli operatori con voci di audit da migrare al registro
+func (r *OperatorRepository) FindLegacyAuditLogs(ctx context.Context) ([]*LegacyAudit, error) {
+	filter := bson.M{"audit_log.0": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"username": 1, "audit_log": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	if result.MatchedCount == 0 {
-		return domain.ErrOperatorNotFound
+	var legacy []*LegacyAudit
+	if err = cursor.All(ctx, &legacy); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return legacy, nil
+}
+
+func (r *OperatorRepository) ClearLegacyAuditLog(ctx context.Context, operatorID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": operatorID}, bson.M{"$unset": bson.M{"audit_log": ""}})
+	return err
 }
 
 func (r *OperatorRepository) Search(ctx context.Context, query string, limit int) ([]*domain.Operator, error) {
@@ -457,12 +535,6 @@ func (r *OperatorRepository) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "is_locked", Value: 1}},
 		},
-		{
-			Keys: bson.D{{Key: "session_token", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "session_expiry", Value: 1}},
-		},
 	}
 
 	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
@@ -486,23 +558,3 @@ func (r *OperatorRepository) EmailExists(ctx context.Context, email string) (boo
 	}
 	return count > 0, nil
 }
-
-func (r *OperatorRepository) CleanupExpiredSessions(ctx context.Context) (int64, error) {
-	filter := bson.M{
-		"session_expiry": bson.M{"$lt": time.Now(), "$ne": time.Time{}},
-	}
-	update := bson.M{
-		"$set": bson.M{
-			"session_token":  "",
-			"session_expiry": time.Time{},
-			"updated_at":     time.Now(),
-		},
-	}
-
-	result, err := r.collection.UpdateMany(ctx, filter, update)
-	if err != nil {
-		return 0, err
-	}
-
-	return result.ModifiedCount, nil
-}
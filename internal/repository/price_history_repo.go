@@ -0,0 +1,201 @@
+// internal/repository/price_history_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type PriceHistoryRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewPriceHistoryRepository(db *mongo.Database) *PriceHistoryRepository {
+	return &PriceHistoryRepository{
+		collection: db.Collection("price_history"),
+		db:         db,
+	}
+}
+
+func (r *PriceHistoryRepository) Create(ctx context.Context, change *domain.PriceChange) error {
+	if change.ID.IsZero() {
+		change.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, change)
+	return err
+}
+
+func (r *PriceHistoryRepository) Update(ctx context.Context, change *domain.PriceChange) error {
+	change.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": change.ID}
+	update := bson.M{"$set": change}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrPriceChangeNotFound
+	}
+
+	return nil
+}
+
+// RunInTransaction esegue fn in una transazione, per registrare la variazione insieme al prezzo dell'articolo
+func (r *PriceHistoryRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTransaction(ctx, r.db, fn)
+}
+
+func (r *PriceHistoryRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.PriceChange, error) {
+	var change domain.PriceChange
+	filter := bson.M{"_id": id}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&change)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrPriceChangeNotFound
+		}
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+func (r *PriceHistoryRepository) FindByArticle(ctx context.Context, articleID primitive.ObjectID, limit int) ([]*domain.PriceChange, error) {
+	filter := bson.M{"article_id": articleID}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "effective_from", Value: -1}, {Key: "changed_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*domain.PriceChange
+	if err = cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *PriceHistoryRepository) FindScheduledByArticle(ctx context.Context, articleID primitive.ObjectID) ([]*domain.PriceChange, error) {
+	filter := bson.M{
+		"article_id": articleID,
+		"status":     domain.PriceChangeScheduled,
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "effective_from", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*domain.PriceChange
+	if err = cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *PriceHistoryRepository) FindEffectiveAt(ctx context.Context, articleID primitive.ObjectID, date time.Time) (*domain.PriceChange, error) {
+	var change domain.PriceChange
+	filter := bson.M{
+		"article_id":     articleID,
+		"status":         domain.PriceChangeApplied,
+		"effective_from": bson.M{"$lte": date},
+	}
+
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "effective_from", Value: -1}, {Key: "applied_at", Value: -1}})
+
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&change)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrPriceChangeNotFound
+		}
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+func (r *PriceHistoryRepository) FindFirstApplied(ctx context.Context, articleID primitive.ObjectID) (*domain.PriceChange, error) {
+	var change domain.PriceChange
+	filter := bson.M{
+		"article_id": articleID,
+		"status":     domain.PriceChangeApplied,
+	}
+
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "effective_from", Value: 1}, {Key: "applied_at", Value: 1}})
+
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&change)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrPriceChangeNotFound
+		}
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+func (r *PriceHistoryRepository) FindDue(ctx context.Context, date time.Time) ([]*domain.PriceChange, error) {
+	filter := bson.M{
+		"status":         domain.PriceChangeScheduled,
+		"effective_from": bson.M{"$lte": date},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "effective_from", Value: 1}, {Key: "changed_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*domain.PriceChange
+	if err = cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *PriceHistoryRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "article_id", Value: 1}, {Key: "effective_from", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "effective_from", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "article_code", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
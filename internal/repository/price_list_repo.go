@@ -0,0 +1,167 @@
+// internal/repository/price_list_repo.go
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type PriceListRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewPriceListRepository(db *mongo.Database) *PriceListRepository {
+	return &PriceListRepository{
+		collection: db.Collection("price_lists"),
+		db:         db,
+	}
+}
+
+func (r *PriceListRepository) Create(ctx context.Context, priceList *domain.PriceList) error {
+	if priceList.ID.IsZero() {
+		priceList.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, priceList)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("price list with this code already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PriceListRepository) Update(ctx context.Context, priceList *domain.PriceList) error {
+	priceList.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": priceList.ID}
+	update := bson.M{"$set": priceList}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrPriceListNotFound
+	}
+
+	return nil
+}
+
+func (r *PriceListRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id}
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrPriceListNotFound
+	}
+
+	return nil
+}
+
+func (r *PriceListRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.PriceList, error) {
+	var priceList domain.PriceList
+	filter := bson.M{"_id": id}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&priceList)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrPriceListNotFound
+		}
+		return nil, err
+	}
+
+	return &priceList, nil
+}
+
+func (r *PriceListRepository) FindByCode(ctx context.Context, code string) (*domain.PriceList, error) {
+	var priceList domain.PriceList
+	filter := bson.M{"code": strings.ToLower(strings.TrimSpace(code))}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&priceList)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrPriceListNotFound
+		}
+		return nil, err
+	}
+
+	return &priceList, nil
+}
+
+func (r *PriceListRepository) FindByCustomerCategory(ctx context.Context, category domain.CustomerCategory) ([]*domain.PriceList, error) {
+	filter := bson.M{
+		"customer_categories": category,
+		"is_active":           true,
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "valid_from", Value: -1}, {Key: "code", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var priceLists []*domain.PriceList
+	if err = cursor.All(ctx, &priceLists); err != nil {
+		return nil, err
+	}
+
+	return priceLists, nil
+}
+
+func (r *PriceListRepository) FindAll(ctx context.Context) ([]*domain.PriceList, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "code", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var priceLists []*domain.PriceList
+	if err = cursor.All(ctx, &priceLists); err != nil {
+		return nil, err
+	}
+
+	return priceLists, nil
+}
+
+func (r *PriceListRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "customer_categories", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "entries.article_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "is_active", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,143 @@
+// internal/repository/promotion_usage_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type PromotionUsageRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewPromotionUsageRepository(db *mongo.Database) *PromotionUsageRepository {
+	return &PromotionUsageRepository{
+		collection: db.Collection("promotion_usages"),
+		db:         db,
+	}
+}
+
+func (r *PromotionUsageRepository) Create(ctx context.Context, usage *domain.PromotionUsage) error {
+	if usage.ID.IsZero() {
+		usage.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, usage)
+	return err
+}
+
+// RunInTransaction esegue fn in una transazione, per registrare gli utilizzi di un documento tutti o nessuno
+func (r *PromotionUsageRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTransaction(ctx, r.db, fn)
+}
+
+func (r *PromotionUsageRepository) DeleteByDocument(ctx context.Context, promotionID, documentID primitive.ObjectID) (*domain.PromotionUsage, error) {
+	var usage domain.PromotionUsage
+	filter := bson.M{"promotion_id": promotionID, "document_id": documentID}
+
+	err := r.collection.FindOneAndDelete(ctx, filter).Decode(&usage)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+func (r *PromotionUsageRepository) FindByDocument(ctx context.Context, documentID primitive.ObjectID) ([]*domain.PromotionUsage, error) {
+	filter := bson.M{"document_id": documentID}
+	return r.find(ctx, filter, options.Find())
+}
+
+func (r *PromotionUsageRepository) FindByPromotion(ctx context.Context, promotionID primitive.ObjectID, from, to time.Time) ([]*domain.PromotionUsage, error) {
+	filter := bson.M{
+		"promotion_id": promotionID,
+		"used_at":      bson.M{"$gte": from, "$lte": to},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "used_at", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+// CountSince conta gli utilizzi per promozione a partire da una data (es. inizio giornata)
+func (r *PromotionUsageRepository) CountSince(ctx context.Context, promotionIDs []primitive.ObjectID, since time.Time) (map[primitive.ObjectID]int, error) {
+	counts := make(map[primitive.ObjectID]int)
+	if len(promotionIDs) == 0 {
+		return counts, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"promotion_id": bson.M{"$in": promotionIDs},
+			"used_at":      bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$promotion_id",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, res := range results {
+		counts[res.ID] = res.Count
+	}
+
+	return counts, nil
+}
+
+func (r *PromotionUsageRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.PromotionUsage, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var usages []*domain.PromotionUsage
+	if err = cursor.All(ctx, &usages); err != nil {
+		return nil, err
+	}
+
+	return usages, nil
+}
+
+func (r *PromotionUsageRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "promotion_id", Value: 1}, {Key: "used_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "document_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "customer_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,135 @@
+// internal/repository/session_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+// SessionRepository conserva le sessioni di login su Mongo, condivise fra le istanze.
+// Implementa auth.SessionStore
+type SessionRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{
+		collection: db.Collection("sessions"),
+		db:         db,
+	}
+}
+
+func (r *SessionRepository) Save(ctx context.Context, session *domain.Session) error {
+	filter := bson.M{"_id": session.Token}
+	_, err := r.collection.ReplaceOne(ctx, filter, session, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Find ignora le sessioni scadute che il monitor TTL non ha ancora rimosso
+func (r *SessionRepository) Find(ctx context.Context, token string) (*domain.Session, error) {
+	var session domain.Session
+	filter := bson.M{
+		"_id":        token,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *SessionRepository) Touch(ctx context.Context, token string, lastActivity, expiresAt time.Time) error {
+	filter := bson.M{
+		"_id":        token,
+		"expires_at": bson.M{"$gt": lastActivity},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"last_activity": lastActivity,
+			"expires_at":    expiresAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) Delete(ctx context.Context, token string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": token})
+	return err
+}
+
+func (r *SessionRepository) DeleteByOperator(ctx context.Context, operatorID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"operator_id": operatorID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *SessionRepository) FindByOperator(ctx context.Context, operatorID string) ([]*domain.Session, error) {
+	filter := bson.M{
+		"operator_id": operatorID,
+		"expires_at":  bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "last_activity", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*domain.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteExpired rimuove subito le sessioni scadute; il TTL index lo fa comunque, ma solo ogni minuto circa
+func (r *SessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *SessionRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "operator_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
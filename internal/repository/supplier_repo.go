@@ -98,6 +98,91 @@ func (r *PromotionRepository) FindByCode(ctx context.Context, code string) (*dom
 	return &promotion, nil
 }
 
+// RecordUsage incrementa i contatori in modo atomico; il filtro rifiuta l'utilizzo oltre i limiti totale e per cliente
+func (r *PromotionRepository) RecordUsage(ctx context.Context, promotion *domain.Promotion, customerID string, revenue, discount float64) error {
+	customerField := "statistics.customer_usages." + customerID
+
+	filter := bson.M{"_id": promotion.ID}
+	if promotion.Limits.MaxUsageTotal > 0 {
+		filter["statistics.total_usages"] = bson.M{"$lt": promotion.Limits.MaxUsageTotal}
+	}
+	if promotion.Limits.MaxUsagePerCustomer > 0 {
+		filter["$or"] = []bson.M{
+			{customerField: bson.M{"$exists": false}},
+			{customerField: bson.M{"$lt": promotion.Limits.MaxUsagePerCustomer}},
+		}
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$inc": bson.M{
+			"statistics.total_usages":   1,
+			"statistics.total_revenue":  revenue,
+			"statistics.total_discount": discount,
+			customerField:               1,
+		},
+		"$set": bson.M{
+			"statistics.last_usage_date": now,
+			"updated_at":                 now,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrPromotionUsageLimit
+	}
+
+	return nil
+}
+
+func (r *PromotionRepository) RevertUsage(ctx context.Context, promotionID primitive.ObjectID, customerID string, revenue, discount float64) error {
+	filter := bson.M{"_id": promotionID}
+	update := bson.M{
+		"$inc": bson.M{
+			"statistics.total_usages":                  -1,
+			"statistics.total_revenue":                 -revenue,
+			"statistics.total_discount":                -discount,
+			"statistics.customer_usages." + customerID: -1,
+		},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrPromotionNotFound
+	}
+
+	return nil
+}
+
+func (r *PromotionRepository) FindExpiredActive(ctx context.Context, date time.Time) ([]*domain.Promotion, error) {
+	filter := bson.M{
+		"is_active": true,
+		"valid_to":  bson.M{"$lt": date, "$ne": time.Time{}},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var promotions []*domain.Promotion
+	if err = cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
 func (r *PromotionRepository) FindActive(ctx context.Context, date time.Time) ([]*domain.Promotion, error) {
 	filter := bson.M{
 		"is_active":  true,
@@ -124,6 +209,32 @@ func (r *PromotionRepository) FindActive(ctx context.Context, date time.Time) ([
 	return promotions, nil
 }
 
+// FindValidAt restituisce le promozioni il cui periodo comprende la data, anche se oggi disattivate
+func (r *PromotionRepository) FindValidAt(ctx context.Context, date time.Time) ([]*domain.Promotion, error) {
+	filter := bson.M{
+		"valid_from": bson.M{"$lte": date},
+		"$or": []bson.M{
+			{"valid_to": bson.M{"$gte": date}},
+			{"valid_to": time.Time{}},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var promotions []*domain.Promotion
+	if err = cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
 func (r *PromotionRepository) FindExpired(ctx context.Context, date time.Time) ([]*domain.Promotion, error) {
 	filter := bson.M{
 		"valid_to": bson.M{"$lt": date, "$ne": time.Time{}},
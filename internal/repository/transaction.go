@@ -0,0 +1,30 @@
+// internal/repository/transaction.go
+
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RunInTransaction esegue fn in una transazione: le operazioni dei repository fatte con il ctx ricevuto
+// vengono confermate o annullate insieme. Il driver può rieseguire fn sugli errori transitori, quindi fn
+// deve poter ripartire da capo. Se ctx appartiene già a una transazione fn vi partecipa, senza aprirne una
+// nuova. Le transazioni richiedono un replica set (vedi docker-compose.yml)
+func RunInTransaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,108 @@
+// internal/repository/voucher_ledger_repo.go
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ricambi-manager/internal/domain"
+)
+
+type VoucherLedgerRepository struct {
+	collection *mongo.Collection
+	db         *mongo.Database
+}
+
+func NewVoucherLedgerRepository(db *mongo.Database) *VoucherLedgerRepository {
+	return &VoucherLedgerRepository{
+		collection: db.Collection("voucher_ledger"),
+		db:         db,
+	}
+}
+
+func (r *VoucherLedgerRepository) Create(ctx context.Context, entry *domain.VoucherLedgerEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+// FindUntil restituisce i movimenti fino alla data compresa, in ordine cronologico
+func (r *VoucherLedgerRepository) FindUntil(ctx context.Context, date time.Time) ([]*domain.VoucherLedgerEntry, error) {
+	filter := bson.M{"date": bson.M{"$lte": date}}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *VoucherLedgerRepository) FindByVoucher(ctx context.Context, voucherID primitive.ObjectID) ([]*domain.VoucherLedgerEntry, error) {
+	filter := bson.M{"voucher_id": voucherID}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+// ReplaceForVoucher sostituisce tutti i movimenti del buono, per la ricostruzione del registro
+func (r *VoucherLedgerRepository) ReplaceForVoucher(ctx context.Context, voucherID primitive.ObjectID, entries []*domain.VoucherLedgerEntry) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"voucher_id": voucherID}); err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		docs = append(docs, entry)
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// UpdateVoucherCode riporta sui movimenti il nuovo codice di un buono ricodificato
+func (r *VoucherLedgerRepository) UpdateVoucherCode(ctx context.Context, voucherID primitive.ObjectID, code string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"voucher_id": voucherID}, bson.M{"$set": bson.M{"voucher_code": code}})
+	return err
+}
+
+func (r *VoucherLedgerRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.VoucherLedgerEntry, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*domain.VoucherLedgerEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *VoucherLedgerRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "voucher_id", Value: 1}, {Key: "date", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "date", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "customer_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,107 @@
+// internal/scheduler/jobs.go
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ricambi-manager/internal/config"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/internal/usecase"
+	"ricambi-manager/pkg/auth"
+)
+
+// nomi dei job di manutenzione, usati come chiavi in scheduler.jobs della configurazione
+const (
+	JobPriceChanges      = "price_changes"
+	JobPromotions        = "promotions"
+	JobNetPriceReminders = "net_price_reminders"
+	JobVouchers          = "vouchers"
+	JobBudgets           = "budgets"
+	JobSessions          = "sessions"
+	JobHistory           = "job_history"
+)
+
+// Housekeeping raccoglie le dipendenze dei job di manutenzione
+type Housekeeping struct {
+	PriceUC     *usecase.ManagePricesUseCase
+	PromotionUC *usecase.ManagePromotionsUseCase
+	NetPriceUC  *usecase.ManageNetPricesUseCase
+	VoucherUC   *usecase.ManageVouchersUseCase
+	BudgetUC    *usecase.ManageBudgetsUseCase
+	JobRepo     *repository.JobRepository
+	AuthService *auth.AuthService
+	LoginUC     *usecase.LoginUseCase
+	HistoryDays int
+}
+
+// RegisterHousekeeping registra i job di manutenzione con la pianificazione di configurazione;
+// un job con pianificazione vuota o "off" non viene registrato
+func RegisterHousekeeping(s *Scheduler, jobs map[string]string, h Housekeeping) error {
+	defs := map[string]JobFunc{
+		JobPriceChanges: func(ctx context.Context) (string, error) {
+			applied, err := h.PriceUC.ApplyDuePriceChanges(ctx, time.Now())
+			return fmt.Sprintf("%d variazioni di prezzo applicate", applied), err
+		},
+		JobPromotions: func(ctx context.Context) (string, error) {
+			// gli utilizzi giornalieri si contano dal registro utilizzi, quindi non c'è un contatore da azzerare
+			expired, err := h.PromotionUC.ExpirePromotions(ctx, time.Now())
+			return fmt.Sprintf("%d promozioni scadute", expired), err
+		},
+		JobNetPriceReminders: func(ctx context.Context) (string, error) {
+			created, err := h.NetPriceUC.GenerateReminders(ctx, time.Now())
+			return fmt.Sprintf("%d promemoria prezzi netti", created), err
+		},
+		JobVouchers: func(ctx context.Context) (string, error) {
+			expired, err := h.VoucherUC.ExpireVouchers(ctx, time.Now())
+			return fmt.Sprintf("%d buoni scaduti", expired), err
+		},
+		JobBudgets: func(ctx context.Context) (string, error) {
+			activated, closed, err := h.BudgetUC.UpdateStatuses(ctx, time.Now())
+			return fmt.Sprintf("%d budget attivati, %d chiusi", activated, closed), err
+		},
+		JobSessions: func(ctx context.Context) (string, error) {
+			cleaned, err := h.AuthService.CleanupExpiredSessions(ctx)
+			if err != nil {
+				return "", err
+			}
+			unlocked, err := h.LoginUC.Cleanup(ctx, time.Now())
+			return fmt.Sprintf("%d sessioni scadute, %d account sbloccati", cleaned, unlocked), err
+		},
+		JobHistory: func(ctx context.Context) (string, error) {
+			if h.HistoryDays <= 0 {
+				return "storico senza scadenza", nil
+			}
+			deleted, err := h.JobRepo.DeleteRunsBefore(ctx, time.Now().AddDate(0, 0, -h.HistoryDays))
+			return fmt.Sprintf("%d esecuzioni eliminate dallo storico", deleted), err
+		},
+	}
+
+	for name, spec := range jobs {
+		run, ok := defs[name]
+		if !ok {
+			log.Printf("Scheduler: unknown job %q in configuration, ignored", name)
+			continue
+		}
+
+		spec = strings.TrimSpace(spec)
+		if spec == "" || strings.EqualFold(spec, "off") {
+			continue
+		}
+
+		if err := s.Register(name, spec, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewFromConfig crea lo scheduler con lock e storico su Mongo secondo la configurazione
+func NewFromConfig(repo *repository.JobRepository, cfg config.SchedulerConfig) *Scheduler {
+	return New(repo, cfg.Instance, time.Duration(cfg.LockTTLMinutes)*time.Minute)
+}
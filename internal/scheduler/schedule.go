@@ -0,0 +1,192 @@
+// internal/scheduler/schedule.go
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule calcola il prossimo istante di esecuzione di un job
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// cronSchedule è un'espressione cron a 5 campi: minuto ora giorno mese giorno-settimana
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// come in cron, se giorno del mese e della settimana sono entrambi ristretti basta uno dei due
+	domRestricted, dowRestricted bool
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minuto
+	{0, 23}, // ora
+	{1, 31}, // giorno del mese
+	{1, 12}, // mese
+	{0, 6},  // giorno della settimana, 0 = domenica
+}
+
+var scheduleAliases = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// Parse accetta un'espressione cron a 5 campi (con *, elenchi, intervalli e passi), gli alias
+// @daily, @hourly, @weekly, @monthly, @yearly oppure "@every <durata>", es. "@every 1m"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || interval < time.Second {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	if alias, ok := scheduleAliases[spec]; ok {
+		spec = alias
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("%w: %q requires 5 fields", ErrInvalidSchedule, spec)
+	}
+
+	bits := make([]uint64, len(parts))
+	for i, part := range parts {
+		value, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSchedule, spec, err)
+		}
+		bits[i] = value
+	}
+
+	// 7 è accettato come domenica
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dom:           bits[2],
+		month:         bits[3],
+		dow:           bits[4],
+		domRestricted: parts[2] != "*",
+		dowRestricted: parts[4] != "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	max := bounds.max
+	if bounds.min == 0 && bounds.max == 6 {
+		max = 7
+	}
+
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		from, to := bounds.min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(ends[0]); err != nil {
+				return 0, fmt.Errorf("bad range %q", item)
+			}
+			if to, err = strconv.Atoi(ends[1]); err != nil {
+				return 0, fmt.Errorf("bad range %q", item)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q", item)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = max
+			}
+		}
+
+		if from < bounds.min || to > max || from > to {
+			return 0, fmt.Errorf("value out of range %q", item)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next allinea gli slot ai multipli dell'intervallo sull'orologio, non all'avvio del processo:
+// così tutte le istanze calcolano lo stesso slot e si contendono lo stesso lock
+func (s everySchedule) Next(after time.Time) time.Time {
+	return after.Truncate(s.interval).Add(s.interval)
+}
+
+// Next cerca minuto per minuto, saltando mesi, giorni e ore che non corrispondono; il limite di
+// cinque anni evita cicli infiniti su espressioni impossibili come il 30 febbraio
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
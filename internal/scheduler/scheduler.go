@@ -0,0 +1,187 @@
+// internal/scheduler/scheduler.go
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+)
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobDuplicate = errors.New("job already registered")
+	ErrJobLocked    = errors.New("job is running on another instance")
+)
+
+// JobFunc esegue il job e restituisce un riepilogo da salvare nello storico, es. "3 buoni scaduti"
+type JobFunc func(ctx context.Context) (string, error)
+
+type Job struct {
+	Name     string
+	Spec     string
+	Schedule Schedule
+	Run      JobFunc
+}
+
+// Scheduler esegue i job registrati secondo la loro pianificazione. Più istanze possono girare insieme:
+// il lock su Mongo fa eseguire ogni scadenza da una sola istanza e le esecuzioni finiscono in job_runs
+type Scheduler struct {
+	repo     *repository.JobRepository
+	instance string
+	lockTTL  time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func New(repo *repository.JobRepository, instance string, lockTTL time.Duration) *Scheduler {
+	if instance == "" {
+		instance = DefaultInstance()
+	}
+	if lockTTL <= 0 {
+		lockTTL = 10 * time.Minute
+	}
+
+	return &Scheduler{
+		repo:     repo,
+		instance: instance,
+		lockTTL:  lockTTL,
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// DefaultInstance identifica il processo come host:pid
+func DefaultInstance() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobDuplicate, name)
+	}
+
+	s.jobs[name] = &Job{Name: name, Spec: spec, Schedule: schedule, Run: run}
+	return nil
+}
+
+// Jobs restituisce i job registrati in ordine di nome
+func (s *Scheduler) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Run avvia un ciclo per ogni job e ritorna quando il contesto viene annullato e i job in corso sono terminati
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.Jobs() {
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Scheduler: job %s has no next run for %q", job.Name, job.Spec)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := s.execute(ctx, job, next); err != nil && !errors.Is(err, ErrJobLocked) {
+			log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+		}
+	}
+}
+
+// RunNow esegue subito il job, se non è già in corso su un'altra istanza
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*domain.JobRun, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	return s.execute(ctx, job, time.Now())
+}
+
+func (s *Scheduler) execute(ctx context.Context, job *Job, slot time.Time) (*domain.JobRun, error) {
+	acquired, err := s.repo.AcquireLock(ctx, job.Name, s.instance, slot, s.lockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrJobLocked
+	}
+	defer func() {
+		if err := s.repo.ReleaseLock(context.Background(), job.Name, s.instance); err != nil {
+			log.Printf("Scheduler: releasing lock of %s: %v", job.Name, err)
+		}
+	}()
+
+	run := domain.NewJobRun(job.Name, s.instance, slot)
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	// il job non può durare più del lock, altrimenti un'altra istanza potrebbe partire in parallelo
+	jobCtx, cancel := context.WithTimeout(ctx, s.lockTTL)
+	result, jobErr := safeRun(jobCtx, job.Run)
+	cancel()
+
+	run.Finish(result, jobErr)
+	if err := s.repo.UpdateRun(context.Background(), run); err != nil {
+		return run, err
+	}
+
+	return run, jobErr
+}
+
+func safeRun(ctx context.Context, run JobFunc) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
@@ -4,17 +4,23 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"ricambi-manager/internal/config"
 	"ricambi-manager/internal/domain"
 	"ricambi-manager/internal/repository"
 	"ricambi-manager/internal/usecase"
 	"ricambi-manager/pkg/auth"
+	"ricambi-manager/pkg/barcode"
 )
 
 type ViewState int
@@ -33,10 +39,19 @@ const (
 	ViewBudgets
 	ViewKits
 	ViewSettings
+	ViewApprovals
+	ViewNetPrices
+	ViewVoucherReport
+	ViewTwoFactor
+	ViewChangePassword
+	ViewSalesDocument
+	ViewPriceLists
+	ViewOperators
 )
 
 type AppModel struct {
 	db          *mongo.Database
+	config      *config.Config
 	width       int
 	height      int
 	currentView ViewState
@@ -52,30 +67,91 @@ type AppModel struct {
 	voucherRepo   *repository.CreditVoucherRepository
 	budgetRepo    *repository.BudgetRepository
 	kitRepo       *repository.KitRepository
-
-	searchUC   *usecase.SearchArticlesUseCase
-	discountUC *usecase.ManageDiscountsUseCase
-	stockUC    *usecase.ManageStockUseCase
-
-	loginView    *LoginView
-	mainMenuView *MainMenuView
-	searchView   *ArticleSearchView
+	priceListRepo *repository.PriceListRepository
+
+	searchUC        *usecase.SearchArticlesUseCase
+	priceUC         *usecase.ManagePricesUseCase
+	discountUC      *usecase.ManageDiscountsUseCase
+	stockUC         *usecase.ManageStockUseCase
+	analyticsUC     *usecase.PromotionAnalyticsUseCase
+	marginUC        *usecase.MarginControlUseCase
+	authorizationUC *usecase.DiscountAuthorizationUseCase
+	netPriceUC      *usecase.ManageNetPricesUseCase
+	budgetUC        *usecase.ManageBudgetsUseCase
+	postUC          *usecase.PostDocumentsUseCase
+	promotionUC     *usecase.ManagePromotionsUseCase
+	couponUC        *usecase.ManageCouponsUseCase
+	priceListUC     *usecase.ManagePriceListsUseCase
+	voucherUC       *usecase.ManageVouchersUseCase
+	loginUC         *usecase.LoginUseCase
+	audit           *auth.AuditLogger
+
+	loginView          *LoginView
+	mainMenuView       *MainMenuView
+	searchView         *ArticleSearchView
+	promotionsView     *PromotionsView
+	approvalsView      *ApprovalsView
+	netPricesView      *NetPricesView
+	priceListsView     *PriceListsView
+	operatorsView      *OperatorsView
+	budgetsView        *BudgetsView
+	creditVouchersView *CreditVouchersView
+	voucherReportView  *VoucherReportView
+	twoFactorView      *TwoFactorView
+	passwordForm       *PasswordForm
+	salesDocumentView  *SalesDocumentView
+
+	netPriceReminders []*domain.NetPriceReminder
+	reminderCount     int64
 
 	error   string
 	message string
 	loading bool
 	quit    bool
 
-	lastActivity   time.Time
-	sessionTimeout time.Duration
-	quitCh         chan struct{}
+	terminal         string
+	session          *domain.Session
+	lastActivity     time.Time
+	lastSessionCheck time.Time
+	sessionTimeout   time.Duration
+	quitCh           chan struct{}
 }
 
+type loginStep int
+
+const (
+	loginStepPassword loginStep = iota
+	loginStepCode
+	loginStepEnroll
+	loginStepRecoveryCodes
+	loginStepChangePassword
+)
+
+// LoginView: dopo la password, se serve il 2FA, pending conserva l'esito fino al codice; enrollment
+// e recoveryCodes servono all'attivazione obbligatoria al primo accesso. currentPassword resta
+// in memoria solo finché non è impostata la nuova password richiesta dalla policy
 type LoginView struct {
-	username   string
-	password   string
+	username        string
+	password        string
+	focusIndex      int
+	error           string
+	step            loginStep
+	code            string
+	pending         *usecase.LoginResult
+	enrollment      *usecase.TOTPEnrollment
+	recoveryCodes   []string
+	currentPassword string
+	passwordForm    *PasswordForm
+}
+
+// PasswordForm raccoglie la nuova password con conferma; askCurrent aggiunge la password attuale,
+// non richiesta quando il cambio è imposto subito dopo il login
+type PasswordForm struct {
+	askCurrent bool
+	current    string
+	next       string
+	confirm    string
 	focusIndex int
-	error      string
 }
 
 type MainMenuView struct {
@@ -90,6 +166,8 @@ type MenuItem struct {
 	Enabled     bool
 }
 
+// ArticleSearchView: con article impostato mostra prezzo e storico delle variazioni dell'articolo
+// selezionato al posto dei risultati; priceForm raccoglie la nuova variazione
 type ArticleSearchView struct {
 	query         string
 	searchType    string
@@ -97,11 +175,132 @@ type ArticleSearchView struct {
 	selectedIndex int
 	loading       bool
 	scrollOffset  int
+	article       *domain.Article
+	history       []*domain.PriceChange
+	historyIndex  int
+	priceForm     *NetPriceForm
+}
+
+type PromotionsView struct {
+	promotions    []*domain.Promotion
+	selectedIndex int
+	report        *usecase.PromotionReport
+	comparison    []*usecase.PromotionReport
+	loading       bool
+	form          *NetPriceForm
+}
+
+type ApprovalsView struct {
+	approvals     []*domain.MarginApproval
+	selectedIndex int
+	loading       bool
+	form          *SupervisorPINForm
+}
+
+// SupervisorPINForm raccoglie username e PIN del supervisore, oppure il nuovo PIN dell'operatore (setPIN)
+type SupervisorPINForm struct {
+	setPIN     bool
+	username   string
+	pin        string
+	focusIndex int
+}
+
+type NetPricesView struct {
+	mode          string
+	filter        string
+	days          int
+	entries       []usecase.NetPriceEntry
+	selectedIndex int
+	loading       bool
+	form          *NetPriceForm
+}
+
+type PriceListsView struct {
+	priceLists    []*domain.PriceList
+	selectedIndex int
+	loading       bool
+	form          *NetPriceForm
+}
+
+type OperatorsView struct {
+	operators     []*domain.Operator
+	selectedIndex int
+	loading       bool
+	form          *NetPriceForm
+}
+
+// NetPriceForm raccoglie i campi dei comandi della distinta prezzi netti (ricerca, nuovo, rinnovo, import)
+type NetPriceForm struct {
+	action     string
+	title      string
+	labels     []string
+	values     []string
+	focusIndex int
+}
+
+type BudgetsView struct {
+	year          int
+	filterIndex   int
+	entries       []usecase.BudgetEntry
+	selectedIndex int
+	loading       bool
+	documents     []*domain.Document
+	documentIndex int
+}
+
+// CreditVouchersView mostra i buoni letti o quelli del cliente; scanner raccoglie sia la lettura
+// del lettore sia il codice cliente digitato, secondo input
+type CreditVouchersView struct {
+	customer      *domain.Customer
+	vouchers      []*domain.CreditVoucher
+	selectedIndex int
+	loading       bool
+	input         string
+	scanner       *barcode.BarcodeScanner
 }
 
+// SalesDocumentView è la bozza di vendita al banco; i comandi lavorano su una copia del documento
+// (vedi runDocumentCommand) e loading blocca i tasti finché non rispondono
+type SalesDocumentView struct {
+	document      *domain.Document
+	customer      *domain.Customer
+	selectedIndex int
+	loading       bool
+	form          *NetPriceForm
+}
+
+// TwoFactorView gestisce il 2FA dell'operatore collegato: input raccoglie il codice per confermare
+// l'attivazione (enrollment non nil) o la disattivazione
+type TwoFactorView struct {
+	enrollment    *usecase.TOTPEnrollment
+	disabling     bool
+	input         string
+	recoveryCodes []string
+	loading       bool
+}
+
+// VoucherReportView è il prospetto dei buoni residui alla data asOf, con i buoni in scadenza nei days successivi
+type VoucherReportView struct {
+	asOf    time.Time
+	days    int
+	report  *usecase.VoucherLiabilityReport
+	loading bool
+}
+
+// loginResultMsg: con pending la password è corretta e serve il secondo passo, o con
+// passwordChange il cambio password imposto dalla policy
 type loginResultMsg struct {
-	operator *domain.Operator
-	err      error
+	operator       *domain.Operator
+	session        *domain.Session
+	pending        *usecase.LoginResult
+	enrollment     *usecase.TOTPEnrollment
+	recoveryCodes  []string
+	passwordChange bool
+	err            error
+}
+
+type passwordChangedMsg struct {
+	err error
 }
 
 type searchResultMsg struct {
@@ -109,13 +308,142 @@ type searchResultMsg struct {
 	err     error
 }
 
+type priceHistoryMsg struct {
+	article *domain.Article
+	history []*domain.PriceChange
+	err     error
+}
+
+type priceChangeMsg struct {
+	message string
+	err     error
+}
+
+type promotionsLoadedMsg struct {
+	promotions []*domain.Promotion
+	err        error
+}
+
+type promotionReportMsg struct {
+	report     *usecase.PromotionReport
+	comparison []*usecase.PromotionReport
+	err        error
+}
+
+type couponMsg struct {
+	message string
+	err     error
+}
+
+type approvalsLoadedMsg struct {
+	approvals []*domain.MarginApproval
+	err       error
+}
+
+type approvalDecisionMsg struct {
+	approval *domain.MarginApproval
+	message  string
+	err      error
+}
+
+type netPricesLoadedMsg struct {
+	entries []usecase.NetPriceEntry
+	err     error
+}
+
+type netPriceRemindersMsg struct {
+	reminders []*domain.NetPriceReminder
+	count     int64
+	err       error
+}
+
+type netPriceActionMsg struct {
+	message string
+	err     error
+}
+
+type priceListsLoadedMsg struct {
+	priceLists []*domain.PriceList
+	err        error
+}
+
+type priceListActionMsg struct {
+	message string
+	err     error
+}
+
+type operatorsLoadedMsg struct {
+	operators []*domain.Operator
+	err       error
+}
+
+type operatorActionMsg struct {
+	message string
+	err     error
+}
+
+type budgetsLoadedMsg struct {
+	entries []usecase.BudgetEntry
+	err     error
+}
+
+type budgetDocumentsMsg struct {
+	documents []*domain.Document
+	err       error
+}
+
+type vouchersLoadedMsg struct {
+	customer *domain.Customer
+	vouchers []*domain.CreditVoucher
+	err      error
+}
+
+type voucherScannedMsg struct {
+	voucher *domain.CreditVoucher
+	err     error
+}
+
+type salesDocumentMsg struct {
+	document *domain.Document
+	customer *domain.Customer
+	message  string
+	err      error
+}
+
+type twoFactorMsg struct {
+	enrollment    *usecase.TOTPEnrollment
+	recoveryCodes []string
+	message       string
+	err           error
+}
+
+type voucherReportLoadedMsg struct {
+	report *usecase.VoucherLiabilityReport
+	err    error
+}
+
+type exportDoneMsg struct {
+	path string
+	err  error
+}
+
 type tickMsg struct {
 	time.Time
 }
 
 type sessionExpiredMsg struct{}
 
-func NewAppModel(db *mongo.Database) *AppModel {
+// sessionCheckMsg è l'esito del rinnovo periodico della sessione sullo SessionStore
+type sessionCheckMsg struct {
+	err error
+}
+
+type logoutDoneMsg struct {
+	sessions int64
+	err      error
+}
+
+func NewAppModel(db *mongo.Database, cfg *config.Config) *AppModel {
 	articleRepo := repository.NewArticleRepository(db)
 	customerRepo := repository.NewCustomerRepository(db)
 	operatorRepo := repository.NewOperatorRepository(db)
@@ -123,31 +451,149 @@ func NewAppModel(db *mongo.Database) *AppModel {
 	voucherRepo := repository.NewCreditVoucherRepository(db)
 	budgetRepo := repository.NewBudgetRepository(db)
 	kitRepo := repository.NewKitRepository(db)
+	priceListRepo := repository.NewPriceListRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
+	usageRepo := repository.NewPromotionUsageRepository(db)
+	documentRepo := repository.NewDocumentRepository(db)
+	approvalRepo := repository.NewMarginApprovalRepository(db)
+	discountLimitRepo := repository.NewDiscountLimitRepository(db)
+	reminderRepo := repository.NewNetPriceReminderRepository(db)
+	audit := auth.NewAuditLogger(repository.NewAuditRepository(db), repository.NewFailedAccessRepository(db), cfg.Logging.AuditLog)
+
+	pricingPolicy := domain.CombinationPolicy{
+		StackOnCustomerDiscount: cfg.Business.Pricing.StackOnCustomerDiscount,
+		StackOnNetPrice:         cfg.Business.Pricing.StackOnNetPrice,
+		MaxTotalDiscountPercent: cfg.Business.Pricing.MaxTotalDiscountPercent,
+	}
+
+	marginPolicy := domain.MarginPolicy{
+		SottocostoThresholdPercent:    cfg.Business.Margin.SottocostoThresholdPercent,
+		SottoguadagnoThresholdPercent: cfg.Business.Margin.SottoguadagnoThresholdPercent,
+	}
+
+	authorizationUC := usecase.NewDiscountAuthorizationUseCase(discountLimitRepo, operatorRepo, cfg.Business.DefaultDiscountLimits(), audit)
+
+	incentiveScheme := cfg.Business.Budget.IncentiveScheme()
+
+	var twoFactorProfiles []domain.ProfileType
+	for _, profile := range cfg.Auth.TwoFactorProfiles {
+		twoFactorProfiles = append(twoFactorProfiles, domain.ProfileType(profile))
+	}
+	loginUC := usecase.NewLoginUseCase(
+		operatorRepo,
+		audit,
+		cfg.Auth.MaxFailedAttempts,
+		cfg.Auth.TerminalMaxAttempts,
+		cfg.Auth.LockoutDurationMinutes,
+		twoFactorProfiles,
+		cfg.App.Name,
+		cfg.Auth.PasswordRules(),
+	)
+
+	marginUC := usecase.NewMarginControlUseCase(approvalRepo, articleRepo, documentRepo, operatorRepo, loginUC, marginPolicy, audit)
+	budgetUC := usecase.NewManageBudgetsUseCase(budgetRepo, documentRepo, customerRepo, operatorRepo, incentiveScheme)
+	voucherUC := usecase.NewManageVouchersUseCase(voucherRepo, customerRepo, repository.NewVoucherLedgerRepository(db), audit)
+	promotionUC := usecase.NewManagePromotionsUseCase(promotionRepo, usageRepo)
+	couponUC := usecase.NewManageCouponsUseCase(couponRepo, promotionRepo, audit)
+	postUC := usecase.NewPostDocumentsUseCase(documentRepo, marginUC, authorizationUC,
+		promotionUC,
+		couponUC,
+		voucherUC,
+		budgetUC,
+		audit)
+
+	var sessionStore auth.SessionStore = repository.NewSessionRepository(db)
+	if cfg.Auth.SessionStore == "memory" {
+		sessionStore = auth.NewMemorySessionStore()
+	}
 
 	return &AppModel{
-		db:             db,
-		currentView:    ViewLogin,
-		viewStack:      []ViewState{},
-		authService:    auth.NewAuthService(480),
-		articleRepo:    articleRepo,
-		customerRepo:   customerRepo,
-		operatorRepo:   operatorRepo,
-		promotionRepo:  promotionRepo,
-		voucherRepo:    voucherRepo,
-		budgetRepo:     budgetRepo,
-		kitRepo:        kitRepo,
-		searchUC:       usecase.NewSearchArticlesUseCase(articleRepo),
-		discountUC:     usecase.NewManageDiscountsUseCase(customerRepo, articleRepo, promotionRepo),
-		stockUC:        usecase.NewManageStockUseCase(articleRepo, kitRepo),
-		loginView:      &LoginView{},
-		mainMenuView:   &MainMenuView{selectedIndex: 0},
-		searchView:     &ArticleSearchView{},
-		sessionTimeout: 480 * time.Minute,
-		lastActivity:   time.Now(),
-		quitCh:         make(chan struct{}),
+		db:                 db,
+		config:             cfg,
+		currentView:        ViewLogin,
+		viewStack:          []ViewState{},
+		authService:        auth.NewAuthService(sessionStore, cfg.Auth.SessionTimeoutMinutes),
+		articleRepo:        articleRepo,
+		customerRepo:       customerRepo,
+		operatorRepo:       operatorRepo,
+		promotionRepo:      promotionRepo,
+		voucherRepo:        voucherRepo,
+		budgetRepo:         budgetRepo,
+		kitRepo:            kitRepo,
+		priceListRepo:      priceListRepo,
+		searchUC:           usecase.NewSearchArticlesUseCase(articleRepo),
+		priceUC:            usecase.NewManagePricesUseCase(articleRepo, repository.NewPriceHistoryRepository(db), audit),
+		discountUC:         usecase.NewManageDiscountsUseCase(customerRepo, articleRepo, promotionRepo, priceListRepo, couponRepo, usageRepo, authorizationUC, pricingPolicy),
+		stockUC:            usecase.NewManageStockUseCase(articleRepo, kitRepo, audit),
+		analyticsUC:        usecase.NewPromotionAnalyticsUseCase(promotionRepo, usageRepo, documentRepo, articleRepo),
+		marginUC:           marginUC,
+		authorizationUC:    authorizationUC,
+		netPriceUC:         usecase.NewManageNetPricesUseCase(articleRepo, customerRepo, reminderRepo, audit, cfg.Business.NetPrices.ReminderDays),
+		budgetUC:           budgetUC,
+		postUC:             postUC,
+		promotionUC:        promotionUC,
+		couponUC:           couponUC,
+		priceListUC:        usecase.NewManagePriceListsUseCase(priceListRepo, articleRepo, customerRepo, audit),
+		voucherUC:          voucherUC,
+		loginUC:            loginUC,
+		audit:              audit,
+		terminal:           terminalID(),
+		loginView:          &LoginView{},
+		mainMenuView:       &MainMenuView{selectedIndex: 0},
+		searchView:         &ArticleSearchView{},
+		promotionsView:     &PromotionsView{},
+		approvalsView:      &ApprovalsView{},
+		netPricesView:      &NetPricesView{},
+		priceListsView:     &PriceListsView{},
+		operatorsView:      &OperatorsView{},
+		budgetsView:        newBudgetsView(),
+		creditVouchersView: newCreditVouchersView(),
+		voucherReportView:  newVoucherReportView(),
+		twoFactorView:      &TwoFactorView{},
+		sessionTimeout:     time.Duration(cfg.Auth.SessionTimeoutMinutes) * time.Minute,
+		lastActivity:       time.Now(),
+		quitCh:             make(chan struct{}),
 	}
 }
 
+// AuthService espone le sessioni alla pulizia pianificata
+func (m *AppModel) AuthService() *auth.AuthService {
+	return m.authService
+}
+
+// LoginUC espone i contatori dei tentativi di login alla pulizia pianificata
+func (m *AppModel) LoginUC() *usecase.LoginUseCase {
+	return m.loginUC
+}
+
+// PriceUC, PromotionUC, NetPriceUC, VoucherUC e BudgetUC espongono ai job pianificati gli stessi
+// use case della TUI
+func (m *AppModel) PriceUC() *usecase.ManagePricesUseCase {
+	return m.priceUC
+}
+
+func (m *AppModel) PromotionUC() *usecase.ManagePromotionsUseCase {
+	return m.promotionUC
+}
+
+func (m *AppModel) NetPriceUC() *usecase.ManageNetPricesUseCase {
+	return m.netPriceUC
+}
+
+func (m *AppModel) VoucherUC() *usecase.ManageVouchersUseCase {
+	return m.voucherUC
+}
+
+func (m *AppModel) BudgetUC() *usecase.ManageBudgetsUseCase {
+	return m.budgetUC
+}
+
+// AuditLogger è condiviso con i job pianificati, così le voci del processo finiscono nel file di
+// audit in ordine di sequenza
+func (m *AppModel) AuditLogger() *auth.AuditLogger {
+	return m.audit
+}
+
 func (m *AppModel) Init() tea.Cmd {
 	return m.tickCmd()
 }
@@ -171,26 +617,105 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tickMsg:
 		if m.operator != nil && time.Since(m.lastActivity) > m.sessionTimeout {
+			cmd := m.endSessionCmd()
 			m.setError("Sessione scaduta. Effettua nuovamente il login.")
-			m.operator = nil
-			m.currentView = ViewLogin
-			m.viewStack = []ViewState{}
-			m.loginView = &LoginView{}
+			m.resetToLogin()
+			return m, tea.Batch(m.tickCmd(), cmd)
+		}
+		if m.session != nil && time.Since(m.lastSessionCheck) >= sessionCheckInterval {
+			return m, tea.Batch(m.tickCmd(), m.checkSession())
 		}
 		return m, m.tickCmd()
 
+	case sessionCheckMsg:
+		return m.handleSessionCheck(msg)
+
+	case logoutDoneMsg:
+		return m.handleLogoutDone(msg)
+
 	case loginResultMsg:
 		return m.handleLoginResult(msg)
 
 	case searchResultMsg:
 		return m.handleSearchResult(msg)
 
+	case priceHistoryMsg:
+		return m.handlePriceHistory(msg)
+
+	case priceChangeMsg:
+		return m.handlePriceChange(msg)
+
+	case promotionsLoadedMsg:
+		return m.handlePromotionsLoaded(msg)
+
+	case couponMsg:
+		return m.handleCoupon(msg)
+
+	case promotionReportMsg:
+		return m.handlePromotionReport(msg)
+
+	case approvalsLoadedMsg:
+		return m.handleApprovalsLoaded(msg)
+
+	case approvalDecisionMsg:
+		return m.handleApprovalDecision(msg)
+
+	case netPricesLoadedMsg:
+		return m.handleNetPricesLoaded(msg)
+
+	case netPriceRemindersMsg:
+		return m.handleNetPriceReminders(msg)
+
+	case priceListsLoadedMsg:
+		return m.handlePriceListsLoaded(msg)
+
+	case priceListActionMsg:
+		return m.handlePriceListAction(msg)
+
+	case operatorsLoadedMsg:
+		return m.handleOperatorsLoaded(msg)
+
+	case operatorActionMsg:
+		return m.handleOperatorAction(msg)
+
+	case netPriceActionMsg:
+		return m.handleNetPriceAction(msg)
+
+	case budgetsLoadedMsg:
+		return m.handleBudgetsLoaded(msg)
+
+	case budgetDocumentsMsg:
+		return m.handleBudgetDocuments(msg)
+
+	case vouchersLoadedMsg:
+		return m.handleVouchersLoaded(msg)
+
+	case voucherScannedMsg:
+		return m.handleVoucherScanned(msg)
+
+	case voucherReportLoadedMsg:
+		return m.handleVoucherReportLoaded(msg)
+
+	case twoFactorMsg:
+		return m.handleTwoFactor(msg)
+
+	case salesDocumentMsg:
+		return m.handleSalesDocument(msg)
+
+	case passwordChangedMsg:
+		return m.handlePasswordChanged(msg)
+
+	case exportDoneMsg:
+		if msg.err != nil {
+			m.setError("Errore esportazione: " + msg.err.Error())
+		} else {
+			m.setMessage("Esportato in " + msg.path)
+		}
+		return m, nil
+
 	case sessionExpiredMsg:
 		m.setError("Sessione scaduta per inattività.")
-		m.operator = nil
-		m.currentView = ViewLogin
-		m.viewStack = []ViewState{}
-		m.loginView = &LoginView{}
+		m.resetToLogin()
 		return m, nil
 
 	case tea.KeyMsg:
@@ -200,12 +725,18 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "q":
+			if m.capturesInput() {
+				break
+			}
 			if m.currentView == ViewLogin || m.currentView == ViewMainMenu {
 				return m, tea.Quit
 			}
 			return m.navigateBack(), nil
 
 		case "esc":
+			if m.capturesInput() {
+				break
+			}
 			return m.navigateBack(), nil
 		}
 	}
@@ -217,6 +748,28 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateMainMenu(msg)
 	case ViewArticleSearch:
 		return m.updateArticleSearch(msg)
+	case ViewPromotions:
+		return m.updatePromotions(msg)
+	case ViewApprovals:
+		return m.updateApprovals(msg)
+	case ViewNetPrices:
+		return m.updateNetPrices(msg)
+	case ViewPriceLists:
+		return m.updatePriceLists(msg)
+	case ViewOperators:
+		return m.updateOperators(msg)
+	case ViewBudgets:
+		return m.updateBudgets(msg)
+	case ViewCreditVouchers:
+		return m.updateCreditVouchers(msg)
+	case ViewVoucherReport:
+		return m.updateVoucherReport(msg)
+	case ViewTwoFactor:
+		return m.updateTwoFactor(msg)
+	case ViewChangePassword:
+		return m.updateChangePassword(msg)
+	case ViewSalesDocument:
+		return m.updateSalesDocument(msg)
 	default:
 		return m, nil
 	}
@@ -238,6 +791,28 @@ func (m *AppModel) View() string {
 		content = m.viewMainMenu()
 	case ViewArticleSearch:
 		content = m.viewArticleSearch()
+	case ViewPromotions:
+		content = m.viewPromotions()
+	case ViewApprovals:
+		content = m.viewApprovals()
+	case ViewNetPrices:
+		content = m.viewNetPrices()
+	case ViewPriceLists:
+		content = m.viewPriceLists()
+	case ViewOperators:
+		content = m.viewOperators()
+	case ViewBudgets:
+		content = m.viewBudgets()
+	case ViewCreditVouchers:
+		content = m.viewCreditVouchers()
+	case ViewVoucherReport:
+		content = m.viewVoucherReport()
+	case ViewTwoFactor:
+		content = m.viewTwoFactor()
+	case ViewChangePassword:
+		content = m.viewChangePassword()
+	case ViewSalesDocument:
+		content = m.viewSalesDocument()
 	default:
 		content = "View not implemented"
 	}
@@ -359,11 +934,92 @@ func (m *AppModel) renderHelp() string {
 	help := ""
 	switch m.currentView {
 	case ViewLogin:
-		help = "tab: campo successivo • enter: login • ctrl+c: esci"
+		switch m.loginView.step {
+		case loginStepCode:
+			help = "codice dell'app o codice di recupero • enter: conferma • esc: annulla • ctrl+c: esci"
+		case loginStepEnroll:
+			help = "inquadra il QR e inserisci il codice • enter: conferma • esc: annulla • ctrl+c: esci"
+		case loginStepRecoveryCodes:
+			help = "conserva i codici di recupero • enter: continua • ctrl+c: esci"
+		case loginStepChangePassword:
+			help = "tab: campo successivo • enter: cambia password • esc: annulla • ctrl+c: esci"
+		default:
+			help = "tab: campo successivo • enter: login • ctrl+c: esci"
+		}
 	case ViewMainMenu:
-		help = "1-7: selezione rapida • ↑/↓/j/k: naviga • enter: conferma • q: esci"
+		help = "1-9: selezione rapida • ↑/↓/j/k: naviga • enter: conferma • p: prezzi netti in scadenza • c: cambia password • t: 2FA • x: logout • X: logout ovunque • q: esci"
 	case ViewArticleSearch:
-		help = "tab: tipo ricerca • digita: cerca • ↑/↓/j/k: naviga • pgup/pgdwn: pagina • home/end: inizio/fine • enter: seleziona • esc: indietro"
+		switch {
+		case m.searchView.priceForm != nil:
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		case m.searchView.article != nil:
+			help = "↑/↓: naviga • n: nuova variazione prezzo • x: annulla variazione programmata • esc: torna ai risultati"
+		default:
+			help = "tab: tipo ricerca • digita: cerca • ↑/↓/j/k: naviga • pgup/pgdwn: pagina • home/end: inizio/fine • enter: prezzo e storico • esc: indietro"
+		}
+	case ViewPromotions:
+		if m.promotionsView.form != nil {
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		} else {
+			help = "↑/↓/j/k: naviga • enter: analisi • c: confronta tutte • e: esporta CSV • g: genera coupon • d: disattiva coupon • esc: indietro"
+		}
+	case ViewPriceLists:
+		if m.priceListsView.form != nil {
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		} else {
+			help = "↑/↓/j/k: naviga • n: nuovo a tabella • v: nuovo derivato • p: prezzo articolo • r: togli articolo • d: validità • g/u: assegna/togli categoria • a: assegna a cliente • x: disattiva • esc: indietro"
+		}
+	case ViewOperators:
+		if m.operatorsView.form != nil {
+			help = "enter: conferma • esc: annulla"
+		} else {
+			help = "↑/↓/j/k: naviga • r: password temporanea e sblocco • esc: indietro"
+		}
+	case ViewApprovals:
+		if m.approvalsView.form != nil {
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		} else if m.operator.CanApproveSottocosto() {
+			help = "↑/↓/j/k: naviga • a: approva • x: respingi • p: PIN supervisore • s: imposta PIN • r: aggiorna • esc: indietro"
+		} else {
+			help = "↑/↓/j/k: naviga • p: PIN supervisore • r: aggiorna • esc: indietro"
+		}
+	case ViewNetPrices:
+		if m.netPricesView.form != nil {
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		} else {
+			help = "↑/↓/j/k: naviga • s: scadenze • +/-: giorni • c: cliente • a: articolo • n: nuovo • r/R: rinnova selezionato/tutti • i: import CSV • x: ignora promemoria • e: esporta • esc: indietro"
+		}
+	case ViewBudgets:
+		if m.budgetsView.documents != nil {
+			help = "↑/↓/j/k: naviga • enter/esc: torna all'elenco"
+		} else {
+			help = "↑/↓/j/k: naviga • tab: tipo • ←/→: anno • enter: documenti • e/E: prospetto incentivi operatore/trimestre • r: aggiorna • esc: indietro"
+		}
+	case ViewCreditVouchers:
+		if m.creditVouchersView.input != "" {
+			help = "leggi o digita il codice • enter: conferma • esc: annulla"
+		} else {
+			help = "↑/↓/j/k: naviga • s: leggi buono • c: buoni cliente • p/P: stampa Code128/QR • r: residui e scadenze • esc: indietro"
+		}
+	case ViewChangePassword:
+		help = "tab: campo successivo • enter: conferma • esc: annulla"
+	case ViewSalesDocument:
+		if m.salesDocumentView.form != nil {
+			help = "tab: campo successivo • enter: conferma • esc: annulla"
+		} else {
+			help = "c: cliente • a: aggiungi riga • d: elimina riga • s: sconto riga • o: coupon • h: spedizione • v: buono • t: fattura/ordine • p: PIN supervisore • enter: registra • n: nuovo • esc: indietro"
+		}
+	case ViewTwoFactor:
+		switch {
+		case m.twoFactorView.enrollment != nil:
+			help = "inquadra il QR e inserisci il codice • enter: conferma • esc: annulla"
+		case m.twoFactorView.disabling:
+			help = "inserisci un codice per disattivare • enter: conferma • esc: annulla"
+		default:
+			help = "a: attiva/riconfigura • r: nuovi codici di recupero • d: disattiva • esc: indietro"
+		}
+	case ViewVoucherReport:
+		help = "←/→: mese precedente/successivo • t: oggi • +/-: giorni scadenza • e/E: esporta CSV/XLSX • esc: indietro"
 	default:
 		help = "esc: indietro • q: esci"
 	}
@@ -387,10 +1043,26 @@ func (m *AppModel) getViewName(view ViewState) string {
 		return "Promozioni"
 	case ViewCreditVouchers:
 		return "Buoni Credito"
+	case ViewVoucherReport:
+		return "Residui Buoni"
+	case ViewTwoFactor:
+		return "Sicurezza Account"
+	case ViewChangePassword:
+		return "Cambio Password"
+	case ViewSalesDocument:
+		return "Vendita al Banco"
+	case ViewPriceLists:
+		return "Listini"
+	case ViewOperators:
+		return "Operatori"
 	case ViewBudgets:
 		return "Budget"
 	case ViewKits:
 		return "Kit"
+	case ViewApprovals:
+		return "Approvazioni"
+	case ViewNetPrices:
+		return "Prezzi Netti"
 	default:
 		return "Unknown"
 	}
@@ -428,16 +1100,25 @@ func (m *AppModel) clearMessages() {
 func (m *AppModel) initMainMenu() {
 	m.mainMenuView.menuItems = []MenuItem{
 		{Label: "🔍 Ricerca Articoli", Description: "Cerca e gestisci articoli", View: ViewArticleSearch, Enabled: true},
+		{Label: "🧾 Vendita al Banco", Description: "Fatture e ordini con promozioni, coupon e buoni", View: ViewSalesDocument, Enabled: true},
 		{Label: "👥 Gestione Clienti", Description: "Gestisci anagrafica clienti", View: ViewCustomerSearch, Enabled: true},
 		{Label: "🎁 Promozioni", Description: "Gestisci promozioni attive", View: ViewPromotions, Enabled: true},
 		{Label: "💰 Buoni Credito", Description: "Gestisci buoni a credito", View: ViewCreditVouchers, Enabled: true},
 		{Label: "📊 Budget", Description: "Monitora obiettivi di vendita", View: ViewBudgets, Enabled: true},
 		{Label: "📦 Kit", Description: "Gestisci kit di vendita", View: ViewKits, Enabled: true},
+		{Label: "🏷️  Prezzi Netti", Description: "Distinta prezzi netti con scadenza", View: ViewNetPrices, Enabled: true},
+		{Label: "📋 Listini", Description: "Listini a tabella e derivati, assegnazione a clienti e categorie", View: ViewPriceLists, Enabled: true},
+		{Label: "✅ Approvazioni", Description: "Vendite sottocosto e sottoguadagno in attesa", View: ViewApprovals, Enabled: true},
+		{Label: "👤 Operatori", Description: "Reset password e sblocco account", View: ViewOperators, Enabled: m.operator.IsAdmin()},
 		{Label: "⚙️  Impostazioni", Description: "Configurazione sistema", View: ViewSettings, Enabled: m.operator.IsAdmin()},
 	}
 }
 
 func (m *AppModel) viewLogin() string {
+	if m.loginView.step != loginStepPassword {
+		return m.viewLoginSecondFactor()
+	}
+
 	title := TitleStyle.Render("🔐 Login - Ricambi Manager")
 
 	usernameLabel := "Username:"
@@ -499,6 +1180,10 @@ func (m *AppModel) viewLogin() string {
 }
 
 func (m *AppModel) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.loginView.step != loginStepPassword {
+		return m.updateLoginSecondFactor(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -550,49 +1235,243 @@ func (m *AppModel) performLogin() tea.Cmd {
 			return loginResultMsg{err: fmt.Errorf("inserire password")}
 		}
 
-		operator, err := m.operatorRepo.FindByUsername(ctx, m.loginView.username)
+		result, err := m.loginUC.Authenticate(ctx, m.loginView.username, m.loginView.password, m.terminal)
 		if err != nil {
-			return loginResultMsg{err: fmt.Errorf("credenziali non valide")}
+			return loginResultMsg{err: loginError(result, err)}
 		}
 
-		if err := operator.CheckPassword(m.loginView.password); err != nil {
-			if updateErr := m.operatorRepo.Update(ctx, operator); updateErr != nil {
-				return loginResultMsg{err: fmt.Errorf("errore di sistema: %w", updateErr)}
+		if result.Enroll {
+			enrollment, err := m.loginUC.BeginTOTPEnrollment(ctx, result.Operator)
+			if err != nil {
+				return loginResultMsg{err: fmt.Errorf("errore di attivazione 2FA: %w", err)}
 			}
-			return loginResultMsg{err: fmt.Errorf("credenziali non valide")}
+			return loginResultMsg{pending: result, enrollment: enrollment}
 		}
-
-		session, err := m.authService.CreateSession(operator, "localhost", "TUI")
-		if err != nil {
-			return loginResultMsg{err: fmt.Errorf("errore di creazione sessione: %w", err)}
+		if result.SecondFactor {
+			return loginResultMsg{pending: result}
 		}
+		if result.PasswordChange {
+			return loginResultMsg{pending: result, passwordChange: true}
+		}
+
+		return m.completeLogin(ctx, result.Operator)
+	}
+}
 
-		operator.SessionToken = session.Token
-		if err := m.operatorRepo.Update(ctx, operator); err != nil {
-			return loginResultMsg{err: fmt.Errorf("errore di aggiornamento sessione: %w", err)}
+// completeLogin apre la sessione dopo la verifica di tutti i fattori richiesti
+func (m *AppModel) completeLogin(ctx context.Context, operator *domain.Operator) loginResultMsg {
+	session, err := m.authService.CreateSession(ctx, operator, m.terminal, "TUI")
+	if err != nil {
+		return loginResultMsg{err: fmt.Errorf("errore di creazione sessione: %w", err)}
+	}
+
+	if err := m.operatorRepo.UpdateLastLogin(ctx, operator.ID); err != nil {
+		return loginResultMsg{err: fmt.Errorf("errore di aggiornamento sessione: %w", err)}
+	}
+
+	return loginResultMsg{operator: operator, session: session}
+}
+
+// loginError traduce l'esito del login nel messaggio per l'operatore
+func loginError(result *usecase.LoginResult, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrLoginThrottled):
+		wait := result.RetryAfter.Round(time.Minute)
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+		return fmt.Errorf("troppi tentativi, riprova tra %d minuti", int(wait.Minutes()))
+	case errors.Is(err, domain.ErrOperatorLocked):
+		if result.LockedUntil.IsZero() {
+			return fmt.Errorf("account bloccato: rivolgersi all'amministratore")
 		}
+		return fmt.Errorf("account bloccato per troppi tentativi fino alle %s", result.LockedUntil.Format("15:04"))
+	case errors.Is(err, domain.ErrInvalidCredentials), errors.Is(err, domain.ErrOperatorInactive):
+		return fmt.Errorf("credenziali non valide")
+	case errors.Is(err, domain.ErrInvalidTOTPCode):
+		return fmt.Errorf("codice non valido")
+	case errors.Is(err, domain.ErrInvalidPassword):
+		return fmt.Errorf("la nuova password non rispetta i requisiti")
+	case errors.Is(err, domain.ErrPasswordReused):
+		return fmt.Errorf("la nuova password è stata usata di recente")
+	default:
+		return fmt.Errorf("errore di sistema: %w", err)
+	}
+}
 
-		return loginResultMsg{operator: operator}
+// terminalID identifica il terminale per il limite dei tentativi: il client SSH se presente, altrimenti l'host
+func terminalID() string {
+	if client := strings.Fields(os.Getenv("SSH_CLIENT")); len(client) > 0 {
+		return client[0]
 	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return host
 }
 
 func (m *AppModel) handleLoginResult(msg loginResultMsg) (*AppModel, tea.Cmd) {
+	lv := m.loginView
+
 	if msg.err != nil {
-		m.loginView.error = msg.err.Error()
-		m.loginView.password = ""
+		lv.error = msg.err.Error()
+		lv.password = ""
+		lv.code = ""
+		if lv.passwordForm != nil {
+			lv.passwordForm = &PasswordForm{}
+		}
+		// con account bloccato o troppi tentativi si ricomincia dalla password
+		if lv.pending != nil && (lv.pending.RetryAfter > 0 || lv.pending.Operator.IsLockedAt(time.Now())) {
+			m.loginView = &LoginView{username: lv.username, focusIndex: 1, error: lv.error}
+		}
+		return m, nil
+	}
+
+	switch {
+	case msg.pending != nil:
+		if lv.password != "" {
+			lv.currentPassword = lv.password
+		}
+		lv.pending = msg.pending
+		lv.enrollment = msg.enrollment
+		lv.password = ""
+		lv.code = ""
+		lv.error = ""
+		switch {
+		case msg.passwordChange:
+			lv.step = loginStepChangePassword
+			lv.passwordForm = &PasswordForm{}
+		case msg.enrollment != nil:
+			lv.step = loginStepEnroll
+		default:
+			lv.step = loginStepCode
+		}
+		return m, nil
+
+	case msg.recoveryCodes != nil:
+		lv.recoveryCodes = msg.recoveryCodes
+		lv.enrollment = nil
+		lv.code = ""
+		lv.error = ""
+		lv.step = loginStepRecoveryCodes
 		return m, nil
 	}
 
 	m.operator = msg.operator
-	m.loginView.username = ""
-	m.loginView.password = ""
-	m.loginView.error = ""
+	m.session = msg.session
+	m.lastActivity = time.Now()
+	m.lastSessionCheck = time.Now()
+	m.loginView = &LoginView{}
 	m.currentView = ViewMainMenu
 	m.initMainMenu()
 
+	return m, m.loadNetPriceReminders()
+}
+
+// sessionCheckInterval limita gli accessi allo SessionStore: la scadenza scorre al più una volta al minuto
+const sessionCheckInterval = time.Minute
+
+// checkSession rinnova la sessione se c'è stata attività dall'ultimo controllo, altrimenti verifica
+// solo che sia ancora valida: un logout da un'altra istanza chiude anche questa
+func (m *AppModel) checkSession() tea.Cmd {
+	token := m.session.Token
+	active := m.lastActivity.After(m.lastSessionCheck)
+	m.lastSessionCheck = time.Now()
+
+	return func() tea.Msg {
+		ctx := context.Background()
+		if active {
+			return sessionCheckMsg{err: m.authService.RefreshSession(ctx, token)}
+		}
+		_, err := m.authService.ValidateSession(ctx, token)
+		return sessionCheckMsg{err: err}
+	}
+}
+
+func (m *AppModel) handleSessionCheck(msg sessionCheckMsg) (*AppModel, tea.Cmd) {
+	if msg.err == nil || m.session == nil {
+		return m, nil
+	}
+
+	if !errors.Is(msg.err, auth.ErrSessionNotFound) && !errors.Is(msg.err, auth.ErrTokenExpired) {
+		// archivio non raggiungibile: la sessione resta aperta e si riprova al controllo successivo
+		log.Printf("Session check failed: %v", msg.err)
+		return m, nil
+	}
+
+	m.setError("Sessione terminata. Effettua nuovamente il login.")
+	m.resetToLogin()
 	return m, nil
 }
 
+// logout chiude la sessione corrente o, con everywhere, tutte le sessioni dell'operatore su ogni istanza
+func (m *AppModel) logout(everywhere bool) tea.Cmd {
+	if m.operator == nil || m.session == nil {
+		return nil
+	}
+	token := m.session.Token
+	operatorID := m.operator.ID.Hex()
+
+	return func() tea.Msg {
+		ctx := context.Background()
+		if everywhere {
+			count, err := m.authService.InvalidateAllSessions(ctx, operatorID)
+			return logoutDoneMsg{sessions: count, err: err}
+		}
+		return logoutDoneMsg{sessions: 1, err: m.authService.InvalidateSession(ctx, token)}
+	}
+}
+
+func (m *AppModel) handleLogoutDone(msg logoutDoneMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		m.setError("Errore durante il logout: " + msg.err.Error())
+		return m, nil
+	}
+
+	m.resetToLogin()
+	if msg.sessions > 1 {
+		m.setMessage(fmt.Sprintf("Disconnesso da %d sessioni", msg.sessions))
+	} else {
+		m.setMessage("Disconnesso")
+	}
+	return m, nil
+}
+
+// endSessionCmd chiude la sessione nello store senza attendere l'esito, per scadenza locale o uscita
+func (m *AppModel) endSessionCmd() tea.Cmd {
+	if m.session == nil {
+		return nil
+	}
+	token := m.session.Token
+
+	return func() tea.Msg {
+		if err := m.authService.InvalidateSession(context.Background(), token); err != nil {
+			log.Printf("Error closing session: %v", err)
+		}
+		return nil
+	}
+}
+
+// EndSession chiude la sessione dell'operatore all'uscita dal programma
+func (m *AppModel) EndSession(ctx context.Context) {
+	if m.session == nil {
+		return
+	}
+	if err := m.authService.InvalidateSession(ctx, m.session.Token); err != nil {
+		log.Printf("Error closing session: %v", err)
+	}
+	m.session = nil
+}
+
+func (m *AppModel) resetToLogin() {
+	m.operator = nil
+	m.session = nil
+	m.currentView = ViewLogin
+	m.viewStack = []ViewState{}
+	m.loginView = &LoginView{}
+}
+
 func (m *AppModel) handleSearchResult(msg searchResultMsg) (*AppModel, tea.Cmd) {
 	m.searchView.loading = false
 
@@ -3,6 +3,8 @@
 package ui
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -224,15 +226,8 @@ func RenderProgressBar(percent float64, width int) string {
 		filled = 0
 	}
 
-	bar := ""
-	for i := 0; i < filled; i++ {
-		bar += "█"
-	}
-	for i := filled; i < width; i++ {
-		bar += "░"
-	}
-
-	return ProgressBarStyle.Render(bar[:filled]) + ProgressBarEmptyStyle.Render(bar[filled:])
+	return ProgressBarStyle.Render(strings.Repeat("█", filled)) +
+		ProgressBarEmptyStyle.Render(strings.Repeat("░", width-filled))
 }
 
 func RenderBadge(text string, style lipgloss.Style) string {
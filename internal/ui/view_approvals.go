@@ -0,0 +1,334 @@
+// internal/ui/view_approvals.go
+
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+)
+
+func (m *AppModel) viewApprovals() string {
+	title := TitleStyle.Render("✅ Approvazioni Sottocosto")
+	av := m.approvalsView
+
+	subtitle := "Richieste in attesa"
+	if !m.operator.CanApproveSottocosto() {
+		subtitle = "Le tue richieste in attesa"
+	}
+
+	var list string
+	if av.loading {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(av.approvals) == 0 {
+		list = InfoStyle.Render("Nessuna richiesta in attesa")
+	} else {
+		var items []string
+		for i, approval := range av.approvals {
+			badge := BadgeWarningStyle.Render(string(approval.Violation))
+			if approval.Violation == domain.MarginViolationSottocosto {
+				badge = BadgeDangerStyle.Render(string(approval.Violation))
+			}
+
+			itemText := fmt.Sprintf("%-10s %-12s %-15s x%-5.0f € %8.2f  margine %6.2f%% (soglia %.0f%%) %s  da %s",
+				approval.RequestedAt.Format("02/01 15:04"),
+				approval.CustomerCode,
+				approval.ArticleCode,
+				approval.Quantity,
+				approval.UnitPrice,
+				approval.MarginPercent,
+				approval.ThresholdPercent,
+				badge,
+				approval.RequestedBy,
+			)
+			if i == av.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{title, SubtitleStyle.Render(subtitle), ContentStyle.Render(list)}
+
+	if av.form != nil {
+		sections = append(sections, CardStyle.Render(m.renderSupervisorPINForm()))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func (m *AppModel) renderSupervisorPINForm() string {
+	form := m.approvalsView.form
+
+	pinField := ""
+	for range form.pin {
+		pinField += "*"
+	}
+
+	if form.setPIN {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			SubtitleStyle.Render("Imposta il tuo PIN supervisore (4-8 cifre)"),
+			InputFocusedStyle.Render(pinField+"█"),
+		)
+	}
+
+	usernameField := form.username
+	if form.focusIndex == 0 {
+		usernameField = InputFocusedStyle.Render(usernameField + "█")
+		pinField = InputStyle.Render(pinField)
+	} else {
+		usernameField = InputStyle.Render(usernameField)
+		pinField = InputFocusedStyle.Render(pinField + "█")
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		SubtitleStyle.Render("Approvazione con PIN supervisore"),
+		"Supervisore:",
+		usernameField,
+		"PIN:",
+		pinField,
+	)
+}
+
+// capturesInput indica se la vista corrente sta raccogliendo testo, così q ed esc non escono dalla vista
+func (m *AppModel) capturesInput() bool {
+	switch m.currentView {
+	case ViewLogin:
+		// il login è sempre un modulo di testo: si esce con ctrl+c
+		return true
+	case ViewTwoFactor:
+		return m.twoFactorView.enrollment != nil || m.twoFactorView.disabling
+	case ViewChangePassword:
+		return true
+	case ViewArticleSearch:
+		return m.searchView.article != nil
+	case ViewSalesDocument:
+		return m.salesDocumentView.form != nil
+	case ViewApprovals:
+		return m.approvalsView.form != nil
+	case ViewNetPrices:
+		return m.netPricesView.form != nil
+	case ViewPromotions:
+		return m.promotionsView.form != nil
+	case ViewPriceLists:
+		return m.priceListsView.form != nil
+	case ViewOperators:
+		return m.operatorsView.form != nil
+	case ViewBudgets:
+		return m.budgetsView.documents != nil
+	case ViewCreditVouchers:
+		return m.creditVouchersView.input != ""
+	default:
+		return false
+	}
+}
+
+func (m *AppModel) updateApprovals(msg tea.Msg) (tea.Model, tea.Cmd) {
+	av := m.approvalsView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if av.form != nil {
+		return m.updateSupervisorPINForm(keyMsg)
+	}
+
+	canApprove := m.operator.CanApproveSottocosto()
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if av.selectedIndex > 0 {
+			av.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if av.selectedIndex < len(av.approvals)-1 {
+			av.selectedIndex++
+		}
+		return m, nil
+
+	case "r":
+		av.loading = true
+		return m, m.loadApprovals()
+
+	case "a", "x":
+		if !canApprove {
+			m.setError("Non sei abilitato ad approvare: usa il PIN di un supervisore (p)")
+			return m, nil
+		}
+		approval := av.selected()
+		if approval == nil {
+			return m, nil
+		}
+		approve := keyMsg.String() == "a"
+		return m, func() tea.Msg {
+			ctx := context.Background()
+			if approve {
+				decided, err := m.marginUC.Approve(ctx, approval.ID, m.operator, "")
+				return approvalDecisionMsg{approval: decided, err: err}
+			}
+			decided, err := m.marginUC.Reject(ctx, approval.ID, m.operator, "")
+			return approvalDecisionMsg{approval: decided, err: err}
+		}
+
+	case "p":
+		if av.selected() == nil {
+			return m, nil
+		}
+		av.form = &SupervisorPINForm{}
+		return m, nil
+
+	case "s":
+		if !canApprove {
+			m.setError("Solo gli operatori abilitati possono impostare un PIN supervisore")
+			return m, nil
+		}
+		av.form = &SupervisorPINForm{setPIN: true}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updateSupervisorPINForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	form := m.approvalsView.form
+
+	switch msg.String() {
+	case "esc":
+		m.approvalsView.form = nil
+		return m, nil
+
+	case "tab", "shift+tab", "up", "down":
+		if !form.setPIN {
+			form.focusIndex = (form.focusIndex + 1) % 2
+		}
+		return m, nil
+
+	case "backspace":
+		if form.focusIndex == 0 && !form.setPIN {
+			if len(form.username) > 0 {
+				form.username = form.username[:len(form.username)-1]
+			}
+		} else if len(form.pin) > 0 {
+			form.pin = form.pin[:len(form.pin)-1]
+		}
+		return m, nil
+
+	case "enter":
+		m.approvalsView.form = nil
+
+		if form.setPIN {
+			return m, func() tea.Msg {
+				err := m.marginUC.SetSupervisorPIN(context.Background(), m.operator, form.pin)
+				return approvalDecisionMsg{err: err, message: "PIN supervisore impostato"}
+			}
+		}
+
+		approval := m.approvalsView.selected()
+		if approval == nil {
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			decided, err := m.marginUC.ApproveWithPIN(context.Background(), approval.ID, form.username, form.pin, "")
+			return approvalDecisionMsg{approval: decided, err: err}
+		}
+
+	default:
+		if len(msg.String()) == 1 {
+			if form.focusIndex == 0 && !form.setPIN {
+				form.username += msg.String()
+			} else {
+				form.pin += msg.String()
+			}
+		}
+		return m, nil
+	}
+}
+
+func (av *ApprovalsView) selected() *domain.MarginApproval {
+	if av.selectedIndex < 0 || av.selectedIndex >= len(av.approvals) {
+		return nil
+	}
+	return av.approvals[av.selectedIndex]
+}
+
+func (m *AppModel) loadApprovals() tea.Cmd {
+	return func() tea.Msg {
+		approvals, err := m.marginUC.GetPendingApprovals(context.Background(), 100)
+		if err != nil {
+			return approvalsLoadedMsg{err: err}
+		}
+
+		// chi non può approvare vede solo le proprie richieste
+		if !m.operator.CanApproveSottocosto() {
+			var own []*domain.MarginApproval
+			for _, approval := range approvals {
+				if approval.RequestedBy == m.operator.Username {
+					own = append(own, approval)
+				}
+			}
+			approvals = own
+		}
+
+		return approvalsLoadedMsg{approvals: approvals}
+	}
+}
+
+func (m *AppModel) handleApprovalsLoaded(msg approvalsLoadedMsg) (*AppModel, tea.Cmd) {
+	m.approvalsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento approvazioni: " + msg.err.Error())
+		m.approvalsView.approvals = []*domain.MarginApproval{}
+		return m, nil
+	}
+
+	m.approvalsView.approvals = msg.approvals
+	if m.approvalsView.selectedIndex >= len(msg.approvals) {
+		m.approvalsView.selectedIndex = 0
+	}
+	return m, nil
+}
+
+func (m *AppModel) handleApprovalDecision(msg approvalDecisionMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		m.setError("Operazione non riuscita: " + msg.err.Error())
+		return m, nil
+	}
+
+	if msg.approval == nil {
+		m.setMessage(msg.message)
+		return m, nil
+	}
+
+	switch msg.approval.Status {
+	case domain.ApprovalStatusApproved:
+		m.setMessage(fmt.Sprintf("%s approvato da %s", msg.approval.ArticleCode, msg.approval.DecidedBy))
+	case domain.ApprovalStatusRejected:
+		m.setMessage(fmt.Sprintf("%s respinto", msg.approval.ArticleCode))
+	}
+
+	m.approvalsView.loading = true
+	return m, m.loadApprovals()
+}
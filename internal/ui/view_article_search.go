@@ -4,15 +4,24 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/export"
 )
 
 func (m *AppModel) viewArticleSearch() string {
+	if m.searchView.article != nil {
+		return m.viewArticlePrice()
+	}
+
 	title := TitleStyle.Render("🔍 Ricerca Articoli")
 
 	searchTypeLabel := "Tipo: "
@@ -135,6 +144,10 @@ func truncateString(s string, maxLen int) string {
 }
 
 func (m *AppModel) updateArticleSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.searchView.article != nil {
+		return m.updateArticlePrice(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -204,10 +217,10 @@ func (m *AppModel) updateArticleSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case "enter":
-			if len(m.searchView.results) > 0 {
+			if len(m.searchView.results) == 0 {
 				return m, nil
 			}
-			return m, nil
+			return m, m.loadPriceHistory(m.searchView.results[m.searchView.selectedIndex].ID)
 
 		case "backspace":
 			if len(m.searchView.query) > 0 {
@@ -292,3 +305,244 @@ func (m *AppModel) performSearch() tea.Cmd {
 		return searchResultMsg{results: results, err: err}
 	}
 }
+
+func (m *AppModel) viewArticlePrice() string {
+	sv := m.searchView
+	article := sv.article
+
+	summary := CardStyle.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		fmt.Sprintf("%s - %s", article.Code, article.Description),
+		"",
+		fmt.Sprintf("Prezzo di listino: € %.2f", article.Pricing.ListPrice),
+		fmt.Sprintf("Costo ultimo acquisto: € %.2f", article.Pricing.LastPurchaseCost),
+	))
+
+	var list string
+	if len(sv.history) == 0 {
+		list = InfoStyle.Render("Nessuna variazione registrata")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-10s %9s %9s %9s  %-10s %-12s %s",
+			"Dal", "Listino", "Prec.", "Costo", "Stato", "Operatore", "Motivo"))
+		items := []string{header}
+		for i, change := range sv.history {
+			status := BadgeSuccessStyle.Render("applicata")
+			switch change.Status {
+			case domain.PriceChangeScheduled:
+				status = BadgeWarningStyle.Render("programmata")
+			case domain.PriceChangeCancelled:
+				status = BadgeDangerStyle.Render("annullata")
+			}
+			itemText := fmt.Sprintf("%-10s %9.2f %9.2f %9.2f  %s %-12s %s",
+				change.EffectiveFrom.Format("02/01/2006"),
+				change.ListPrice,
+				change.PreviousListPrice,
+				change.Cost,
+				status,
+				truncateString(change.ChangedBy, 12),
+				truncateString(change.Reason, 30),
+			)
+			if i == sv.historyIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("💶 Prezzo Articolo"),
+		"",
+		summary,
+		"",
+		ContentStyle.Render(lipgloss.JoinVertical(lipgloss.Left, SubtitleStyle.Render("Storico variazioni"), "", list)),
+	}
+	if sv.priceForm != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(sv.priceForm)))
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height-6,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, sections...)),
+	)
+}
+
+func (m *AppModel) updateArticlePrice(msg tea.Msg) (tea.Model, tea.Cmd) {
+	sv := m.searchView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if sv.priceForm != nil {
+		return m.updatePriceForm(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if sv.historyIndex > 0 {
+			sv.historyIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if sv.historyIndex < len(sv.history)-1 {
+			sv.historyIndex++
+		}
+		return m, nil
+
+	case "n":
+		sv.priceForm = newNetPriceForm("change_price", "Nuova variazione prezzo",
+			"Prezzo di listino", "Costo", "In vigore dal (gg/mm/aaaa)", "Motivo")
+		sv.priceForm.values[0] = fmt.Sprintf("%.2f", sv.article.Pricing.ListPrice)
+		sv.priceForm.values[1] = fmt.Sprintf("%.2f", sv.article.Pricing.LastPurchaseCost)
+		sv.priceForm.values[2] = time.Now().Format("02/01/2006")
+		return m, nil
+
+	case "x":
+		if sv.historyIndex >= len(sv.history) {
+			return m, nil
+		}
+		change := sv.history[sv.historyIndex]
+		if change.Status != domain.PriceChangeScheduled {
+			m.setError("Si possono annullare solo le variazioni programmate")
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			err := m.priceUC.CancelPriceChange(context.Background(), change.ID, m.operator)
+			return priceChangeMsg{err: err, message: "Variazione annullata"}
+		}
+
+	case "esc":
+		sv.article = nil
+		sv.history = nil
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updatePriceForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sv := m.searchView
+	form := sv.priceForm
+
+	switch msg.String() {
+	case "esc":
+		sv.priceForm = nil
+		return m, nil
+
+	case "tab", "down":
+		form.focusIndex = (form.focusIndex + 1) % len(form.labels)
+		return m, nil
+
+	case "shift+tab", "up":
+		form.focusIndex--
+		if form.focusIndex < 0 {
+			form.focusIndex = len(form.labels) - 1
+		}
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		if form.focusIndex < len(form.labels)-1 {
+			form.focusIndex++
+			return m, nil
+		}
+		sv.priceForm = nil
+		return m, m.submitPriceChange(form.values)
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) submitPriceChange(values []string) tea.Cmd {
+	articleID := m.searchView.article.ID
+
+	return func() tea.Msg {
+		listPrice, err := export.ParseAmount(values[0])
+		if err != nil {
+			return priceChangeMsg{err: fmt.Errorf("prezzo non valido")}
+		}
+		cost, err := export.ParseAmount(values[1])
+		if err != nil {
+			return priceChangeMsg{err: fmt.Errorf("costo non valido")}
+		}
+		effectiveFrom, err := export.ParseDate(values[2])
+		if err != nil {
+			return priceChangeMsg{err: fmt.Errorf("data non valida")}
+		}
+		// una data odierna vale da subito, non dalla mezzanotte già passata
+		if !effectiveFrom.After(time.Now()) {
+			effectiveFrom = time.Now()
+		}
+
+		change, err := m.priceUC.ChangePrice(context.Background(), articleID, listPrice, cost,
+			effectiveFrom, strings.TrimSpace(values[3]), m.operator)
+		if err != nil {
+			return priceChangeMsg{err: err}
+		}
+		if change.Status == domain.PriceChangeScheduled {
+			return priceChangeMsg{message: "Variazione programmata dal " + change.EffectiveFrom.Format("02/01/2006")}
+		}
+		return priceChangeMsg{message: "Prezzo aggiornato"}
+	}
+}
+
+func (m *AppModel) loadPriceHistory(articleID primitive.ObjectID) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		article, err := m.articleRepo.FindByID(ctx, articleID)
+		if err != nil {
+			return priceHistoryMsg{err: err}
+		}
+		history, err := m.priceUC.GetPriceHistory(ctx, articleID, 20)
+		return priceHistoryMsg{article: article, history: history, err: err}
+	}
+}
+
+func (m *AppModel) handlePriceHistory(msg priceHistoryMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		m.setError("Errore caricamento prezzi: " + msg.err.Error())
+		return m, nil
+	}
+
+	m.searchView.article = msg.article
+	m.searchView.history = msg.history
+	if m.searchView.historyIndex >= len(msg.history) {
+		m.searchView.historyIndex = 0
+	}
+	return m, nil
+}
+
+func (m *AppModel) handlePriceChange(msg priceChangeMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		if errors.Is(msg.err, domain.ErrInsufficientPermissions) {
+			m.setError("Non hai i permessi per modificare i prezzi")
+		} else {
+			m.setError("Operazione non riuscita: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	m.setMessage(msg.message)
+	if m.searchView.article == nil {
+		return m, nil
+	}
+	return m, m.loadPriceHistory(m.searchView.article.ID)
+}
@@ -0,0 +1,466 @@
+// internal/ui/view_budgets.go
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/usecase"
+)
+
+// filtri per tipo di budget, nell'ordine in cui si scorrono con tab
+var budgetTypeFilters = []domain.BudgetType{
+	"",
+	domain.BudgetTypeGlobal,
+	domain.BudgetTypeOperator,
+	domain.BudgetTypeCustomer,
+	domain.BudgetTypeSupplier,
+}
+
+func budgetTypeLabel(t domain.BudgetType) string {
+	switch t {
+	case domain.BudgetTypeGlobal:
+		return "Globale"
+	case domain.BudgetTypeOperator:
+		return "Operatore"
+	case domain.BudgetTypeCustomer:
+		return "Cliente"
+	case domain.BudgetTypeSupplier:
+		return "Fornitore"
+	default:
+		return "Tutti"
+	}
+}
+
+func budgetPeriodLabel(b *domain.Budget) string {
+	if b.Quarter == 0 {
+		return fmt.Sprintf("%d", b.Year)
+	}
+	return fmt.Sprintf("Q%d %d", b.Quarter, b.Year)
+}
+
+func (m *AppModel) viewBudgets() string {
+	bv := m.budgetsView
+	title := TitleStyle.Render("📊 Budget")
+	subtitle := SubtitleStyle.Render(fmt.Sprintf("Anno %d • %s", bv.year, budgetTypeLabel(budgetTypeFilters[bv.filterIndex])))
+
+	entries := bv.filtered()
+
+	var list string
+	if bv.loading && len(bv.entries) == 0 {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(entries) == 0 {
+		list = InfoStyle.Render("Nessun budget nel periodo")
+	} else {
+		items := []string{
+			TableHeaderStyle.Render(fmt.Sprintf("  %-9s %-28s %-8s %-22s %-22s %s",
+				"Tipo", "Intestatario", "Periodo", "Tempo", "Fatturato", "Stato")),
+		}
+		for i, entry := range entries {
+			b := entry.Budget
+			status := RenderStatusBadge("ok")
+			if !b.IsOnTrack() {
+				status = RenderStatusBadge("warning")
+			}
+			if b.IsFuture() {
+				status = RenderStatusBadge("future")
+			}
+
+			itemText := fmt.Sprintf("%-9s %-28s %-8s %s %3.0f%% %s %3.0f%% %s",
+				budgetTypeLabel(b.Type),
+				truncateString(entry.EntityName, 28),
+				budgetPeriodLabel(b),
+				RenderProgressBar(b.GetTimeProgress(), 16),
+				b.GetTimeProgress(),
+				RenderProgressBar(b.GetRevenueAchievement(), 16),
+				b.GetRevenueAchievement(),
+				status,
+			)
+			if i == bv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{title, subtitle, ContentStyle.Render(list)}
+
+	if entry := bv.selected(); entry != nil {
+		if bv.documents != nil {
+			sections = append(sections, CardStyle.Render(renderBudgetDocuments(entry, bv.documents, bv.documentIndex)))
+		} else {
+			sections = append(sections, CardStyle.Render(renderBudgetDetail(entry)))
+		}
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func renderBudgetDetail(entry *usecase.BudgetEntry) string {
+	b := entry.Budget
+
+	track := SuccessStyle.Render("✓ In linea con l'obiettivo")
+	if !b.IsOnTrack() {
+		track = ErrorStyle.Render("⚠ In ritardo rispetto al tempo trascorso")
+	}
+
+	lines := []string{
+		SubtitleStyle.Render(fmt.Sprintf("%s %s - %s (%s → %s)",
+			budgetTypeLabel(b.Type), entry.EntityName, budgetPeriodLabel(b),
+			b.StartDate.Format("02/01/2006"), b.EndDate.Format("02/01/2006"))),
+		track,
+		"",
+		fmt.Sprintf("%-10s %s %5.1f%%  %d/%d giorni, %d rimanenti",
+			"Tempo", RenderProgressBar(b.GetTimeProgress(), 30), b.GetTimeProgress(),
+			b.GetElapsedDays(), b.GetTotalDays(), b.GetDaysRemaining()),
+		fmt.Sprintf("%-10s %s %5.1f%%  € %.2f / € %.2f, mancano € %.2f",
+			"Fatturato", RenderProgressBar(b.GetRevenueAchievement(), 30), b.GetRevenueAchievement(),
+			b.ActualRevenue, b.TargetRevenue, b.GetRemainingRevenue()),
+		fmt.Sprintf("%-10s %s %5.1f%%  € %.2f / € %.2f, mancano € %.2f",
+			"Margine", RenderProgressBar(b.GetMarginAchievement(), 30), b.GetMarginAchievement(),
+			b.ActualMargin, b.TargetMargin, b.GetRemainingMargin()),
+		fmt.Sprintf("%-10s %s %5.1f%%  %d / %d, mancano %d",
+			"Ordini", RenderProgressBar(b.GetOrdersAchievement(), 30), b.GetOrdersAchievement(),
+			b.ActualOrders, b.TargetOrders, b.GetRemainingOrders()),
+		"",
+	}
+
+	evaluation := b.EvaluateIncentive()
+
+	for _, kpi := range evaluation.KPIs {
+		if kpi.KPI != domain.BudgetKPIFamily {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-10s %s %5.1f%%  € %.2f / € %.2f",
+			truncateString(kpi.Family, 10), RenderProgressBar(kpi.Achievement, 30), kpi.Achievement, kpi.Actual, kpi.Target))
+	}
+
+	weights := ""
+	for _, kpi := range evaluation.KPIs {
+		if kpi.Weight == 0 {
+			continue
+		}
+		label := string(kpi.KPI)
+		if kpi.KPI == domain.BudgetKPIFamily {
+			label = kpi.Family
+		}
+		weights += fmt.Sprintf(" %s %.0f%%", label, kpi.Weight*100)
+	}
+	lines = append(lines, fmt.Sprintf("Raggiungimento ponderato: %.1f%% (pesi:%s)", evaluation.WeightedAchievement, weights))
+
+	for _, gate := range evaluation.Gates {
+		if gate.Passed {
+			lines = append(lines, SuccessStyle.Render(fmt.Sprintf("✓ Soglia %s: %.1f%%", gate.Gate.Label(), gate.Achievement)))
+		} else {
+			lines = append(lines, ErrorStyle.Render(fmt.Sprintf("✗ Soglia %s: %.1f%%", gate.Gate.Label(), gate.Achievement)))
+		}
+	}
+
+	switch {
+	case evaluation.Tier != nil && evaluation.GatesPassed:
+		lines = append(lines, fmt.Sprintf("Incentivo maturato: € %.2f (%s)", evaluation.Amount, evaluation.Tier.Description))
+	case evaluation.Tier != nil:
+		lines = append(lines, fmt.Sprintf("Incentivo maturato: € 0.00 (%s, soglie non superate)", evaluation.Tier.Description))
+	case len(b.Incentives) > 0:
+		lines = append(lines, "Incentivo maturato: € 0.00 (nessuno scaglione raggiunto)")
+	default:
+		lines = append(lines, "Nessun incentivo previsto")
+	}
+
+	if entry.Forecast != nil {
+		lines = append(lines, "", renderBudgetForecast(b, entry.Forecast))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func forecastMethodLabel(method domain.ForecastMethod) string {
+	switch method {
+	case domain.ForecastMethodSeasonal:
+		return "stagionalità anno precedente"
+	case domain.ForecastMethodRunRate:
+		return "run-rate giornaliero"
+	case domain.ForecastMethodFinal:
+		return "periodo chiuso"
+	default:
+		return "non disponibile"
+	}
+}
+
+func renderBudgetForecast(b *domain.Budget, f *domain.BudgetForecast) string {
+	method := forecastMethodLabel(f.Method)
+	if f.Method == domain.ForecastMethodSeasonal {
+		method = fmt.Sprintf("%s, %.0f%% realizzato a questa data", method, f.SeasonalShare*100)
+	}
+
+	lines := []string{
+		TableHeaderStyle.Render("Proiezione a fine periodo (" + method + ")"),
+	}
+
+	if f.Method == domain.ForecastMethodNone {
+		lines = append(lines, InfoStyle.Render("Periodo non ancora iniziato"))
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	projected := fmt.Sprintf("Fatturato € %.2f (%.1f%% dell'obiettivo) • margine € %.2f • ordini %d",
+		f.ProjectedRevenue, f.ProjectedAchievement, f.ProjectedMargin, f.ProjectedOrders)
+	if f.ProjectedAchievement >= 100 {
+		lines = append(lines, SuccessStyle.Render(projected))
+	} else {
+		lines = append(lines, WarningStyle.Render(projected))
+	}
+	lines = append(lines, fmt.Sprintf("Run-rate: € %.2f al giorno", f.RunRatePerDay))
+
+	if f.ExpectedIncentive != nil {
+		lines = append(lines, fmt.Sprintf("Scaglione atteso: %s, incentivo € %.2f",
+			f.ExpectedIncentive.Description, f.ExpectedIncentiveAmount))
+	} else if len(b.Incentives) > 0 {
+		lines = append(lines, "Scaglione atteso: nessuno")
+	}
+	if f.NextIncentive != nil && f.NextIncentive.Basis == domain.IncentiveBasisWeighted {
+		lines = append(lines, fmt.Sprintf("Prossimo scaglione: %s da %.1f%% ponderato (proiezione %.1f%%), mancano %.1f punti",
+			f.NextIncentive.Description, f.NextIncentive.MinRevenue, f.ProjectedWeightedAchievement, f.GapToNextTier))
+	} else if f.NextIncentive != nil {
+		lines = append(lines, fmt.Sprintf("Prossimo scaglione: %s da € %.2f, mancano € %.2f alla proiezione",
+			f.NextIncentive.Description, f.NextIncentive.MinRevenue, f.GapToNextTier))
+	}
+
+	if f.RemainingDays > 0 {
+		needed := fmt.Sprintf("Necessari € %.2f al giorno per l'obiettivo", f.NeededPerDay)
+		if f.NextIncentive != nil {
+			needed += fmt.Sprintf(", € %.2f al giorno per il prossimo scaglione", f.NeededPerDayNextTier)
+		}
+		lines = append(lines, SubtitleStyle.Render(needed))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func renderBudgetDocuments(entry *usecase.BudgetEntry, documents []*domain.Document, selectedIndex int) string {
+	lines := []string{
+		SubtitleStyle.Render(fmt.Sprintf("Documenti di %s - %s", entry.EntityName, budgetPeriodLabel(entry.Budget))),
+	}
+
+	if len(documents) == 0 {
+		lines = append(lines, InfoStyle.Render("Nessun documento registrato nel periodo"))
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+	}
+
+	lines = append(lines, TableHeaderStyle.Render(fmt.Sprintf("  %-10s %-16s %-14s %-12s %12s %12s",
+		"Data", "Numero", "Tipo", "Intestatario", "Fatturato", "Margine")))
+
+	var revenue, margin float64
+	for i, document := range documents {
+		contribution := domain.DocumentBudgetContribution(document)
+		revenue += contribution.Revenue
+		margin += contribution.Margin
+
+		holder := document.CustomerCode
+		if document.IsPurchase() {
+			holder = document.SupplierCode
+		}
+
+		itemText := fmt.Sprintf("%-10s %-16s %-14s %-12s %12.2f %12.2f",
+			document.Date.Format("02/01/2006"),
+			document.Number,
+			document.Type,
+			truncateString(holder, 12),
+			contribution.Revenue,
+			contribution.Margin,
+		)
+		if i == selectedIndex {
+			lines = append(lines, SelectedItemStyle.Render("  "+itemText))
+		} else {
+			lines = append(lines, TableCellStyle.Render("  "+itemText))
+		}
+	}
+
+	lines = append(lines, "", fmt.Sprintf("%d documenti • fatturato € %.2f • margine € %.2f", len(documents), revenue, margin))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (bv *BudgetsView) filtered() []usecase.BudgetEntry {
+	filter := budgetTypeFilters[bv.filterIndex]
+	if filter == "" {
+		return bv.entries
+	}
+
+	var entries []usecase.BudgetEntry
+	for _, entry := range bv.entries {
+		if entry.Budget.Type == filter {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (bv *BudgetsView) selected() *usecase.BudgetEntry {
+	entries := bv.filtered()
+	if bv.selectedIndex < 0 || bv.selectedIndex >= len(entries) {
+		return nil
+	}
+	return &entries[bv.selectedIndex]
+}
+
+func (m *AppModel) updateBudgets(msg tea.Msg) (tea.Model, tea.Cmd) {
+	bv := m.budgetsView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	// dettaglio documenti aperto: esc torna all'elenco
+	if bv.documents != nil {
+		switch keyMsg.String() {
+		case "esc", "q", "enter":
+			bv.documents = nil
+		case "up", "k":
+			if bv.documentIndex > 0 {
+				bv.documentIndex--
+			}
+		case "down", "j":
+			if bv.documentIndex < len(bv.documents)-1 {
+				bv.documentIndex++
+			}
+		}
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if bv.selectedIndex > 0 {
+			bv.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if bv.selectedIndex < len(bv.filtered())-1 {
+			bv.selectedIndex++
+		}
+		return m, nil
+
+	case "tab":
+		bv.filterIndex = (bv.filterIndex + 1) % len(budgetTypeFilters)
+		bv.selectedIndex = 0
+		return m, nil
+
+	case "left", "h":
+		bv.year--
+		bv.loading = true
+		return m, m.loadBudgets()
+
+	case "right", "l":
+		bv.year++
+		bv.loading = true
+		return m, m.loadBudgets()
+
+	case "r":
+		bv.loading = true
+		return m, m.loadBudgets()
+
+	case "e":
+		entry := bv.selected()
+		if entry == nil {
+			return m, nil
+		}
+		if entry.Budget.Type != domain.BudgetTypeOperator {
+			m.setError("Il prospetto incentivi è disponibile solo per i budget operatore")
+			return m, nil
+		}
+		budget := entry.Budget
+		return m, func() tea.Msg {
+			path, err := m.budgetUC.ExportIncentiveStatement(context.Background(), m.operator, budget, exportDir)
+			return exportDoneMsg{path: path, err: err}
+		}
+
+	case "E":
+		entry := bv.selected()
+		if entry == nil {
+			return m, nil
+		}
+		year, quarter := entry.Budget.Year, entry.Budget.Quarter
+		return m, func() tea.Msg {
+			paths, err := m.budgetUC.ExportIncentiveStatements(context.Background(), m.operator, year, quarter, exportDir)
+			if err == nil && len(paths) == 0 {
+				err = fmt.Errorf("nessun budget operatore nel periodo")
+			}
+			return exportDoneMsg{path: exportDir, err: err}
+		}
+
+	case "enter":
+		entry := bv.selected()
+		if entry == nil || bv.loading {
+			return m, nil
+		}
+		bv.loading = true
+		budget := entry.Budget
+		return m, func() tea.Msg {
+			documents, err := m.budgetUC.GetBudgetDocuments(context.Background(), budget)
+			return budgetDocumentsMsg{documents: documents, err: err}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) loadBudgets() tea.Cmd {
+	year := m.budgetsView.year
+	return func() tea.Msg {
+		entries, err := m.budgetUC.GetBudgets(context.Background(), m.operator, year)
+		return budgetsLoadedMsg{entries: entries, err: err}
+	}
+}
+
+func (m *AppModel) handleBudgetsLoaded(msg budgetsLoadedMsg) (*AppModel, tea.Cmd) {
+	m.budgetsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento budget: " + msg.err.Error())
+		m.budgetsView.entries = []usecase.BudgetEntry{}
+		return m, nil
+	}
+
+	m.budgetsView.entries = msg.entries
+	m.budgetsView.selectedIndex = 0
+	return m, nil
+}
+
+func (m *AppModel) handleBudgetDocuments(msg budgetDocumentsMsg) (*AppModel, tea.Cmd) {
+	m.budgetsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento documenti: " + msg.err.Error())
+		return m, nil
+	}
+
+	if msg.documents == nil {
+		msg.documents = []*domain.Document{}
+	}
+	m.budgetsView.documents = msg.documents
+	m.budgetsView.documentIndex = 0
+	return m, nil
+}
+
+func newBudgetsView() *BudgetsView {
+	return &BudgetsView{year: time.Now().Year(), loading: true}
+}
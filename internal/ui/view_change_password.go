@@ -0,0 +1,202 @@
+// internal/ui/view_change_password.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+)
+
+// passwordRequirements descrive la policy configurata, così l'operatore sa cosa serve prima di sbagliare
+func passwordRequirements(policy domain.PasswordPolicy) string {
+	parts := []string{fmt.Sprintf("almeno %d caratteri", policy.MinLength)}
+	if policy.RequireUppercase {
+		parts = append(parts, "una maiuscola")
+	}
+	if policy.RequireLowercase {
+		parts = append(parts, "una minuscola")
+	}
+	if policy.RequireDigit {
+		parts = append(parts, "una cifra")
+	}
+	if policy.RequireSpecial {
+		parts = append(parts, "un carattere speciale")
+	}
+
+	text := "Requisiti: " + strings.Join(parts, ", ")
+	if policy.HistorySize > 0 {
+		text += fmt.Sprintf("; diversa dalle ultime %d", policy.HistorySize)
+	}
+	return text
+}
+
+func (f *PasswordForm) fields() []*string {
+	if f.askCurrent {
+		return []*string{&f.current, &f.next, &f.confirm}
+	}
+	return []*string{&f.next, &f.confirm}
+}
+
+func (m *AppModel) renderPasswordForm(f *PasswordForm) string {
+	labels := []string{"Nuova password:", "Conferma password:"}
+	if f.askCurrent {
+		labels = append([]string{"Password attuale:"}, labels...)
+	}
+
+	lines := []string{InfoStyle.Render(passwordRequirements(m.loginUC.PasswordPolicy())), ""}
+	for i, field := range f.fields() {
+		masked := strings.Repeat("*", len([]rune(*field)))
+		if i == f.focusIndex {
+			masked = InputFocusedStyle.Render(masked + "█")
+		} else {
+			masked = InputStyle.Render(masked)
+		}
+		lines = append(lines, labels[i], masked, "")
+	}
+	lines = append(lines, ButtonStyle.Render("[ Cambia password ]"))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// editPasswordForm gestisce navigazione e digitazione; true quando il modulo è pronto per l'invio
+func editPasswordForm(f *PasswordForm, msg tea.KeyMsg) bool {
+	fields := f.fields()
+
+	switch msg.String() {
+	case "tab", "down":
+		f.focusIndex = (f.focusIndex + 1) % len(fields)
+
+	case "shift+tab", "up":
+		f.focusIndex = (f.focusIndex + len(fields) - 1) % len(fields)
+
+	case "enter":
+		if f.focusIndex < len(fields)-1 {
+			f.focusIndex++
+			return false
+		}
+		return true
+
+	case "backspace":
+		field := fields[f.focusIndex]
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+		}
+
+	default:
+		if msg.Type == tea.KeyRunes {
+			*fields[f.focusIndex] += string(msg.Runes)
+		}
+	}
+
+	return false
+}
+
+// checkPasswordForm verifica in locale la conferma, prima di chiamare il caso d'uso
+func checkPasswordForm(f *PasswordForm) error {
+	if f.next == "" {
+		return errors.New("inserisci la nuova password")
+	}
+	if f.next != f.confirm {
+		return errors.New("le password non coincidono")
+	}
+	return nil
+}
+
+func (m *AppModel) updateLoginPasswordChange(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	lv := m.loginView
+
+	if msg.String() == "esc" {
+		m.loginView = &LoginView{username: lv.username, focusIndex: 1}
+		return m, nil
+	}
+
+	if !editPasswordForm(lv.passwordForm, msg) {
+		return m, nil
+	}
+
+	if err := checkPasswordForm(lv.passwordForm); err != nil {
+		lv.error = err.Error()
+		lv.passwordForm = &PasswordForm{}
+		return m, nil
+	}
+
+	operator, current, next := lv.pending.Operator, lv.currentPassword, lv.passwordForm.next
+	return m, func() tea.Msg {
+		ctx := context.Background()
+		if err := m.loginUC.ChangePassword(ctx, operator, current, next); err != nil {
+			return loginResultMsg{err: loginError(lv.pending, err)}
+		}
+		return m.completeLogin(ctx, operator)
+	}
+}
+
+func (m *AppModel) viewChangePassword() string {
+	sections := []string{TitleStyle.Render("🔑 Cambio Password"), ""}
+
+	policy := m.loginUC.PasswordPolicy()
+	if policy.MaxAge > 0 {
+		expires := m.operator.LastPasswordChange.Add(policy.MaxAge)
+		sections = append(sections, SubtitleStyle.Render("La password attuale scade il "+expires.Format("02/01/2006")), "")
+	}
+
+	sections = append(sections, m.renderPasswordForm(m.passwordForm))
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+func (m *AppModel) updateChangePassword(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if keyMsg.String() == "esc" {
+		m.currentView = ViewMainMenu
+		return m, nil
+	}
+
+	form := m.passwordForm
+	if !editPasswordForm(form, keyMsg) {
+		return m, nil
+	}
+
+	if err := checkPasswordForm(form); err != nil {
+		m.setError(err.Error())
+		m.passwordForm = &PasswordForm{askCurrent: true}
+		return m, nil
+	}
+
+	operator := m.operator
+	return m, func() tea.Msg {
+		err := m.loginUC.ChangePassword(context.Background(), operator, form.current, form.next)
+		return passwordChangedMsg{err: err}
+	}
+}
+
+func (m *AppModel) handlePasswordChanged(msg passwordChangedMsg) (*AppModel, tea.Cmd) {
+	m.passwordForm = &PasswordForm{askCurrent: true}
+
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, domain.ErrInvalidCredentials):
+			m.setError("Password attuale errata")
+		case errors.Is(msg.err, domain.ErrInvalidPassword):
+			m.setError("La nuova password non rispetta i requisiti")
+		case errors.Is(msg.err, domain.ErrPasswordReused):
+			m.setError("La nuova password è stata usata di recente")
+		default:
+			m.setError("Cambio password non riuscito: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	m.currentView = ViewMainMenu
+	m.setMessage("Password cambiata")
+	return m, nil
+}
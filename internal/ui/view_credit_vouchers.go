@@ -0,0 +1,286 @@
+// internal/ui/view_credit_vouchers.go
+
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/barcode"
+)
+
+const (
+	vouchersInputScan     = "scan"
+	vouchersInputCustomer = "customer"
+)
+
+func newCreditVouchersView() *CreditVouchersView {
+	// all'apertura la vista è pronta a ricevere la lettura del lettore
+	return &CreditVouchersView{
+		scanner: barcode.NewBarcodeScanner(),
+		input:   vouchersInputScan,
+	}
+}
+
+func voucherStatusBadge(v *domain.CreditVoucher) string {
+	switch {
+	case v.Status == domain.VoucherStatusCancelled:
+		return BadgeDangerStyle.Render("annullato")
+	case v.Status == domain.VoucherStatusUsed:
+		return BadgeStyle.Render("esaurito")
+	case v.Status == domain.VoucherStatusExpired || v.IsExpired():
+		return BadgeDangerStyle.Render("scaduto")
+	case v.Status == domain.VoucherStatusPartiallyUsed:
+		return BadgeWarningStyle.Render("parziale")
+	default:
+		return BadgeSuccessStyle.Render("valido")
+	}
+}
+
+func (m *AppModel) viewCreditVouchers() string {
+	cvv := m.creditVouchersView
+
+	subtitle := "Leggi un buono con il lettore o cerca per cliente"
+	if cvv.customer != nil {
+		subtitle = fmt.Sprintf("Buoni del cliente %s - %s", cvv.customer.Code, cvv.customer.CompanyName)
+	}
+
+	var list string
+	if cvv.loading {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(cvv.vouchers) == 0 {
+		list = InfoStyle.Render("Nessun buono")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-16s %-10s %-10s %10s %10s  %s",
+			"Codice", "Emesso", "Scadenza", "Importo", "Residuo", "Stato"))
+		items := []string{header}
+		for i, voucher := range cvv.vouchers {
+			expiry := "-"
+			if !voucher.ExpiryDate.IsZero() {
+				expiry = voucher.ExpiryDate.Format("02/01/2006")
+			}
+			itemText := fmt.Sprintf("%-16s %-10s %-10s %10.2f %10.2f  %s",
+				voucher.Code,
+				voucher.IssuedDate.Format("02/01/2006"),
+				expiry,
+				voucher.OriginalAmount,
+				voucher.RemainingAmount,
+				voucherStatusBadge(voucher),
+			)
+			if i == cvv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("💰 Buoni Credito"),
+		SubtitleStyle.Render(subtitle),
+	}
+
+	switch cvv.input {
+	case vouchersInputScan:
+		sections = append(sections, CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+			"Codice buono (lettore o tastiera):",
+			InputFocusedStyle.Render(cvv.scanner.GetBuffer()+"█"))))
+	case vouchersInputCustomer:
+		sections = append(sections, CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+			"Codice cliente:",
+			InputFocusedStyle.Render(cvv.scanner.GetBuffer()+"█"))))
+	}
+
+	sections = append(sections, ContentStyle.Render(list))
+
+	if voucher := cvv.selected(); voucher != nil && len(voucher.UsageHistory) > 0 {
+		lines := []string{SubtitleStyle.Render("Movimenti")}
+		for _, usage := range voucher.UsageHistory {
+			lines = append(lines, fmt.Sprintf("%s  %-8s %10.2f  %-12s %s",
+				usage.UsedAt.Format("02/01/2006"), usage.DocumentType, usage.Amount, usage.UsedBy, usage.Notes))
+		}
+		sections = append(sections, CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func (m *AppModel) updateCreditVouchers(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cvv := m.creditVouchersView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if cvv.input != "" {
+		return m.updateVoucherInput(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if cvv.selectedIndex > 0 {
+			cvv.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if cvv.selectedIndex < len(cvv.vouchers)-1 {
+			cvv.selectedIndex++
+		}
+		return m, nil
+
+	case "s":
+		cvv.input = vouchersInputScan
+		cvv.scanner.Reset()
+		return m, nil
+
+	case "c":
+		cvv.input = vouchersInputCustomer
+		cvv.scanner.Reset()
+		return m, nil
+
+	case "r":
+		m.voucherReportView = newVoucherReportView()
+		m.voucherReportView.loading = true
+		return m.navigateTo(ViewVoucherReport), m.loadVoucherReport()
+
+	case "p", "P":
+		voucher := cvv.selected()
+		if voucher == nil {
+			return m, nil
+		}
+		format := barcode.FormatCode128
+		if keyMsg.String() == "P" {
+			format = barcode.FormatQR
+		}
+		return m, func() tea.Msg {
+			path, err := m.voucherUC.PrintVoucher(context.Background(), voucher, format, exportDir)
+			return exportDoneMsg{path: path, err: err}
+		}
+	}
+
+	return m, nil
+}
+
+// updateVoucherInput passa i tasti al BarcodeScanner: il lettore invia i caratteri seguiti da invio
+// come una tastiera, quindi lettura e digitazione manuale seguono lo stesso percorso
+func (m *AppModel) updateVoucherInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	cvv := m.creditVouchersView
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		cvv.input = ""
+		cvv.scanner.Reset()
+		return m, nil
+
+	case tea.KeyBackspace:
+		buffer := []rune(cvv.scanner.GetBuffer())
+		cvv.scanner.Reset()
+		if len(buffer) > 0 {
+			for _, r := range buffer[:len(buffer)-1] {
+				cvv.scanner.ProcessInput(r)
+			}
+		}
+		return m, nil
+
+	case tea.KeyEnter:
+		value, done := cvv.scanner.ProcessInput('\n')
+		if !done {
+			return m, nil
+		}
+		input := cvv.input
+		cvv.input = ""
+		cvv.loading = true
+		if input == vouchersInputCustomer {
+			return m, m.loadCustomerVouchers(value)
+		}
+		return m, func() tea.Msg {
+			voucher, err := m.voucherUC.ScanVoucher(context.Background(), value)
+			return voucherScannedMsg{voucher: voucher, err: err}
+		}
+
+	case tea.KeyRunes, tea.KeySpace:
+		for _, r := range msg.Runes {
+			cvv.scanner.ProcessInput(r)
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) loadCustomerVouchers(customerCode string) tea.Cmd {
+	return func() tea.Msg {
+		customer, vouchers, err := m.voucherUC.GetCustomerVouchers(context.Background(), customerCode)
+		return vouchersLoadedMsg{customer: customer, vouchers: vouchers, err: err}
+	}
+}
+
+func (m *AppModel) handleVouchersLoaded(msg vouchersLoadedMsg) (*AppModel, tea.Cmd) {
+	cvv := m.creditVouchersView
+	cvv.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento buoni: " + msg.err.Error())
+		return m, nil
+	}
+
+	cvv.customer = msg.customer
+	cvv.vouchers = msg.vouchers
+	cvv.selectedIndex = 0
+	return m, nil
+}
+
+func (m *AppModel) handleVoucherScanned(msg voucherScannedMsg) (*AppModel, tea.Cmd) {
+	cvv := m.creditVouchersView
+	cvv.loading = false
+
+	if msg.err != nil {
+		m.setError("Buono non riconosciuto: " + msg.err.Error())
+		return m, nil
+	}
+
+	// il buono letto va in testa all'elenco, senza duplicarlo se era già mostrato
+	vouchers := []*domain.CreditVoucher{msg.voucher}
+	for _, voucher := range cvv.vouchers {
+		if voucher.ID != msg.voucher.ID {
+			vouchers = append(vouchers, voucher)
+		}
+	}
+	if cvv.customer != nil && cvv.customer.ID != msg.voucher.CustomerID {
+		cvv.customer = nil
+		vouchers = vouchers[:1]
+	}
+	cvv.vouchers = vouchers
+	cvv.selectedIndex = 0
+
+	if msg.voucher.IsValid() {
+		m.setMessage(fmt.Sprintf("Buono %s: residuo € %.2f", msg.voucher.Code, msg.voucher.RemainingAmount))
+	} else {
+		m.setError(fmt.Sprintf("Buono %s non utilizzabile (%s)", msg.voucher.Code, msg.voucher.Status))
+	}
+	return m, nil
+}
+
+func (cvv *CreditVouchersView) selected() *domain.CreditVoucher {
+	if cvv.selectedIndex < 0 || cvv.selectedIndex >= len(cvv.vouchers) {
+		return nil
+	}
+	return cvv.vouchers[cvv.selectedIndex]
+}
@@ -62,6 +62,10 @@ func (m *AppModel) viewMainMenu() string {
 		menuBox,
 	)
 
+	if reminders := m.renderNetPriceReminders(); reminders != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, "", reminders)
+	}
+
 	availableHeight := m.height - 6
 
 	return lipgloss.Place(
@@ -77,7 +81,7 @@ func (m *AppModel) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "1", "2", "3", "4", "5", "6", "7":
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 			num := int(msg.String()[0] - '0')
 
 			enabledIndex := 0
@@ -90,15 +94,7 @@ func (m *AppModel) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.mainMenuView.selectedIndex = i
 					m.clearMessages()
 
-					switch item.View {
-					case ViewArticleSearch:
-						m.searchView = &ArticleSearchView{
-							searchType: "code",
-							results:    []*domain.Article{},
-						}
-					}
-
-					return m.navigateTo(item.View), nil
+					return m.openView(item.View)
 				}
 			}
 			return m, nil
@@ -134,18 +130,28 @@ func (m *AppModel) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if selectedItem.Enabled {
 				m.clearMessages()
 
-				switch selectedItem.View {
-				case ViewArticleSearch:
-					m.searchView = &ArticleSearchView{
-						searchType: "code",
-						results:    []*domain.Article{},
-					}
-				}
-
-				return m.navigateTo(selectedItem.View), nil
+				return m.openView(selectedItem.View)
 			}
 			return m, nil
 
+		case "p":
+			m.clearMessages()
+			return m.openView(ViewNetPrices)
+
+		case "t":
+			m.clearMessages()
+			return m.openView(ViewTwoFactor)
+
+		case "c":
+			m.clearMessages()
+			return m.openView(ViewChangePassword)
+
+		case "x":
+			return m, m.logout(false)
+
+		case "X":
+			return m, m.logout(true)
+
 		case "q":
 			return m, tea.Quit
 		}
@@ -153,3 +159,47 @@ func (m *AppModel) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, nil
 }
+
+func (m *AppModel) openView(view ViewState) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch view {
+	case ViewArticleSearch:
+		m.searchView = &ArticleSearchView{
+			searchType: "code",
+			results:    []*domain.Article{},
+		}
+	case ViewPromotions:
+		m.promotionsView = &PromotionsView{loading: true}
+		cmd = m.loadPromotions()
+	case ViewApprovals:
+		m.approvalsView = &ApprovalsView{loading: true}
+		cmd = m.loadApprovals()
+	case ViewNetPrices:
+		m.netPricesView = &NetPricesView{
+			mode:    netPricesModeExpiring,
+			days:    m.netPriceUC.ReminderDays(),
+			loading: true,
+		}
+		cmd = m.loadNetPrices()
+	case ViewPriceLists:
+		m.priceListsView = &PriceListsView{loading: true}
+		cmd = m.loadPriceLists()
+	case ViewOperators:
+		m.operatorsView = &OperatorsView{loading: true}
+		cmd = m.loadOperators()
+	case ViewBudgets:
+		m.budgetsView = newBudgetsView()
+		cmd = m.loadBudgets()
+	case ViewCreditVouchers:
+		m.creditVouchersView = newCreditVouchersView()
+	case ViewTwoFactor:
+		m.twoFactorView = &TwoFactorView{}
+	case ViewChangePassword:
+		m.passwordForm = &PasswordForm{askCurrent: true}
+	case ViewSalesDocument:
+		m.salesDocumentView = newSalesDocumentView(m.operator, nil)
+	}
+
+	return m.navigateTo(view), cmd
+}
@@ -0,0 +1,438 @@
+// internal/ui/view_net_prices.go
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/usecase"
+	"ricambi-manager/pkg/export"
+)
+
+const (
+	netPricesModeExpiring = "expiring"
+	netPricesModeCustomer = "customer"
+	netPricesModeArticle  = "article"
+)
+
+func (m *AppModel) viewNetPrices() string {
+	npv := m.netPricesView
+
+	var subtitle string
+	switch npv.mode {
+	case netPricesModeCustomer:
+		subtitle = "Prezzi netti del cliente " + npv.filter
+	case netPricesModeArticle:
+		subtitle = "Prezzi netti dell'articolo " + npv.filter
+	default:
+		subtitle = fmt.Sprintf("In scadenza nei prossimi %d giorni", npv.days)
+	}
+
+	var list string
+	if npv.loading {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(npv.entries) == 0 {
+		list = InfoStyle.Render("Nessun prezzo netto")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-15s %-12s %-22s %9s %9s %7s  %-10s %-10s",
+			"Articolo", "Cliente", "Ragione sociale", "Listino", "Netto", "Sconto", "Dal", "Scadenza"))
+		items := []string{header}
+		for i, entry := range npv.entries {
+			expiry := "-"
+			if !entry.NetPrice.ValidTo.IsZero() {
+				expiry = entry.NetPrice.ValidTo.Format("02/01/2006")
+			}
+			itemText := fmt.Sprintf("%-15s %-12s %-22s %9.2f %9.2f %6.1f%%  %-10s %-10s",
+				truncateString(entry.ArticleCode, 15),
+				truncateString(entry.CustomerCode, 12),
+				truncateString(entry.CustomerName, 22),
+				entry.ListPrice,
+				entry.NetPrice.Price,
+				entry.DiscountPercent(),
+				entry.NetPrice.ValidFrom.Format("02/01/2006"),
+				expiry,
+			)
+			switch {
+			case entry.DaysLeft >= 0 && entry.DaysLeft <= 7:
+				itemText += " " + BadgeDangerStyle.Render(fmt.Sprintf("%dgg", entry.DaysLeft))
+			case entry.DaysLeft >= 0 && entry.DaysLeft <= npv.days:
+				itemText += " " + BadgeWarningStyle.Render(fmt.Sprintf("%dgg", entry.DaysLeft))
+			}
+			if i == npv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("🏷️  Distinta Prezzi Netti"),
+		SubtitleStyle.Render(subtitle),
+		ContentStyle.Render(list),
+	}
+
+	if npv.form != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(npv.form)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func renderNetPriceForm(form *NetPriceForm) string {
+	lines := []string{SubtitleStyle.Render(form.title)}
+	for i, label := range form.labels {
+		lines = append(lines, label+":")
+		if i == form.focusIndex {
+			lines = append(lines, InputFocusedStyle.Render(form.values[i]+"█"))
+		} else {
+			lines = append(lines, InputStyle.Render(form.values[i]))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func newNetPriceForm(action, title string, labels ...string) *NetPriceForm {
+	return &NetPriceForm{
+		action: action,
+		title:  title,
+		labels: labels,
+		values: make([]string, len(labels)),
+	}
+}
+
+func (m *AppModel) updateNetPrices(msg tea.Msg) (tea.Model, tea.Cmd) {
+	npv := m.netPricesView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if npv.form != nil {
+		return m.updateNetPriceForm(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if npv.selectedIndex > 0 {
+			npv.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if npv.selectedIndex < len(npv.entries)-1 {
+			npv.selectedIndex++
+		}
+		return m, nil
+
+	case "+", "-":
+		if npv.mode != netPricesModeExpiring {
+			return m, nil
+		}
+		if keyMsg.String() == "+" {
+			npv.days += 15
+		} else if npv.days > 15 {
+			npv.days -= 15
+		}
+		npv.loading = true
+		return m, m.loadNetPrices()
+
+	case "s":
+		npv.mode = netPricesModeExpiring
+		npv.filter = ""
+		npv.loading = true
+		return m, m.loadNetPrices()
+
+	case "c":
+		npv.form = newNetPriceForm(netPricesModeCustomer, "Prezzi netti per cliente", "Codice cliente")
+		return m, nil
+
+	case "a":
+		npv.form = newNetPriceForm(netPricesModeArticle, "Prezzi netti per articolo", "Codice articolo")
+		return m, nil
+
+	case "n":
+		npv.form = newNetPriceForm("new", "Nuovo prezzo netto",
+			"Codice articolo", "Codice cliente", "Prezzo", "Valido dal (gg/mm/aaaa)", "Valido al (gg/mm/aaaa)")
+		npv.form.values[3] = time.Now().Format("02/01/2006")
+		npv.form.values[4] = time.Now().AddDate(1, 0, 0).Format("02/01/2006")
+		return m, nil
+
+	case "r", "R":
+		if len(npv.entries) == 0 {
+			return m, nil
+		}
+		action, title := "renew", "Rinnovo prezzo netto selezionato"
+		if keyMsg.String() == "R" {
+			action, title = "renew_all", fmt.Sprintf("Rinnovo di tutti i %d prezzi in elenco", len(npv.entries))
+		}
+		npv.form = newNetPriceForm(action, title, "Aumento %", "Nuova scadenza (gg/mm/aaaa)")
+		npv.form.values[0] = "0"
+		if entry := npv.selected(); entry != nil && !entry.NetPrice.ValidTo.IsZero() {
+			npv.form.values[1] = entry.NetPrice.ValidTo.AddDate(1, 0, 0).Format("02/01/2006")
+		}
+		return m, nil
+
+	case "i":
+		npv.form = newNetPriceForm("import", "Import CSV (articolo;cliente;prezzo;valido_dal;valido_al)", "File CSV")
+		return m, nil
+
+	case "x":
+		entry := npv.selected()
+		if entry == nil {
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			err := m.netPriceUC.DismissReminder(context.Background(), m.operator, *entry)
+			return netPriceActionMsg{err: err, message: "Promemoria ignorato per " + entry.ArticleCode}
+		}
+
+	case "e":
+		days := npv.days
+		return m, func() tea.Msg {
+			path, err := m.netPriceUC.ExportExpiring(context.Background(), days, exportDir)
+			return exportDoneMsg{path: path, err: err}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updateNetPriceForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	npv := m.netPricesView
+	form := npv.form
+
+	switch msg.String() {
+	case "esc":
+		npv.form = nil
+		return m, nil
+
+	case "tab", "down":
+		form.focusIndex = (form.focusIndex + 1) % len(form.labels)
+		return m, nil
+
+	case "shift+tab", "up":
+		form.focusIndex--
+		if form.focusIndex < 0 {
+			form.focusIndex = len(form.labels) - 1
+		}
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		if form.focusIndex < len(form.labels)-1 {
+			form.focusIndex++
+			return m, nil
+		}
+		npv.form = nil
+		return m, m.submitNetPriceForm(form)
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) submitNetPriceForm(form *NetPriceForm) tea.Cmd {
+	npv := m.netPricesView
+	values := make([]string, len(form.values))
+	for i, value := range form.values {
+		values[i] = strings.TrimSpace(value)
+	}
+
+	switch form.action {
+	case netPricesModeCustomer, netPricesModeArticle:
+		npv.mode = form.action
+		npv.filter = strings.ToUpper(values[0])
+		npv.selectedIndex = 0
+		npv.loading = true
+		return m.loadNetPrices()
+
+	case "new":
+		return func() tea.Msg {
+			price, err := export.ParseAmount(values[2])
+			if err != nil {
+				return netPriceActionMsg{err: fmt.Errorf("prezzo non valido")}
+			}
+			validFrom, err := export.ParseDate(values[3])
+			if err != nil {
+				return netPriceActionMsg{err: fmt.Errorf("data di inizio non valida")}
+			}
+			validTo, err := export.ParseDate(values[4])
+			if err != nil {
+				return netPriceActionMsg{err: fmt.Errorf("data di scadenza non valida")}
+			}
+			err = m.netPriceUC.SetNetPrice(context.Background(), m.operator,
+				strings.ToUpper(values[0]), strings.ToUpper(values[1]), price, validFrom, validTo)
+			return netPriceActionMsg{err: err, message: "Prezzo netto salvato"}
+		}
+
+	case "renew", "renew_all":
+		entries := npv.entries
+		if form.action == "renew" {
+			entry := npv.selected()
+			if entry == nil {
+				return nil
+			}
+			entries = []usecase.NetPriceEntry{*entry}
+		}
+		return func() tea.Msg {
+			uplift, err := export.ParseAmount(values[0])
+			if err != nil {
+				return netPriceActionMsg{err: fmt.Errorf("percentuale non valida")}
+			}
+			validTo, err := export.ParseDate(values[1])
+			if err != nil {
+				return netPriceActionMsg{err: fmt.Errorf("data di scadenza non valida")}
+			}
+			renewed, err := m.netPriceUC.Renew(context.Background(), m.operator, entries, uplift, validTo)
+			return netPriceActionMsg{err: err, message: fmt.Sprintf("%d prezzi netti rinnovati", renewed)}
+		}
+
+	case "import":
+		return func() tea.Msg {
+			file, err := os.Open(values[0])
+			if err != nil {
+				return netPriceActionMsg{err: err}
+			}
+			defer file.Close()
+
+			result, err := m.netPriceUC.ImportCSV(context.Background(), m.operator, file)
+			if err != nil {
+				return netPriceActionMsg{err: err}
+			}
+			message := fmt.Sprintf("%d prezzi netti importati", result.Imported)
+			if len(result.Errors) > 0 {
+				message += fmt.Sprintf(", %d righe scartate (%s)", len(result.Errors), result.Errors[0])
+			}
+			return netPriceActionMsg{message: message}
+		}
+	}
+
+	return nil
+}
+
+func (npv *NetPricesView) selected() *usecase.NetPriceEntry {
+	if npv.selectedIndex < 0 || npv.selectedIndex >= len(npv.entries) {
+		return nil
+	}
+	return &npv.entries[npv.selectedIndex]
+}
+
+func (m *AppModel) loadNetPrices() tea.Cmd {
+	mode, filter, days := m.netPricesView.mode, m.netPricesView.filter, m.netPricesView.days
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		var entries []usecase.NetPriceEntry
+		var err error
+		switch mode {
+		case netPricesModeCustomer:
+			entries, err = m.netPriceUC.GetCustomerNetPrices(ctx, filter)
+		case netPricesModeArticle:
+			entries, err = m.netPriceUC.GetArticleNetPrices(ctx, filter)
+		default:
+			entries, err = m.netPriceUC.GetExpiring(ctx, days)
+		}
+
+		return netPricesLoadedMsg{entries: entries, err: err}
+	}
+}
+
+func (m *AppModel) loadNetPriceReminders() tea.Cmd {
+	return func() tea.Msg {
+		reminders, count, err := m.netPriceUC.GetReminders(context.Background(), 3)
+		return netPriceRemindersMsg{reminders: reminders, count: count, err: err}
+	}
+}
+
+func (m *AppModel) handleNetPricesLoaded(msg netPricesLoadedMsg) (*AppModel, tea.Cmd) {
+	m.netPricesView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento prezzi netti: " + msg.err.Error())
+		m.netPricesView.entries = []usecase.NetPriceEntry{}
+		return m, nil
+	}
+
+	m.netPricesView.entries = msg.entries
+	if m.netPricesView.selectedIndex >= len(msg.entries) {
+		m.netPricesView.selectedIndex = 0
+	}
+	return m, nil
+}
+
+func (m *AppModel) handleNetPriceAction(msg netPriceActionMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		m.setError("Operazione non riuscita: " + msg.err.Error())
+		return m, nil
+	}
+
+	m.setMessage(msg.message)
+	m.netPricesView.loading = true
+	return m, tea.Batch(m.loadNetPrices(), m.loadNetPriceReminders())
+}
+
+// renderNetPriceReminders è il riquadro dei promemoria mostrato sotto il menu principale
+func (m *AppModel) renderNetPriceReminders() string {
+	if m.reminderCount == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	lines := []string{WarningStyle.Render(fmt.Sprintf("🔔 %d prezzi netti in scadenza (p: distinta)", m.reminderCount))}
+	for _, reminder := range m.netPriceReminders {
+		lines = append(lines, fmt.Sprintf("  %s  %-15s %-12s € %.2f  tra %d gg",
+			reminder.ValidTo.Format("02/01/2006"),
+			truncateString(reminder.ArticleCode, 15),
+			truncateString(reminder.CustomerCode, 12),
+			reminder.Price,
+			reminder.DaysLeft(now),
+		))
+	}
+	if int64(len(m.netPriceReminders)) < m.reminderCount {
+		lines = append(lines, fmt.Sprintf("  … e altri %d", m.reminderCount-int64(len(m.netPriceReminders))))
+	}
+
+	return CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func (m *AppModel) handleNetPriceReminders(msg netPriceRemindersMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		m.netPriceReminders = []*domain.NetPriceReminder{}
+		m.reminderCount = 0
+		return m, nil
+	}
+
+	m.netPriceReminders = msg.reminders
+	m.reminderCount = msg.count
+	return m, nil
+}
@@ -0,0 +1,205 @@
+// internal/ui/view_operators.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+)
+
+func (m *AppModel) viewOperators() string {
+	ov := m.operatorsView
+
+	var list string
+	if ov.loading {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(ov.operators) == 0 {
+		list = InfoStyle.Render("Nessun operatore")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-16s %-24s %-12s %-9s %-16s",
+			"Username", "Nome", "Profilo", "Tentativi", "Ultimo accesso"))
+		items := []string{header}
+		now := time.Now()
+		for i, operator := range ov.operators {
+			lastLogin := "-"
+			if !operator.LastLogin.IsZero() {
+				lastLogin = operator.LastLogin.Format("02/01/2006 15:04")
+			}
+			status := "active"
+			switch {
+			case operator.IsLockedAt(now):
+				status = "blocked"
+			case !operator.IsActive:
+				status = "inactive"
+			}
+			itemText := fmt.Sprintf("%-16s %-24s %-12s %9d %-16s %s",
+				truncateString(operator.Username, 16),
+				truncateString(operator.FullName, 24),
+				string(operator.Profile),
+				operator.FailedAttempts,
+				lastLogin,
+				RenderStatusBadge(status),
+			)
+			if i == ov.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("👤 Operatori"),
+		ContentStyle.Render(list),
+	}
+
+	if ov.form != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(ov.form)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func (m *AppModel) updateOperators(msg tea.Msg) (tea.Model, tea.Cmd) {
+	ov := m.operatorsView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if ov.form != nil {
+		return m.updateOperatorForm(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if ov.selectedIndex > 0 {
+			ov.selectedIndex--
+		}
+
+	case "down", "j":
+		if ov.selectedIndex < len(ov.operators)-1 {
+			ov.selectedIndex++
+		}
+
+	case "r":
+		if operator := ov.selected(); operator != nil {
+			// la password temporanea resta visibile: l'amministratore la comunica all'operatore,
+			// che dovrà cambiarla al primo accesso
+			ov.form = newNetPriceForm("reset_password", "Password temporanea per "+operator.Username, "Nuova password")
+		}
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updateOperatorForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ov := m.operatorsView
+	form := ov.form
+
+	switch msg.String() {
+	case "esc":
+		ov.form = nil
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		ov.form = nil
+		return m, m.resetOperatorPassword(ov.selected(), form.values[0])
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) resetOperatorPassword(operator *domain.Operator, password string) tea.Cmd {
+	if operator == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		err := m.loginUC.ResetPassword(context.Background(), m.operator, operator, password)
+		return operatorActionMsg{
+			err:     err,
+			message: "Password di " + operator.Username + " reimpostata e account sbloccato: andrà cambiata al prossimo accesso",
+		}
+	}
+}
+
+func (ov *OperatorsView) selected() *domain.Operator {
+	if ov.selectedIndex < 0 || ov.selectedIndex >= len(ov.operators) {
+		return nil
+	}
+	return ov.operators[ov.selectedIndex]
+}
+
+func (m *AppModel) loadOperators() tea.Cmd {
+	return func() tea.Msg {
+		operators, err := m.operatorRepo.FindAll(context.Background(), 0, 0)
+		return operatorsLoadedMsg{operators: operators, err: err}
+	}
+}
+
+func (m *AppModel) handleOperatorsLoaded(msg operatorsLoadedMsg) (*AppModel, tea.Cmd) {
+	m.operatorsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento operatori: " + msg.err.Error())
+		m.operatorsView.operators = []*domain.Operator{}
+		return m, nil
+	}
+
+	m.operatorsView.operators = msg.operators
+	if m.operatorsView.selectedIndex >= len(msg.operators) {
+		m.operatorsView.selectedIndex = 0
+	}
+	return m, nil
+}
+
+func (m *AppModel) handleOperatorAction(msg operatorActionMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, domain.ErrInsufficientPermissions):
+			m.setError("Solo un amministratore può reimpostare le password")
+		case errors.Is(msg.err, domain.ErrInvalidPassword):
+			m.setError("La password non rispetta i requisiti")
+		case errors.Is(msg.err, domain.ErrPasswordReused):
+			m.setError("La password è stata usata di recente")
+		default:
+			m.setError("Operazione non riuscita: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	m.setMessage(msg.message)
+	m.operatorsView.loading = true
+	return m, m.loadOperators()
+}
@@ -0,0 +1,381 @@
+// internal/ui/view_price_lists.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/export"
+)
+
+var customerCategories = []domain.CustomerCategory{
+	domain.CategoryRetail,
+	domain.CategoryWholesale,
+	domain.CategoryWorkshop,
+	domain.CategoryDealer,
+	domain.CategoryVIP,
+}
+
+func (m *AppModel) viewPriceLists() string {
+	plv := m.priceListsView
+
+	var list string
+	if plv.loading {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(plv.priceLists) == 0 {
+		list = InfoStyle.Render("Nessun listino")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-12s %-24s %-14s %-24s %-10s %-10s",
+			"Codice", "Nome", "Tipo", "Categorie", "Dal", "Al"))
+		items := []string{header}
+		for i, priceList := range plv.priceLists {
+			kind := fmt.Sprintf("%d articoli", len(priceList.Entries))
+			if priceList.Type == domain.PriceListTypeDerived {
+				kind = fmt.Sprintf("listino %+.1f%%", priceList.DerivedPercent)
+			}
+			categories := make([]string, 0, len(priceList.CustomerCategories))
+			for _, category := range priceList.CustomerCategories {
+				categories = append(categories, string(category))
+			}
+			status := "active"
+			if !priceList.IsActive {
+				status = "inactive"
+			}
+			itemText := fmt.Sprintf("%-12s %-24s %-14s %-24s %-10s %-10s %s",
+				truncateString(priceList.Code, 12),
+				truncateString(priceList.Name, 24),
+				kind,
+				truncateString(strings.Join(categories, ","), 24),
+				export.Date(priceList.ValidFrom),
+				export.Date(priceList.ValidTo),
+				RenderStatusBadge(status),
+			)
+			if i == plv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("📋 Listini"),
+		ContentStyle.Render(list),
+	}
+
+	if priceList := plv.selected(); priceList != nil && len(priceList.Entries) > 0 {
+		lines := []string{TableHeaderStyle.Render(fmt.Sprintf("%-15s %10s", "Articolo", "Prezzo"))}
+		for i, entry := range priceList.Entries {
+			if i == 10 {
+				lines = append(lines, fmt.Sprintf("… e altri %d", len(priceList.Entries)-i))
+				break
+			}
+			lines = append(lines, TableCellStyle.Render(fmt.Sprintf("%-15s %10.2f", truncateString(entry.ArticleCode, 15), entry.Price)))
+		}
+		sections = append(sections, CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+	}
+
+	if plv.form != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(plv.form)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func (m *AppModel) updatePriceLists(msg tea.Msg) (tea.Model, tea.Cmd) {
+	plv := m.priceListsView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if plv.form != nil {
+		return m.updatePriceListForm(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if plv.selectedIndex > 0 {
+			plv.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if plv.selectedIndex < len(plv.priceLists)-1 {
+			plv.selectedIndex++
+		}
+		return m, nil
+
+	case "n":
+		plv.form = newNetPriceForm("new_table", "Nuovo listino a tabella", "Codice", "Nome")
+		return m, nil
+
+	case "v":
+		plv.form = newNetPriceForm("new_derived", "Nuovo listino derivato dal listino base",
+			"Codice", "Nome", "Variazione % sul prezzo di listino")
+		return m, nil
+
+	case "a":
+		plv.form = newNetPriceForm("assign_customer", "Assegna listino a cliente",
+			"Codice cliente", "Codice listino (vuoto = standard)")
+		if priceList := plv.selected(); priceList != nil {
+			plv.form.values[1] = priceList.Code
+		}
+		return m, nil
+	}
+
+	priceList := plv.selected()
+	if priceList == nil {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "p":
+		plv.form = newNetPriceForm("set_entry", "Prezzo articolo nel listino "+priceList.Code, "Codice articolo", "Prezzo")
+	case "r":
+		plv.form = newNetPriceForm("remove_entry", "Togli articolo dal listino "+priceList.Code, "Codice articolo")
+	case "d":
+		plv.form = newNetPriceForm("validity", "Validità del listino "+priceList.Code,
+			"Valido dal (gg/mm/aaaa, vuoto = sempre)", "Valido al (gg/mm/aaaa, vuoto = senza scadenza)")
+		plv.form.values[0] = export.Date(priceList.ValidFrom)
+		plv.form.values[1] = export.Date(priceList.ValidTo)
+	case "g":
+		plv.form = newNetPriceForm("assign_category", "Assegna "+priceList.Code+" a categoria", categoryLabel())
+	case "u":
+		plv.form = newNetPriceForm("unassign_category", "Togli "+priceList.Code+" da categoria", categoryLabel())
+	case "x":
+		return m, func() tea.Msg {
+			err := m.priceListUC.Deactivate(context.Background(), priceList.ID, m.operator)
+			return priceListActionMsg{err: err, message: "Listino " + priceList.Code + " disattivato"}
+		}
+	}
+
+	return m, nil
+}
+
+func categoryLabel() string {
+	names := make([]string, 0, len(customerCategories))
+	for _, category := range customerCategories {
+		names = append(names, string(category))
+	}
+	return "Categoria (" + strings.Join(names, ", ") + ")"
+}
+
+func parseCustomerCategory(value string) (domain.CustomerCategory, error) {
+	for _, category := range customerCategories {
+		if strings.EqualFold(value, string(category)) {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("categoria %q non valida", value)
+}
+
+func (m *AppModel) updatePriceListForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	plv := m.priceListsView
+	form := plv.form
+
+	switch msg.String() {
+	case "esc":
+		plv.form = nil
+		return m, nil
+
+	case "tab", "down":
+		form.focusIndex = (form.focusIndex + 1) % len(form.labels)
+		return m, nil
+
+	case "shift+tab", "up":
+		form.focusIndex--
+		if form.focusIndex < 0 {
+			form.focusIndex = len(form.labels) - 1
+		}
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		if form.focusIndex < len(form.labels)-1 {
+			form.focusIndex++
+			return m, nil
+		}
+		plv.form = nil
+		return m, m.submitPriceListForm(form)
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) submitPriceListForm(form *NetPriceForm) tea.Cmd {
+	values := make([]string, len(form.values))
+	for i, value := range form.values {
+		values[i] = strings.TrimSpace(value)
+	}
+	priceList := m.priceListsView.selected()
+
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		switch form.action {
+		case "new_table":
+			created, err := m.priceListUC.CreateTablePriceList(ctx, strings.ToUpper(values[0]), values[1], m.operator)
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			return priceListActionMsg{message: "Listino " + created.Code + " creato"}
+
+		case "new_derived":
+			percent, err := export.ParseAmount(values[2])
+			if err != nil {
+				return priceListActionMsg{err: fmt.Errorf("percentuale non valida")}
+			}
+			created, err := m.priceListUC.CreateDerivedPriceList(ctx, strings.ToUpper(values[0]), values[1], percent, m.operator)
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			return priceListActionMsg{message: "Listino " + created.Code + " creato"}
+
+		case "assign_customer":
+			customer, err := m.customerRepo.FindByCode(ctx, strings.ToUpper(values[0]))
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			code := strings.ToUpper(values[1])
+			if err := m.priceListUC.AssignToCustomer(ctx, customer.ID, code, m.operator); err != nil {
+				return priceListActionMsg{err: err}
+			}
+			if code == "" {
+				code = domain.PriceListStandard
+			}
+			return priceListActionMsg{message: "Listino " + code + " assegnato a " + customer.Code}
+		}
+
+		if priceList == nil {
+			return nil
+		}
+
+		switch form.action {
+		case "set_entry":
+			price, err := export.ParseAmount(values[1])
+			if err != nil {
+				return priceListActionMsg{err: fmt.Errorf("prezzo non valido")}
+			}
+			article, err := m.articleRepo.FindByCode(ctx, strings.ToUpper(values[0]))
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			err = m.priceListUC.SetEntry(ctx, priceList.ID, article.ID, price, m.operator)
+			return priceListActionMsg{err: err, message: fmt.Sprintf("%s: %s a € %.2f", priceList.Code, article.Code, price)}
+
+		case "remove_entry":
+			article, err := m.articleRepo.FindByCode(ctx, strings.ToUpper(values[0]))
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			err = m.priceListUC.RemoveEntry(ctx, priceList.ID, article.ID, m.operator)
+			return priceListActionMsg{err: err, message: article.Code + " tolto da " + priceList.Code}
+
+		case "validity":
+			validFrom, err := export.ParseDate(values[0])
+			if err != nil {
+				return priceListActionMsg{err: fmt.Errorf("data di inizio non valida")}
+			}
+			validTo, err := export.ParseDate(values[1])
+			if err != nil {
+				return priceListActionMsg{err: fmt.Errorf("data di scadenza non valida")}
+			}
+			err = m.priceListUC.SetValidity(ctx, priceList.ID, validFrom, validTo, m.operator)
+			return priceListActionMsg{err: err, message: "Validità di " + priceList.Code + " aggiornata"}
+
+		case "assign_category", "unassign_category":
+			category, err := parseCustomerCategory(values[0])
+			if err != nil {
+				return priceListActionMsg{err: err}
+			}
+			if form.action == "assign_category" {
+				err = m.priceListUC.AssignToCategory(ctx, priceList.ID, category, m.operator)
+				return priceListActionMsg{err: err, message: priceList.Code + " assegnato alla categoria " + string(category)}
+			}
+			err = m.priceListUC.UnassignFromCategory(ctx, priceList.ID, category, m.operator)
+			return priceListActionMsg{err: err, message: priceList.Code + " tolto dalla categoria " + string(category)}
+		}
+
+		return nil
+	}
+}
+
+func (plv *PriceListsView) selected() *domain.PriceList {
+	if plv.selectedIndex < 0 || plv.selectedIndex >= len(plv.priceLists) {
+		return nil
+	}
+	return plv.priceLists[plv.selectedIndex]
+}
+
+func (m *AppModel) loadPriceLists() tea.Cmd {
+	return func() tea.Msg {
+		priceLists, err := m.priceListUC.ListPriceLists(context.Background())
+		return priceListsLoadedMsg{priceLists: priceLists, err: err}
+	}
+}
+
+func (m *AppModel) handlePriceListsLoaded(msg priceListsLoadedMsg) (*AppModel, tea.Cmd) {
+	m.priceListsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento listini: " + msg.err.Error())
+		m.priceListsView.priceLists = []*domain.PriceList{}
+		return m, nil
+	}
+
+	m.priceListsView.priceLists = msg.priceLists
+	if m.priceListsView.selectedIndex >= len(msg.priceLists) {
+		m.priceListsView.selectedIndex = 0
+	}
+	return m, nil
+}
+
+func (m *AppModel) handlePriceListAction(msg priceListActionMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, domain.ErrInsufficientPermissions):
+			m.setError("Non hai i permessi per modificare i listini")
+		case errors.Is(msg.err, domain.ErrArticleNotFound):
+			m.setError("Articolo non trovato")
+		case errors.Is(msg.err, domain.ErrCustomerNotFound):
+			m.setError("Cliente non trovato")
+		default:
+			m.setError("Operazione non riuscita: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	m.setMessage(msg.message)
+	m.priceListsView.loading = true
+	return m, m.loadPriceLists()
+}
@@ -0,0 +1,368 @@
+// internal/ui/view_promotions.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/usecase"
+)
+
+const exportDir = "exports"
+
+func (m *AppModel) viewPromotions() string {
+	title := TitleStyle.Render("🎁 Promozioni")
+	pv := m.promotionsView
+
+	var list string
+	if pv.loading && len(pv.promotions) == 0 {
+		list = InfoStyle.Render("⏳ Caricamento in corso...")
+	} else if len(pv.promotions) == 0 {
+		list = InfoStyle.Render("Nessuna promozione")
+	} else {
+		var items []string
+		for i, promo := range pv.promotions {
+			status := "active"
+			if !promo.IsValid(time.Now()) {
+				status = "expired"
+			}
+			itemText := fmt.Sprintf("%-12s %s %s → %s %s",
+				promo.Code,
+				truncateString(promo.Name, 30),
+				promo.ValidFrom.Format("02/01/06"),
+				promo.ValidTo.Format("02/01/06"),
+				RenderStatusBadge(status),
+			)
+			if i == pv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{title, ContentStyle.Render(list)}
+
+	if pv.loading && len(pv.promotions) > 0 {
+		sections = append(sections, InfoStyle.Render("⏳ Analisi in corso..."))
+	} else if len(pv.comparison) > 0 {
+		sections = append(sections, CardStyle.Render(renderPromotionComparison(pv.comparison)))
+	} else if pv.report != nil {
+		sections = append(sections, CardStyle.Render(renderPromotionReport(pv.report)))
+	}
+
+	if pv.form != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(pv.form)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func renderPromotionReport(r *usecase.PromotionReport) string {
+	lines := []string{
+		SubtitleStyle.Render(fmt.Sprintf("%s - %s (%s → %s)",
+			r.Promotion.Code, r.Promotion.Name, r.From.Format("02/01/2006"), r.To.Format("02/01/2006"))),
+		fmt.Sprintf("Utilizzi: %d   Fatturato: € %.2f   Sconto concesso: € %.2f", r.Redemptions, r.Revenue, r.Discount),
+		fmt.Sprintf("Unità vendute: %.0f   Periodo base: %.0f   Incrementali: %+.0f (%+.1f%%)",
+			r.UnitsSold, r.BaselineUnits, r.IncrementalUnits, r.IncrementalPercent),
+		fmt.Sprintf("Margine: %.1f%%   Base: %.1f%%   Erosione: %.1f punti   Sconto per unità incrementale: € %.2f",
+			r.MarginPercent, r.BaselineMargin, r.MarginErosionPoints, r.DiscountPerUnit),
+	}
+
+	if len(r.TopCustomers) > 0 {
+		lines = append(lines, "", TableHeaderStyle.Render("Clienti principali"))
+		for _, c := range r.TopCustomers {
+			lines = append(lines, TableCellStyle.Render(fmt.Sprintf("%-12s %3d utilizzi  € %10.2f  sconto € %8.2f",
+				c.CustomerCode, c.Redemptions, c.Revenue, c.Discount)))
+		}
+	}
+
+	if len(r.DailyRedemptions) > 0 {
+		max := 0
+		for _, d := range r.DailyRedemptions {
+			if d.Redemptions > max {
+				max = d.Redemptions
+			}
+		}
+		lines = append(lines, "", TableHeaderStyle.Render("Utilizzi per giorno"))
+		for _, d := range r.DailyRedemptions {
+			lines = append(lines, fmt.Sprintf("%s %s %d",
+				d.Date.Format("02/01"),
+				RenderProgressBar(float64(d.Redemptions)/float64(max)*100, 30),
+				d.Redemptions))
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func renderPromotionComparison(reports []*usecase.PromotionReport) string {
+	lines := []string{
+		TableHeaderStyle.Render(fmt.Sprintf("%-12s %8s %12s %12s %10s %10s",
+			"Codice", "Utilizzi", "Sconto", "Incrementali", "Margine %", "Erosione")),
+	}
+
+	for _, r := range reports {
+		lines = append(lines, TableCellStyle.Render(fmt.Sprintf("%-12s %8d %12.2f %+12.0f %10.1f %10.1f",
+			r.Promotion.Code, r.Redemptions, r.Discount, r.IncrementalUnits, r.MarginPercent, r.MarginErosionPoints)))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m *AppModel) updatePromotions(msg tea.Msg) (tea.Model, tea.Cmd) {
+	pv := m.promotionsView
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if pv.form != nil {
+			return m.updateCouponForm(msg)
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if pv.selectedIndex > 0 {
+				pv.selectedIndex--
+			}
+			return m, nil
+
+		case "down", "j":
+			if pv.selectedIndex < len(pv.promotions)-1 {
+				pv.selectedIndex++
+			}
+			return m, nil
+
+		case "enter":
+			if len(pv.promotions) == 0 || pv.loading {
+				return m, nil
+			}
+			pv.loading = true
+			pv.comparison = nil
+			promo := pv.promotions[pv.selectedIndex]
+			return m, func() tea.Msg {
+				report, err := m.analyticsUC.AnalyzePromotion(context.Background(), promo.ID, time.Time{}, time.Time{})
+				return promotionReportMsg{report: report, err: err}
+			}
+
+		case "c":
+			if len(pv.promotions) == 0 || pv.loading {
+				return m, nil
+			}
+			pv.loading = true
+			ids := make([]primitive.ObjectID, 0, len(pv.promotions))
+			for _, promo := range pv.promotions {
+				ids = append(ids, promo.ID)
+			}
+			return m, func() tea.Msg {
+				reports, err := m.analyticsUC.ComparePromotions(context.Background(), ids)
+				return promotionReportMsg{comparison: reports, err: err}
+			}
+
+		case "e":
+			reports := pv.comparison
+			if len(reports) == 0 && pv.report != nil {
+				reports = []*usecase.PromotionReport{pv.report}
+			}
+			if len(reports) == 0 {
+				m.setError("Nessuna analisi da esportare")
+				return m, nil
+			}
+			return m, func() tea.Msg {
+				path, err := m.analyticsUC.ExportReports(reports, exportDir)
+				return exportDoneMsg{path: path, err: err}
+			}
+
+		case "g":
+			if len(pv.promotions) == 0 {
+				return m, nil
+			}
+			promo := pv.promotions[pv.selectedIndex]
+			pv.form = newNetPriceForm("coupon_batch", "Nuovo lotto coupon per "+promo.Code,
+				"Nome lotto", "Prefisso", "Quantità", "Monouso (s/n)", "Utilizzi per coupon (0 = illimitati)",
+				"Codice cliente (vuoto = tutti)")
+			pv.form.values[2] = "10"
+			pv.form.values[3] = "s"
+			pv.form.values[4] = "1"
+			return m, nil
+
+		case "d":
+			pv.form = newNetPriceForm("deactivate_coupon", "Disattiva coupon", "Codice coupon")
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updateCouponForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pv := m.promotionsView
+	form := pv.form
+
+	switch msg.String() {
+	case "esc":
+		pv.form = nil
+		return m, nil
+
+	case "tab", "down":
+		form.focusIndex = (form.focusIndex + 1) % len(form.labels)
+		return m, nil
+
+	case "shift+tab", "up":
+		form.focusIndex--
+		if form.focusIndex < 0 {
+			form.focusIndex = len(form.labels) - 1
+		}
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		if form.focusIndex < len(form.labels)-1 {
+			form.focusIndex++
+			return m, nil
+		}
+		pv.form = nil
+		if form.action == "deactivate_coupon" {
+			return m, m.deactivateCoupon(form.values[0])
+		}
+		return m, m.createCouponBatch(pv.promotions[pv.selectedIndex], form.values)
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) createCouponBatch(promo *domain.Promotion, values []string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		quantity, err := strconv.Atoi(strings.TrimSpace(values[2]))
+		if err != nil || quantity <= 0 {
+			return couponMsg{err: fmt.Errorf("quantità non valida")}
+		}
+		usage := domain.CouponMultiUse
+		if strings.EqualFold(strings.TrimSpace(values[3]), "s") {
+			usage = domain.CouponSingleUse
+		}
+		maxRedemptions, err := strconv.Atoi(strings.TrimSpace(values[4]))
+		if err != nil {
+			return couponMsg{err: fmt.Errorf("utilizzi non validi")}
+		}
+
+		var customerID primitive.ObjectID
+		if code := strings.ToUpper(strings.TrimSpace(values[5])); code != "" {
+			customer, err := m.customerRepo.FindByCode(ctx, code)
+			if err != nil {
+				return couponMsg{err: err}
+			}
+			customerID = customer.ID
+		}
+
+		batch, coupons, err := m.couponUC.CreateBatch(ctx, promo.ID, values[0], values[1], quantity, usage,
+			maxRedemptions, customerID, m.operator)
+		if err != nil {
+			return couponMsg{err: err}
+		}
+
+		path, err := m.couponUC.ExportBatch(batch, coupons, exportDir)
+		if err != nil {
+			return couponMsg{err: fmt.Errorf("lotto creato, esportazione non riuscita: %w", err)}
+		}
+		return couponMsg{message: fmt.Sprintf("%d coupon generati, codici in %s", len(coupons), path)}
+	}
+}
+
+func (m *AppModel) deactivateCoupon(code string) tea.Cmd {
+	return func() tea.Msg {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if err := m.couponUC.DeactivateCoupon(context.Background(), code, m.operator); err != nil {
+			return couponMsg{err: err}
+		}
+		return couponMsg{message: "Coupon " + code + " disattivato"}
+	}
+}
+
+func (m *AppModel) handleCoupon(msg couponMsg) (*AppModel, tea.Cmd) {
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, domain.ErrInsufficientPermissions):
+			m.setError("Non hai i permessi per gestire i coupon")
+		case errors.Is(msg.err, domain.ErrCouponNotFound):
+			m.setError("Coupon non trovato")
+		case errors.Is(msg.err, domain.ErrCustomerNotFound):
+			m.setError("Cliente non trovato")
+		case errors.Is(msg.err, domain.ErrInvalidCouponBatch):
+			m.setError("Dati del lotto non validi")
+		default:
+			m.setError("Operazione non riuscita: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	m.setMessage(msg.message)
+	// la promozione ora richiede il coupon
+	return m, m.loadPromotions()
+}
+
+func (m *AppModel) loadPromotions() tea.Cmd {
+	return func() tea.Msg {
+		promotions, err := m.promotionRepo.FindAll(context.Background(), 0, 100)
+		return promotionsLoadedMsg{promotions: promotions, err: err}
+	}
+}
+
+func (m *AppModel) handlePromotionsLoaded(msg promotionsLoadedMsg) (*AppModel, tea.Cmd) {
+	m.promotionsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore caricamento promozioni: " + msg.err.Error())
+		m.promotionsView.promotions = []*domain.Promotion{}
+		return m, nil
+	}
+
+	m.promotionsView.promotions = msg.promotions
+	m.promotionsView.selectedIndex = 0
+	return m, nil
+}
+
+func (m *AppModel) handlePromotionReport(msg promotionReportMsg) (*AppModel, tea.Cmd) {
+	m.promotionsView.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore analisi promozione: " + msg.err.Error())
+		return m, nil
+	}
+
+	m.promotionsView.report = msg.report
+	m.promotionsView.comparison = msg.comparison
+	return m, nil
+}
@@ -0,0 +1,510 @@
+// internal/ui/view_sales_document.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/export"
+)
+
+func newSalesDocumentView(operator *domain.Operator, customer *domain.Customer) *SalesDocumentView {
+	document, _ := domain.NewDocument(domain.DocumentTypeInvoice, operator.Username)
+	document.SetOperator(operator)
+	if customer != nil {
+		document.SetCustomer(customer)
+	}
+	return &SalesDocumentView{document: document, customer: customer}
+}
+
+// cloneDocument copia la bozza su cui lavorano i comandi: la vista continua a mostrare l'originale e
+// lo sostituisce solo se l'operazione riesce
+func cloneDocument(document *domain.Document) *domain.Document {
+	clone := *document
+	clone.Lines = append([]domain.DocumentLine(nil), document.Lines...)
+	clone.Promotions = append([]domain.DocumentPromotion(nil), document.Promotions...)
+	clone.Vouchers = append([]domain.DocumentVoucher(nil), document.Vouchers...)
+	return &clone
+}
+
+func (m *AppModel) viewSalesDocument() string {
+	sv := m.salesDocumentView
+	document := sv.document
+
+	docType := "Fattura"
+	if document.Type == domain.DocumentTypeOrder {
+		docType = "Ordine"
+	}
+	customer := InfoStyle.Render("nessun cliente (c: seleziona)")
+	if sv.customer != nil {
+		customer = sv.customer.Code + " - " + sv.customer.CompanyName
+	}
+	headerLines := []string{
+		"Documento: " + BadgeStyle.Render(docType),
+		"Cliente: " + customer,
+	}
+	if document.CouponCode != "" {
+		headerLines = append(headerLines, "Coupon: "+document.CouponCode)
+	}
+
+	var list string
+	if len(document.Lines) == 0 {
+		list = InfoStyle.Render("Nessuna riga (a: aggiungi articolo)")
+	} else {
+		header := TableHeaderStyle.Render(fmt.Sprintf("  %-15s %-24s %7s %9s %9s %6s %10s",
+			"Articolo", "Descrizione", "Q.tà", "Listino", "Prezzo", "Sc.%", "Totale"))
+		items := []string{header}
+		for i, line := range document.Lines {
+			itemText := fmt.Sprintf("%-15s %-24s %7.2f %9.2f %9.2f %6.1f %10.2f",
+				truncateString(line.ArticleCode, 15),
+				truncateString(line.Description, 24),
+				line.Quantity,
+				line.ListPrice,
+				line.UnitPrice,
+				line.ManualDiscount,
+				line.Total,
+			)
+			if line.Approval != nil {
+				switch line.Approval.Status {
+				case domain.ApprovalStatusApproved:
+					itemText += " " + BadgeSuccessStyle.Render(string(line.Approval.Violation)+" approvato")
+				case domain.ApprovalStatusRejected:
+					itemText += " " + BadgeDangerStyle.Render(string(line.Approval.Violation)+" respinto")
+				default:
+					itemText += " " + BadgeWarningStyle.Render(string(line.Approval.Violation)+" da approvare")
+				}
+			}
+			if i == sv.selectedIndex {
+				items = append(items, SelectedItemStyle.Render("  "+itemText))
+			} else {
+				items = append(items, UnselectedItemStyle.Render("  "+itemText))
+			}
+		}
+		list = lipgloss.JoinVertical(lipgloss.Left, items...)
+	}
+
+	sections := []string{
+		TitleStyle.Render("🧾 Vendita al Banco"),
+		"",
+		CardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, headerLines...)),
+		ContentStyle.Render(list),
+	}
+
+	if sv.selectedIndex < len(document.Lines) {
+		var steps []string
+		for _, step := range document.Lines[sv.selectedIndex].Pricing {
+			steps = append(steps, "  "+step.String())
+		}
+		for _, adjustment := range document.Lines[sv.selectedIndex].Adjustments {
+			steps = append(steps, fmt.Sprintf("  %s -%.2f sulla riga", adjustment.Description, adjustment.Amount))
+		}
+		if len(steps) > 0 {
+			sections = append(sections, lipgloss.JoinVertical(lipgloss.Left,
+				append([]string{SubtitleStyle.Render("Calcolo prezzo riga")}, steps...)...))
+		}
+	}
+
+	sections = append(sections, CardStyle.Render(m.renderDocumentTotals()))
+
+	if sv.loading {
+		sections = append(sections, InfoStyle.Render("⏳ Attendere..."))
+	}
+	if sv.form != nil {
+		sections = append(sections, CardStyle.Render(renderNetPriceForm(sv.form)))
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height-6,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, sections...)),
+	)
+}
+
+func (m *AppModel) renderDocumentTotals() string {
+	document := m.salesDocumentView.document
+	totals := document.Totals
+
+	lines := []string{}
+	for _, promo := range document.Promotions {
+		lines = append(lines, SuccessStyle.Render(fmt.Sprintf("🎁 %s: -%.2f", promo.Description, promo.Discount)))
+	}
+	if document.ShippingCharge > 0 {
+		lines = append(lines, fmt.Sprintf("Spedizione: %.2f (sconto %.2f)", document.ShippingCharge, document.ShippingDiscount))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Imponibile: € %.2f   IVA: € %.2f   Totale: € %.2f", totals.Net, totals.VAT, totals.Total),
+		fmt.Sprintf("Margine: € %.2f", totals.Margin),
+	)
+	for _, voucher := range document.Vouchers {
+		lines = append(lines, fmt.Sprintf("Buono %s: -%.2f", voucher.Code, voucher.Amount))
+	}
+	lines = append(lines, TitleStyle.Render(fmt.Sprintf("Da pagare: € %.2f", totals.Due)))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m *AppModel) updateSalesDocument(msg tea.Msg) (tea.Model, tea.Cmd) {
+	sv := m.salesDocumentView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if sv.form != nil {
+		return m.updateSalesDocumentForm(keyMsg)
+	}
+	if sv.loading {
+		return m, nil
+	}
+	customer := sv.customer
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if sv.selectedIndex > 0 {
+			sv.selectedIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if sv.selectedIndex < len(sv.document.Lines)-1 {
+			sv.selectedIndex++
+		}
+		return m, nil
+
+	case "c":
+		sv.form = newNetPriceForm("customer", "Cliente", "Codice cliente")
+		return m, nil
+
+	case "a":
+		if sv.customer == nil {
+			m.setError("Seleziona prima il cliente")
+			return m, nil
+		}
+		sv.form = newNetPriceForm("line", "Nuova riga", "Codice articolo o barcode", "Quantità")
+		sv.form.values[1] = "1"
+		return m, nil
+
+	case "d", "delete":
+		if len(sv.document.Lines) == 0 {
+			return m, nil
+		}
+		index := sv.selectedIndex
+		return m, m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			if err := document.RemoveLine(index); err != nil {
+				return "", err
+			}
+			return "Riga eliminata", m.priceSalesDocument(ctx, customer, document)
+		})
+
+	case "s":
+		if len(sv.document.Lines) == 0 {
+			return m, nil
+		}
+		sv.form = newNetPriceForm("discount", "Sconto sulla riga "+sv.document.Lines[sv.selectedIndex].ArticleCode, "Sconto %")
+		sv.form.values[0] = fmt.Sprintf("%.2f", sv.document.Lines[sv.selectedIndex].ManualDiscount)
+		return m, nil
+
+	case "o":
+		sv.form = newNetPriceForm("coupon", "Coupon (vuoto per toglierlo)", "Codice coupon")
+		sv.form.values[0] = sv.document.CouponCode
+		return m, nil
+
+	case "h":
+		sv.form = newNetPriceForm("shipping", "Spese di spedizione", "Importo")
+		sv.form.values[0] = fmt.Sprintf("%.2f", sv.document.ShippingCharge)
+		return m, nil
+
+	case "v":
+		if sv.customer == nil {
+			m.setError("Seleziona prima il cliente")
+			return m, nil
+		}
+		sv.form = newNetPriceForm("voucher", "Buono a credito", "Codice buono (lettore o tastiera)")
+		return m, nil
+
+	case "t":
+		docType := domain.DocumentTypeOrder
+		if sv.document.Type == domain.DocumentTypeOrder {
+			docType = domain.DocumentTypeInvoice
+		}
+		return m, m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			document.Type = docType
+			return "", m.priceSalesDocument(ctx, customer, document)
+		})
+
+	case "p":
+		if len(sv.document.UnapprovedLines()) == 0 {
+			m.setError("Nessuna riga in attesa di approvazione")
+			return m, nil
+		}
+		sv.form = newNetPriceForm("pin", "Approvazione del supervisore", "Username supervisore", "PIN")
+		return m, nil
+
+	case "enter":
+		if sv.customer == nil || len(sv.document.Lines) == 0 {
+			m.setError("Il documento deve avere cliente e almeno una riga")
+			return m, nil
+		}
+		return m, m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			if err := m.postUC.Post(ctx, document, m.operator); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Registrato %s, totale € %.2f", document.Number, document.Totals.Total), nil
+		})
+
+	case "n":
+		m.salesDocumentView = newSalesDocumentView(m.operator, sv.customer)
+		m.clearMessages()
+		return m, nil
+
+	case "esc":
+		return m.navigateBack(), nil
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) updateSalesDocumentForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sv := m.salesDocumentView
+	form := sv.form
+
+	switch msg.String() {
+	case "esc":
+		sv.form = nil
+		return m, nil
+
+	case "tab", "down":
+		form.focusIndex = (form.focusIndex + 1) % len(form.labels)
+		return m, nil
+
+	case "shift+tab", "up":
+		form.focusIndex--
+		if form.focusIndex < 0 {
+			form.focusIndex = len(form.labels) - 1
+		}
+		return m, nil
+
+	case "backspace":
+		value := form.values[form.focusIndex]
+		if len(value) > 0 {
+			form.values[form.focusIndex] = value[:len(value)-1]
+		}
+		return m, nil
+
+	case "enter":
+		if form.focusIndex < len(form.labels)-1 {
+			form.focusIndex++
+			return m, nil
+		}
+		sv.form = nil
+		return m, m.submitSalesDocumentForm(form)
+
+	default:
+		if len(msg.Runes) > 0 {
+			form.values[form.focusIndex] += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+func (m *AppModel) submitSalesDocumentForm(form *NetPriceForm) tea.Cmd {
+	index := m.salesDocumentView.selectedIndex
+	customer := m.salesDocumentView.customer
+	values := make([]string, len(form.values))
+	for i, value := range form.values {
+		values[i] = strings.TrimSpace(value)
+	}
+
+	switch form.action {
+	case "customer":
+		code := strings.ToUpper(values[0])
+		m.salesDocumentView.loading = true
+		document := cloneDocument(m.salesDocumentView.document)
+		return func() tea.Msg {
+			ctx := context.Background()
+			customer, err := m.customerRepo.FindByCode(ctx, code)
+			if err != nil {
+				return salesDocumentMsg{err: err}
+			}
+			document.SetCustomer(customer)
+			_, err = m.discountUC.PriceDocument(ctx, customer, document)
+			return salesDocumentMsg{document: document, customer: customer, err: err}
+		}
+
+	case "line":
+		code := strings.ToUpper(values[0])
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			quantity, err := export.ParseAmount(values[1])
+			if err != nil {
+				return "", fmt.Errorf("quantità non valida")
+			}
+			article, err := m.articleRepo.FindByCode(ctx, code)
+			if errors.Is(err, domain.ErrArticleNotFound) {
+				article, err = m.articleRepo.FindByBarcode(ctx, values[0])
+			}
+			if err != nil {
+				return "", err
+			}
+			if err := document.AddLine(article, quantity); err != nil {
+				return "", err
+			}
+			return "", m.priceSalesDocument(ctx, customer, document)
+		})
+
+	case "discount":
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			percent, err := export.ParseAmount(values[0])
+			if err != nil {
+				return "", fmt.Errorf("sconto non valido")
+			}
+			if _, err := m.authorizationUC.ApplyLineDiscount(ctx, m.operator, document, index, percent); err != nil {
+				return "", err
+			}
+			return "Sconto applicato", nil
+		})
+
+	case "coupon":
+		code := strings.ToUpper(values[0])
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			document.CouponCode = code
+			return "", m.priceSalesDocument(ctx, customer, document)
+		})
+
+	case "shipping":
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			amount, err := export.ParseAmount(values[0])
+			if err != nil {
+				return "", fmt.Errorf("importo non valido")
+			}
+			if err := document.SetShippingCharge(amount); err != nil {
+				return "", err
+			}
+			return "", m.priceSalesDocument(ctx, customer, document)
+		})
+
+	case "voucher":
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			voucher, err := m.voucherUC.ApplyScannedVoucher(ctx, document, values[0])
+			if err != nil {
+				return "", err
+			}
+			return "Buono " + voucher.Code + " applicato", nil
+		})
+
+	case "pin":
+		username, pin := values[0], values[1]
+		return m.runDocumentCommand(func(ctx context.Context, document *domain.Document) (string, error) {
+			if err := m.marginUC.ApproveDocumentWithPIN(ctx, document, username, pin, ""); err != nil {
+				return "", err
+			}
+			return "Righe approvate: enter per registrare", nil
+		})
+	}
+
+	return nil
+}
+
+// priceSalesDocument ricalcola le righe e le promozioni di carrello per il cliente del documento
+func (m *AppModel) priceSalesDocument(ctx context.Context, customer *domain.Customer, document *domain.Document) error {
+	if customer == nil {
+		return nil
+	}
+
+	_, err := m.discountUC.PriceDocument(ctx, customer, document)
+	return err
+}
+
+// runDocumentCommand esegue fn su una copia della bozza; la copia prende il posto dell'originale se fn
+// riesce, o se la registrazione lascia la bozza in attesa di approvazione
+func (m *AppModel) runDocumentCommand(fn func(ctx context.Context, document *domain.Document) (string, error)) tea.Cmd {
+	m.salesDocumentView.loading = true
+	document := cloneDocument(m.salesDocumentView.document)
+
+	return func() tea.Msg {
+		message, err := fn(context.Background(), document)
+		return salesDocumentMsg{document: document, message: message, err: err}
+	}
+}
+
+func (m *AppModel) handleSalesDocument(msg salesDocumentMsg) (*AppModel, tea.Cmd) {
+	sv := m.salesDocumentView
+	sv.loading = false
+
+	// un documento registrato non si riapre, anche se un passo successivo alla registrazione è fallito
+	if msg.document != nil && msg.document.IsPosted() {
+		m.salesDocumentView = newSalesDocumentView(m.operator, sv.customer)
+		if msg.err != nil {
+			m.setError(msg.document.Number + " registrato con errori: " + msg.err.Error())
+		} else {
+			m.setMessage(msg.message)
+		}
+		return m, nil
+	}
+
+	if msg.err != nil {
+		if errors.Is(msg.err, domain.ErrMarginApprovalRequired) {
+			sv.document = msg.document
+			m.setError("Righe sotto soglia di margine: serve l'approvazione (p: PIN supervisore, o dalla coda Approvazioni)")
+			return m, nil
+		}
+		m.setError(salesDocumentError(msg.err))
+		return m, nil
+	}
+
+	if msg.customer != nil {
+		sv.customer = msg.customer
+	}
+
+	sv.document = msg.document
+	if sv.selectedIndex >= len(sv.document.Lines) {
+		sv.selectedIndex = len(sv.document.Lines) - 1
+	}
+	if sv.selectedIndex < 0 {
+		sv.selectedIndex = 0
+	}
+	if msg.message != "" {
+		m.setMessage(msg.message)
+	} else {
+		m.clearMessages()
+	}
+	return m, nil
+}
+
+func salesDocumentError(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrCustomerNotFound):
+		return "Cliente non trovato"
+	case errors.Is(err, domain.ErrArticleNotFound):
+		return "Articolo non trovato"
+	case errors.Is(err, domain.ErrCouponNotFound):
+		return "Coupon non trovato"
+	case errors.Is(err, domain.ErrCouponAlreadyUsed), errors.Is(err, domain.ErrCouponExpired),
+		errors.Is(err, domain.ErrCouponNotActive), errors.Is(err, domain.ErrCouponWrongOwner):
+		return "Coupon non utilizzabile: " + err.Error()
+	case errors.Is(err, domain.ErrDiscountNotAuthorized):
+		return "Sconto oltre il limite autorizzato: " + err.Error()
+	case errors.Is(err, domain.ErrInvalidSupervisorPIN):
+		return "Supervisore o PIN non validi"
+	case errors.Is(err, domain.ErrOperatorLocked), errors.Is(err, domain.ErrLoginThrottled):
+		return "Supervisore bloccato per troppi tentativi, riprovare più tardi"
+	case errors.Is(err, domain.ErrInsufficientPermissions):
+		return "Il supervisore non è abilitato alle approvazioni"
+	case errors.Is(err, domain.ErrVoucherNotApplicable), errors.Is(err, domain.ErrVoucherExpired),
+		errors.Is(err, domain.ErrVoucherUsed), errors.Is(err, domain.ErrVoucherCancelled),
+		errors.Is(err, domain.ErrInsufficientBalance), errors.Is(err, domain.ErrVoucherNotFound),
+		errors.Is(err, domain.ErrInvalidVoucherCode):
+		return "Buono non utilizzabile: " + err.Error()
+	default:
+		return "Operazione non riuscita: " + err.Error()
+	}
+}
@@ -0,0 +1,350 @@
+// internal/ui/view_two_factor.go
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/usecase"
+)
+
+// renderTOTPEnrollment mostra il QR da inquadrare e il segreto per l'inserimento manuale
+func renderTOTPEnrollment(enrollment *usecase.TOTPEnrollment, code string) string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		"Inquadra il QR con l'app di autenticazione (Google Authenticator, Aegis, ...):",
+		"",
+		enrollment.QR,
+		InfoStyle.Render("Oppure inserisci a mano il segreto: "+enrollment.Secret),
+		"",
+		"Codice a 6 cifre:",
+		InputFocusedStyle.Render(code+"█"),
+	)
+}
+
+func renderRecoveryCodes(codes []string) string {
+	lines := []string{
+		WarningStyle.Render("Codici di recupero: conservali in un luogo sicuro, non verranno più mostrati."),
+		"Ogni codice vale una sola volta al posto del codice dell'app.",
+		"",
+	}
+	for i := 0; i < len(codes); i += 2 {
+		row := "  " + codes[i]
+		if i+1 < len(codes) {
+			row += "    " + codes[i+1]
+		}
+		lines = append(lines, row)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// editCode gestisce la digitazione di un codice TOTP o di recupero
+func editCode(code string, msg tea.KeyMsg) string {
+	switch msg.String() {
+	case "backspace":
+		if len(code) > 0 {
+			return code[:len(code)-1]
+		}
+	default:
+		if len(msg.String()) == 1 && len(code) < 16 {
+			return code + strings.ToUpper(msg.String())
+		}
+	}
+	return code
+}
+
+func (m *AppModel) viewLoginSecondFactor() string {
+	lv := m.loginView
+
+	title := "🔐 Verifica in due passaggi"
+	var body string
+	switch lv.step {
+	case loginStepChangePassword:
+		title = "🔑 Cambio password"
+		reason := "La password è scaduta: scegline una nuova."
+		if lv.pending.Operator.MustChangePassword {
+			reason = "Al primo accesso o dopo un reset la password va cambiata."
+		}
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			WarningStyle.Render(reason),
+			"",
+			m.renderPasswordForm(lv.passwordForm),
+		)
+	case loginStepEnroll:
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			WarningStyle.Render("Il tuo profilo richiede l'autenticazione a due fattori."),
+			"",
+			renderTOTPEnrollment(lv.enrollment, lv.code),
+		)
+	case loginStepRecoveryCodes:
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			SuccessStyle.Render("✓ Autenticazione a due fattori attivata"),
+			"",
+			renderRecoveryCodes(lv.recoveryCodes),
+			"",
+			ButtonStyle.Render("[ Continua ]"),
+		)
+	default:
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			"Inserisci il codice dell'app di autenticazione",
+			"o un codice di recupero:",
+			"",
+			InputFocusedStyle.Render(lv.code+"█"),
+			"",
+			ButtonStyle.Render("[ Verifica ]"),
+		)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		TitleStyle.Render(title),
+		"",
+		body,
+	)
+
+	if lv.error != "" {
+		content = lipgloss.JoinVertical(
+			lipgloss.Center,
+			content,
+			"",
+			ErrorStyle.Render("❌ "+lv.error),
+		)
+	}
+
+	return lipgloss.Place(
+		m.width,
+		m.height-6,
+		lipgloss.Center,
+		lipgloss.Center,
+		ContentStyle.Render(content),
+	)
+}
+
+func (m *AppModel) updateLoginSecondFactor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	lv := m.loginView
+
+	if lv.step == loginStepChangePassword {
+		return m.updateLoginPasswordChange(keyMsg)
+	}
+
+	if lv.step == loginStepRecoveryCodes {
+		if keyMsg.String() == "enter" {
+			if lv.pending.PasswordChange {
+				lv.step = loginStepChangePassword
+				lv.passwordForm = &PasswordForm{}
+				return m, nil
+			}
+			operator := lv.pending.Operator
+			return m, func() tea.Msg {
+				return m.completeLogin(context.Background(), operator)
+			}
+		}
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		m.loginView = &LoginView{username: lv.username, focusIndex: 1}
+		return m, nil
+
+	case "enter":
+		if lv.code == "" {
+			return m, nil
+		}
+		pending, enrollment, code := lv.pending, lv.enrollment, lv.code
+
+		if lv.step == loginStepEnroll {
+			return m, func() tea.Msg {
+				codes, err := m.loginUC.ConfirmTOTPEnrollment(context.Background(), pending.Operator, enrollment, code)
+				if err != nil {
+					return loginResultMsg{err: loginError(pending, err)}
+				}
+				return loginResultMsg{recoveryCodes: codes}
+			}
+		}
+
+		return m, func() tea.Msg {
+			ctx := context.Background()
+			if err := m.loginUC.VerifySecondFactor(ctx, pending, code, m.terminal); err != nil {
+				return loginResultMsg{err: loginError(pending, err)}
+			}
+			if pending.PasswordChange {
+				return loginResultMsg{pending: pending, passwordChange: true}
+			}
+			return m.completeLogin(ctx, pending.Operator)
+		}
+
+	default:
+		lv.code = editCode(lv.code, keyMsg)
+		return m, nil
+	}
+}
+
+func (m *AppModel) viewTwoFactor() string {
+	tv := m.twoFactorView
+	op := m.operator
+
+	sections := []string{TitleStyle.Render("🔐 Sicurezza Account")}
+
+	switch {
+	case tv.enrollment != nil:
+		sections = append(sections, renderTOTPEnrollment(tv.enrollment, tv.input))
+
+	case tv.recoveryCodes != nil:
+		sections = append(sections, renderRecoveryCodes(tv.recoveryCodes))
+
+	default:
+		status := BadgeWarningStyle.Render("NON ATTIVA")
+		if op.TOTPEnabled {
+			status = BadgeSuccessStyle.Render("ATTIVA")
+		}
+		sections = append(sections, "Autenticazione a due fattori: "+status)
+
+		if m.loginUC.TwoFactorMandatory(op) {
+			sections = append(sections, InfoStyle.Render("Obbligatoria per il profilo "+string(op.Profile)))
+		}
+		if op.TOTPEnabled {
+			sections = append(sections, fmt.Sprintf("Codici di recupero disponibili: %d", len(op.RecoveryCodeHashes)))
+		}
+		if tv.disabling {
+			sections = append(sections, "", "Codice per confermare la disattivazione:", InputFocusedStyle.Render(tv.input+"█"))
+		}
+	}
+
+	if tv.loading {
+		sections = append(sections, "", InfoStyle.Render("Attendere..."))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+func (m *AppModel) updateTwoFactor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	tv := m.twoFactorView
+	op := m.operator
+
+	if tv.enrollment != nil || tv.disabling {
+		switch keyMsg.String() {
+		case "esc":
+			tv.enrollment = nil
+			tv.disabling = false
+			tv.input = ""
+			return m, nil
+
+		case "enter":
+			if tv.input == "" || tv.loading {
+				return m, nil
+			}
+			tv.loading = true
+			enrollment, code := tv.enrollment, tv.input
+			tv.input = ""
+
+			if enrollment != nil {
+				return m, func() tea.Msg {
+					codes, err := m.loginUC.ConfirmTOTPEnrollment(context.Background(), op, enrollment, code)
+					return twoFactorMsg{recoveryCodes: codes, message: "Autenticazione a due fattori attivata", err: err}
+				}
+			}
+			return m, func() tea.Msg {
+				err := m.loginUC.DisableTOTP(context.Background(), op, code)
+				return twoFactorMsg{message: "Autenticazione a due fattori disattivata", err: err}
+			}
+
+		default:
+			tv.input = editCode(tv.input, keyMsg)
+			return m, nil
+		}
+	}
+
+	if tv.loading {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "a":
+		tv.recoveryCodes = nil
+		tv.loading = true
+		return m, func() tea.Msg {
+			enrollment, err := m.loginUC.BeginTOTPEnrollment(context.Background(), op)
+			return twoFactorMsg{enrollment: enrollment, err: err}
+		}
+
+	case "r":
+		if !op.TOTPEnabled {
+			m.setError("Attiva prima l'autenticazione a due fattori")
+			return m, nil
+		}
+		tv.loading = true
+		return m, func() tea.Msg {
+			codes, err := m.loginUC.RegenerateRecoveryCodes(context.Background(), op)
+			return twoFactorMsg{recoveryCodes: codes, message: "Nuovi codici di recupero generati", err: err}
+		}
+
+	case "d":
+		if !op.TOTPEnabled {
+			return m, nil
+		}
+		if m.loginUC.TwoFactorMandatory(op) {
+			m.setError("Il 2FA è obbligatorio per il tuo profilo")
+			return m, nil
+		}
+		tv.recoveryCodes = nil
+		tv.disabling = true
+		return m, nil
+
+	case "esc":
+		tv.recoveryCodes = nil
+		m.currentView = ViewMainMenu
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *AppModel) handleTwoFactor(msg twoFactorMsg) (*AppModel, tea.Cmd) {
+	tv := m.twoFactorView
+	tv.loading = false
+
+	if msg.err != nil {
+		switch {
+		case errors.Is(msg.err, domain.ErrInvalidTOTPCode):
+			m.setError("Codice non valido")
+		case errors.Is(msg.err, domain.ErrInsufficientPermissions):
+			m.setError("Il 2FA è obbligatorio per il tuo profilo")
+		default:
+			m.setError("Errore 2FA: " + msg.err.Error())
+		}
+		return m, nil
+	}
+
+	if msg.enrollment != nil {
+		tv.enrollment = msg.enrollment
+		tv.input = ""
+		m.clearMessages()
+		return m, nil
+	}
+
+	tv.enrollment = nil
+	tv.disabling = false
+	tv.recoveryCodes = msg.recoveryCodes
+	m.setMessage(msg.message)
+	return m, nil
+}
@@ -0,0 +1,192 @@
+// internal/ui/view_voucher_report.go
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/usecase"
+)
+
+const voucherReportDefaultDays = 30
+
+func newVoucherReportView() *VoucherReportView {
+	return &VoucherReportView{
+		asOf: time.Now(),
+		days: voucherReportDefaultDays,
+	}
+}
+
+func (m *AppModel) loadVoucherReport() tea.Cmd {
+	vrv := m.voucherReportView
+	asOf, days := vrv.asOf, vrv.days
+	return func() tea.Msg {
+		report, err := m.voucherUC.LiabilityReport(context.Background(), asOf, days)
+		return voucherReportLoadedMsg{report: report, err: err}
+	}
+}
+
+func (m *AppModel) handleVoucherReportLoaded(msg voucherReportLoadedMsg) (*AppModel, tea.Cmd) {
+	vrv := m.voucherReportView
+	vrv.loading = false
+
+	if msg.err != nil {
+		m.setError("Errore prospetto buoni: " + msg.err.Error())
+		return m, nil
+	}
+
+	vrv.report = msg.report
+	return m, nil
+}
+
+// monthEnd restituisce l'ultimo giorno del mese spostato di delta mesi
+func monthEnd(t time.Time, delta int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return first.AddDate(0, delta+1, -1)
+}
+
+func (m *AppModel) viewVoucherReport() string {
+	vrv := m.voucherReportView
+
+	sections := []string{
+		TitleStyle.Render("📒 Residui Buoni Credito"),
+		SubtitleStyle.Render(fmt.Sprintf("Situazione al %s • scadenze nei prossimi %d giorni",
+			vrv.asOf.Format("02/01/2006"), vrv.days)),
+	}
+
+	switch {
+	case vrv.loading:
+		sections = append(sections, InfoStyle.Render("⏳ Caricamento in corso..."))
+	case vrv.report == nil:
+		sections = append(sections, InfoStyle.Render("Nessun dato"))
+	default:
+		report := vrv.report
+		sections = append(sections,
+			ContentStyle.Render(renderVoucherLiability(report)),
+			CardStyle.Render(renderVoucherLines(
+				fmt.Sprintf("In scadenza entro %d giorni: € %.2f", report.ExpiringDays, report.ExpiringTotal),
+				report.Expiring)),
+			CardStyle.Render(renderVoucherLines(
+				fmt.Sprintf("Scaduti non usati dal %s: € %.2f", report.ExpiredFrom.Format("02/01/2006"), report.ExpiredTotal),
+				report.Expired)),
+		)
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, sections...)
+
+	availableHeight := m.height - 6
+
+	return lipgloss.Place(
+		m.width,
+		availableHeight,
+		lipgloss.Left,
+		lipgloss.Top,
+		lipgloss.NewStyle().Padding(1, 2).Render(content),
+	)
+}
+
+func renderVoucherLiability(report *usecase.VoucherLiabilityReport) string {
+	if len(report.Rows) == 0 {
+		return InfoStyle.Render("Nessun buono con residuo alla data")
+	}
+
+	header := fmt.Sprintf("%-10s %-24s", "Cliente", "Ragione sociale")
+	for _, bucket := range domain.VoucherAgeBuckets {
+		header += fmt.Sprintf(" %10s", bucket.Label)
+	}
+	header += fmt.Sprintf(" %11s", "Totale")
+
+	lines := []string{TableHeaderStyle.Render(header)}
+	for _, row := range report.Rows {
+		line := fmt.Sprintf("%-10s %-24s", truncateString(row.CustomerCode, 10), truncateString(row.CustomerName, 24))
+		for _, amount := range row.Buckets {
+			line += fmt.Sprintf(" %10.2f", amount)
+		}
+		line += fmt.Sprintf(" %11.2f", row.Total)
+		lines = append(lines, UnselectedItemStyle.Render(line))
+	}
+
+	total := fmt.Sprintf("%-35s", "Totale")
+	for _, amount := range report.BucketTotals {
+		total += fmt.Sprintf(" %10.2f", amount)
+	}
+	total += fmt.Sprintf(" %11.2f", report.Total)
+	lines = append(lines, SelectedItemStyle.Render(total))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func renderVoucherLines(title string, vouchers []usecase.VoucherReportLine) string {
+	lines := []string{SubtitleStyle.Render(title)}
+	for _, line := range vouchers {
+		lines = append(lines, fmt.Sprintf("%-16s %-10s %-24s %-10s %10.2f",
+			line.Code, truncateString(line.CustomerCode, 10), truncateString(line.CustomerName, 24),
+			line.ExpiryDate.Format("02/01/2006"), line.Amount))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (m *AppModel) updateVoucherReport(msg tea.Msg) (tea.Model, tea.Cmd) {
+	vrv := m.voucherReportView
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "left", "h":
+		vrv.asOf = monthEnd(vrv.asOf, -1)
+		vrv.loading = true
+		return m, m.loadVoucherReport()
+
+	case "right", "l":
+		next := monthEnd(vrv.asOf, 1)
+		if now := time.Now(); next.After(now) {
+			next = now
+		}
+		vrv.asOf = next
+		vrv.loading = true
+		return m, m.loadVoucherReport()
+
+	case "t":
+		vrv.asOf = time.Now()
+		vrv.loading = true
+		return m, m.loadVoucherReport()
+
+	case "+":
+		vrv.days += 15
+		vrv.loading = true
+		return m, m.loadVoucherReport()
+
+	case "-":
+		if vrv.days > 15 {
+			vrv.days -= 15
+			vrv.loading = true
+			return m, m.loadVoucherReport()
+		}
+		return m, nil
+
+	case "e", "E":
+		if vrv.report == nil {
+			return m, nil
+		}
+		report := vrv.report
+		format := usecase.ReportFormatCSV
+		if keyMsg.String() == "E" {
+			format = usecase.ReportFormatXLSX
+		}
+		return m, func() tea.Msg {
+			path, err := m.voucherUC.ExportLiabilityReport(report, format, exportDir)
+			return exportDoneMsg{path: path, err: err}
+		}
+	}
+
+	return m, nil
+}
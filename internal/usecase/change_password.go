@@ -0,0 +1,50 @@
+// internal/usecase/change_password.go
+
+package usecase
+
+import (
+	"context"
+
+	"ricambi-manager/internal/domain"
+)
+
+func (uc *LoginUseCase) PasswordPolicy() domain.PasswordPolicy {
+	return uc.passwordPolicy
+}
+
+// ChangePassword sostituisce la password dell'operatore secondo la policy, anche quando il cambio
+// è imposto al login per scadenza o dopo un reset
+func (uc *LoginUseCase) ChangePassword(ctx context.Context, operator *domain.Operator, oldPassword, newPassword string) error {
+	if err := operator.ChangePassword(oldPassword, newPassword, uc.passwordPolicy); err != nil {
+		return err
+	}
+
+	if err := uc.operatorRepo.UpdatePassword(ctx, operator); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "change_password", "auth", operator.ID.Hex(), "", "")
+}
+
+// ResetPassword assegna una password temporanea e sblocca l'account; l'operatore dovrà cambiarla
+// al prossimo accesso
+func (uc *LoginUseCase) ResetPassword(ctx context.Context, admin, operator *domain.Operator, newPassword string) error {
+	if !admin.IsAdmin() {
+		return domain.ErrInsufficientPermissions
+	}
+
+	if err := operator.ResetPassword(newPassword, uc.passwordPolicy); err != nil {
+		return err
+	}
+
+	if err := uc.operatorRepo.UpdatePassword(ctx, operator); err != nil {
+		return err
+	}
+	if err := uc.operatorRepo.Unlock(ctx, operator.ID); err != nil {
+		return err
+	}
+	// senza azzerare il limitatore l'operatore resterebbe rallentato fino alla fine della finestra
+	uc.userLimiter.Reset("user:" + operator.Username)
+
+	return uc.audit.LogAction(ctx, operator, "reset_password", "auth", operator.ID.Hex(), "reset by "+admin.Username, "")
+}
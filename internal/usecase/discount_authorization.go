@@ -0,0 +1,228 @@
+// internal/usecase/discount_authorization.go
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+// DiscountAuthorizationUseCase è l'unico punto che decide se un operatore può concedere uno sconto:
+// prezzi, documenti e PermissionChecker passano tutti da qui
+type DiscountAuthorizationUseCase struct {
+	limitRepo    *repository.DiscountLimitRepository
+	operatorRepo *repository.OperatorRepository
+	defaults     domain.DiscountLimits
+	audit        *auth.AuditLogger
+}
+
+func NewDiscountAuthorizationUseCase(
+	limitRepo *repository.DiscountLimitRepository,
+	operatorRepo *repository.OperatorRepository,
+	defaults domain.DiscountLimits,
+	audit *auth.AuditLogger,
+) *DiscountAuthorizationUseCase {
+	return &DiscountAuthorizationUseCase{
+		limitRepo:    limitRepo,
+		operatorRepo: operatorRepo,
+		defaults:     defaults,
+		audit:        audit,
+	}
+}
+
+// LimitsFor unisce i limiti salvati per l'operatore e il suo profilo con i default di configurazione
+func (uc *DiscountAuthorizationUseCase) LimitsFor(ctx context.Context, operator *domain.Operator) (domain.DiscountLimits, error) {
+	stored, err := uc.limitRepo.FindForOperator(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(stored, uc.defaults...), nil
+}
+
+// Authorize verifica uno sconto manuale; article può essere nil e unitPrice 0 quando non sono noti
+func (uc *DiscountAuthorizationUseCase) Authorize(
+	ctx context.Context,
+	operator *domain.Operator,
+	article *domain.Article,
+	discountPercent float64,
+	unitPrice float64,
+) (*domain.DiscountAuthorization, error) {
+	limits, err := uc.LimitsFor(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	request := domain.DiscountRequest{DiscountPercent: discountPercent, UnitPrice: unitPrice}
+	if article != nil {
+		request.Family = article.Family
+		request.Brand = article.Brand
+		request.UnitCost = article.Pricing.LastPurchaseCost
+	}
+
+	return limits.Authorize(operator, request), nil
+}
+
+// ApplyLineDiscount concede lo sconto manuale sulla riga solo se rientra nei limiti dell'operatore;
+// altrimenti la riga resta com'era. Il documento va poi salvato dal chiamante
+func (uc *DiscountAuthorizationUseCase) ApplyLineDiscount(
+	ctx context.Context,
+	operator *domain.Operator,
+	document *domain.Document,
+	index int,
+	discountPercent float64,
+) (*domain.DiscountAuthorization, error) {
+	if index < 0 || index >= len(document.Lines) {
+		return nil, domain.ErrInvalidDocumentLine
+	}
+
+	limits, err := uc.LimitsFor(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := document.Lines[index].ManualDiscount
+	previousBy := document.Lines[index].ManualDiscountBy
+
+	if err := document.SetLineDiscount(index, discountPercent, operator.Username); err != nil {
+		return nil, err
+	}
+
+	auth := limits.Authorize(operator, lineDiscountRequest(document.Lines[index]))
+	if !auth.Allowed {
+		if err := document.SetLineDiscount(index, previous, previousBy); err != nil {
+			return nil, err
+		}
+		return auth, auth.Err()
+	}
+
+	line := document.Lines[index]
+	if err := uc.persistAudit(ctx, operator, "apply_line_discount", "documents", document.ID.Hex(),
+		fmt.Sprintf("%s: %.2f%%, price %.2f", line.ArticleCode, discountPercent, line.UnitPrice)); err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// AuthorizeDocument ricontrolla gli sconti manuali del documento prima della registrazione:
+// un nuovo calcolo prezzi può aver abbassato il margine sotto il minimo
+func (uc *DiscountAuthorizationUseCase) AuthorizeDocument(
+	ctx context.Context,
+	operator *domain.Operator,
+	document *domain.Document,
+) error {
+	limits, err := uc.LimitsFor(ctx, operator)
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+	for _, line := range document.Lines {
+		if line.ManualDiscount == 0 {
+			continue
+		}
+		if auth := limits.Authorize(operator, lineDiscountRequest(line)); !auth.Allowed {
+			denied = append(denied, line.ArticleCode+" "+auth.Reason)
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrDiscountNotAuthorized, strings.Join(denied, "; "))
+	}
+
+	return nil
+}
+
+// lineDiscountRequest valuta il margine sul prezzo effettivo, comprensivo delle rettifiche di documento
+func lineDiscountRequest(line domain.DocumentLine) domain.DiscountRequest {
+	unitPrice := line.UnitPrice
+	if line.Quantity > 0 {
+		unitPrice = line.Total / line.Quantity
+	}
+
+	return domain.DiscountRequest{
+		Family:          line.Family,
+		Brand:           line.Brand,
+		DiscountPercent: line.ManualDiscount,
+		UnitPrice:       unitPrice,
+		UnitCost:        line.UnitCost,
+	}
+}
+
+func (uc *DiscountAuthorizationUseCase) GetLimits(ctx context.Context) (domain.DiscountLimits, error) {
+	return uc.limitRepo.FindAll(ctx)
+}
+
+func (uc *DiscountAuthorizationUseCase) GetDefaultLimits() domain.DiscountLimits {
+	return uc.defaults
+}
+
+func (uc *DiscountAuthorizationUseCase) SaveLimit(ctx context.Context, admin *domain.Operator, limit *domain.DiscountLimit) error {
+	if !admin.IsAdmin() {
+		return domain.ErrInsufficientPermissions
+	}
+
+	limit.Username = strings.TrimSpace(limit.Username)
+	limit.Family = strings.TrimSpace(limit.Family)
+	limit.Brand = strings.TrimSpace(limit.Brand)
+
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+
+	if limit.Username != "" && limit.OperatorID.IsZero() {
+		operator, err := uc.operatorRepo.FindByUsername(ctx, limit.Username)
+		if err != nil {
+			return err
+		}
+		limit.OperatorID = operator.ID
+	}
+
+	limit.UpdatedAt = time.Now()
+	limit.UpdatedBy = admin.Username
+
+	if err := uc.limitRepo.Save(ctx, limit); err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("%s: max %.2f%%", limit.Scope(), limit.MaxDiscountPercent)
+	if limit.MinMarginPercent != nil {
+		details += fmt.Sprintf(", min margin %.2f%%", *limit.MinMarginPercent)
+	}
+
+	return uc.persistAudit(ctx, admin, "save_discount_limit", "discount_limits", limit.ID.Hex(), details)
+}
+
+func (uc *DiscountAuthorizationUseCase) DeleteLimit(ctx context.Context, admin *domain.Operator, id primitive.ObjectID) error {
+	if !admin.IsAdmin() {
+		return domain.ErrInsufficientPermissions
+	}
+
+	limit, err := uc.limitRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.limitRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return uc.persistAudit(ctx, admin, "delete_discount_limit", "discount_limits", id.Hex(), limit.Scope())
+}
+
+func (uc *DiscountAuthorizationUseCase) persistAudit(
+	ctx context.Context,
+	operator *domain.Operator,
+	action, area, resourceID, details string,
+) error {
+	return uc.audit.LogAction(ctx, operator, action, area, resourceID, details, "")
+}
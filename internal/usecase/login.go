@@ -0,0 +1,235 @@
+// internal/usecase/login.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+// LoginResult accompagna l'esito del login: con ErrOperatorLocked riporta la fine del blocco
+// (zero se manuale), con ErrLoginThrottled l'attesa prima di poter riprovare. Con SecondFactor la
+// password è corretta ma il login si completa solo con VerifySecondFactor, o con l'attivazione
+// del 2FA se Enroll. Con PasswordChange la password è scaduta o da cambiare al primo accesso e va
+// sostituita con ChangePassword prima di aprire la sessione
+type LoginResult struct {
+	Operator       *domain.Operator
+	LockedUntil    time.Time
+	RetryAfter     time.Duration
+	SecondFactor   bool
+	Enroll         bool
+	PasswordChange bool
+}
+
+// LoginUseCase verifica le credenziali con due difese: il RateLimiter rallenta i tentativi per username
+// e per terminale nel processo, il conteggio su Mongo blocca l'account per tutte le istanze
+type LoginUseCase struct {
+	operatorRepo      *repository.OperatorRepository
+	audit             *auth.AuditLogger
+	userLimiter       *auth.RateLimiter
+	terminalLimiter   *auth.RateLimiter
+	maxFailedAttempts int
+	lockoutDuration   time.Duration
+	twoFactorProfiles []domain.ProfileType
+	issuer            string
+	passwordPolicy    domain.PasswordPolicy
+}
+
+func NewLoginUseCase(
+	operatorRepo *repository.OperatorRepository,
+	audit *auth.AuditLogger,
+	maxFailedAttempts int,
+	terminalMaxAttempts int,
+	lockoutMinutes int,
+	twoFactorProfiles []domain.ProfileType,
+	issuer string,
+	passwordPolicy domain.PasswordPolicy,
+) *LoginUseCase {
+	return &LoginUseCase{
+		operatorRepo:      operatorRepo,
+		audit:             audit,
+		userLimiter:       auth.NewRateLimiter(maxFailedAttempts, lockoutMinutes),
+		terminalLimiter:   auth.NewRateLimiter(terminalMaxAttempts, lockoutMinutes),
+		maxFailedAttempts: maxFailedAttempts,
+		lockoutDuration:   time.Duration(lockoutMinutes) * time.Minute,
+		twoFactorProfiles: twoFactorProfiles,
+		issuer:            issuer,
+		passwordPolicy:    passwordPolicy,
+	}
+}
+
+// Authenticate restituisce sempre un LoginResult; l'errore è ErrInvalidCredentials anche per username
+// inesistenti, così il messaggio non rivela quali account esistono
+func (uc *LoginUseCase) Authenticate(ctx context.Context, username, password, terminal string) (*LoginResult, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	userKey := "user:" + username
+	terminalKey := "terminal:" + terminal
+	result := &LoginResult{}
+
+	if blocked, wait := uc.userLimiter.Blocked(userKey); blocked {
+		result.RetryAfter = wait
+		uc.audit.LogFailedAccess(username, "login", "auth", "throttled username", terminal)
+		return result, domain.ErrLoginThrottled
+	}
+	if blocked, wait := uc.terminalLimiter.Blocked(terminalKey); blocked {
+		result.RetryAfter = wait
+		uc.audit.LogFailedAccess(username, "login", "auth", "throttled terminal", terminal)
+		return result, domain.ErrLoginThrottled
+	}
+
+	operator, err := uc.operatorRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, domain.ErrOperatorNotFound) {
+			return result, err
+		}
+		uc.recordFailure(userKey, terminalKey)
+		uc.audit.LogFailedAccess(username, "login", "auth", "unknown username", terminal)
+		return result, domain.ErrInvalidCredentials
+	}
+	result.Operator = operator
+
+	now := time.Now()
+	if operator.IsLocked && !operator.IsLockedAt(now) {
+		// il blocco per tentativi falliti è scaduto: si riparte da zero tentativi
+		if err := uc.operatorRepo.Unlock(ctx, operator.ID); err != nil {
+			return result, err
+		}
+		operator.Unlock()
+	}
+
+	err = operator.CheckPassword(password)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrOperatorLocked):
+		result.LockedUntil = operator.LockedUntil
+		uc.audit.LogFailedAccess(username, "login", "auth", "account locked", terminal)
+		return result, err
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		uc.recordFailure(userKey, terminalKey)
+		return result, uc.failedPassword(ctx, result, terminal, "wrong password")
+	default:
+		uc.audit.LogFailedAccess(username, "login", "auth", err.Error(), terminal)
+		return result, err
+	}
+
+	result.PasswordChange = operator.PasswordChangeRequired(uc.passwordPolicy, now)
+
+	// con il secondo passo i tentativi si azzerano solo dopo il codice corretto
+	if uc.SecondFactorRequired(operator) {
+		result.SecondFactor = true
+		result.Enroll = !operator.TOTPEnabled
+		return result, nil
+	}
+
+	uc.userLimiter.Reset(userKey)
+	if operator.FailedAttempts > 0 {
+		if err := uc.operatorRepo.ResetFailedAttempts(ctx, operator.ID); err != nil {
+			return result, err
+		}
+		operator.FailedAttempts = 0
+	}
+
+	return result, nil
+}
+
+// failedPassword conta il tentativo sull'account e lo blocca al raggiungimento del limite
+func (uc *LoginUseCase) failedPassword(ctx context.Context, result *LoginResult, terminal, reason string) error {
+	operator := result.Operator
+
+	attempts, err := uc.operatorRepo.IncrementFailedAttempts(ctx, operator.ID)
+	if err != nil {
+		return err
+	}
+	operator.FailedAttempts = attempts
+
+	if uc.maxFailedAttempts <= 0 || attempts < uc.maxFailedAttempts {
+		uc.audit.LogFailedAccess(operator.Username, "login", "auth", reason, terminal)
+		return domain.ErrInvalidCredentials
+	}
+
+	// senza durata configurata il blocco resta fino allo sblocco da parte di un amministratore
+	var until time.Time
+	if uc.lockoutDuration > 0 {
+		until = time.Now().Add(uc.lockoutDuration)
+	}
+	if err := uc.operatorRepo.LockUntil(ctx, operator.ID, until); err != nil {
+		return err
+	}
+	operator.IsLocked = true
+	operator.LockedUntil = until
+	result.LockedUntil = until
+
+	uc.audit.LogFailedAccess(operator.Username, "login", "auth", reason+", account locked", terminal)
+	return domain.ErrOperatorLocked
+}
+
+// CheckSupervisorPIN verifica il PIN del supervisore con le stesse difese del login: i PIN errati contano
+// come password errate e bloccano l'account. L'operatore è restituito anche con PIN errato, se esiste
+func (uc *LoginUseCase) CheckSupervisorPIN(ctx context.Context, username, pin string) (*domain.Operator, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	userKey := "user:" + username
+
+	if blocked, _ := uc.userLimiter.Blocked(userKey); blocked {
+		uc.audit.LogFailedAccess(username, "supervisor_pin", "auth", "throttled username", "")
+		return nil, domain.ErrLoginThrottled
+	}
+
+	operator, err := uc.operatorRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, domain.ErrOperatorNotFound) {
+			return nil, err
+		}
+		uc.userLimiter.Record(userKey)
+		uc.audit.LogFailedAccess(username, "supervisor_pin", "auth", "unknown username", "")
+		return nil, domain.ErrInvalidSupervisorPIN
+	}
+
+	now := time.Now()
+	if operator.IsLocked && !operator.IsLockedAt(now) {
+		if err := uc.operatorRepo.Unlock(ctx, operator.ID); err != nil {
+			return operator, err
+		}
+		operator.Unlock()
+	}
+	if operator.IsLockedAt(now) {
+		uc.audit.LogFailedAccess(username, "supervisor_pin", "auth", "account locked", "")
+		return operator, domain.ErrOperatorLocked
+	}
+
+	if err := operator.CheckSupervisorPIN(pin); err != nil {
+		uc.userLimiter.Record(userKey)
+		result := &LoginResult{Operator: operator}
+		if err := uc.failedPassword(ctx, result, "", "wrong supervisor PIN"); err != domain.ErrInvalidCredentials {
+			return operator, err
+		}
+		return operator, domain.ErrInvalidSupervisorPIN
+	}
+
+	uc.userLimiter.Reset(userKey)
+	if operator.FailedAttempts > 0 {
+		if err := uc.operatorRepo.ResetFailedAttempts(ctx, operator.ID); err != nil {
+			return operator, err
+		}
+		operator.FailedAttempts = 0
+	}
+
+	return operator, nil
+}
+
+func (uc *LoginUseCase) recordFailure(userKey, terminalKey string) {
+	uc.userLimiter.Record(userKey)
+	uc.terminalLimiter.Record(terminalKey)
+}
+
+// Cleanup sblocca gli account con blocco scaduto e libera i contatori del RateLimiter fuori finestra
+func (uc *LoginUseCase) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	uc.userLimiter.Cleanup()
+	uc.terminalLimiter.Cleanup()
+	return uc.operatorRepo.UnlockExpired(ctx, now)
+}
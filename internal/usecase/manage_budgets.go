@@ -0,0 +1,508 @@
+// internal/usecase/manage_budgets.go
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/export"
+)
+
+type ManageBudgetsUseCase struct {
+	budgetRepo   *repository.BudgetRepository
+	documentRepo *repository.DocumentRepository
+	customerRepo *repository.CustomerRepository
+	operatorRepo *repository.OperatorRepository
+	scheme       domain.IncentiveScheme
+}
+
+func NewManageBudgetsUseCase(
+	budgetRepo *repository.BudgetRepository,
+	documentRepo *repository.DocumentRepository,
+	customerRepo *repository.CustomerRepository,
+	operatorRepo *repository.OperatorRepository,
+	scheme domain.IncentiveScheme,
+) *ManageBudgetsUseCase {
+	return &ManageBudgetsUseCase{
+		budgetRepo:   budgetRepo,
+		documentRepo: documentRepo,
+		customerRepo: customerRepo,
+		operatorRepo: operatorRepo,
+		scheme:       scheme,
+	}
+}
+
+// budgetDocumentTypes sono i documenti che alimentano i consuntivi
+var budgetDocumentTypes = []domain.DocumentType{
+	domain.DocumentTypeInvoice,
+	domain.DocumentTypeCreditNote,
+	domain.DocumentTypePurchaseInvoice,
+}
+
+// BudgetEntry è un budget con il nome dell'intestatario, per il cruscotto
+type BudgetEntry struct {
+	Budget     *domain.Budget
+	EntityName string
+	Forecast   *domain.BudgetForecast
+}
+
+type BudgetRecomputeResult struct {
+	Budgets   int
+	Documents int
+}
+
+// ApplyDocument aggiunge il documento registrato ai budget attivi alla sua data e restituisce quanti ne ha aggiornati
+func (uc *ManageBudgetsUseCase) ApplyDocument(ctx context.Context, document *domain.Document) (int, error) {
+	if !document.IsPosted() {
+		return 0, domain.ErrDocumentNotPosted
+	}
+
+	return uc.increment(ctx, document, domain.DocumentBudgetContribution(document))
+}
+
+// RevertDocument toglie dai budget l'apporto di un documento stornato
+func (uc *ManageBudgetsUseCase) RevertDocument(ctx context.Context, document *domain.Document) (int, error) {
+	return uc.increment(ctx, document, domain.DocumentBudgetContribution(document).Negate())
+}
+
+func (uc *ManageBudgetsUseCase) increment(
+	ctx context.Context,
+	document *domain.Document,
+	contribution domain.BudgetContribution,
+) (int, error) {
+	if !document.IsSale() && !document.IsPurchase() {
+		return 0, nil
+	}
+
+	budgets, err := uc.budgetRepo.FindActive(ctx, document.Date)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, budget := range budgets {
+		if !budget.AppliesTo(document) {
+			continue
+		}
+		if err := uc.budgetRepo.IncrementActuals(ctx, budget.ID, contribution); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// Recompute ricostruisce da zero i consuntivi dei budget che intersecano l'intervallo, rileggendo
+// i documenti registrati sull'intero periodo di ciascun budget
+func (uc *ManageBudgetsUseCase) Recompute(ctx context.Context, from, to time.Time) (*BudgetRecomputeResult, error) {
+	budgets, err := uc.budgetRepo.FindOverlapping(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BudgetRecomputeResult{}
+	if len(budgets) == 0 {
+		return result, nil
+	}
+
+	start, end := budgets[0].StartDate, budgets[0].EndDate
+	for _, budget := range budgets {
+		if budget.StartDate.Before(start) {
+			start = budget.StartDate
+		}
+		if budget.EndDate.After(end) {
+			end = budget.EndDate
+		}
+	}
+
+	documents, err := uc.documentRepo.FindPosted(ctx, budgetDocumentTypes, start, end)
+	if err != nil {
+		return nil, err
+	}
+	result.Documents = len(documents)
+
+	for _, budget := range budgets {
+		budget.ResetActuals()
+		for _, document := range documents {
+			if budget.AppliesTo(document) {
+				budget.ApplyContribution(domain.DocumentBudgetContribution(document))
+			}
+		}
+
+		if err := uc.budgetRepo.Update(ctx, budget); err != nil {
+			return result, err
+		}
+		result.Budgets++
+	}
+
+	return result, nil
+}
+
+// GetBudgets restituisce i budget dell'anno visibili all'operatore: chi non ha accesso alle statistiche
+// vede solo i propri budget e quello globale
+func (uc *ManageBudgetsUseCase) GetBudgets(ctx context.Context, operator *domain.Operator, year int) ([]BudgetEntry, error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	budgets, err := uc.budgetRepo.FindOverlapping(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	seeAll := canSeeAllBudgets(operator)
+
+	var customerIDs []primitive.ObjectID
+	operatorIDs := map[primitive.ObjectID]bool{}
+	visible := make([]*domain.Budget, 0, len(budgets))
+	for _, budget := range budgets {
+		if !seeAll && budget.Type != domain.BudgetTypeGlobal &&
+			!(budget.Type == domain.BudgetTypeOperator && budget.EntityID == operator.ID) {
+			continue
+		}
+		visible = append(visible, budget)
+
+		switch budget.Type {
+		case domain.BudgetTypeCustomer:
+			customerIDs = append(customerIDs, budget.EntityID)
+		case domain.BudgetTypeOperator:
+			operatorIDs[budget.EntityID] = true
+		}
+	}
+
+	names := map[primitive.ObjectID]string{}
+	if len(customerIDs) > 0 {
+		customers, err := uc.customerRepo.FindByIDs(ctx, customerIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, customer := range customers {
+			names[customer.ID] = customer.Code + " " + customer.CompanyName
+		}
+	}
+	for id := range operatorIDs {
+		op, err := uc.operatorRepo.FindByID(ctx, id)
+		if err == nil {
+			names[id] = op.FullName
+		} else if err != domain.ErrOperatorNotFound {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	seasonality, err := uc.seasonality(ctx, visible, now)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]BudgetEntry, 0, len(visible))
+	for _, budget := range visible {
+		uc.applyScheme(budget)
+		name := names[budget.EntityID]
+		switch {
+		case budget.Type == domain.BudgetTypeGlobal:
+			name = "Globale"
+		case name == "":
+			// i fornitori non hanno un'anagrafica consultabile: il codice compare nei documenti del dettaglio
+			name = budget.EntityID.Hex()
+		}
+		entries = append(entries, BudgetEntry{
+			Budget:     budget,
+			EntityName: name,
+			Forecast:   budget.Forecast(now, seasonality[budget.ID]),
+		})
+	}
+
+	order := map[domain.BudgetType]int{
+		domain.BudgetTypeGlobal:   0,
+		domain.BudgetTypeOperator: 1,
+		domain.BudgetTypeCustomer: 2,
+		domain.BudgetTypeSupplier: 3,
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Budget, entries[j].Budget
+		if order[a.Type] != order[b.Type] {
+			return order[a.Type] < order[b.Type]
+		}
+		if a.Quarter != b.Quarter {
+			return a.Quarter < b.Quarter
+		}
+		return entries[i].EntityName < entries[j].EntityName
+	})
+
+	return entries, nil
+}
+
+// GetBudgetDocuments restituisce i documenti registrati che compongono il consuntivo del budget
+func (uc *ManageBudgetsUseCase) GetBudgetDocuments(ctx context.Context, budget *domain.Budget) ([]*domain.Document, error) {
+	documents, err := uc.documentRepo.FindPosted(ctx, budgetDocumentTypes, budget.StartDate, budget.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.Document, 0, len(documents))
+	for _, document := range documents {
+		if budget.AppliesTo(document) {
+			result = append(result, document)
+		}
+	}
+
+	return result, nil
+}
+
+// Forecast proietta il budget a fine periodo usando la stagionalità dello stesso periodo dell'anno precedente
+func (uc *ManageBudgetsUseCase) Forecast(ctx context.Context, budget *domain.Budget, now time.Time) (*domain.BudgetForecast, error) {
+	seasonality, err := uc.seasonality(ctx, []*domain.Budget{budget}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return budget.Forecast(now, seasonality[budget.ID]), nil
+}
+
+// seasonality legge una sola volta i documenti dell'anno precedente che coprono tutti i budget in corso
+func (uc *ManageBudgetsUseCase) seasonality(
+	ctx context.Context,
+	budgets []*domain.Budget,
+	now time.Time,
+) (map[primitive.ObjectID]domain.BudgetSeasonality, error) {
+	result := map[primitive.ObjectID]domain.BudgetSeasonality{}
+
+	var running []*domain.Budget
+	var start, end time.Time
+	for _, budget := range budgets {
+		if !budget.Covers(now) {
+			continue
+		}
+		last := budget.LastYear()
+		if len(running) == 0 || last.StartDate.Before(start) {
+			start = last.StartDate
+		}
+		if len(running) == 0 || last.EndDate.After(end) {
+			end = last.EndDate
+		}
+		running = append(running, last)
+	}
+
+	if len(running) == 0 {
+		return result, nil
+	}
+
+	documents, err := uc.documentRepo.FindPosted(ctx, budgetDocumentTypes, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	lastNow := now.AddDate(-1, 0, 0)
+	for _, last := range running {
+		var s domain.BudgetSeasonality
+		for _, document := range documents {
+			if !last.AppliesTo(document) {
+				continue
+			}
+			revenue := domain.DocumentBudgetContribution(document).Revenue
+			s.RevenueTotal += revenue
+			if !document.Date.After(lastNow) {
+				s.RevenueToDate += revenue
+			}
+		}
+		result[last.ID] = s
+	}
+
+	return result, nil
+}
+
+// IncentiveStatement è il prospetto incentivi di un operatore per un periodo
+type IncentiveStatement struct {
+	Budget       *domain.Budget
+	Username     string
+	OperatorName string
+	Evaluation   *domain.IncentiveEvaluation
+}
+
+// GetIncentiveStatements calcola i prospetti incentivi dei budget operatore del trimestre (0 = anno intero)
+func (uc *ManageBudgetsUseCase) GetIncentiveStatements(ctx context.Context, year, quarter int) ([]*IncentiveStatement, error) {
+	budgets, err := uc.budgetRepo.FindByType(ctx, domain.BudgetTypeOperator)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []*IncentiveStatement
+	for _, budget := range budgets {
+		if budget.Year != year || budget.Quarter != quarter {
+			continue
+		}
+		statement, err := uc.incentiveStatement(ctx, budget)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+
+	sort.Slice(statements, func(i, j int) bool { return statements[i].Username < statements[j].Username })
+
+	return statements, nil
+}
+
+// ExportIncentiveStatement esporta il prospetto del budget operatore; l'operatore può esportare solo il proprio
+func (uc *ManageBudgetsUseCase) ExportIncentiveStatement(
+	ctx context.Context,
+	requester *domain.Operator,
+	budget *domain.Budget,
+	dir string,
+) (string, error) {
+	if budget.Type != domain.BudgetTypeOperator {
+		return "", fmt.Errorf("incentive statements are available for operator budgets only")
+	}
+	if !canSeeAllBudgets(requester) && budget.EntityID != requester.ID {
+		return "", domain.ErrInsufficientPermissions
+	}
+
+	statement, err := uc.incentiveStatement(ctx, budget)
+	if err != nil {
+		return "", err
+	}
+
+	return saveIncentiveStatement(statement, dir)
+}
+
+// ExportIncentiveStatements esporta un prospetto per ogni operatore con budget nel periodo.
+// requester è nil quando l'export è lanciato da riga di comando
+func (uc *ManageBudgetsUseCase) ExportIncentiveStatements(
+	ctx context.Context,
+	requester *domain.Operator,
+	year, quarter int,
+	dir string,
+) ([]string, error) {
+	if requester != nil && !canSeeAllBudgets(requester) {
+		return nil, domain.ErrInsufficientPermissions
+	}
+
+	statements, err := uc.GetIncentiveStatements(ctx, year, quarter)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		path, err := saveIncentiveStatement(statement, dir)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func (uc *ManageBudgetsUseCase) incentiveStatement(ctx context.Context, budget *domain.Budget) (*IncentiveStatement, error) {
+	uc.applyScheme(budget)
+
+	statement := &IncentiveStatement{
+		Budget:     budget,
+		Username:   budget.EntityID.Hex(),
+		Evaluation: budget.EvaluateIncentive(),
+	}
+
+	operator, err := uc.operatorRepo.FindByID(ctx, budget.EntityID)
+	if err == nil {
+		statement.Username = operator.Username
+		statement.OperatorName = operator.FullName
+	} else if err != domain.ErrOperatorNotFound {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+func saveIncentiveStatement(statement *IncentiveStatement, dir string) (string, error) {
+	b := statement.Budget
+	e := statement.Evaluation
+
+	period := fmt.Sprintf("%d", b.Year)
+	if b.Quarter > 0 {
+		period = fmt.Sprintf("%d_Q%d", b.Year, b.Quarter)
+	}
+
+	table := export.NewTable("Voce", "Obiettivo", "Consuntivo", "Raggiungimento %", "Peso %", "Esito")
+	table.AddRow("Operatore", statement.Username, statement.OperatorName, "", "", "")
+	table.AddRow("Periodo", export.Date(b.StartDate), export.Date(b.EndDate), "", "", "")
+
+	for _, kpi := range e.KPIs {
+		label := string(kpi.KPI)
+		if kpi.KPI == domain.BudgetKPIFamily {
+			label = "famiglia " + kpi.Family
+		}
+		table.AddRow(label, export.Amount(kpi.Target), export.Amount(kpi.Actual),
+			export.Percent(kpi.Achievement), export.Percent(kpi.Weight*100), "")
+	}
+	table.AddRow("Raggiungimento ponderato", "", "", export.Percent(e.WeightedAchievement), "100,0", "")
+
+	for _, gate := range e.Gates {
+		outcome := "superata"
+		if !gate.Passed {
+			outcome = "non superata"
+		}
+		table.AddRow("Soglia "+gate.Gate.Label(), export.Percent(gate.Gate.MinAchievement), "",
+			export.Percent(gate.Achievement), "", outcome)
+	}
+
+	tier := "nessuno"
+	if e.Tier != nil {
+		tier = e.Tier.Description
+	}
+	outcome := "maturato"
+	if !e.GatesPassed {
+		outcome = "soglie non superate"
+	}
+	table.AddRow("Scaglione", tier, "", "", "", "")
+	table.AddRow("Incentivo", "", export.Amount(e.Amount), "", "", outcome)
+
+	return export.SaveCSV(dir, fmt.Sprintf("incentivi_%s_%s", statement.Username, period), table)
+}
+
+// UpdateStatuses attiva i budget iniziati e chiude quelli terminati, congelando incentivo e schema
+func (uc *ManageBudgetsUseCase) UpdateStatuses(ctx context.Context, now time.Time) (activated, closed int, err error) {
+	budgets, err := uc.budgetRepo.FindStatusChanges(ctx, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, budget := range budgets {
+		uc.applyScheme(budget)
+		if !budget.UpdateStatus(now) {
+			continue
+		}
+
+		if err := uc.budgetRepo.UpdateStatus(ctx, budget); err != nil {
+			return activated, closed, err
+		}
+
+		if budget.Status == domain.BudgetStatusClosed {
+			closed++
+		} else {
+			activated++
+		}
+	}
+
+	return activated, closed, nil
+}
+
+// applyScheme assegna lo schema di configurazione ai budget che non ne hanno uno proprio
+func (uc *ManageBudgetsUseCase) applyScheme(budget *domain.Budget) {
+	if budget.Scheme == nil {
+		scheme := uc.scheme
+		budget.Scheme = &scheme
+	}
+}
+
+func canSeeAllBudgets(operator *domain.Operator) bool {
+	return operator.IsAdmin() || operator.HasPermission(domain.AreaStatistics, domain.ActionView)
+}
@@ -0,0 +1,260 @@
+// internal/usecase/manage_coupons.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+	"ricambi-manager/pkg/export"
+)
+
+type ManageCouponsUseCase struct {
+	couponRepo    *repository.CouponRepository
+	promotionRepo *repository.PromotionRepository
+	audit         *auth.AuditLogger
+}
+
+func NewManageCouponsUseCase(
+	couponRepo *repository.CouponRepository,
+	promotionRepo *repository.PromotionRepository,
+	audit *auth.AuditLogger,
+) *ManageCouponsUseCase {
+	return &ManageCouponsUseCase{
+		couponRepo:    couponRepo,
+		promotionRepo: promotionRepo,
+		audit:         audit,
+	}
+}
+
+func (uc *ManageCouponsUseCase) CreateBatch(
+	ctx context.Context,
+	promotionID primitive.ObjectID,
+	name, prefix string,
+	quantity int,
+	usage domain.CouponUsage,
+	maxRedemptions int,
+	customerID primitive.ObjectID,
+	operator *domain.Operator,
+) (*domain.CouponBatch, []*domain.Coupon, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return nil, nil, err
+	}
+
+	promo, err := uc.promotionRepo.FindByID(ctx, promotionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batch, err := domain.NewCouponBatch(promo, name, prefix, quantity, usage, maxRedemptions, operator.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !customerID.IsZero() {
+		batch.BindToCustomer(customerID)
+	}
+
+	coupons, err := batch.GenerateCoupons()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := uc.couponRepo.CreateBatch(ctx, batch, coupons); err != nil {
+		return nil, nil, err
+	}
+
+	if !promo.Conditions.RequiresCoupon {
+		promo.Conditions.RequiresCoupon = true
+		promo.UpdatedBy = operator.Username
+		if err := uc.promotionRepo.Update(ctx, promo); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return batch, coupons, uc.audit.LogAction(
+		ctx,
+		operator,
+		"create_coupon_batch",
+		"promotions",
+		promo.ID.Hex(),
+		fmt.Sprintf("%s: %d %s coupons", promo.Code, quantity, usage),
+		"",
+	)
+}
+
+// ExportBatch salva in CSV i codici del lotto, da stampare o inviare ai clienti
+func (uc *ManageCouponsUseCase) ExportBatch(batch *domain.CouponBatch, coupons []*domain.Coupon, dir string) (string, error) {
+	table := export.NewTable("Codice", "Promozione", "Lotto", "Utilizzo", "Utilizzi massimi", "Scadenza")
+	for _, coupon := range coupons {
+		table.AddRow(
+			coupon.Code,
+			coupon.PromotionCode,
+			batch.Name,
+			string(coupon.Usage),
+			strconv.Itoa(coupon.MaxRedemptions),
+			export.Date(coupon.ValidTo),
+		)
+	}
+
+	return export.SaveCSV(dir, "coupon_"+batch.PromotionCode, table)
+}
+
+func (uc *ManageCouponsUseCase) GetCoupon(ctx context.Context, code string) (*domain.Coupon, error) {
+	return uc.couponRepo.FindByCode(ctx, code)
+}
+
+func (uc *ManageCouponsUseCase) GetBatchCoupons(ctx context.Context, batchID primitive.ObjectID) ([]*domain.Coupon, error) {
+	return uc.couponRepo.FindByBatch(ctx, batchID)
+}
+
+func (uc *ManageCouponsUseCase) GetPromotionBatches(ctx context.Context, promotionID primitive.ObjectID) ([]*domain.CouponBatch, error) {
+	return uc.couponRepo.FindBatchesByPromotion(ctx, promotionID)
+}
+
+// RedeemForDocument consuma il coupon del documento registrato, se la sua promozione è stata applicata.
+// Va chiamato nella transazione della registrazione; restituisce il coupon consumato (nil se nessuno)
+// da passare a LogRedemption dopo la conferma
+func (uc *ManageCouponsUseCase) RedeemForDocument(
+	ctx context.Context,
+	document *domain.Document,
+	operator *domain.Operator,
+) (*domain.Coupon, error) {
+	if document.CouponCode == "" {
+		return nil, nil
+	}
+	if !document.IsPosted() {
+		return nil, domain.ErrDocumentNotPosted
+	}
+
+	coupon, err := uc.couponRepo.FindByCode(ctx, document.CouponCode)
+	if err != nil {
+		// i codici statici delle promozioni non hanno utilizzi da registrare
+		if errors.Is(err, domain.ErrCouponNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	discount := document.PromotionDiscount(coupon.PromotionCode)
+	if discount == 0 {
+		return nil, nil
+	}
+
+	redemption := domain.CouponRedemption{
+		DocumentID:     document.ID,
+		DocumentNumber: document.Number,
+		CustomerID:     document.CustomerID,
+		Discount:       discount,
+		RedeemedAt:     time.Now(),
+		RedeemedBy:     operator.Username,
+	}
+
+	if err := coupon.CanBeRedeemedBy(document.CustomerID, redemption.RedeemedAt); err != nil {
+		return nil, err
+	}
+
+	if err := uc.couponRepo.Redeem(ctx, coupon, redemption); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (uc *ManageCouponsUseCase) LogRedemption(
+	ctx context.Context,
+	coupon *domain.Coupon,
+	document *domain.Document,
+	operator *domain.Operator,
+) error {
+	if coupon == nil {
+		return nil
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
+		"redeem_coupon",
+		"promotions",
+		coupon.PromotionID.Hex(),
+		fmt.Sprintf("%s on %s: %.2f", coupon.Code, document.Number, document.PromotionDiscount(coupon.PromotionCode)),
+		"",
+	)
+}
+
+// ReleaseForDocument libera il coupon del documento stornato. Come RedeemForDocument va chiamato nella
+// transazione dello storno e l'audit si registra dopo la conferma con LogRelease
+func (uc *ManageCouponsUseCase) ReleaseForDocument(
+	ctx context.Context,
+	document *domain.Document,
+) (*domain.Coupon, error) {
+	if document.CouponCode == "" {
+		return nil, nil
+	}
+
+	coupon, err := uc.couponRepo.FindByCode(ctx, document.CouponCode)
+	if err != nil {
+		if errors.Is(err, domain.ErrCouponNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := uc.couponRepo.ReleaseRedemption(ctx, coupon.ID, document.ID); err != nil {
+		if errors.Is(err, domain.ErrCouponNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (uc *ManageCouponsUseCase) LogRelease(
+	ctx context.Context,
+	coupon *domain.Coupon,
+	document *domain.Document,
+	operator *domain.Operator,
+) error {
+	if coupon == nil {
+		return nil
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
+		"release_coupon",
+		"promotions",
+		coupon.PromotionID.Hex(),
+		fmt.Sprintf("%s from %s", coupon.Code, document.Number),
+		"",
+	)
+}
+
+func (uc *ManageCouponsUseCase) DeactivateCoupon(
+	ctx context.Context,
+	code string,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	coupon, err := uc.couponRepo.FindByCode(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.couponRepo.Deactivate(ctx, coupon.ID); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "deactivate_coupon", "promotions", coupon.PromotionID.Hex(), coupon.Code, "")
+}
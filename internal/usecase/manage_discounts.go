@@ -5,6 +5,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,30 +18,59 @@ type ManageDiscountsUseCase struct {
 	customerRepo  *repository.CustomerRepository
 	articleRepo   *repository.ArticleRepository
 	promotionRepo *repository.PromotionRepository
+	priceListRepo *repository.PriceListRepository
+	couponRepo    *repository.CouponRepository
+	usageRepo     *repository.PromotionUsageRepository
+	authorization *DiscountAuthorizationUseCase
+	policy        domain.CombinationPolicy
 }
 
 func NewManageDiscountsUseCase(
 	customerRepo *repository.CustomerRepository,
 	articleRepo *repository.ArticleRepository,
 	promotionRepo *repository.PromotionRepository,
+	priceListRepo *repository.PriceListRepository,
+	couponRepo *repository.CouponRepository,
+	usageRepo *repository.PromotionUsageRepository,
+	authorization *DiscountAuthorizationUseCase,
+	policy domain.CombinationPolicy,
 ) *ManageDiscountsUseCase {
 	return &ManageDiscountsUseCase{
 		customerRepo:  customerRepo,
 		articleRepo:   articleRepo,
 		promotionRepo: promotionRepo,
+		priceListRepo: priceListRepo,
+		couponRepo:    couponRepo,
+		usageRepo:     usageRepo,
+		authorization: authorization,
+		policy:        policy,
 	}
 }
 
 type DiscountCalculation struct {
+	ListPrice         float64
 	BasePrice         float64
 	CustomerDiscount  float64
 	PromotionDiscount float64
 	NetPrice          float64
+	PriceListPrice    float64
 	FinalPrice        float64
 	TotalDiscount     float64
 	DiscountPercent   float64
 	AppliedRule       *domain.DiscountRule
 	AppliedPromotion  *domain.Promotion
+	AppliedPromotions []*domain.Promotion
+	AppliedPriceList  *domain.PriceList
+	Coupon            *domain.Coupon
+	Breakdown         *domain.PriceBreakdown
+	Decisions         []domain.PricingDecision
+}
+
+func (c *DiscountCalculation) Steps() []domain.PricingStep {
+	if c.Breakdown == nil {
+		return nil
+	}
+	return c.Breakdown.Steps
 }
 
 func (uc *ManageDiscountsUseCase) CalculateFinalPrice(
@@ -49,23 +79,99 @@ func (uc *ManageDiscountsUseCase) CalculateFinalPrice(
 	article *domain.Article,
 	quantity float64,
 ) (*DiscountCalculation, error) {
-	calc := &DiscountCalculation{
-		BasePrice: article.Pricing.ListPrice,
+	now := time.Now()
+
+	activePromotions, err := uc.activePromotions(ctx, now)
+	if err != nil {
+		return nil, err
 	}
 
-	netPrice := article.GetNetPrice(customer.ID)
-	if netPrice != nil {
-		calc.NetPrice = netPrice.Price
-		calc.BasePrice = netPrice.Price
+	return uc.calculateFinalPrice(ctx, customer, article, quantity, nil, activePromotions, now)
+}
+
+func (uc *ManageDiscountsUseCase) CalculateFinalPriceWithCoupon(
+	ctx context.Context,
+	customer *domain.Customer,
+	article *domain.Article,
+	quantity float64,
+	couponCode string,
+) (*DiscountCalculation, error) {
+	coupon, err := uc.ResolveCoupon(ctx, couponCode)
+	if err != nil {
+		return nil, err
 	}
 
-	activePromotions, err := uc.promotionRepo.FindActive(ctx, time.Now())
+	now := time.Now()
+
+	activePromotions, err := uc.activePromotions(ctx, now)
 	if err != nil {
 		return nil, err
 	}
 
-	var bestPromotion *domain.Promotion
-	var bestPromotionDiscount float64
+	return uc.calculateFinalPrice(ctx, customer, article, quantity, coupon, activePromotions, now)
+}
+
+// ResolveCoupon cerca il codice tra i coupon generati e, in mancanza, tra i codici statici delle promozioni attive
+func (uc *ManageDiscountsUseCase) ResolveCoupon(ctx context.Context, code string) (*domain.Coupon, error) {
+	code = domain.NormalizeCouponCode(code)
+	if code == "" {
+		return nil, nil
+	}
+
+	coupon, err := uc.couponRepo.FindByCode(ctx, code)
+	if err == nil {
+		return coupon, nil
+	}
+	if !errors.Is(err, domain.ErrCouponNotFound) {
+		return nil, err
+	}
+
+	activePromotions, err := uc.activePromotions(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, promo := range activePromotions {
+		if promo.Conditions.CouponCode != "" && domain.NormalizeCouponCode(promo.Conditions.CouponCode) == code {
+			return domain.StaticCoupon(promo), nil
+		}
+	}
+
+	return nil, domain.ErrCouponNotFound
+}
+
+func (uc *ManageDiscountsUseCase) calculateFinalPrice(
+	ctx context.Context,
+	customer *domain.Customer,
+	article *domain.Article,
+	quantity float64,
+	coupon *domain.Coupon,
+	activePromotions []*domain.Promotion,
+	date time.Time,
+) (*DiscountCalculation, error) {
+	breakdown := domain.NewPriceBreakdown(article.Pricing.ListPrice)
+	calc := &DiscountCalculation{
+		ListPrice: article.Pricing.ListPrice,
+	}
+
+	netPrice := article.GetNetPriceAt(customer.ID, date)
+	if netPrice != nil {
+		calc.NetPrice = netPrice.Price
+		breakdown.SetBasePrice(domain.PricingStepNetPrice, "Customer net price", "", netPrice.Price)
+	} else {
+		priceList, price, err := uc.resolvePriceList(ctx, customer, article, date)
+		if err != nil {
+			return nil, err
+		}
+		if priceList != nil {
+			calc.AppliedPriceList = priceList
+			calc.PriceListPrice = price
+			breakdown.SetBasePrice(domain.PricingStepPriceList, priceList.Name, priceList.Code, price)
+		}
+	}
+	calc.BasePrice = breakdown.BasePrice
+
+	var candidates []domain.PromotionCandidate
 
 	for _, promo := range activePromotions {
 		if !promo.IsApplicableToArticle(article) {
@@ -75,49 +181,306 @@ func (uc *ManageDiscountsUseCase) CalculateFinalPrice(
 			continue
 		}
 
-		canUse, _ := promo.CanBeUsed(customer.ID.Hex(), quantity, calc.BasePrice*quantity)
+		canUse, reason := promo.CanBeUsedAt(customer.ID.Hex(), quantity, calc.BasePrice*quantity, coupon, date)
 		if !canUse {
+			calc.Decisions = append(calc.Decisions, domain.RejectDecision(promo.Code, reason))
+			continue
+		}
+
+		if netPrice != nil && !uc.policy.StackOnNetPrice {
+			calc.Decisions = append(calc.Decisions, domain.RejectDecision(promo.Code, "promotions do not apply to customer net prices"))
 			continue
 		}
 
 		discount := promo.CalculateDiscount(calc.BasePrice, quantity)
-		if discount > bestPromotionDiscount {
-			bestPromotionDiscount = discount
-			bestPromotion = promo
+		if discount <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, domain.PromotionCandidate{Promotion: promo, Discount: discount})
+	}
+
+	selected, decisions := domain.SelectPromotions(candidates)
+	calc.Decisions = append(calc.Decisions, decisions...)
+
+	discountRule := customer.GetApplicableDiscountAt(article, quantity, date)
+	if discountRule != nil && discountRule.EffectivePercent() <= 0 {
+		discountRule = nil
+	}
+
+	if discountRule != nil && len(selected) > 0 && !uc.policy.StackOnCustomerDiscount {
+		customerDiscount := calc.BasePrice - discountRule.Apply(calc.BasePrice)
+
+		promotionsOnly := domain.NewPriceBreakdown(calc.BasePrice)
+		for _, c := range selected {
+			promotionsOnly.ApplyPromotion(c.Promotion, quantity)
+		}
+
+		if promotionsOnly.TotalDiscount() > customerDiscount {
+			calc.Decisions = append(calc.Decisions, domain.RejectDecision(ruleSubject(discountRule),
+				fmt.Sprintf("promotions give a better discount (%.2f > %.2f)", promotionsOnly.TotalDiscount(), customerDiscount)))
+			discountRule = nil
+		} else {
+			for _, c := range selected {
+				calc.Decisions = append(calc.Decisions, domain.RejectDecision(c.Promotion.Code,
+					fmt.Sprintf("customer discount is better and does not stack (%.2f >= %.2f)", customerDiscount, promotionsOnly.TotalDiscount())))
+			}
+			selected = nil
 		}
 	}
 
-	discountRule := customer.GetApplicableDiscount(article, quantity)
 	if discountRule != nil {
 		calc.AppliedRule = discountRule
-		calc.CustomerDiscount = calc.BasePrice * (discountRule.DiscountPercent / 100)
+		calc.Decisions = append(calc.Decisions, domain.AcceptDecision(ruleSubject(discountRule),
+			fmt.Sprintf("customer discount %.2f%%", discountRule.EffectivePercent())))
+		breakdown.ApplyDiscountRule(discountRule)
 	}
 
-	if bestPromotion != nil {
-		calc.AppliedPromotion = bestPromotion
-		calc.PromotionDiscount = bestPromotionDiscount / quantity
+	for _, c := range selected {
+		calc.AppliedPromotions = append(calc.AppliedPromotions, c.Promotion)
+		breakdown.ApplyPromotion(c.Promotion, quantity)
+	}
+	if len(calc.AppliedPromotions) > 0 {
+		calc.AppliedPromotion = calc.AppliedPromotions[0]
 	}
 
-	if calc.CustomerDiscount > 0 && calc.PromotionDiscount > 0 {
-		if calc.PromotionDiscount > calc.CustomerDiscount {
-			calc.CustomerDiscount = 0
-			calc.AppliedRule = nil
-		} else {
-			calc.PromotionDiscount = 0
-			calc.AppliedPromotion = nil
+	if coupon != nil {
+		for _, promo := range calc.AppliedPromotions {
+			if coupon.IsForPromotion(promo) {
+				calc.Coupon = coupon
+			}
 		}
 	}
 
-	calc.FinalPrice = calc.BasePrice - calc.CustomerDiscount - calc.PromotionDiscount
-	calc.TotalDiscount = calc.CustomerDiscount + calc.PromotionDiscount
-
-	if calc.BasePrice > 0 {
-		calc.DiscountPercent = (calc.TotalDiscount / calc.BasePrice) * 100
+	if breakdown.ApplyDiscountCap(uc.policy.MaxTotalDiscountPercent) {
+		calc.Decisions = append(calc.Decisions, domain.AcceptDecision("cap",
+			fmt.Sprintf("total discount limited to %.2f%%", uc.policy.MaxTotalDiscountPercent)))
 	}
 
+	calc.Breakdown = breakdown
+	calc.CustomerDiscount = breakdown.DiscountFor(domain.PricingStepCustomerDiscount)
+	calc.PromotionDiscount = breakdown.DiscountFor(domain.PricingStepPromotion)
+	calc.FinalPrice = breakdown.FinalPrice
+	calc.TotalDiscount = breakdown.TotalDiscount()
+	calc.DiscountPercent = breakdown.DiscountPercent()
+
 	return calc, nil
 }
 
+type DocumentPricing struct {
+	Lines     []*DiscountCalculation
+	Coupon    *domain.Coupon
+	Decisions []domain.PricingDecision
+}
+
+// PriceDocument prezza ogni riga e poi applica le promozioni che dipendono dal carrello intero
+func (uc *ManageDiscountsUseCase) PriceDocument(
+	ctx context.Context,
+	customer *domain.Customer,
+	document *domain.Document,
+) (*DocumentPricing, error) {
+	if !document.IsDraft() {
+		return nil, domain.ErrDocumentNotDraft
+	}
+
+	coupon, err := uc.ResolveCoupon(ctx, document.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	activePromotions, err := uc.activePromotions(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	pricing := &DocumentPricing{Coupon: coupon}
+	document.ClearDocumentPromotions()
+
+	for i, line := range document.Lines {
+		article, err := uc.articleRepo.FindByID(ctx, line.ArticleID)
+		if err != nil {
+			return nil, err
+		}
+
+		calc, err := uc.calculateFinalPrice(ctx, customer, article, line.Quantity, coupon, activePromotions, now)
+		if err != nil {
+			return nil, err
+		}
+
+		document.SetLinePricing(i, calc.Breakdown)
+		pricing.Lines = append(pricing.Lines, calc)
+	}
+
+	available := make([]float64, len(document.Lines))
+	for i, line := range document.Lines {
+		available[i] = line.Quantity
+	}
+
+	shippingApplied := false
+
+	for _, promo := range activePromotions {
+		if !promo.IsDocumentLevel() || !promo.IsApplicableToCustomer(customer) {
+			continue
+		}
+
+		canUse, reason := promo.CanBeUsed(customer.ID.Hex(), document.TotalQuantity(), document.MerchandiseTotal(), coupon)
+		if !canUse {
+			pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code, reason))
+			continue
+		}
+
+		switch promo.Type {
+		case domain.PromotionTypeBundle:
+			uc.applyBundle(document, pricing, promo, available)
+
+		case domain.PromotionTypeFreeShipping:
+			if shippingApplied {
+				pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code, "shipping already free"))
+				continue
+			}
+			discount := promo.CalculateShippingDiscount(document.ShippingCharge)
+			if discount <= 0 {
+				pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code, "no shipping charge on document"))
+				continue
+			}
+			document.ShippingDiscount = discount
+			document.AddPromotion(promo, discount)
+			shippingApplied = true
+			pricing.Decisions = append(pricing.Decisions, domain.AcceptDecision(promo.Code,
+				fmt.Sprintf("free shipping, %.2f", discount)))
+		}
+	}
+
+	document.Recalculate()
+
+	if coupon != nil && document.PromotionDiscount(coupon.PromotionCode) == 0 {
+		pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(coupon.Code, "no promotion of this coupon applies to the document"))
+	}
+
+	return pricing, nil
+}
+
+func (uc *ManageDiscountsUseCase) applyBundle(
+	document *domain.Document,
+	pricing *DocumentPricing,
+	promo *domain.Promotion,
+	available []float64,
+) {
+	// lavora su una copia: le quantità si consumano solo se il bundle viene applicato
+	remaining := make([]float64, len(available))
+	copy(remaining, available)
+
+	match := promo.MatchBundle(document.Lines, remaining)
+	if match == nil {
+		pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code, "required bundle articles not in document"))
+		return
+	}
+
+	if promo.CombinationMode() == domain.CombinationExclusive {
+		for _, alloc := range match.Allocations {
+			if len(pricing.Lines[alloc.LineIndex].AppliedPromotions) > 0 {
+				pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code,
+					fmt.Sprintf("exclusive, %s already has a promotion", document.Lines[alloc.LineIndex].ArticleCode)))
+				return
+			}
+		}
+	}
+
+	discounts := promo.CalculateBundleDiscount(document.Lines, match)
+
+	total := 0.0
+	for index, amount := range discounts {
+		if amount <= 0 {
+			continue
+		}
+		document.AddLineAdjustment(index, domain.DocumentAdjustment{
+			Type:          domain.PricingStepBundle,
+			PromotionID:   promo.ID,
+			PromotionCode: promo.Code,
+			Description:   promo.Name,
+			Amount:        amount,
+		})
+		total += amount
+	}
+
+	if total <= 0 {
+		pricing.Decisions = append(pricing.Decisions, domain.RejectDecision(promo.Code, "bundle price not lower than current prices"))
+		return
+	}
+
+	copy(available, remaining)
+	document.AddPromotion(promo, total)
+	pricing.Decisions = append(pricing.Decisions, domain.AcceptDecision(promo.Code,
+		fmt.Sprintf("bundle x%d, discount %.2f", match.Sets, total)))
+}
+
+// activePromotions carica le promozioni valide con gli utilizzi odierni presi dal registro utilizzi
+func (uc *ManageDiscountsUseCase) activePromotions(ctx context.Context, now time.Time) ([]*domain.Promotion, error) {
+	promotions, err := uc.promotionRepo.FindActive(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var limited []primitive.ObjectID
+	for _, promo := range promotions {
+		if promo.Limits.MaxUsagePerDay > 0 {
+			limited = append(limited, promo.ID)
+		}
+	}
+
+	counts, err := uc.usageRepo.CountSince(ctx, limited, domain.StartOfDay(now))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, promo := range promotions {
+		promo.SetDailyUsage(counts[promo.ID])
+	}
+
+	return promotions, nil
+}
+
+func ruleSubject(rule *domain.DiscountRule) string {
+	return "rule " + rule.ID.Hex()
+}
+
+func (uc *ManageDiscountsUseCase) resolvePriceList(
+	ctx context.Context,
+	customer *domain.Customer,
+	article *domain.Article,
+	date time.Time,
+) (*domain.PriceList, float64, error) {
+	if customer.HasCustomPriceList() {
+		priceList, err := uc.priceListRepo.FindByCode(ctx, customer.PriceList)
+		if err != nil && !errors.Is(err, domain.ErrPriceListNotFound) {
+			return nil, 0, err
+		}
+		if err == nil && priceList.IsValid(date) {
+			if price, ok := priceList.PriceFor(article); ok {
+				return priceList, price, nil
+			}
+		}
+	}
+
+	priceLists, err := uc.priceListRepo.FindByCustomerCategory(ctx, customer.Category)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, priceList := range priceLists {
+		if !priceList.IsValid(date) {
+			continue
+		}
+		if price, ok := priceList.PriceFor(article); ok {
+			return priceList, price, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
 func (uc *ManageDiscountsUseCase) AddCustomerDiscountRule(
 	ctx context.Context,
 	customerID primitive.ObjectID,
@@ -169,18 +532,18 @@ func (uc *ManageDiscountsUseCase) GetExpiredNetPrices(ctx context.Context) ([]*d
 	return uc.articleRepo.FindWithExpiredNetPrices(ctx, time.Now())
 }
 
+// ValidateDiscount verifica lo sconto manuale con i limiti dell'operatore; unitPrice è il prezzo risultante
 func (uc *ManageDiscountsUseCase) ValidateDiscount(
 	ctx context.Context,
 	operator *domain.Operator,
+	article *domain.Article,
 	discountPercent float64,
+	unitPrice float64,
 ) error {
-	if discountPercent < 0 || discountPercent > 100 {
-		return errors.New("invalid discount percentage")
-	}
-
-	if discountPercent > 20 && operator.Profile != domain.ProfileAdmin {
-		return errors.New("discount exceeds authorized limit")
+	auth, err := uc.authorization.Authorize(ctx, operator, article, discountPercent, unitPrice)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return auth.Err()
 }
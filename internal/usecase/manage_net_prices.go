@@ -0,0 +1,485 @@
+// internal/usecase/manage_net_prices.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+	"ricambi-manager/pkg/export"
+)
+
+type ManageNetPricesUseCase struct {
+	articleRepo  *repository.ArticleRepository
+	customerRepo *repository.CustomerRepository
+	reminderRepo *repository.NetPriceReminderRepository
+	audit        *auth.AuditLogger
+	reminderDays int
+}
+
+func NewManageNetPricesUseCase(
+	articleRepo *repository.ArticleRepository,
+	customerRepo *repository.CustomerRepository,
+	reminderRepo *repository.NetPriceReminderRepository,
+	audit *auth.AuditLogger,
+	reminderDays int,
+) *ManageNetPricesUseCase {
+	return &ManageNetPricesUseCase{
+		articleRepo:  articleRepo,
+		customerRepo: customerRepo,
+		reminderRepo: reminderRepo,
+		audit:        audit,
+		reminderDays: reminderDays,
+	}
+}
+
+// NetPriceEntry è una riga della distinta prezzi netti: articolo, cliente e periodo di validità
+type NetPriceEntry struct {
+	ArticleID    primitive.ObjectID
+	ArticleCode  string
+	Description  string
+	ListPrice    float64
+	CustomerID   primitive.ObjectID
+	CustomerCode string
+	CustomerName string
+	NetPrice     domain.NetPrice
+	DaysLeft     int
+}
+
+func (e NetPriceEntry) DiscountPercent() float64 {
+	if e.ListPrice <= 0 {
+		return 0
+	}
+	return (1 - e.NetPrice.Price/e.ListPrice) * 100
+}
+
+type NetPriceImportResult struct {
+	Imported int
+	Errors   []string
+}
+
+func (uc *ManageNetPricesUseCase) ReminderDays() int {
+	return uc.reminderDays
+}
+
+func (uc *ManageNetPricesUseCase) GetCustomerNetPrices(ctx context.Context, customerCode string) ([]NetPriceEntry, error) {
+	customer, err := uc.customerRepo.FindByCode(ctx, customerCode)
+	if err != nil {
+		return nil, err
+	}
+
+	articles, err := uc.articleRepo.FindWithNetPricesForCustomer(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []NetPriceEntry
+	for _, article := range articles {
+		for _, np := range article.NetPricesFor(customer.ID) {
+			entries = append(entries, newNetPriceEntry(article, customer, np, now))
+		}
+	}
+
+	return entries, nil
+}
+
+func (uc *ManageNetPricesUseCase) GetArticleNetPrices(ctx context.Context, articleCode string) ([]NetPriceEntry, error) {
+	article, err := uc.articleRepo.FindByCode(ctx, articleCode)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, err := uc.customersOf(ctx, []*domain.Article{article})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []NetPriceEntry
+	for _, np := range article.Pricing.NetPrices {
+		entries = append(entries, newNetPriceEntry(article, customers[np.CustomerID], np, now))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].CustomerCode != entries[j].CustomerCode {
+			return entries[i].CustomerCode < entries[j].CustomerCode
+		}
+		return entries[i].NetPrice.ValidFrom.Before(entries[j].NetPrice.ValidFrom)
+	})
+
+	return entries, nil
+}
+
+// GetExpiring è la distinta dei prezzi netti che scadono nei prossimi giorni e non sono ancora stati rinnovati
+func (uc *ManageNetPricesUseCase) GetExpiring(ctx context.Context, days int) ([]NetPriceEntry, error) {
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+
+	articles, err := uc.articleRepo.FindWithNetPricesExpiring(ctx, now, until)
+	if err != nil {
+		return nil, err
+	}
+
+	customers, err := uc.customersOf(ctx, articles)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []NetPriceEntry
+	for _, article := range articles {
+		for _, np := range article.ExpiringNetPrices(now, until) {
+			entries = append(entries, newNetPriceEntry(article, customers[np.CustomerID], np, now))
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].NetPrice.ValidTo.Before(entries[j].NetPrice.ValidTo)
+	})
+
+	return entries, nil
+}
+
+func (uc *ManageNetPricesUseCase) ExportExpiring(ctx context.Context, days int, dir string) (string, error) {
+	entries, err := uc.GetExpiring(ctx, days)
+	if err != nil {
+		return "", err
+	}
+
+	table := export.NewTable("Articolo", "Descrizione", "Cliente", "Ragione sociale",
+		"Listino", "Netto", "Sconto %", "Valido dal", "Scadenza", "Giorni")
+	for _, e := range entries {
+		table.AddRow(
+			e.ArticleCode,
+			e.Description,
+			e.CustomerCode,
+			e.CustomerName,
+			export.Amount(e.ListPrice),
+			export.Amount(e.NetPrice.Price),
+			export.Percent(e.DiscountPercent()),
+			export.Date(e.NetPrice.ValidFrom),
+			export.Date(e.NetPrice.ValidTo),
+			fmt.Sprintf("%d", e.DaysLeft),
+		)
+	}
+
+	return export.SaveCSV(dir, "prezzi_netti_in_scadenza", table)
+}
+
+func (uc *ManageNetPricesUseCase) SetNetPrice(
+	ctx context.Context,
+	operator *domain.Operator,
+	articleCode, customerCode string,
+	price float64,
+	validFrom, validTo time.Time,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	article, err := uc.articleRepo.FindByCode(ctx, articleCode)
+	if err != nil {
+		return err
+	}
+
+	customer, err := uc.customerRepo.FindByCode(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.addNetPrice(ctx, operator, article, customer, price, validFrom, validTo); err != nil {
+		return err
+	}
+
+	return uc.persistAudit(ctx, operator, "set_net_price", article.ID.Hex(),
+		fmt.Sprintf("%s for %s: %.2f until %s", article.Code, customer.Code, price, export.Date(validTo)))
+}
+
+// ImportCSV crea i prezzi netti in blocco da un file con colonne articolo;cliente;prezzo;valido_dal;valido_al.
+// Le righe non valide vengono saltate e riportate nel risultato
+func (uc *ManageNetPricesUseCase) ImportCSV(ctx context.Context, operator *domain.Operator, r io.Reader) (*NetPriceImportResult, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return nil, err
+	}
+
+	table, err := export.ReadCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(table.Headers))
+	for i, header := range table.Headers {
+		columns[strings.ToLower(strings.TrimSpace(header))] = i
+	}
+	for _, required := range []string{"articolo", "cliente", "prezzo"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	result := &NetPriceImportResult{}
+	customers := make(map[string]*domain.Customer)
+
+	for n, row := range table.Rows {
+		line := n + 2
+
+		price, err := export.ParseAmount(field(row, "prezzo"))
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: invalid price", line))
+			continue
+		}
+
+		validFrom, err := export.ParseDate(field(row, "valido_dal"))
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: invalid valido_dal", line))
+			continue
+		}
+		if validFrom.IsZero() {
+			validFrom = time.Now()
+		}
+
+		validTo, err := export.ParseDate(field(row, "valido_al"))
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: invalid valido_al", line))
+			continue
+		}
+
+		customerCode := field(row, "cliente")
+		customer, ok := customers[customerCode]
+		if !ok {
+			customer, err = uc.customerRepo.FindByCode(ctx, customerCode)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("row %d: customer %s: %v", line, customerCode, err))
+				continue
+			}
+			customers[customerCode] = customer
+		}
+
+		article, err := uc.articleRepo.FindByCode(ctx, field(row, "articolo"))
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("row %d: article %s: %v", line, field(row, "articolo"), err))
+			continue
+		}
+
+		if err := uc.addNetPrice(ctx, operator, article, customer, price, validFrom, validTo); err != nil {
+			if errors.Is(err, domain.ErrInvalidNetPrice) {
+				result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", line, err))
+				continue
+			}
+			return result, err
+		}
+		result.Imported++
+	}
+
+	if err := uc.persistAudit(ctx, operator, "import_net_prices", "",
+		fmt.Sprintf("%d imported, %d rejected", result.Imported, len(result.Errors))); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (uc *ManageNetPricesUseCase) addNetPrice(
+	ctx context.Context,
+	operator *domain.Operator,
+	article *domain.Article,
+	customer *domain.Customer,
+	price float64,
+	validFrom, validTo time.Time,
+) error {
+	netPrice := domain.NetPrice{
+		CustomerID: customer.ID,
+		Price:      price,
+		ValidFrom:  validFrom,
+		ValidTo:    validTo,
+		CreatedBy:  operator.Username,
+	}
+	if err := netPrice.Validate(); err != nil {
+		return err
+	}
+
+	article.AddNetPrice(netPrice)
+	if err := uc.articleRepo.Update(ctx, article); err != nil {
+		return err
+	}
+
+	return uc.reminderRepo.CloseFor(ctx, article.ID, customer.ID, domain.ReminderStatusRenewed, operator.Username)
+}
+
+// Renew rinnova i prezzi netti indicati dalla loro scadenza fino a validTo, aumentandoli di upliftPercent
+func (uc *ManageNetPricesUseCase) Renew(
+	ctx context.Context,
+	operator *domain.Operator,
+	entries []NetPriceEntry,
+	upliftPercent float64,
+	validTo time.Time,
+) (int, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return 0, err
+	}
+
+	renewed := 0
+	for _, entry := range entries {
+		article, err := uc.articleRepo.FindByID(ctx, entry.ArticleID)
+		if err != nil {
+			return renewed, err
+		}
+
+		netPrice, err := article.RenewNetPrice(entry.CustomerID, upliftPercent, validTo, operator.Username)
+		if err != nil {
+			return renewed, fmt.Errorf("%s/%s: %w", entry.ArticleCode, entry.CustomerCode, err)
+		}
+
+		if err := uc.articleRepo.Update(ctx, article); err != nil {
+			return renewed, err
+		}
+
+		if err := uc.reminderRepo.CloseFor(ctx, article.ID, entry.CustomerID, domain.ReminderStatusRenewed, operator.Username); err != nil {
+			return renewed, err
+		}
+
+		if err := uc.persistAudit(ctx, operator, "renew_net_price", article.ID.Hex(),
+			fmt.Sprintf("%s for %s: %.2f → %.2f (%+.2f%%) until %s", entry.ArticleCode, entry.CustomerCode,
+				entry.NetPrice.Price, netPrice.Price, upliftPercent, export.Date(validTo))); err != nil {
+			return renewed, err
+		}
+		renewed++
+	}
+
+	return renewed, nil
+}
+
+// GenerateReminders accoda un promemoria per ogni prezzo netto che scade entro i giorni di configurazione
+func (uc *ManageNetPricesUseCase) GenerateReminders(ctx context.Context, now time.Time) (int, error) {
+	until := now.AddDate(0, 0, uc.reminderDays)
+
+	articles, err := uc.articleRepo.FindWithNetPricesExpiring(ctx, now, until)
+	if err != nil {
+		return 0, err
+	}
+
+	customers, err := uc.customersOf(ctx, articles)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, article := range articles {
+		for _, np := range article.ExpiringNetPrices(now, until) {
+			customer, ok := customers[np.CustomerID]
+			if !ok {
+				continue
+			}
+
+			inserted, err := uc.reminderRepo.CreateIfMissing(ctx, domain.NewNetPriceReminder(article, customer, np))
+			if err != nil {
+				return created, err
+			}
+			if inserted {
+				created++
+			}
+		}
+	}
+
+	return created, nil
+}
+
+func (uc *ManageNetPricesUseCase) GetReminders(ctx context.Context, limit int) ([]*domain.NetPriceReminder, int64, error) {
+	reminders, err := uc.reminderRepo.FindOpen(ctx, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := uc.reminderRepo.CountOpen(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reminders, count, nil
+}
+
+// DismissReminder toglie dalla coda il promemoria della riga senza rinnovare il prezzo
+func (uc *ManageNetPricesUseCase) DismissReminder(ctx context.Context, operator *domain.Operator, entry NetPriceEntry) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	return uc.reminderRepo.CloseFor(ctx, entry.ArticleID, entry.CustomerID, domain.ReminderStatusDismissed, operator.Username)
+}
+
+func (uc *ManageNetPricesUseCase) customersOf(ctx context.Context, articles []*domain.Article) (map[primitive.ObjectID]*domain.Customer, error) {
+	seen := make(map[primitive.ObjectID]bool)
+	var ids []primitive.ObjectID
+	for _, article := range articles {
+		for _, np := range article.Pricing.NetPrices {
+			if !seen[np.CustomerID] {
+				seen[np.CustomerID] = true
+				ids = append(ids, np.CustomerID)
+			}
+		}
+	}
+
+	customers := make(map[primitive.ObjectID]*domain.Customer, len(ids))
+	if len(ids) == 0 {
+		return customers, nil
+	}
+
+	found, err := uc.customerRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, customer := range found {
+		customers[customer.ID] = customer
+	}
+
+	return customers, nil
+}
+
+func (uc *ManageNetPricesUseCase) persistAudit(ctx context.Context, operator *domain.Operator, action, resourceID, details string) error {
+	return uc.audit.LogAction(ctx, operator, action, "articles", resourceID, details, "")
+}
+
+func newNetPriceEntry(article *domain.Article, customer *domain.Customer, np domain.NetPrice, now time.Time) NetPriceEntry {
+	entry := NetPriceEntry{
+		ArticleID:   article.ID,
+		ArticleCode: article.Code,
+		Description: article.Description,
+		ListPrice:   article.Pricing.ListPrice,
+		CustomerID:  np.CustomerID,
+		NetPrice:    np,
+		DaysLeft:    -1,
+	}
+	if customer != nil {
+		entry.CustomerCode = customer.Code
+		entry.CustomerName = customer.CompanyName
+	}
+	if !np.ValidTo.IsZero() {
+		entry.DaysLeft = int(np.ValidTo.Sub(now).Hours() / 24)
+	}
+	return entry
+}
+
+func requireCommercialEdit(operator *domain.Operator) error {
+	if !operator.HasPermission(domain.AreaCommercial, domain.ActionEdit) {
+		return domain.ErrInsufficientPermissions
+	}
+	return nil
+}
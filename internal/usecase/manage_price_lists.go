@@ -0,0 +1,275 @@
+// internal/usecase/manage_price_lists.go
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+type ManagePriceListsUseCase struct {
+	priceListRepo *repository.PriceListRepository
+	articleRepo   *repository.ArticleRepository
+	customerRepo  *repository.CustomerRepository
+	audit         *auth.AuditLogger
+}
+
+func NewManagePriceListsUseCase(
+	priceListRepo *repository.PriceListRepository,
+	articleRepo *repository.ArticleRepository,
+	customerRepo *repository.CustomerRepository,
+	audit *auth.AuditLogger,
+) *ManagePriceListsUseCase {
+	return &ManagePriceListsUseCase{
+		priceListRepo: priceListRepo,
+		articleRepo:   articleRepo,
+		customerRepo:  customerRepo,
+		audit:         audit,
+	}
+}
+
+func (uc *ManagePriceListsUseCase) CreateTablePriceList(
+	ctx context.Context,
+	code, name string,
+	operator *domain.Operator,
+) (*domain.PriceList, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return nil, err
+	}
+
+	priceList, err := domain.NewPriceList(code, name, domain.PriceListTypeTable, operator.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.priceListRepo.Create(ctx, priceList); err != nil {
+		return nil, err
+	}
+
+	return priceList, uc.audit.LogAction(ctx, operator, "create_price_list", "price_lists", priceList.ID.Hex(), priceList.Code, "")
+}
+
+func (uc *ManagePriceListsUseCase) CreateDerivedPriceList(
+	ctx context.Context,
+	code, name string,
+	percent float64,
+	operator *domain.Operator,
+) (*domain.PriceList, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return nil, err
+	}
+
+	priceList, err := domain.NewPriceList(code, name, domain.PriceListTypeDerived, operator.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	priceList.DerivedPercent = percent
+	if err := priceList.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := uc.priceListRepo.Create(ctx, priceList); err != nil {
+		return nil, err
+	}
+
+	return priceList, uc.audit.LogAction(
+		ctx,
+		operator,
+		"create_price_list",
+		"price_lists",
+		priceList.ID.Hex(),
+		fmt.Sprintf("%s: list price %+.2f%%", priceList.Code, percent),
+		"",
+	)
+}
+
+func (uc *ManagePriceListsUseCase) SetValidity(
+	ctx context.Context,
+	priceListID primitive.ObjectID,
+	validFrom, validTo time.Time,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	priceList.ValidFrom = validFrom
+	priceList.ValidTo = validTo
+	if err := priceList.Validate(); err != nil {
+		return err
+	}
+
+	priceList.UpdatedBy = operator.Username
+	return uc.priceListRepo.Update(ctx, priceList)
+}
+
+func (uc *ManagePriceListsUseCase) SetEntry(
+	ctx context.Context,
+	priceListID, articleID primitive.ObjectID,
+	price float64,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	article, err := uc.articleRepo.FindByID(ctx, articleID)
+	if err != nil {
+		return err
+	}
+
+	if err := priceList.SetEntry(article, price); err != nil {
+		return err
+	}
+
+	priceList.UpdatedBy = operator.Username
+	if err := uc.priceListRepo.Update(ctx, priceList); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
+		"set_price_list_entry",
+		"price_lists",
+		priceList.ID.Hex(),
+		fmt.Sprintf("%s: %s = %.2f", priceList.Code, article.Code, price),
+		"",
+	)
+}
+
+func (uc *ManagePriceListsUseCase) RemoveEntry(
+	ctx context.Context,
+	priceListID, articleID primitive.ObjectID,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	priceList.RemoveEntry(articleID)
+	priceList.UpdatedBy = operator.Username
+
+	return uc.priceListRepo.Update(ctx, priceList)
+}
+
+func (uc *ManagePriceListsUseCase) AssignToCustomer(
+	ctx context.Context,
+	customerID primitive.ObjectID,
+	code string,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	customer, err := uc.customerRepo.FindByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+
+	if code != "" && code != domain.PriceListStandard {
+		priceList, err := uc.priceListRepo.FindByCode(ctx, code)
+		if err != nil {
+			return err
+		}
+		code = priceList.Code
+	}
+
+	customer.AssignPriceList(code)
+	customer.UpdatedBy = operator.Username
+	if err := uc.customerRepo.Update(ctx, customer); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "assign_price_list", "customers", customer.ID.Hex(), customer.PriceList, "")
+}
+
+func (uc *ManagePriceListsUseCase) AssignToCategory(
+	ctx context.Context,
+	priceListID primitive.ObjectID,
+	category domain.CustomerCategory,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	priceList.AssignCategory(category)
+	priceList.UpdatedBy = operator.Username
+
+	return uc.priceListRepo.Update(ctx, priceList)
+}
+
+func (uc *ManagePriceListsUseCase) UnassignFromCategory(
+	ctx context.Context,
+	priceListID primitive.ObjectID,
+	category domain.CustomerCategory,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	priceList.UnassignCategory(category)
+	priceList.UpdatedBy = operator.Username
+
+	return uc.priceListRepo.Update(ctx, priceList)
+}
+
+func (uc *ManagePriceListsUseCase) Deactivate(
+	ctx context.Context,
+	priceListID primitive.ObjectID,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	priceList, err := uc.priceListRepo.FindByID(ctx, priceListID)
+	if err != nil {
+		return err
+	}
+
+	priceList.IsActive = false
+	priceList.UpdatedBy = operator.Username
+
+	return uc.priceListRepo.Update(ctx, priceList)
+}
+
+func (uc *ManagePriceListsUseCase) ListPriceLists(ctx context.Context) ([]*domain.PriceList, error) {
+	return uc.priceListRepo.FindAll(ctx)
+}
@@ -0,0 +1,223 @@
+// internal/usecase/manage_prices.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+type ManagePricesUseCase struct {
+	articleRepo      *repository.ArticleRepository
+	priceHistoryRepo *repository.PriceHistoryRepository
+	audit            *auth.AuditLogger
+}
+
+func NewManagePricesUseCase(
+	articleRepo *repository.ArticleRepository,
+	priceHistoryRepo *repository.PriceHistoryRepository,
+	audit *auth.AuditLogger,
+) *ManagePricesUseCase {
+	return &ManagePricesUseCase{
+		articleRepo:      articleRepo,
+		priceHistoryRepo: priceHistoryRepo,
+		audit:            audit,
+	}
+}
+
+type PriceAtDate struct {
+	ArticleID primitive.ObjectID
+	Date      time.Time
+	ListPrice float64
+	Cost      float64
+	Source    string
+	Change    *domain.PriceChange
+}
+
+// ChangePrice è l'unico punto da cui cambia il prezzo di listino: la variazione in vigore subito
+// aggiorna l'articolo nella stessa transazione che la registra nello storico; una data passata vale come adesso
+func (uc *ManagePricesUseCase) ChangePrice(
+	ctx context.Context,
+	articleID primitive.ObjectID,
+	listPrice, cost float64,
+	effectiveFrom time.Time,
+	reason string,
+	operator *domain.Operator,
+) (*domain.PriceChange, error) {
+	if err := requireCommercialEdit(operator); err != nil {
+		return nil, err
+	}
+
+	var article *domain.Article
+	var change *domain.PriceChange
+	err := uc.priceHistoryRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		article, err = uc.articleRepo.FindByID(ctx, articleID)
+		if err != nil {
+			return err
+		}
+
+		change, err = domain.NewPriceChange(article, listPrice, cost, effectiveFrom, reason, operator.Username)
+		if err != nil {
+			return err
+		}
+
+		if change.IsDue(time.Now()) {
+			if err := article.ApplyPriceChange(change, operator.Username); err != nil {
+				return err
+			}
+			if err := uc.articleRepo.UpdatePricing(ctx, article); err != nil {
+				return err
+			}
+		}
+
+		return uc.priceHistoryRepo.Create(ctx, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, uc.audit.LogAction(
+		ctx,
+		operator,
+		"change_price",
+		"articles",
+		articleID.Hex(),
+		fmt.Sprintf("%s: list price %.2f, cost %.2f from %s (%s)",
+			article.Code, listPrice, cost, change.EffectiveFrom.Format("02/01/2006"), change.Status),
+		"",
+	)
+}
+
+func (uc *ManagePricesUseCase) CancelPriceChange(
+	ctx context.Context,
+	changeID primitive.ObjectID,
+	operator *domain.Operator,
+) error {
+	if err := requireCommercialEdit(operator); err != nil {
+		return err
+	}
+
+	change, err := uc.priceHistoryRepo.FindByID(ctx, changeID)
+	if err != nil {
+		return err
+	}
+
+	if err := change.Cancel(operator.Username); err != nil {
+		return err
+	}
+
+	if err := uc.priceHistoryRepo.Update(ctx, change); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
+		"cancel_price_change",
+		"articles",
+		change.ArticleID.Hex(),
+		fmt.Sprintf("%s: cancelled change to %.2f scheduled for %s",
+			change.ArticleCode, change.ListPrice, change.EffectiveFrom.Format("02/01/2006")),
+		"",
+	)
+}
+
+func (uc *ManagePricesUseCase) ApplyDuePriceChanges(ctx context.Context, now time.Time) (int, error) {
+	changes, err := uc.priceHistoryRepo.FindDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	var errs []error
+
+	for _, change := range changes {
+		// il driver può rieseguire la transazione: ogni tentativo riparte dalla variazione programmata
+		scheduled := *change
+		err := uc.priceHistoryRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+			*change = scheduled
+
+			article, err := uc.articleRepo.FindByID(ctx, change.ArticleID)
+			if err != nil {
+				return err
+			}
+
+			if err := article.ApplyPriceChange(change, change.ChangedBy); err != nil {
+				return err
+			}
+
+			if err := uc.articleRepo.UpdatePricing(ctx, article); err != nil {
+				return err
+			}
+
+			return uc.priceHistoryRepo.Update(ctx, change)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", change.ArticleCode, err))
+			continue
+		}
+
+		applied++
+	}
+
+	return applied, errors.Join(errs...)
+}
+
+func (uc *ManagePricesUseCase) GetPriceHistory(ctx context.Context, articleID primitive.ObjectID, limit int) ([]*domain.PriceChange, error) {
+	return uc.priceHistoryRepo.FindByArticle(ctx, articleID, limit)
+}
+
+func (uc *ManagePricesUseCase) GetScheduledChanges(ctx context.Context, articleID primitive.ObjectID) ([]*domain.PriceChange, error) {
+	return uc.priceHistoryRepo.FindScheduledByArticle(ctx, articleID)
+}
+
+func (uc *ManagePricesUseCase) GetPriceAt(ctx context.Context, articleID primitive.ObjectID, date time.Time) (*PriceAtDate, error) {
+	result := &PriceAtDate{
+		ArticleID: articleID,
+		Date:      date,
+	}
+
+	change, err := uc.priceHistoryRepo.FindEffectiveAt(ctx, articleID, date)
+	if err == nil {
+		result.ListPrice = change.ListPrice
+		result.Cost = change.Cost
+		result.Source = "history"
+		result.Change = change
+		return result, nil
+	}
+	if !errors.Is(err, domain.ErrPriceChangeNotFound) {
+		return nil, err
+	}
+
+	// Prima della prima variazione registrata vale il prezzo che quella variazione ha sostituito
+	first, err := uc.priceHistoryRepo.FindFirstApplied(ctx, articleID)
+	if err == nil && date.Before(first.EffectiveFrom) {
+		result.ListPrice = first.PreviousListPrice
+		result.Cost = first.PreviousCost
+		result.Source = "previous"
+		result.Change = first
+		return result, nil
+	}
+	if err != nil && !errors.Is(err, domain.ErrPriceChangeNotFound) {
+		return nil, err
+	}
+
+	article, err := uc.articleRepo.FindByID(ctx, articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ListPrice = article.Pricing.ListPrice
+	result.Cost = article.Pricing.LastPurchaseCost
+	result.Source = "current"
+	return result, nil
+}
@@ -0,0 +1,131 @@
+// internal/usecase/manage_promotions.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+)
+
+type ManagePromotionsUseCase struct {
+	promotionRepo *repository.PromotionRepository
+	usageRepo     *repository.PromotionUsageRepository
+}
+
+func NewManagePromotionsUseCase(
+	promotionRepo *repository.PromotionRepository,
+	usageRepo *repository.PromotionUsageRepository,
+) *ManagePromotionsUseCase {
+	return &ManagePromotionsUseCase{
+		promotionRepo: promotionRepo,
+		usageRepo:     usageRepo,
+	}
+}
+
+// RecordDocumentUsage registra un utilizzo per ogni promozione applicata al documento registrato
+func (uc *ManagePromotionsUseCase) RecordDocumentUsage(
+	ctx context.Context,
+	document *domain.Document,
+	operator *domain.Operator,
+) error {
+	if !document.IsPosted() {
+		return domain.ErrDocumentNotPosted
+	}
+
+	now := time.Now()
+
+	// tutti gli utilizzi del documento o nessuno. RecordUsage aggiorna il documento della promozione:
+	// due registrazioni concorrenti vanno in conflitto e il driver riesegue la seconda, che ricontando
+	// vede l'utilizzo già confermato, quindi il limite giornaliero non può essere superato
+	return uc.usageRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, code := range document.AppliedPromotionCodes() {
+			promo, err := uc.promotionRepo.FindByCode(ctx, code)
+			if err != nil {
+				return err
+			}
+
+			revenue := document.PromotionRevenue(code)
+			discount := document.PromotionDiscount(code)
+
+			if err := uc.promotionRepo.RecordUsage(ctx, promo, document.CustomerID.Hex(), revenue, discount); err != nil {
+				if errors.Is(err, domain.ErrPromotionUsageLimit) {
+					return fmt.Errorf("%s: %w", promo.Code, err)
+				}
+				return err
+			}
+
+			if promo.Limits.MaxUsagePerDay > 0 {
+				counts, err := uc.usageRepo.CountSince(ctx, []primitive.ObjectID{promo.ID}, domain.StartOfDay(now))
+				if err != nil {
+					return err
+				}
+				if counts[promo.ID] >= promo.Limits.MaxUsagePerDay {
+					return fmt.Errorf("%s: daily usage limit reached", promo.Code)
+				}
+			}
+
+			usage := domain.NewPromotionUsage(promo, document, revenue, discount, operator.Username)
+			if err := uc.usageRepo.Create(ctx, usage); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (uc *ManagePromotionsUseCase) RevertDocumentUsage(ctx context.Context, document *domain.Document) error {
+	usages, err := uc.usageRepo.FindByDocument(ctx, document.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, usage := range usages {
+		if _, err := uc.usageRepo.DeleteByDocument(ctx, usage.PromotionID, document.ID); err != nil {
+			return err
+		}
+		if err := uc.promotionRepo.RevertUsage(ctx, usage.PromotionID, usage.CustomerID.Hex(), usage.Revenue, usage.Discount); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *ManagePromotionsUseCase) GetUsagesToday(ctx context.Context, promo *domain.Promotion) (int, error) {
+	counts, err := uc.usageRepo.CountSince(ctx, []primitive.ObjectID{promo.ID}, domain.StartOfDay(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return counts[promo.ID], nil
+}
+
+// ExpirePromotions disattiva le promozioni con ValidTo superata
+func (uc *ManagePromotionsUseCase) ExpirePromotions(ctx context.Context, now time.Time) (int, error) {
+	expired, err := uc.promotionRepo.FindExpiredActive(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	deactivated := 0
+
+	for _, promo := range expired {
+		promo.Deactivate()
+		promo.UpdatedBy = "system"
+		if err := uc.promotionRepo.Update(ctx, promo); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", promo.Code, err))
+			continue
+		}
+		deactivated++
+	}
+
+	return deactivated, errors.Join(errs...)
+}
@@ -11,20 +11,24 @@ import (
 
 	"ricambi-manager/internal/domain"
 	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
 )
 
 type ManageStockUseCase struct {
 	articleRepo *repository.ArticleRepository
 	kitRepo     *repository.KitRepository
+	audit       *auth.AuditLogger
 }
 
 func NewManageStockUseCase(
 	articleRepo *repository.ArticleRepository,
 	kitRepo *repository.KitRepository,
+	audit *auth.AuditLogger,
 ) *ManageStockUseCase {
 	return &ManageStockUseCase{
 		articleRepo: articleRepo,
 		kitRepo:     kitRepo,
+		audit:       audit,
 	}
 }
 
@@ -56,15 +60,19 @@ func (uc *ManageStockUseCase) AddStock(
 		return err
 	}
 
-	operator.AddAuditEntry(
+	if err := uc.articleRepo.Update(ctx, article); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
 		"add_stock",
 		"warehouse",
 		articleID.Hex(),
 		fmt.Sprintf("Added %.2f units to %s", quantity, article.Code),
 		"",
 	)
-
-	return uc.articleRepo.Update(ctx, article)
 }
 
 func (uc *ManageStockUseCase) RemoveStock(
@@ -86,15 +94,19 @@ func (uc *ManageStockUseCase) RemoveStock(
 		return err
 	}
 
-	operator.AddAuditEntry(
+	if err := uc.articleRepo.Update(ctx, article); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(
+		ctx,
+		operator,
 		"remove_stock",
 		"warehouse",
 		articleID.Hex(),
 		fmt.Sprintf("Removed %.2f units from %s", quantity, article.Code),
 		"",
 	)
-
-	return uc.articleRepo.Update(ctx, article)
 }
 
 func (uc *ManageStockUseCase) ReserveStock(
@@ -0,0 +1,471 @@
+// internal/usecase/manage_vouchers.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+	"ricambi-manager/pkg/barcode"
+	"ricambi-manager/pkg/export"
+)
+
+// ManageVouchersUseCase gestisce i buoni a credito; ogni variazione di saldo o scadenza è registrata
+// nel registro buoni nella stessa transazione, così la situazione si può ricostruire a qualsiasi data
+type ManageVouchersUseCase struct {
+	voucherRepo  *repository.CreditVoucherRepository
+	customerRepo *repository.CustomerRepository
+	ledgerRepo   *repository.VoucherLedgerRepository
+	generator    *barcode.BarcodeGenerator
+	printer      *barcode.LabelPrinter
+	audit        *auth.AuditLogger
+}
+
+func NewManageVouchersUseCase(
+	voucherRepo *repository.CreditVoucherRepository,
+	customerRepo *repository.CustomerRepository,
+	ledgerRepo *repository.VoucherLedgerRepository,
+	audit *auth.AuditLogger,
+) *ManageVouchersUseCase {
+	return &ManageVouchersUseCase{
+		voucherRepo:  voucherRepo,
+		customerRepo: customerRepo,
+		ledgerRepo:   ledgerRepo,
+		generator:    barcode.NewBarcodeGenerator(),
+		printer:      barcode.NewLabelPrinter(100, 60, 203),
+		audit:        audit,
+	}
+}
+
+// IssueVoucher emette un buono per il cliente; expiryDays 0 crea un buono senza scadenza
+func (uc *ManageVouchersUseCase) IssueVoucher(
+	ctx context.Context,
+	operator *domain.Operator,
+	customerCode string,
+	amount float64,
+	reason string,
+	expiryDays int,
+) (*domain.CreditVoucher, error) {
+	customer, err := uc.customerRepo.FindByCode(ctx, strings.ToUpper(strings.TrimSpace(customerCode)))
+	if err != nil {
+		return nil, err
+	}
+
+	voucher, err := domain.NewCreditVoucher(customer.ID, amount, reason, expiryDays, operator.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// una collisione del codice annulla la transazione: si riprova con un codice nuovo
+	for attempt := 1; ; attempt++ {
+		err = uc.voucherRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+			if err := uc.voucherRepo.Create(ctx, voucher); err != nil {
+				return err
+			}
+			return uc.ledgerRepo.Create(ctx, domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventIssue, amount, operator.Username, reason))
+		})
+		if !errors.Is(err, domain.ErrDuplicateVoucherCode) || attempt == maxVoucherIssueAttempts {
+			break
+		}
+
+		code, genErr := domain.GenerateVoucherCode()
+		if genErr != nil {
+			return nil, genErr
+		}
+		voucher.Code = code
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return voucher, uc.audit.LogAction(ctx, operator, "issue_voucher", "credit_vouchers", voucher.ID.Hex(),
+		fmt.Sprintf("%s %s: %.2f", voucher.Code, customer.Code, amount), "")
+}
+
+// CancelVoucher annulla il buono; il saldo residuo esce dal debito verso il cliente
+func (uc *ManageVouchersUseCase) CancelVoucher(ctx context.Context, operator *domain.Operator, voucher *domain.CreditVoucher, reason string) error {
+	// il saldo di un buono già scaduto è uscito col movimento di scadenza
+	amount := voucher.RemainingAmount
+	if voucher.Status == domain.VoucherStatusExpired {
+		amount = 0
+	}
+
+	before := *voucher
+	err := uc.voucherRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		*voucher = before
+		if err := voucher.Cancel(reason); err != nil {
+			return err
+		}
+		if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+			return err
+		}
+		return uc.ledgerRepo.Create(ctx, domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventCancel, -amount, operator.Username, reason))
+	})
+	if err != nil {
+		*voucher = before
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "cancel_voucher", "credit_vouchers", voucher.ID.Hex(), voucher.Code+": "+reason, "")
+}
+
+// ExtendVoucher proroga la scadenza; un buono già scaduto torna spendibile per il saldo residuo
+func (uc *ManageVouchersUseCase) ExtendVoucher(ctx context.Context, operator *domain.Operator, voucher *domain.CreditVoucher, days int) error {
+	if days <= 0 {
+		return domain.ErrInvalidVoucherExtension
+	}
+	if voucher.Status == domain.VoucherStatusCancelled || voucher.Status == domain.VoucherStatusUsed {
+		return domain.ErrInvalidVoucherExtension
+	}
+
+	amount := 0.0
+	if voucher.Status == domain.VoucherStatusExpired {
+		amount = voucher.RemainingAmount
+	}
+
+	before := *voucher
+	err := uc.voucherRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		*voucher = before
+		if err := voucher.Extend(days); err != nil {
+			return err
+		}
+		if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+			return err
+		}
+		return uc.ledgerRepo.Create(ctx, domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventExtend, amount,
+			operator.Username, fmt.Sprintf("proroga %d giorni", days)))
+	})
+	if err != nil {
+		*voucher = before
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "extend_voucher", "credit_vouchers", voucher.ID.Hex(),
+		fmt.Sprintf("%s: +%d giorni", voucher.Code, days), "")
+}
+
+// GetCustomerVouchers restituisce tutti i buoni del cliente, compresi quelli esauriti o scaduti
+const maxVoucherIssueAttempts = 3
+
+func (uc *ManageVouchersUseCase) GetCustomerVouchers(ctx context.Context, customerCode string) (*domain.Customer, []*domain.CreditVoucher, error) {
+	customer, err := uc.customerRepo.FindByCode(ctx, strings.ToUpper(strings.TrimSpace(customerCode)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vouchers, err := uc.voucherRepo.FindByCustomer(ctx, customer.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return customer, vouchers, nil
+}
+
+// ScanVoucher cerca il buono letto dal lettore o digitato; il carattere di controllo scarta le letture
+// errate senza interrogare il database
+func (uc *ManageVouchersUseCase) ScanVoucher(ctx context.Context, scanned string) (*domain.CreditVoucher, error) {
+	if err := domain.ValidateVoucherCode(scanned); err != nil {
+		return nil, err
+	}
+
+	return uc.voucherRepo.FindByCode(ctx, scanned)
+}
+
+// GetUsableVouchers restituisce i buoni del cliente spendibili oggi, prima quelli in scadenza
+func (uc *ManageVouchersUseCase) GetUsableVouchers(ctx context.Context, customerID primitive.ObjectID) ([]*domain.CreditVoucher, error) {
+	vouchers, err := uc.voucherRepo.FindActiveByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	usable := make([]*domain.CreditVoucher, 0, len(vouchers))
+	for _, voucher := range vouchers {
+		if voucher.IsValid() {
+			usable = append(usable, voucher)
+		}
+	}
+
+	sort.SliceStable(usable, func(i, j int) bool {
+		a, b := usable[i], usable[j]
+		if a.ExpiryDate.IsZero() != b.ExpiryDate.IsZero() {
+			return !a.ExpiryDate.IsZero()
+		}
+		if !a.ExpiryDate.Equal(b.ExpiryDate) {
+			return a.ExpiryDate.Before(b.ExpiryDate)
+		}
+		return a.IssuedDate.Before(b.IssuedDate)
+	})
+
+	return usable, nil
+}
+
+// ApplyVoucher aggiunge il buono alla bozza; amount 0 usa il massimo tra saldo e dovuto
+func (uc *ManageVouchersUseCase) ApplyVoucher(ctx context.Context, document *domain.Document, code string, amount float64) error {
+	voucher, err := uc.voucherRepo.FindByCode(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return document.ApplyVoucher(voucher, amount)
+}
+
+// ApplyScannedVoucher applica al documento in cassa il buono letto dal lettore, per il massimo spendibile.
+// Il saldo viene scalato alla registrazione del documento
+func (uc *ManageVouchersUseCase) ApplyScannedVoucher(ctx context.Context, document *domain.Document, scanned string) (*domain.CreditVoucher, error) {
+	voucher, err := uc.ScanVoucher(ctx, scanned)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := document.ApplyVoucher(voucher, 0); err != nil {
+		return nil, err
+	}
+
+	return voucher, nil
+}
+
+// ApplyVouchers usa i buoni del cliente, prima quelli in scadenza, fino a coprire il dovuto
+func (uc *ManageVouchersUseCase) ApplyVouchers(ctx context.Context, document *domain.Document) (float64, error) {
+	vouchers, err := uc.GetUsableVouchers(ctx, document.CustomerID)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0.0
+	document.Recalculate()
+	for _, voucher := range vouchers {
+		if document.Totals.Due <= 0 {
+			break
+		}
+		if err := document.ApplyVoucher(voucher, 0); err != nil {
+			return applied, err
+		}
+		applied += document.Vouchers[len(document.Vouchers)-1].Amount
+	}
+
+	return applied, nil
+}
+
+// RedeemForDocument scala dai buoni gli importi del documento registrato. Va chiamato nella stessa
+// transazione della registrazione: un saldo non più sufficiente annulla tutta l'operazione
+func (uc *ManageVouchersUseCase) RedeemForDocument(
+	ctx context.Context,
+	document *domain.Document,
+	operator *domain.Operator,
+) error {
+	if len(document.Vouchers) == 0 {
+		return nil
+	}
+	if !document.IsPosted() {
+		return domain.ErrDocumentNotPosted
+	}
+
+	for _, applied := range document.Vouchers {
+		if applied.Amount <= 0 {
+			continue
+		}
+
+		voucher, err := uc.voucherRepo.FindByID(ctx, applied.VoucherID)
+		if err != nil {
+			return err
+		}
+		if voucher.CustomerID != document.CustomerID {
+			return domain.ErrVoucherNotApplicable
+		}
+
+		if err := voucher.Use(applied.Amount, document.ID.Hex(), string(document.Type), operator.Username, document.Number); err != nil {
+			return fmt.Errorf("voucher %s: %w", voucher.Code, err)
+		}
+
+		if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+			return err
+		}
+
+		entry := domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventUse, -applied.Amount, operator.Username, document.Number)
+		entry.DocumentID = document.ID.Hex()
+		entry.DocumentType = string(document.Type)
+		if err := uc.ledgerRepo.Create(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestoreForDocument riaccredita i buoni usati da un documento stornato
+func (uc *ManageVouchersUseCase) RestoreForDocument(
+	ctx context.Context,
+	document *domain.Document,
+	operator *domain.Operator,
+) error {
+	for _, applied := range document.Vouchers {
+		voucher, err := uc.voucherRepo.FindByID(ctx, applied.VoucherID)
+		if err != nil {
+			return err
+		}
+
+		restored, err := voucher.Restore(document.ID.Hex(), string(document.Type), operator.Username, "storno "+document.Number)
+		if errors.Is(err, domain.ErrVoucherNotUsed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+			return err
+		}
+
+		entry := domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventRestore, restored, operator.Username, "storno "+document.Number)
+		entry.DocumentID = document.ID.Hex()
+		entry.DocumentType = string(document.Type)
+		if err := uc.ledgerRepo.Create(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpireVouchers porta a scaduto i buoni oltre la data di scadenza, che altrimenti cambierebbero
+// stato solo al primo tentativo di utilizzo
+func (uc *ManageVouchersUseCase) ExpireVouchers(ctx context.Context, now time.Time) (int, error) {
+	vouchers, err := uc.voucherRepo.FindExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	expired := 0
+	for _, voucher := range vouchers {
+		if !voucher.Expire(now) {
+			continue
+		}
+		err := uc.voucherRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+			if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+				return err
+			}
+			return uc.ledgerRepo.Create(ctx, domain.NewVoucherLedgerEntry(voucher, domain.VoucherEventExpire,
+				-voucher.RemainingAmount, "system", ""))
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", voucher.Code, err))
+			continue
+		}
+		expired++
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// RebuildLedger ricostruisce il registro dei buoni dallo storico utilizzi, per i buoni emessi prima
+// del registro o dopo un ripristino del database
+func (uc *ManageVouchersUseCase) RebuildLedger(ctx context.Context) (int, error) {
+	vouchers, err := uc.voucherRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rebuilt := 0
+	for _, voucher := range vouchers {
+		if err := uc.ledgerRepo.ReplaceForVoucher(ctx, voucher.ID, voucher.LedgerFromHistory()); err != nil {
+			return rebuilt, fmt.Errorf("%s: %w", voucher.Code, err)
+		}
+		rebuilt++
+	}
+
+	return rebuilt, nil
+}
+
+// VoucherCodeChange è un buono ricodificato da RenameDuplicateCodes, da ristampare per il cliente
+type VoucherCodeChange struct {
+	VoucherID primitive.ObjectID
+	OldCode   string
+	NewCode   string
+}
+
+// RenameDuplicateCodes assegna un codice nuovo ai buoni che condividono il codice con un buono
+// precedente, che lo conserva. Va eseguito prima di creare l'indice univoco su code
+func (uc *ManageVouchersUseCase) RenameDuplicateCodes(ctx context.Context) ([]VoucherCodeChange, error) {
+	duplicates, err := uc.voucherRepo.FindDuplicateCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []VoucherCodeChange
+	for _, voucher := range duplicates {
+		code, err := domain.GenerateVoucherCode()
+		if err != nil {
+			return changes, err
+		}
+
+		change := VoucherCodeChange{VoucherID: voucher.ID, OldCode: voucher.Code, NewCode: code}
+		err = uc.voucherRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+			voucher.Code = code
+			if err := uc.voucherRepo.Update(ctx, voucher); err != nil {
+				return err
+			}
+			return uc.ledgerRepo.UpdateVoucherCode(ctx, voucher.ID, code)
+		})
+		if err != nil {
+			return changes, fmt.Errorf("%s: %w", change.OldCode, err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// PrintVoucher salva l'etichetta ZPL del buono e l'immagine PNG del codice a barre (Code128 o QR)
+// e restituisce il percorso dell'etichetta
+func (uc *ManageVouchersUseCase) PrintVoucher(
+	ctx context.Context,
+	voucher *domain.CreditVoucher,
+	format barcode.BarcodeFormat,
+	dir string,
+) (string, error) {
+	customerName := ""
+	if customer, err := uc.customerRepo.FindByID(ctx, voucher.CustomerID); err == nil {
+		customerName = customer.CompanyName
+	} else if !errors.Is(err, domain.ErrCustomerNotFound) {
+		return "", err
+	}
+
+	expiry := ""
+	if !voucher.ExpiryDate.IsZero() {
+		expiry = export.Date(voucher.ExpiryDate)
+	}
+
+	png, err := uc.generator.GeneratePNG(voucher.Code, format)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := "buono_" + voucher.Code
+	zpl := uc.printer.GenerateVoucherZPL(voucher.Code, customerName, voucher.RemainingAmount, expiry, format)
+	path := filepath.Join(dir, export.Filename(name, "zpl"))
+	if err := os.WriteFile(path, []byte(zpl), 0o644); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(strings.TrimSuffix(path, ".zpl")+".png", png, 0o644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
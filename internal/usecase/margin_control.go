@@ -0,0 +1,374 @@
+// internal/usecase/margin_control.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+type MarginControlUseCase struct {
+	approvalRepo *repository.MarginApprovalRepository
+	articleRepo  *repository.ArticleRepository
+	documentRepo *repository.DocumentRepository
+	operatorRepo *repository.OperatorRepository
+	loginUC      *LoginUseCase
+	policy       domain.MarginPolicy
+	auditLogger  *auth.AuditLogger
+}
+
+func NewMarginControlUseCase(
+	approvalRepo *repository.MarginApprovalRepository,
+	articleRepo *repository.ArticleRepository,
+	documentRepo *repository.DocumentRepository,
+	operatorRepo *repository.OperatorRepository,
+	loginUC *LoginUseCase,
+	policy domain.MarginPolicy,
+	auditLogger *auth.AuditLogger,
+) *MarginControlUseCase {
+	return &MarginControlUseCase{
+		approvalRepo: approvalRepo,
+		articleRepo:  articleRepo,
+		documentRepo: documentRepo,
+		operatorRepo: operatorRepo,
+		loginUC:      loginUC,
+		policy:       policy,
+		auditLogger:  auditLogger,
+	}
+}
+
+// CheckDocument confronta ogni riga con le soglie di margine e apre le richieste di approvazione mancanti.
+// Va chiamato dopo ogni ricalcolo prezzi e prima della registrazione; il documento va poi salvato dal chiamante
+func (uc *MarginControlUseCase) CheckDocument(
+	ctx context.Context,
+	document *domain.Document,
+	operator *domain.Operator,
+) ([]*domain.MarginApproval, error) {
+	existing, err := uc.approvalRepo.FindByDocument(ctx, document.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*domain.MarginApproval, len(existing))
+	for _, approval := range existing {
+		byID[approval.ID] = approval
+	}
+
+	var pending []*domain.MarginApproval
+
+	for i, line := range document.Lines {
+		var current *domain.MarginApproval
+		if line.Approval != nil {
+			current = byID[line.Approval.ApprovalID]
+		}
+
+		violation := domain.MarginViolationNone
+		unitPrice := line.UnitPrice
+
+		if document.RequiresMarginControl() {
+			article, err := uc.articleRepo.FindByID(ctx, line.ArticleID)
+			if err != nil {
+				return nil, err
+			}
+
+			// il prezzo effettivo comprende gli sconti di documento (bundle) ripartiti sulla riga
+			if line.Quantity > 0 {
+				unitPrice = line.Total / line.Quantity
+			}
+
+			violation = uc.policy.Classify(article, unitPrice)
+
+			if violation != domain.MarginViolationNone {
+				if current != nil && current.AppliesTo(article.ID, unitPrice) {
+					document.SetLineApproval(i, current)
+					if current.IsPending() {
+						pending = append(pending, current)
+					}
+					continue
+				}
+
+				if err := uc.cancel(ctx, current, operator); err != nil {
+					return nil, err
+				}
+
+				approval := domain.NewMarginApproval(document, i, article, unitPrice, violation, uc.policy, operator.Username)
+				if err := uc.approvalRepo.Create(ctx, approval); err != nil {
+					return nil, err
+				}
+
+				if err := uc.audit(ctx, operator, "request_margin_approval", approval,
+					fmt.Sprintf("%s %s: price %.2f, margin %.2f%% < %.2f%%",
+						approval.Violation, approval.ArticleCode, approval.UnitPrice, approval.MarginPercent, approval.ThresholdPercent)); err != nil {
+					return nil, err
+				}
+
+				document.SetLineApproval(i, approval)
+				pending = append(pending, approval)
+				continue
+			}
+		}
+
+		if err := uc.cancel(ctx, current, operator); err != nil {
+			return nil, err
+		}
+		document.SetLineApproval(i, nil)
+	}
+
+	// le richieste di righe eliminate non sono più referenziate da nessuna riga
+	referenced := make(map[primitive.ObjectID]bool, len(document.Lines))
+	for _, line := range document.Lines {
+		if line.Approval != nil {
+			referenced[line.Approval.ApprovalID] = true
+		}
+	}
+	for _, approval := range existing {
+		if referenced[approval.ID] {
+			continue
+		}
+		if err := uc.cancel(ctx, approval, operator); err != nil {
+			return nil, err
+		}
+	}
+
+	return pending, nil
+}
+
+func (uc *MarginControlUseCase) cancel(ctx context.Context, approval *domain.MarginApproval, operator *domain.Operator) error {
+	if approval == nil || !approval.IsPending() {
+		return nil
+	}
+
+	approval.Cancel(operator.Username)
+	if err := uc.approvalRepo.SaveDecision(ctx, approval); err != nil && !errors.Is(err, domain.ErrApprovalNotPending) {
+		return err
+	}
+
+	return uc.audit(ctx, operator, "cancel_margin_approval", approval, approval.ArticleCode)
+}
+
+func (uc *MarginControlUseCase) GetPendingApprovals(ctx context.Context, limit int) ([]*domain.MarginApproval, error) {
+	return uc.approvalRepo.FindPending(ctx, limit)
+}
+
+func (uc *MarginControlUseCase) GetDocumentApprovals(ctx context.Context, documentID primitive.ObjectID) ([]*domain.MarginApproval, error) {
+	return uc.approvalRepo.FindByDocument(ctx, documentID)
+}
+
+// Approve concede l'approvazione dalla coda di un operatore abilitato
+func (uc *MarginControlUseCase) Approve(
+	ctx context.Context,
+	approvalID primitive.ObjectID,
+	approver *domain.Operator,
+	note string,
+) (*domain.MarginApproval, error) {
+	approval, err := uc.approvalRepo.FindByID(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := approval.Approve(approver, domain.ApprovalMethodQueue, note); err != nil {
+		return nil, err
+	}
+
+	if err := uc.saveDecision(ctx, approval, approver); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+func (uc *MarginControlUseCase) Reject(
+	ctx context.Context,
+	approvalID primitive.ObjectID,
+	approver *domain.Operator,
+	note string,
+) (*domain.MarginApproval, error) {
+	approval, err := uc.approvalRepo.FindByID(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := approval.Reject(approver, note); err != nil {
+		return nil, err
+	}
+
+	if err := uc.saveDecision(ctx, approval, approver); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// ApproveWithPIN concede l'approvazione sul posto: il supervisore si identifica con username e PIN
+func (uc *MarginControlUseCase) ApproveWithPIN(
+	ctx context.Context,
+	approvalID primitive.ObjectID,
+	supervisorUsername, pin, note string,
+) (*domain.MarginApproval, error) {
+	supervisor, err := uc.authenticateSupervisor(ctx, supervisorUsername, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	approval, err := uc.approvalRepo.FindByID(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := approval.Approve(supervisor, domain.ApprovalMethodPIN, note); err != nil {
+		return nil, err
+	}
+
+	if err := uc.saveDecision(ctx, approval, supervisor); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// ApproveDocumentWithPIN approva in un colpo tutte le righe in attesa del documento aperto al banco
+func (uc *MarginControlUseCase) ApproveDocumentWithPIN(
+	ctx context.Context,
+	document *domain.Document,
+	supervisorUsername, pin, note string,
+) error {
+	supervisor, err := uc.authenticateSupervisor(ctx, supervisorUsername, pin)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range document.UnapprovedLines() {
+		approval, err := uc.approvalRepo.FindByID(ctx, document.Lines[index].Approval.ApprovalID)
+		if err != nil {
+			return err
+		}
+		if !approval.IsPending() {
+			continue
+		}
+
+		if err := approval.Approve(supervisor, domain.ApprovalMethodPIN, note); err != nil {
+			return err
+		}
+		if err := uc.approvalRepo.SaveDecision(ctx, approval); err != nil {
+			return err
+		}
+		if err := uc.audit(ctx, supervisor, "approve_margin", approval, decisionDetails(approval)); err != nil {
+			return err
+		}
+
+		document.SetLineApproval(index, approval)
+	}
+
+	return nil
+}
+
+func (uc *MarginControlUseCase) SetSupervisorPIN(ctx context.Context, operator *domain.Operator, pin string) error {
+	if !operator.CanApproveSottocosto() {
+		return domain.ErrInsufficientPermissions
+	}
+
+	if err := operator.SetSupervisorPIN(pin); err != nil {
+		return err
+	}
+
+	if err := uc.operatorRepo.UpdateSupervisorPIN(ctx, operator); err != nil {
+		return err
+	}
+
+	return uc.persistAudit(ctx, operator, "set_supervisor_pin", "operators", operator.ID.Hex(), "")
+}
+
+// authenticateSupervisor passa dal LoginUseCase, così i PIN errati contano per il blocco dell'account
+func (uc *MarginControlUseCase) authenticateSupervisor(ctx context.Context, username, pin string) (*domain.Operator, error) {
+	supervisor, err := uc.loginUC.CheckSupervisorPIN(ctx, username, pin)
+	if err != nil {
+		if supervisor != nil && (errors.Is(err, domain.ErrInvalidSupervisorPIN) || errors.Is(err, domain.ErrOperatorLocked)) {
+			if auditErr := uc.persistAudit(ctx, supervisor, "supervisor_pin_failed", "margin_approvals", "", ""); auditErr != nil {
+				return nil, auditErr
+			}
+		}
+		return nil, err
+	}
+
+	if !supervisor.CanApproveSottocosto() {
+		return nil, domain.ErrInsufficientPermissions
+	}
+
+	return supervisor, nil
+}
+
+func (uc *MarginControlUseCase) saveDecision(ctx context.Context, approval *domain.MarginApproval, approver *domain.Operator) error {
+	if err := uc.approvalRepo.SaveDecision(ctx, approval); err != nil {
+		return err
+	}
+
+	action := "approve_margin"
+	if approval.Status == domain.ApprovalStatusRejected {
+		action = "reject_margin"
+	}
+	if err := uc.audit(ctx, approver, action, approval, decisionDetails(approval)); err != nil {
+		return err
+	}
+
+	return uc.syncDocument(ctx, approval)
+}
+
+// syncDocument riporta l'esito sulla riga della bozza salvata; le bozze non ancora salvate
+// si allineano alla prossima CheckDocument
+func (uc *MarginControlUseCase) syncDocument(ctx context.Context, approval *domain.MarginApproval) error {
+	document, err := uc.documentRepo.FindByID(ctx, approval.DocumentID)
+	if errors.Is(err, domain.ErrDocumentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !document.IsDraft() {
+		return nil
+	}
+
+	for i, line := range document.Lines {
+		if line.Approval != nil && line.Approval.ApprovalID == approval.ID {
+			document.SetLineApproval(i, approval)
+			return uc.documentRepo.Update(ctx, document)
+		}
+	}
+
+	return nil
+}
+
+func (uc *MarginControlUseCase) audit(
+	ctx context.Context,
+	operator *domain.Operator,
+	action string,
+	approval *domain.MarginApproval,
+	details string,
+) error {
+	return uc.persistAudit(ctx, operator, action, "margin_approvals", approval.ID.Hex(), details)
+}
+
+// persistAudit scrive subito la voce nel registro di audit: le decisioni sul margine devono restare tracciate
+func (uc *MarginControlUseCase) persistAudit(
+	ctx context.Context,
+	operator *domain.Operator,
+	action, area, resourceID, details string,
+) error {
+	return uc.auditLogger.LogAction(ctx, operator, action, area, resourceID, details, "")
+}
+
+func decisionDetails(approval *domain.MarginApproval) string {
+	details := fmt.Sprintf("%s %s for %s (requested by %s): price %.2f, margin %.2f%%, %s",
+		approval.Violation, approval.ArticleCode, approval.CustomerCode, approval.RequestedBy,
+		approval.UnitPrice, approval.MarginPercent, approval.Method)
+	if approval.Note != "" {
+		details += ": " + approval.Note
+	}
+	return details
+}
@@ -0,0 +1,178 @@
+// internal/usecase/post_documents.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/auth"
+)
+
+// PostDocumentsUseCase registra e storna i documenti, propagando l'effetto su buoni, promozioni, coupon e budget
+type PostDocumentsUseCase struct {
+	documentRepo    *repository.DocumentRepository
+	marginUC        *MarginControlUseCase
+	authorizationUC *DiscountAuthorizationUseCase
+	promotionUC     *ManagePromotionsUseCase
+	couponUC        *ManageCouponsUseCase
+	voucherUC       *ManageVouchersUseCase
+	budgetUC        *ManageBudgetsUseCase
+	audit           *auth.AuditLogger
+}
+
+func NewPostDocumentsUseCase(
+	documentRepo *repository.DocumentRepository,
+	marginUC *MarginControlUseCase,
+	authorizationUC *DiscountAuthorizationUseCase,
+	promotionUC *ManagePromotionsUseCase,
+	couponUC *ManageCouponsUseCase,
+	voucherUC *ManageVouchersUseCase,
+	budgetUC *ManageBudgetsUseCase,
+	audit *auth.AuditLogger,
+) *PostDocumentsUseCase {
+	return &PostDocumentsUseCase{
+		documentRepo:    documentRepo,
+		marginUC:        marginUC,
+		authorizationUC: authorizationUC,
+		promotionUC:     promotionUC,
+		couponUC:        couponUC,
+		voucherUC:       voucherUC,
+		budgetUC:        budgetUC,
+		audit:           audit,
+	}
+}
+
+// Post registra la bozza. Se ci sono righe sotto soglia di margine da approvare salva la bozza
+// con le richieste aperte e restituisce ErrMarginApprovalRequired
+func (uc *PostDocumentsUseCase) Post(ctx context.Context, document *domain.Document, operator *domain.Operator) error {
+	if !document.IsDraft() {
+		return domain.ErrDocumentNotDraft
+	}
+
+	if document.OperatorID.IsZero() {
+		document.SetOperator(operator)
+	}
+
+	pending, err := uc.marginUC.CheckDocument(ctx, document, operator)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if err := uc.save(ctx, document); err != nil {
+			return err
+		}
+		return domain.ErrMarginApprovalRequired
+	}
+
+	if err := uc.authorizationUC.AuthorizeDocument(ctx, operator, document); err != nil {
+		return err
+	}
+
+	// numerazione, registrazione, utilizzo di buoni, promozioni e coupon e consuntivi dei budget sono
+	// atomici: se un buono non ha più saldo o una promozione ha esaurito i suoi limiti il documento resta
+	// in bozza e il numero non viene consumato
+	var coupon *domain.Coupon
+	draft := *document
+	err = uc.documentRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		*document = draft
+
+		number, err := uc.documentRepo.NextNumber(ctx, document.Type, document.Date.Year())
+		if err != nil {
+			return err
+		}
+
+		if err := document.Post(number, operator.Username); err != nil {
+			return err
+		}
+
+		if err := uc.save(ctx, document); err != nil {
+			return err
+		}
+
+		if err := uc.voucherUC.RedeemForDocument(ctx, document, operator); err != nil {
+			return err
+		}
+
+		if _, err := uc.budgetUC.ApplyDocument(ctx, document); err != nil {
+			return err
+		}
+
+		if document.Type != domain.DocumentTypeInvoice {
+			return nil
+		}
+		if err := uc.promotionUC.RecordDocumentUsage(ctx, document, operator); err != nil {
+			return err
+		}
+		coupon, err = uc.couponUC.RedeemForDocument(ctx, document, operator)
+		return err
+	})
+	if err != nil {
+		*document = draft
+		return err
+	}
+
+	if err := uc.couponUC.LogRedemption(ctx, coupon, document, operator); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "post_document", "documents", document.ID.Hex(),
+		fmt.Sprintf("%s %s: %.2f", document.Type, document.Number, document.Totals.Total), "")
+}
+
+// Reverse storna un documento registrato e ne annulla l'effetto su buoni, promozioni, coupon e budget
+func (uc *PostDocumentsUseCase) Reverse(ctx context.Context, document *domain.Document, operator *domain.Operator) error {
+	var coupon *domain.Coupon
+	posted := *document
+	err := uc.documentRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		*document = posted
+
+		if err := document.Reverse(operator.Username); err != nil {
+			return err
+		}
+
+		if err := uc.documentRepo.Update(ctx, document); err != nil {
+			return err
+		}
+
+		if err := uc.voucherUC.RestoreForDocument(ctx, document, operator); err != nil {
+			return err
+		}
+
+		if _, err := uc.budgetUC.RevertDocument(ctx, document); err != nil {
+			return err
+		}
+
+		if document.Type != domain.DocumentTypeInvoice {
+			return nil
+		}
+		if err := uc.promotionUC.RevertDocumentUsage(ctx, document); err != nil {
+			return err
+		}
+		released, err := uc.couponUC.ReleaseForDocument(ctx, document)
+		coupon = released
+		return err
+	})
+	if err != nil {
+		*document = posted
+		return err
+	}
+
+	if err := uc.couponUC.LogRelease(ctx, coupon, document, operator); err != nil {
+		return err
+	}
+
+	return uc.audit.LogAction(ctx, operator, "reverse_document", "documents", document.ID.Hex(),
+		fmt.Sprintf("%s %s", document.Type, document.Number), "")
+}
+
+func (uc *PostDocumentsUseCase) save(ctx context.Context, document *domain.Document) error {
+	err := uc.documentRepo.Update(ctx, document)
+	if errors.Is(err, domain.ErrDocumentNotFound) {
+		return uc.documentRepo.Create(ctx, document)
+	}
+	return err
+}
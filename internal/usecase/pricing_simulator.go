@@ -0,0 +1,400 @@
+// internal/usecase/pricing_simulator.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+)
+
+// PricingSimulatorUseCase ricostruisce il prezzo di un articolo per un cliente a una data qualsiasi,
+// usando lo stesso motore di ManageDiscountsUseCase e spiegando ogni scelta
+type PricingSimulatorUseCase struct {
+	discountUC    *ManageDiscountsUseCase
+	priceUC       *ManagePricesUseCase
+	customerRepo  *repository.CustomerRepository
+	articleRepo   *repository.ArticleRepository
+	promotionRepo *repository.PromotionRepository
+	couponRepo    *repository.CouponRepository
+	usageRepo     *repository.PromotionUsageRepository
+}
+
+func NewPricingSimulatorUseCase(
+	discountUC *ManageDiscountsUseCase,
+	priceUC *ManagePricesUseCase,
+	customerRepo *repository.CustomerRepository,
+	articleRepo *repository.ArticleRepository,
+	promotionRepo *repository.PromotionRepository,
+	couponRepo *repository.CouponRepository,
+	usageRepo *repository.PromotionUsageRepository,
+) *PricingSimulatorUseCase {
+	return &PricingSimulatorUseCase{
+		discountUC:    discountUC,
+		priceUC:       priceUC,
+		customerRepo:  customerRepo,
+		articleRepo:   articleRepo,
+		promotionRepo: promotionRepo,
+		couponRepo:    couponRepo,
+		usageRepo:     usageRepo,
+	}
+}
+
+type PricingSimulation struct {
+	CustomerCode string
+	ArticleCode  string
+	Quantity     float64
+	Date         time.Time
+	CouponCode   string
+}
+
+type PricingTrace struct {
+	Customer   *domain.Customer
+	Article    *domain.Article
+	Quantity   float64
+	Date       time.Time
+	ListPrice  *PriceAtDate
+	CouponCode string
+	Coupon     *domain.Coupon
+	CouponNote string
+	NetPrices  []NetPriceEvaluation
+	Rules      []RuleEvaluation
+	Promotions []PromotionEvaluation
+	Result     *DiscountCalculation
+}
+
+type NetPriceEvaluation struct {
+	NetPrice domain.NetPrice
+	Applied  bool
+	Reason   string
+}
+
+type RuleEvaluation struct {
+	Rule    domain.DiscountRule
+	Applied bool
+	Reason  string
+}
+
+type PromotionEvaluation struct {
+	Promotion *domain.Promotion
+	Applied   bool
+	Reason    string
+}
+
+// Explain simula il prezzo alla data indicata e restituisce la traccia completa delle decisioni
+func (uc *PricingSimulatorUseCase) Explain(ctx context.Context, sim PricingSimulation) (*PricingTrace, error) {
+	if sim.Quantity <= 0 {
+		sim.Quantity = 1
+	}
+	if sim.Date.IsZero() {
+		sim.Date = time.Now()
+	}
+
+	customer, err := uc.customerRepo.FindByCode(ctx, sim.CustomerCode)
+	if err != nil {
+		return nil, err
+	}
+
+	article, err := uc.articleRepo.FindByCode(ctx, sim.ArticleCode)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &PricingTrace{
+		Customer:   customer,
+		Quantity:   sim.Quantity,
+		Date:       sim.Date,
+		CouponCode: domain.NormalizeCouponCode(sim.CouponCode),
+	}
+
+	trace.ListPrice, err = uc.priceUC.GetPriceAt(ctx, article.ID, sim.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	// il motore legge il listino dall'articolo: si prezza una copia con il listino valido alla data
+	historical := *article
+	historical.Pricing.ListPrice = trace.ListPrice.ListPrice
+	trace.Article = &historical
+
+	promotions, err := uc.promotionsAt(ctx, customer, sim.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	var usable []*domain.Promotion
+	for _, promo := range promotions {
+		if reason := deactivationReason(promo, sim.Date); reason != "" {
+			trace.Promotions = append(trace.Promotions, PromotionEvaluation{Promotion: promo, Reason: reason})
+			continue
+		}
+		usable = append(usable, promo)
+	}
+
+	if trace.CouponCode != "" {
+		trace.Coupon, err = uc.couponAt(ctx, trace.CouponCode, usable, sim.Date)
+		if errors.Is(err, domain.ErrCouponNotFound) {
+			trace.CouponNote = "coupon not found"
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := uc.discountUC.calculateFinalPrice(ctx, customer, trace.Article, sim.Quantity, trace.Coupon, usable, sim.Date)
+	if err != nil {
+		return nil, err
+	}
+	trace.Result = result
+
+	if trace.Coupon != nil && result.Coupon == nil {
+		trace.CouponNote = "no promotion of this coupon was applied"
+	}
+
+	trace.NetPrices = explainNetPrices(trace.Article, customer, sim.Date)
+	trace.Rules = explainRules(trace.Article, customer, sim.Quantity, sim.Date, result)
+
+	for _, promo := range usable {
+		trace.Promotions = append(trace.Promotions, explainPromotion(promo, trace.Article, customer, result))
+	}
+
+	return trace, nil
+}
+
+// promotionsAt carica le promozioni valide alla data. Per i giorni passati i contatori di utilizzo
+// vengono ricostruiti dal registro utilizzi, altrimenti si usano quelli correnti
+func (uc *PricingSimulatorUseCase) promotionsAt(
+	ctx context.Context,
+	customer *domain.Customer,
+	date time.Time,
+) ([]*domain.Promotion, error) {
+	promotions, err := uc.promotionRepo.FindValidAt(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart := domain.StartOfDay(date)
+	past := dayStart.Before(domain.StartOfDay(time.Now()))
+
+	for _, promo := range promotions {
+		usages, err := uc.usageRepo.FindByPromotion(ctx, promo.ID, time.Time{}, date)
+		if err != nil {
+			return nil, err
+		}
+
+		today := 0
+		byCustomer := 0
+		for _, usage := range usages {
+			if !usage.UsedAt.Before(dayStart) {
+				today++
+			}
+			if usage.CustomerID == customer.ID {
+				byCustomer++
+			}
+		}
+
+		if past {
+			promo.Statistics.TotalUsages = len(usages)
+			promo.Statistics.CustomerUsages = map[string]int{customer.ID.Hex(): byCustomer}
+		}
+		promo.SetDailyUsage(today)
+	}
+
+	return promotions, nil
+}
+
+// couponAt risolve il codice come ResolveCoupon, ma con i riscatti e le promozioni della data
+func (uc *PricingSimulatorUseCase) couponAt(
+	ctx context.Context,
+	code string,
+	promotions []*domain.Promotion,
+	date time.Time,
+) (*domain.Coupon, error) {
+	coupon, err := uc.couponRepo.FindByCode(ctx, code)
+	if err == nil {
+		return coupon.AsOf(date), nil
+	}
+	if !errors.Is(err, domain.ErrCouponNotFound) {
+		return nil, err
+	}
+
+	for _, promo := range promotions {
+		if promo.Conditions.CouponCode != "" && domain.NormalizeCouponCode(promo.Conditions.CouponCode) == code {
+			return domain.StaticCoupon(promo), nil
+		}
+	}
+
+	return nil, domain.ErrCouponNotFound
+}
+
+// deactivationReason distingue le promozioni scadute (disattivate dal job di scadenza, valide alla data)
+// da quelle disattivate a mano prima della data
+func deactivationReason(promo *domain.Promotion, date time.Time) string {
+	if promo.IsActive || promo.IsExpired(time.Now()) {
+		return ""
+	}
+	if promo.UpdatedAt.After(date) {
+		return ""
+	}
+	return "deactivated on " + promo.UpdatedAt.Format("02/01/2006")
+}
+
+func explainNetPrices(article *domain.Article, customer *domain.Customer, date time.Time) []NetPriceEvaluation {
+	applied := article.GetNetPriceAt(customer.ID, date)
+
+	var evaluations []NetPriceEvaluation
+	for _, np := range article.NetPricesFor(customer.ID) {
+		evaluation := NetPriceEvaluation{NetPrice: np, Reason: np.InvalidReason(date)}
+		if evaluation.Reason == "" {
+			if applied != nil && applied.ValidFrom.Equal(np.ValidFrom) && applied.Price == np.Price {
+				evaluation.Applied = true
+				evaluation.Reason = "valid, replaces list price and price lists"
+			} else {
+				evaluation.Reason = "valid, but another net price takes precedence"
+			}
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+
+	return evaluations
+}
+
+func explainRules(
+	article *domain.Article,
+	customer *domain.Customer,
+	quantity float64,
+	date time.Time,
+	result *DiscountCalculation,
+) []RuleEvaluation {
+	best := customer.GetApplicableDiscountAt(article, quantity, date)
+
+	var evaluations []RuleEvaluation
+	for i := range customer.DiscountGrid {
+		rule := &customer.DiscountGrid[i]
+		evaluation := RuleEvaluation{Rule: *rule, Reason: rule.RejectionReason(article, quantity, date)}
+
+		switch {
+		case evaluation.Reason != "":
+		case best != nil && best.ID != rule.ID:
+			evaluation.Reason = fmt.Sprintf("priority %d lower than rule %s (%d)", rule.Priority, best.ID.Hex(), best.Priority)
+		case rule.EffectivePercent() <= 0:
+			evaluation.Reason = "rule has no discount"
+		case result.AppliedRule != nil && result.AppliedRule.ID == rule.ID:
+			evaluation.Applied = true
+			evaluation.Reason = fmt.Sprintf("customer discount %.2f%%", rule.EffectivePercent())
+		default:
+			evaluation.Reason = decisionReason(result.Decisions, ruleSubject(rule), "not applied")
+		}
+
+		evaluations = append(evaluations, evaluation)
+	}
+
+	return evaluations
+}
+
+func explainPromotion(
+	promo *domain.Promotion,
+	article *domain.Article,
+	customer *domain.Customer,
+	result *DiscountCalculation,
+) PromotionEvaluation {
+	evaluation := PromotionEvaluation{Promotion: promo}
+
+	for _, applied := range result.AppliedPromotions {
+		if applied.ID == promo.ID {
+			evaluation.Applied = true
+			discount := 0.0
+			for _, step := range result.Steps() {
+				if step.Type == domain.PricingStepPromotion && step.Reference == promo.Code {
+					discount += step.Amount
+				}
+			}
+			evaluation.Reason = fmt.Sprintf("unit discount %.2f", discount)
+			return evaluation
+		}
+	}
+
+	switch {
+	case !promo.IsApplicableToArticle(article):
+		evaluation.Reason = "not applicable to article"
+	case !promo.IsApplicableToCustomer(customer):
+		evaluation.Reason = "not applicable to customer"
+	case promo.IsDocumentLevel():
+		evaluation.Reason = "evaluated on the whole document, not on the single line"
+	default:
+		evaluation.Reason = decisionReason(result.Decisions, promo.Code, "no discount at this price and quantity")
+	}
+
+	return evaluation
+}
+
+func decisionReason(decisions []domain.PricingDecision, subject, fallback string) string {
+	for _, decision := range decisions {
+		if decision.Subject == subject && !decision.Accepted {
+			return decision.Reason
+		}
+	}
+	return fallback
+}
+
+func (t *PricingTrace) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("Customer %s, article %s, quantity %.2f, date %s",
+			t.Customer.Code, t.Article.Code, t.Quantity, t.Date.Format("02/01/2006 15:04")),
+		fmt.Sprintf("List price %.2f (%s)", t.ListPrice.ListPrice, t.ListPrice.Source),
+	}
+
+	if t.CouponCode != "" {
+		line := "Coupon " + t.CouponCode
+		if t.CouponNote != "" {
+			line += ": " + t.CouponNote
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", "Net prices:")
+	if len(t.NetPrices) == 0 {
+		lines = append(lines, "  none for this customer")
+	}
+	for _, np := range t.NetPrices {
+		lines = append(lines, fmt.Sprintf("  %s %.2f: %s", mark(np.Applied), np.NetPrice.Price, np.Reason))
+	}
+
+	if t.Result.AppliedPriceList != nil {
+		lines = append(lines, "", fmt.Sprintf("Price list %s: %.2f", t.Result.AppliedPriceList.Code, t.Result.PriceListPrice))
+	}
+
+	lines = append(lines, "", "Discount rules:")
+	if len(t.Rules) == 0 {
+		lines = append(lines, "  none for this customer")
+	}
+	for _, r := range t.Rules {
+		lines = append(lines, fmt.Sprintf("  %s rule %s (priority %d): %s", mark(r.Applied), r.Rule.ID.Hex(), r.Rule.Priority, r.Reason))
+	}
+
+	lines = append(lines, "", "Promotions:")
+	if len(t.Promotions) == 0 {
+		lines = append(lines, "  none valid on this date")
+	}
+	for _, p := range t.Promotions {
+		lines = append(lines, fmt.Sprintf("  %s %s: %s", mark(p.Applied), p.Promotion.Code, p.Reason))
+	}
+
+	lines = append(lines, "", "Price:")
+	for _, step := range t.Result.Breakdown.Lines() {
+		lines = append(lines, "  "+step)
+	}
+	lines = append(lines, fmt.Sprintf("Final price %.2f (discount %.2f%%)", t.Result.FinalPrice, t.Result.DiscountPercent))
+
+	return lines
+}
+
+func mark(applied bool) string {
+	if applied {
+		return "[x]"
+	}
+	return "[ ]"
+}
@@ -0,0 +1,297 @@
+// internal/usecase/promotion_analytics.go
+
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/internal/repository"
+	"ricambi-manager/pkg/export"
+)
+
+type PromotionAnalyticsUseCase struct {
+	promotionRepo *repository.PromotionRepository
+	usageRepo     *repository.PromotionUsageRepository
+	documentRepo  *repository.DocumentRepository
+	articleRepo   *repository.ArticleRepository
+}
+
+func NewPromotionAnalyticsUseCase(
+	promotionRepo *repository.PromotionRepository,
+	usageRepo *repository.PromotionUsageRepository,
+	documentRepo *repository.DocumentRepository,
+	articleRepo *repository.ArticleRepository,
+) *PromotionAnalyticsUseCase {
+	return &PromotionAnalyticsUseCase{
+		promotionRepo: promotionRepo,
+		usageRepo:     usageRepo,
+		documentRepo:  documentRepo,
+		articleRepo:   articleRepo,
+	}
+}
+
+type PromotionReport struct {
+	Promotion           *domain.Promotion
+	From                time.Time
+	To                  time.Time
+	BaselineFrom        time.Time
+	BaselineTo          time.Time
+	Redemptions         int
+	Revenue             float64
+	Discount            float64
+	UnitsSold           float64
+	BaselineUnits       float64
+	IncrementalUnits    float64
+	IncrementalPercent  float64
+	MarginPercent       float64
+	BaselineMargin      float64
+	MarginErosionPoints float64
+	DiscountPerUnit     float64
+	TopCustomers        []CustomerRedemptions
+	DailyRedemptions    []DailyRedemptions
+}
+
+type CustomerRedemptions struct {
+	CustomerID   primitive.ObjectID
+	CustomerCode string
+	Redemptions  int
+	Revenue      float64
+	Discount     float64
+}
+
+type DailyRedemptions struct {
+	Date        time.Time
+	Redemptions int
+	Discount    float64
+}
+
+type salesTotals struct {
+	units   float64
+	revenue float64
+	margin  float64
+}
+
+// AnalyzePromotion confronta il periodo della promozione con un periodo base della stessa durata che lo precede
+func (uc *PromotionAnalyticsUseCase) AnalyzePromotion(
+	ctx context.Context,
+	promotionID primitive.ObjectID,
+	from, to time.Time,
+) (*PromotionReport, error) {
+	promo, err := uc.promotionRepo.FindByID(ctx, promotionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.IsZero() {
+		from = promo.ValidFrom
+	}
+	if to.IsZero() || (!promo.ValidTo.IsZero() && to.After(promo.ValidTo)) {
+		to = promo.ValidTo
+	}
+	if to.IsZero() || to.After(time.Now()) {
+		to = time.Now()
+	}
+	if to.Before(from) {
+		to = from
+	}
+
+	report := &PromotionReport{
+		Promotion:    promo,
+		From:         from,
+		To:           to,
+		BaselineFrom: from.Add(-to.Sub(from)),
+		BaselineTo:   from,
+	}
+
+	usages, err := uc.usageRepo.FindByPromotion(ctx, promo.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	customers := make(map[primitive.ObjectID]*CustomerRedemptions)
+	days := make(map[time.Time]*DailyRedemptions)
+
+	for _, usage := range usages {
+		report.Redemptions++
+		report.Revenue += usage.Revenue
+		report.Discount += usage.Discount
+
+		c, exists := customers[usage.CustomerID]
+		if !exists {
+			c = &CustomerRedemptions{CustomerID: usage.CustomerID}
+			customers[usage.CustomerID] = c
+		}
+		c.Redemptions++
+		c.Revenue += usage.Revenue
+		c.Discount += usage.Discount
+
+		day := domain.StartOfDay(usage.UsedAt)
+		d, exists := days[day]
+		if !exists {
+			d = &DailyRedemptions{Date: day}
+			days[day] = d
+		}
+		d.Redemptions++
+		d.Discount += usage.Discount
+	}
+
+	articles := make(map[primitive.ObjectID]*domain.Article)
+	customerCodes := make(map[primitive.ObjectID]string)
+
+	current, err := uc.salesOfApplicableArticles(ctx, promo, from, to, articles, customerCodes)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := uc.salesOfApplicableArticles(ctx, promo, report.BaselineFrom, report.BaselineTo, articles, customerCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	report.UnitsSold = current.units
+	report.BaselineUnits = baseline.units
+	report.IncrementalUnits = current.units - baseline.units
+	if baseline.units > 0 {
+		report.IncrementalPercent = report.IncrementalUnits / baseline.units * 100
+	}
+	if current.revenue > 0 {
+		report.MarginPercent = current.margin / current.revenue * 100
+	}
+	if baseline.revenue > 0 {
+		report.BaselineMargin = baseline.margin / baseline.revenue * 100
+	}
+	if baseline.revenue > 0 && current.revenue > 0 {
+		report.MarginErosionPoints = report.BaselineMargin - report.MarginPercent
+	}
+	if report.IncrementalUnits > 0 {
+		report.DiscountPerUnit = report.Discount / report.IncrementalUnits
+	}
+
+	for _, c := range customers {
+		c.CustomerCode = customerCodes[c.CustomerID]
+		report.TopCustomers = append(report.TopCustomers, *c)
+	}
+	sort.Slice(report.TopCustomers, func(i, j int) bool {
+		return report.TopCustomers[i].Revenue > report.TopCustomers[j].Revenue
+	})
+	if len(report.TopCustomers) > 10 {
+		report.TopCustomers = report.TopCustomers[:10]
+	}
+
+	for _, d := range days {
+		report.DailyRedemptions = append(report.DailyRedemptions, *d)
+	}
+	sort.Slice(report.DailyRedemptions, func(i, j int) bool {
+		return report.DailyRedemptions[i].Date.Before(report.DailyRedemptions[j].Date)
+	})
+
+	return report, nil
+}
+
+func (uc *PromotionAnalyticsUseCase) salesOfApplicableArticles(
+	ctx context.Context,
+	promo *domain.Promotion,
+	from, to time.Time,
+	articles map[primitive.ObjectID]*domain.Article,
+	customerCodes map[primitive.ObjectID]string,
+) (salesTotals, error) {
+	var totals salesTotals
+
+	documents, err := uc.documentRepo.FindPosted(ctx,
+		[]domain.DocumentType{domain.DocumentTypeInvoice, domain.DocumentTypeCreditNote}, from, to)
+	if err != nil {
+		return totals, err
+	}
+
+	for _, document := range documents {
+		customerCodes[document.CustomerID] = document.CustomerCode
+
+		for _, line := range document.Lines {
+			article, exists := articles[line.ArticleID]
+			if !exists {
+				article, err = uc.articleRepo.FindByID(ctx, line.ArticleID)
+				if errors.Is(err, domain.ErrArticleNotFound) {
+					// articolo eliminato: non si può valutare l'applicabilità
+					articles[line.ArticleID] = nil
+					continue
+				}
+				if err != nil {
+					return totals, err
+				}
+				articles[line.ArticleID] = article
+			}
+			if article == nil || !promo.IsApplicableToArticle(article) {
+				continue
+			}
+
+			sign := document.Sign()
+			totals.units += sign * line.Quantity
+			totals.revenue += sign * line.Total
+			totals.margin += sign * line.Margin
+		}
+	}
+
+	return totals, nil
+}
+
+// ComparePromotions analizza più promozioni, ordinate per unità incrementali
+func (uc *PromotionAnalyticsUseCase) ComparePromotions(
+	ctx context.Context,
+	promotionIDs []primitive.ObjectID,
+) ([]*PromotionReport, error) {
+	var reports []*PromotionReport
+
+	for _, id := range promotionIDs {
+		report, err := uc.AnalyzePromotion(ctx, id, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].IncrementalUnits > reports[j].IncrementalUnits
+	})
+
+	return reports, nil
+}
+
+func (uc *PromotionAnalyticsUseCase) ReportsTable(reports []*PromotionReport) *export.Table {
+	table := export.NewTable(
+		"Codice", "Promozione", "Dal", "Al", "Utilizzi", "Fatturato", "Sconto",
+		"Unità", "Unità base", "Unità incrementali", "Incremento %",
+		"Margine %", "Margine base %", "Erosione margine (punti)", "Sconto per unità incrementale",
+	)
+
+	for _, r := range reports {
+		table.AddRow(
+			r.Promotion.Code,
+			r.Promotion.Name,
+			export.Date(r.From),
+			export.Date(r.To),
+			strconv.Itoa(r.Redemptions),
+			export.Amount(r.Revenue),
+			export.Amount(r.Discount),
+			export.Amount(r.UnitsSold),
+			export.Amount(r.BaselineUnits),
+			export.Amount(r.IncrementalUnits),
+			export.Percent(r.IncrementalPercent),
+			export.Percent(r.MarginPercent),
+			export.Percent(r.BaselineMargin),
+			export.Percent(r.MarginErosionPoints),
+			export.Amount(r.DiscountPerUnit),
+		)
+	}
+
+	return table
+}
+
+func (uc *PromotionAnalyticsUseCase) ExportReports(reports []*PromotionReport, dir string) (string, error) {
+	return export.SaveCSV(dir, "promozioni", uc.ReportsTable(reports))
+}
@@ -0,0 +1,215 @@
+// internal/usecase/two_factor.go
+
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/auth"
+	"ricambi-manager/pkg/barcode"
+)
+
+const recoveryCodeCount = 10
+
+// TOTPEnrollment è quanto serve per configurare l'app di autenticazione: il QR da inquadrare
+// oppure il segreto da digitare a mano
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QR     string
+}
+
+// SecondFactorRequired dice se l'operatore deve inserire il codice dopo la password: sempre se ha
+// attivato il 2FA, e per i profili che la configurazione lo rende obbligatorio
+func (uc *LoginUseCase) SecondFactorRequired(operator *domain.Operator) bool {
+	return operator.TOTPEnabled || uc.TwoFactorMandatory(operator)
+}
+
+func (uc *LoginUseCase) TwoFactorMandatory(operator *domain.Operator) bool {
+	for _, profile := range uc.twoFactorProfiles {
+		if operator.Profile == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifySecondFactor completa il login con un codice TOTP o un codice di recupero. I codici errati
+// contano come password errate, quindi il blocco dell'account vale anche per il secondo passo
+func (uc *LoginUseCase) VerifySecondFactor(ctx context.Context, result *LoginResult, code, terminal string) error {
+	operator := result.Operator
+	userKey := "user:" + operator.Username
+	terminalKey := "terminal:" + terminal
+
+	if blocked, wait := uc.userLimiter.Blocked(userKey); blocked {
+		result.RetryAfter = wait
+		uc.audit.LogFailedAccess(operator.Username, "login_2fa", "auth", "throttled username", terminal)
+		return domain.ErrLoginThrottled
+	}
+	// anche il secondo passo conta per il terminale, altrimenti da una postazione bloccata si proverebbero codici su altri utenti
+	if blocked, wait := uc.terminalLimiter.Blocked(terminalKey); blocked {
+		result.RetryAfter = wait
+		uc.audit.LogFailedAccess(operator.Username, "login_2fa", "auth", "throttled terminal", terminal)
+		return domain.ErrLoginThrottled
+	}
+
+	ok, err := uc.checkCode(ctx, operator, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		uc.recordFailure(userKey, terminalKey)
+		if err := uc.failedPassword(ctx, result, terminal, "wrong two-factor code"); err != domain.ErrInvalidCredentials {
+			return err
+		}
+		return domain.ErrInvalidTOTPCode
+	}
+
+	uc.userLimiter.Reset(userKey)
+	if operator.FailedAttempts > 0 {
+		if err := uc.operatorRepo.ResetFailedAttempts(ctx, operator.ID); err != nil {
+			return err
+		}
+		operator.FailedAttempts = 0
+	}
+
+	return nil
+}
+
+func (uc *LoginUseCase) checkCode(ctx context.Context, operator *domain.Operator, code string) (bool, error) {
+	if !operator.TOTPEnabled {
+		return false, domain.ErrTOTPNotEnrolled
+	}
+
+	if auth.IsRecoveryCode(code) {
+		hash := auth.HashRecoveryCode(code)
+		used, err := uc.operatorRepo.UseRecoveryCode(ctx, operator.ID, hash)
+		if err != nil || !used {
+			return false, err
+		}
+		// l'operatore in memoria resta allineato all'archivio, che potrebbe essere salvato di nuovo
+		remaining := make([]string, 0, len(operator.RecoveryCodeHashes))
+		for _, h := range operator.RecoveryCodeHashes {
+			if h != hash {
+				remaining = append(remaining, h)
+			}
+		}
+		operator.RecoveryCodeHashes = remaining
+		return true, uc.audit.LogAction(ctx, operator, "use_recovery_code", "auth", operator.ID.Hex(), "", "")
+	}
+
+	counter, ok := auth.ValidateTOTP(operator.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	used, err := uc.operatorRepo.UseTOTPCounter(ctx, operator.ID, counter)
+	if used {
+		operator.TOTPLastCounter = counter
+	}
+	return used, err
+}
+
+// BeginTOTPEnrollment genera un nuovo segreto senza salvarlo: il 2FA già attivo resta valido finché
+// il nuovo segreto non viene confermato da ConfirmTOTPEnrollment
+func (uc *LoginUseCase) BeginTOTPEnrollment(ctx context.Context, operator *domain.Operator) (*TOTPEnrollment, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := auth.TOTPProvisioningURI(uc.issuer, operator.Username, secret)
+	qr, err := barcode.NewBarcodeGenerator().GenerateTerminalQR(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{Secret: secret, URI: uri, QR: qr}, nil
+}
+
+// ConfirmTOTPEnrollment attiva il 2FA se il codice corrisponde al segreto e restituisce i codici
+// di recupero, mostrati una sola volta: sul database restano solo le impronte
+func (uc *LoginUseCase) ConfirmTOTPEnrollment(ctx context.Context, operator *domain.Operator, enrollment *TOTPEnrollment, code string) ([]string, error) {
+	if enrollment == nil {
+		return nil, domain.ErrTOTPNotEnrolled
+	}
+
+	counter, ok := auth.ValidateTOTP(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.operatorRepo.EnableTOTP(ctx, operator.ID, enrollment.Secret, counter, hashes); err != nil {
+		return nil, err
+	}
+
+	operator.TOTPSecret = enrollment.Secret
+	operator.TOTPEnabled = true
+	operator.TOTPLastCounter = counter
+	operator.RecoveryCodeHashes = hashes
+
+	return codes, uc.audit.LogAction(ctx, operator, "enable_2fa", "auth", operator.ID.Hex(), "", "")
+}
+
+// RegenerateRecoveryCodes sostituisce i codici di recupero, invalidando quelli precedenti
+func (uc *LoginUseCase) RegenerateRecoveryCodes(ctx context.Context, operator *domain.Operator) ([]string, error) {
+	if !operator.TOTPEnabled {
+		return nil, domain.ErrTOTPNotEnrolled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.operatorRepo.SetRecoveryCodes(ctx, operator.ID, hashes); err != nil {
+		return nil, err
+	}
+	operator.RecoveryCodeHashes = hashes
+
+	return codes, uc.audit.LogAction(ctx, operator, "regenerate_recovery_codes", "auth", operator.ID.Hex(), "", "")
+}
+
+// DisableTOTP richiede un codice valido; per i profili con 2FA obbligatorio non è consentito
+func (uc *LoginUseCase) DisableTOTP(ctx context.Context, operator *domain.Operator, code string) error {
+	if uc.TwoFactorMandatory(operator) {
+		return domain.ErrInsufficientPermissions
+	}
+
+	ok, err := uc.checkCode(ctx, operator, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrInvalidTOTPCode
+	}
+
+	if err := uc.operatorRepo.DisableTOTP(ctx, operator.ID); err != nil {
+		return err
+	}
+	operator.TOTPSecret = ""
+	operator.TOTPEnabled = false
+	operator.TOTPLastCounter = 0
+	operator.RecoveryCodeHashes = nil
+
+	return uc.audit.LogAction(ctx, operator, "disable_2fa", "auth", operator.ID.Hex(), "", "")
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
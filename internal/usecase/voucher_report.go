@@ -0,0 +1,203 @@
+// internal/usecase/voucher_report.go
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"ricambi-manager/internal/domain"
+	"ricambi-manager/pkg/export"
+)
+
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatXLSX = "xlsx"
+)
+
+type VoucherReportLine struct {
+	Code         string
+	CustomerCode string
+	CustomerName string
+	IssuedDate   time.Time
+	ExpiryDate   time.Time
+	Amount       float64
+}
+
+// VoucherLiabilityRow è il debito residuo verso un cliente, diviso per anzianità dei buoni
+type VoucherLiabilityRow struct {
+	CustomerID   primitive.ObjectID
+	CustomerCode string
+	CustomerName string
+	Buckets      []float64
+	Total        float64
+	Vouchers     int
+}
+
+// VoucherLiabilityReport è la situazione dei buoni a una data, ricostruita dal registro: residuo per
+// cliente e anzianità, buoni in scadenza nei giorni successivi e importi scaduti non usati dall'inizio dell'anno
+type VoucherLiabilityReport struct {
+	AsOf         time.Time
+	ExpiringDays int
+
+	Rows         []*VoucherLiabilityRow
+	BucketTotals []float64
+	Total        float64
+
+	Expiring      []VoucherReportLine
+	ExpiringTotal float64
+
+	ExpiredFrom  time.Time
+	Expired      []VoucherReportLine
+	ExpiredTotal float64
+}
+
+func (uc *ManageVouchersUseCase) LiabilityReport(ctx context.Context, asOf time.Time, expiringDays int) (*VoucherLiabilityReport, error) {
+	asOf = time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 23, 59, 59, 0, asOf.Location())
+
+	entries, err := uc.ledgerRepo.FindUntil(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	states := domain.ReplayVoucherLedger(entries, asOf)
+
+	customerIDs := make([]primitive.ObjectID, 0, len(states))
+	seen := map[primitive.ObjectID]bool{}
+	for _, state := range states {
+		if !seen[state.CustomerID] {
+			seen[state.CustomerID] = true
+			customerIDs = append(customerIDs, state.CustomerID)
+		}
+	}
+	customers, err := uc.customerRepo.FindByIDs(ctx, customerIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*domain.Customer, len(customers))
+	for _, customer := range customers {
+		byID[customer.ID] = customer
+	}
+
+	report := &VoucherLiabilityReport{
+		AsOf:         asOf,
+		ExpiringDays: expiringDays,
+		BucketTotals: make([]float64, len(domain.VoucherAgeBuckets)),
+		ExpiredFrom:  time.Date(asOf.Year(), 1, 1, 0, 0, 0, 0, asOf.Location()),
+	}
+	rows := map[primitive.ObjectID]*VoucherLiabilityRow{}
+	expiringUntil := asOf.AddDate(0, 0, expiringDays)
+
+	for _, state := range states {
+		line := VoucherReportLine{
+			Code:         state.Code,
+			CustomerCode: state.CustomerID.Hex(),
+			IssuedDate:   state.IssuedDate,
+			ExpiryDate:   state.ExpiryDate,
+		}
+		if customer, ok := byID[state.CustomerID]; ok {
+			line.CustomerCode = customer.Code
+			line.CustomerName = customer.CompanyName
+		}
+
+		if state.Balance > 0 && !state.Cancelled {
+			row, ok := rows[state.CustomerID]
+			if !ok {
+				row = &VoucherLiabilityRow{
+					CustomerID:   state.CustomerID,
+					CustomerCode: line.CustomerCode,
+					CustomerName: line.CustomerName,
+					Buckets:      make([]float64, len(domain.VoucherAgeBuckets)),
+				}
+				rows[state.CustomerID] = row
+				report.Rows = append(report.Rows, row)
+			}
+
+			bucket := domain.VoucherAgeBucket(state.IssuedDate, asOf)
+			row.Buckets[bucket] = roundCents(row.Buckets[bucket] + state.Balance)
+			row.Total = roundCents(row.Total + state.Balance)
+			row.Vouchers++
+			report.BucketTotals[bucket] = roundCents(report.BucketTotals[bucket] + state.Balance)
+			report.Total = roundCents(report.Total + state.Balance)
+
+			if !state.ExpiryDate.IsZero() && !state.ExpiryDate.After(expiringUntil) {
+				line.Amount = state.Balance
+				report.Expiring = append(report.Expiring, line)
+				report.ExpiringTotal = roundCents(report.ExpiringTotal + state.Balance)
+			}
+		}
+
+		if state.ExpiredAmount > 0 && !state.ExpiredAt.Before(report.ExpiredFrom) {
+			line.Amount = state.ExpiredAmount
+			report.Expired = append(report.Expired, line)
+			report.ExpiredTotal = roundCents(report.ExpiredTotal + state.ExpiredAmount)
+		}
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool { return report.Rows[i].Total > report.Rows[j].Total })
+	sort.SliceStable(report.Expiring, func(i, j int) bool {
+		return report.Expiring[i].ExpiryDate.Before(report.Expiring[j].ExpiryDate)
+	})
+	sort.SliceStable(report.Expired, func(i, j int) bool {
+		return report.Expired[i].ExpiryDate.Before(report.Expired[j].ExpiryDate)
+	})
+
+	return report, nil
+}
+
+// ExportLiabilityReport salva il prospetto in CSV o XLSX, con le tre sezioni una sotto l'altra
+func (uc *ManageVouchersUseCase) ExportLiabilityReport(report *VoucherLiabilityReport, format, dir string) (string, error) {
+	headers := []string{"Sezione", "Cliente", "Ragione sociale", "Buono", "Emesso", "Scadenza"}
+	for _, bucket := range domain.VoucherAgeBuckets {
+		headers = append(headers, "Giorni "+bucket.Label)
+	}
+	headers = append(headers, "Importo")
+	table := export.NewTable(headers...)
+
+	empty := make([]string, len(domain.VoucherAgeBuckets))
+	row := func(section, customer, name, code string, issued, expiry time.Time, buckets []string, amount float64) {
+		values := []string{section, customer, name, code, export.Date(issued), export.Date(expiry)}
+		values = append(values, buckets...)
+		table.AddRow(append(values, export.Amount(amount))...)
+	}
+	amounts := func(values []float64) []string {
+		formatted := make([]string, len(values))
+		for i, value := range values {
+			formatted[i] = export.Amount(value)
+		}
+		return formatted
+	}
+
+	table.AddRow("Situazione al", export.Date(report.AsOf))
+	for _, r := range report.Rows {
+		row("Residuo", r.CustomerCode, r.CustomerName, fmt.Sprintf("%d buoni", r.Vouchers),
+			time.Time{}, time.Time{}, amounts(r.Buckets), r.Total)
+	}
+	row("Totale residuo", "", "", "", time.Time{}, time.Time{}, amounts(report.BucketTotals), report.Total)
+
+	for _, line := range report.Expiring {
+		row(fmt.Sprintf("In scadenza %d gg", report.ExpiringDays), line.CustomerCode, line.CustomerName, line.Code,
+			line.IssuedDate, line.ExpiryDate, empty, line.Amount)
+	}
+	row("Totale in scadenza", "", "", "", time.Time{}, time.Time{}, empty, report.ExpiringTotal)
+
+	for _, line := range report.Expired {
+		row("Scaduto non usato", line.CustomerCode, line.CustomerName, line.Code,
+			line.IssuedDate, line.ExpiryDate, empty, line.Amount)
+	}
+	row("Totale scaduto dal "+export.Date(report.ExpiredFrom), "", "", "", time.Time{}, time.Time{}, empty, report.ExpiredTotal)
+
+	name := "buoni_residui_" + report.AsOf.Format("20060102")
+	if format == ReportFormatXLSX {
+		return export.SaveXLSX(dir, name, table)
+	}
+	return export.SaveCSV(dir, name, table)
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
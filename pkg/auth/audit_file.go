@@ -0,0 +1,75 @@
+// pkg/auth/audit_file.go
+
+package auth
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"ricambi-manager/internal/domain"
+)
+
+// AuditFile copia le voci di audit, una riga JSON ciascuna, in un file aperto solo in aggiunta
+type AuditFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewAuditFile(path string) *AuditFile {
+	return &AuditFile{path: path}
+}
+
+func (af *AuditFile) Write(record *domain.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	af.mu.Lock()
+	defer af.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(af.path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(af.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadAuditFile rilegge le voci del file; con più istanze ogni file contiene solo le voci scritte
+// dalla propria, quindi la catena completa va verificata sul database
+func ReadAuditFile(path string) ([]*domain.AuditRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*domain.AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record domain.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		records = append(records, &record)
+	}
+
+	return records, scanner.Err()
+}
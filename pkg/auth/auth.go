@@ -3,9 +3,12 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
 	"ricambi-manager/internal/domain"
@@ -14,34 +17,27 @@ import (
 var (
 	ErrInvalidToken    = errors.New("invalid authentication token")
 	ErrTokenExpired    = errors.New("authentication token expired")
-	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotFound = domain.ErrSessionNotFound
 	ErrUnauthorized    = errors.New("unauthorized access")
 )
 
+// AuthService gestisce le sessioni sullo SessionStore, unica fonte delle sessioni attive.
+// La scadenza è scorrevole: ogni RefreshSession la sposta di sessionTimeout dall'ultima attività
 type AuthService struct {
+	store          SessionStore
 	sessionTimeout time.Duration
-	sessions       map[string]*Session
 }
 
-type Session struct {
-	Token      string
-	OperatorID string
-	Username   string
-	Profile    domain.ProfileType
-	CreatedAt  time.Time
-	ExpiresAt  time.Time
-	IPAddress  string
-	UserAgent  string
-}
+type Session = domain.Session
 
-func NewAuthService(sessionTimeoutMinutes int) *AuthService {
+func NewAuthService(store SessionStore, sessionTimeoutMinutes int) *AuthService {
 	return &AuthService{
+		store:          store,
 		sessionTimeout: time.Duration(sessionTimeoutMinutes) * time.Minute,
-		sessions:       make(map[string]*Session),
 	}
 }
 
-func (s *AuthService) CreateSession(operator *domain.Operator, ipAddress, userAgent string) (*Session, error) {
+func (s *AuthService) CreateSession(ctx context.Context, operator *domain.Operator, ipAddress, userAgent string) (*Session, error) {
 	token, err := generateSecureToken(32)
 	if err != nil {
 		return nil, err
@@ -49,83 +45,76 @@ func (s *AuthService) CreateSession(operator *domain.Operator, ipAddress, userAg
 
 	now := time.Now()
 	session := &Session{
-		Token:      token,
-		OperatorID: operator.ID.Hex(),
-		Username:   operator.Username,
-		Profile:    operator.Profile,
-		CreatedAt:  now,
-		ExpiresAt:  now.Add(s.sessionTimeout),
-		IPAddress:  ipAddress,
-		UserAgent:  userAgent,
+		Token:        token,
+		OperatorID:   operator.ID.Hex(),
+		Username:     operator.Username,
+		Profile:      operator.Profile,
+		CreatedAt:    now,
+		LastActivity: now,
+		ExpiresAt:    now.Add(s.sessionTimeout),
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+	}
+
+	if err := s.store.Save(ctx, session); err != nil {
+		return nil, err
 	}
-
-	s.sessions[token] = session
-	operator.CreateSession(token, s.sessionTimeout)
+	operator.RecordLogin()
 
 	return session, nil
 }
 
-func (s *AuthService) ValidateSession(token string) (*Session, error) {
-	session, exists := s.sessions[token]
-	if !exists {
-		return nil, ErrSessionNotFound
+func (s *AuthService) ValidateSession(ctx context.Context, token string) (*Session, error) {
+	session, err := s.store.Find(ctx, token)
+	if err != nil {
+		return nil, err
 	}
 
-	if time.Now().After(session.ExpiresAt) {
-		delete(s.sessions, token)
+	if session.IsExpired(time.Now()) {
+		if err := s.store.Delete(ctx, token); err != nil {
+			return nil, err
+		}
 		return nil, ErrTokenExpired
 	}
 
 	return session, nil
 }
 
-func (s *AuthService) RefreshSession(token string) error {
-	session, err := s.ValidateSession(token)
-	if err != nil {
-		return err
-	}
+// RefreshSession registra l'attività dell'operatore e sposta in avanti la scadenza;
+// una sessione scaduta o chiusa da un'altra istanza restituisce ErrSessionNotFound
+func (s *AuthService) RefreshSession(ctx context.Context, token string) error {
+	now := time.Now()
+	return s.store.Touch(ctx, token, now, now.Add(s.sessionTimeout))
+}
 
-	session.ExpiresAt = time.Now().Add(s.sessionTimeout)
-	return nil
+func (s *AuthService) InvalidateSession(ctx context.Context, token string) error {
+	return s.store.Delete(ctx, token)
 }
 
-func (s *AuthService) InvalidateSession(token string) {
-	delete(s.sessions, token)
+// InvalidateAllSessions chiude tutte le sessioni dell'operatore, su ogni istanza
+func (s *AuthService) InvalidateAllSessions(ctx context.Context, operatorID string) (int64, error) {
+	return s.store.DeleteByOperator(ctx, operatorID)
 }
 
-func (s *AuthService) InvalidateAllSessions(operatorID string) {
-	for token, session := range s.sessions {
-		if session.OperatorID == operatorID {
-			delete(s.sessions, token)
-		}
+func (s *AuthService) GetActiveSessions(ctx context.Context, operatorID string) ([]*Session, error) {
+	sessions, err := s.store.FindByOperator(ctx, operatorID)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (s *AuthService) GetActiveSessions(operatorID string) []*Session {
-	var sessions []*Session
 	now := time.Now()
-
-	for _, session := range s.sessions {
-		if session.OperatorID == operatorID && now.Before(session.ExpiresAt) {
-			sessions = append(sessions, session)
+	active := sessions[:0]
+	for _, session := range sessions {
+		if !session.IsExpired(now) {
+			active = append(active, session)
 		}
 	}
 
-	return sessions
+	return active, nil
 }
 
-func (s *AuthService) CleanupExpiredSessions() int {
-	now := time.Now()
-	count := 0
-
-	for token, session := range s.sessions {
-		if now.After(session.ExpiresAt) {
-			delete(s.sessions, token)
-			count++
-		}
-	}
-
-	return count
+func (s *AuthService) CleanupExpiredSessions(ctx context.Context) (int64, error) {
+	return s.store.DeleteExpired(ctx, time.Now())
 }
 
 func generateSecureToken(length int) (string, error) {
@@ -136,10 +125,18 @@ func generateSecureToken(length int) (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-type PermissionChecker struct{}
+// DiscountLimitSource fornisce i limiti di sconto dell'operatore (DiscountAuthorizationUseCase)
+type DiscountLimitSource interface {
+	LimitsFor(ctx context.Context, operator *domain.Operator) (domain.DiscountLimits, error)
+}
+
+type PermissionChecker struct {
+	discountLimits DiscountLimitSource
+}
 
-func NewPermissionChecker() *PermissionChecker {
-	return &PermissionChecker{}
+// NewPermissionChecker senza sorgente dei limiti usa domain.DefaultDiscountLimits
+func NewPermissionChecker(discountLimits DiscountLimitSource) *PermissionChecker {
+	return &PermissionChecker{discountLimits: discountLimits}
 }
 
 func (pc *PermissionChecker) CheckPermission(operator *domain.Operator, area domain.PermissionArea, action domain.PermissionAction) error {
@@ -147,7 +144,7 @@ func (pc *PermissionChecker) CheckPermission(operator *domain.Operator, area dom
 		return errors.New("operator is not active")
 	}
 
-	if operator.IsLocked {
+	if operator.IsLockedAt(time.Now()) {
 		return domain.ErrOperatorLocked
 	}
 
@@ -163,7 +160,7 @@ func (pc *PermissionChecker) RequireAdmin(operator *domain.Operator) error {
 		return errors.New("operator is not active")
 	}
 
-	if operator.IsLocked {
+	if operator.IsLockedAt(time.Now()) {
 		return domain.ErrOperatorLocked
 	}
 
@@ -197,15 +194,16 @@ func (pc *PermissionChecker) CanViewSensitiveData(operator *domain.Operator, dat
 }
 
 func (pc *PermissionChecker) CanApproveDiscount(operator *domain.Operator, discountPercent float64) bool {
-	if operator.IsAdmin() {
-		return true
-	}
-
-	if operator.Profile == domain.ProfileSales && discountPercent <= 20 {
-		return true
+	limits := domain.DefaultDiscountLimits()
+	if pc.discountLimits != nil {
+		var err error
+		limits, err = pc.discountLimits.LimitsFor(context.Background(), operator)
+		if err != nil {
+			return false
+		}
 	}
 
-	return false
+	return limits.Authorize(operator, domain.DiscountRequest{DiscountPercent: discountPercent}).Allowed
 }
 
 func (pc *PermissionChecker) CanOverrideFido(operator *domain.Operator) bool {
@@ -216,35 +214,102 @@ func (pc *PermissionChecker) CanApproveSottocosto(operator *domain.Operator) boo
 	return operator.CanApproveSottocosto()
 }
 
-type AuditLogger struct{}
+// FailedAccessStore registra gli accessi respinti (repository.FailedAccessRepository)
+type FailedAccessStore interface {
+	Create(ctx context.Context, attempt *domain.FailedAccess) error
+}
 
-func NewAuditLogger() *AuditLogger {
-	return &AuditLogger{}
+// AuditStore è il registro di audit a catena (repository.AuditRepository)
+type AuditStore interface {
+	Append(ctx context.Context, record *domain.AuditRecord) error
 }
 
-func (al *AuditLogger) LogAction(operator *domain.Operator, action, area, resourceID, details, ipAddress string) {
+// AuditLogger scrive ogni voce nel registro a catena e, se configurato, la copia sul file di audit:
+// la copia fuori dal database permette di accorgersi anche di una catena ricalcolata per intero.
+// mu mantiene nel file l'ordine di sequenza delle voci scritte dal processo
+type AuditLogger struct {
+	mu           sync.Mutex
+	store        AuditStore
+	file         *AuditFile
+	failedAccess FailedAccessStore
+}
+
+// NewAuditLogger: con filePath vuoto non c'è copia su file, senza failedAccess gli accessi respinti
+// vanno solo nel registro di audit
+func NewAuditLogger(store AuditStore, failedAccess FailedAccessStore, filePath string) *AuditLogger {
+	al := &AuditLogger{store: store, failedAccess: failedAccess}
+	if filePath != "" {
+		al.file = NewAuditFile(filePath)
+	}
+	return al
+}
+
+func (al *AuditLogger) LogAction(ctx context.Context, operator *domain.Operator, action, area, resourceID, details, ipAddress string) error {
 	if operator == nil {
-		return
+		return nil
 	}
-	operator.AddAuditEntry(action, area, resourceID, details, ipAddress)
+	return al.Append(ctx, domain.NewAuditRecord(operator, action, area, resourceID, details, ipAddress))
 }
 
-func (al *AuditLogger) LogSensitiveAction(operator *domain.Operator, action, area, resourceID, details, ipAddress string) {
+func (al *AuditLogger) LogSensitiveAction(ctx context.Context, operator *domain.Operator, action, area, resourceID, details, ipAddress string) error {
 	if operator == nil {
-		return
+		return nil
 	}
 
 	sensitiveDetails := "[SENSITIVE] " + details
-	operator.AddAuditEntry(action, area, resourceID, sensitiveDetails, ipAddress)
+	return al.Append(ctx, domain.NewAuditRecord(operator, action, area, resourceID, sensitiveDetails, ipAddress))
 }
 
+// LogFailedAccess non restituisce errori: un tentativo respinto non deve diventare un errore di
+// sistema per chi sta facendo login, i problemi di scrittura finiscono nel log applicativo
 func (al *AuditLogger) LogFailedAccess(username, action, area, reason, ipAddress string) {
+	log.Printf("Failed access: user=%q action=%s area=%s reason=%s ip=%s", username, action, area, reason, ipAddress)
+
+	ctx := context.Background()
+
+	record := domain.NewAuditRecord(nil, action, area, "", reason, ipAddress)
+	record.Username = username
+	record.Denied = true
+	if err := al.Append(ctx, record); err != nil {
+		log.Printf("Error auditing failed access of %q: %v", username, err)
+	}
+
+	if al.failedAccess == nil {
+		return
+	}
+
+	attempt := domain.NewFailedAccess(username, action, area, reason, ipAddress)
+	if err := al.failedAccess.Create(ctx, attempt); err != nil {
+		log.Printf("Error recording failed access of %q: %v", username, err)
+	}
 }
 
+// Append registra una voce già compilata, ad esempio quelle migrate dal vecchio audit_log operatore
+func (al *AuditLogger) Append(ctx context.Context, record *domain.AuditRecord) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if err := al.store.Append(ctx, record); err != nil {
+		return err
+	}
+
+	if al.file != nil {
+		if err := al.file.Write(record); err != nil {
+			log.Printf("Error writing audit file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// RateLimiter conta i tentativi per identificativo in una finestra scorrevole; è condiviso fra la TUI
+// e la pulizia pianificata, quindi l'accesso è protetto da mu
 type RateLimiter struct {
-	attempts       map[string][]time.Time
 	maxAttempts    int
 	windowDuration time.Duration
+
+	mu       sync.Mutex
+	attempts map[string][]time.Time
 }
 
 func NewRateLimiter(maxAttempts int, windowMinutes int) *RateLimiter {
@@ -256,6 +321,9 @@ func NewRateLimiter(maxAttempts int, windowMinutes int) *RateLimiter {
 }
 
 func (rl *RateLimiter) CheckLimit(identifier string) (bool, int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
 	now := time.Now()
 	windowStart := now.Add(-rl.windowDuration)
 
@@ -282,13 +350,61 @@ func (rl *RateLimiter) CheckLimit(identifier string) (bool, int) {
 	return true, len(recentAttempts)
 }
 
+// Blocked dice, senza registrare un tentativo, se l'identificativo ha esaurito i tentativi
+// e fra quanto si libera il primo
+func (rl *RateLimiter) Blocked(identifier string) (bool, time.Duration) {
+	if rl.maxAttempts <= 0 {
+		return false, 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	recent := rl.recent(identifier, time.Now())
+	if len(recent) < rl.maxAttempts {
+		return false, 0
+	}
+
+	return true, time.Until(recent[len(recent)-rl.maxAttempts].Add(rl.windowDuration))
+}
+
+// Record registra un tentativo e restituisce quanti ce ne sono nella finestra
+func (rl *RateLimiter) Record(identifier string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	recent := append(rl.recent(identifier, time.Now()), time.Now())
+	rl.attempts[identifier] = recent
+	return len(recent)
+}
+
+func (rl *RateLimiter) recent(identifier string, now time.Time) []time.Time {
+	windowStart := now.Add(-rl.windowDuration)
+
+	var recent []time.Time
+	for _, attempt := range rl.attempts[identifier] {
+		if attempt.After(windowStart) {
+			recent = append(recent, attempt)
+		}
+	}
+	return recent
+}
+
 func (rl *RateLimiter) Reset(identifier string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
 	delete(rl.attempts, identifier)
 }
 
-func (rl *RateLimiter) Cleanup() {
+// Cleanup elimina i tentativi fuori finestra e restituisce quanti identificativi sono stati liberati
+func (rl *RateLimiter) Cleanup() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
 	now := time.Now()
 	windowStart := now.Add(-rl.windowDuration)
+	released := 0
 
 	for identifier, attempts := range rl.attempts {
 		var recentAttempts []time.Time
@@ -300,87 +416,53 @@ func (rl *RateLimiter) Cleanup() {
 
 		if len(recentAttempts) == 0 {
 			delete(rl.attempts, identifier)
+			released++
 		} else {
 			rl.attempts[identifier] = recentAttempts
 		}
 	}
+
+	return released
 }
 
+// PasswordValidator applica la policy della configurazione; la stessa policy è verificata dal
+// dominio in Operator.ChangePassword e ResetPassword
 type PasswordValidator struct {
-	minLength        int
-	requireUppercase bool
-	requireLowercase bool
-	requireDigit     bool
-	requireSpecial   bool
+	policy domain.PasswordPolicy
 }
 
-func NewPasswordValidator() *PasswordValidator {
-	return &PasswordValidator{
-		minLength:        8,
-		requireUppercase: true,
-		requireLowercase: true,
-		requireDigit:     true,
-		requireSpecial:   false,
-	}
+func NewPasswordValidator(policy domain.PasswordPolicy) *PasswordValidator {
+	return &PasswordValidator{policy: policy}
 }
 
 func (pv *PasswordValidator) Validate(password string) error {
-	if len(password) < pv.minLength {
-		return errors.New("password must be at least 8 characters long")
-	}
-
-	hasUpper := false
-	hasLower := false
-	hasDigit := false
-	hasSpecial := false
-
-	for _, char := range password {
-		switch {
-		case char >= 'A' && char <= 'Z':
-			hasUpper = true
-		case char >= 'a' && char <= 'z':
-			hasLower = true
-		case char >= '0' && char <= '9':
-			hasDigit = true
-		case char >= '!' && char <= '/' || char >= ':' && char <= '@' || char >= '[' && char <= '`' || char >= '{' && char <= '~':
-			hasSpecial = true
-		}
-	}
-
-	if pv.requireUppercase && !hasUpper {
-		return errors.New("password must contain at least one uppercase letter")
-	}
-
-	if pv.requireLowercase && !hasLower {
-		return errors.New("password must contain at least one lowercase letter")
-	}
-
-	if pv.requireDigit && !hasDigit {
-		return errors.New("password must contain at least one digit")
-	}
-
-	if pv.requireSpecial && !hasSpecial {
-		return errors.New("password must contain at least one special character")
-	}
-
-	return nil
+	return pv.policy.Validate(password)
 }
 
+// GenerateRandomPassword estrae password finché una rispetta la policy: con almeno 8 caratteri
+// bastano pochi tentativi
 func (pv *PasswordValidator) GenerateRandomPassword(length int) (string, error) {
-	if length < pv.minLength {
-		length = pv.minLength
+	if length < pv.policy.MinLength {
+		length = pv.policy.MinLength
+	}
+	if length < 8 {
+		length = 8
 	}
 
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
 	password := make([]byte, length)
 
-	if _, err := rand.Read(password); err != nil {
-		return "", err
-	}
+	for {
+		if _, err := rand.Read(password); err != nil {
+			return "", err
+		}
 
-	for i := range password {
-		password[i] = charset[int(password[i])%len(charset)]
-	}
+		for i := range password {
+			password[i] = charset[int(password[i])%len(charset)]
+		}
 
-	return string(password), nil
+		if pv.policy.Validate(string(password)) == nil {
+			return string(password), nil
+		}
+	}
 }
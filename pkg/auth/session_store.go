@@ -0,0 +1,122 @@
+// pkg/auth/session_store.go
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ricambi-manager/internal/domain"
+)
+
+// SessionStore conserva le sessioni di login. Con più istanze serve un archivio condiviso
+// (repository.SessionRepository su Mongo); MemorySessionStore vale solo per il processo corrente
+type SessionStore interface {
+	Save(ctx context.Context, session *domain.Session) error
+	Find(ctx context.Context, token string) (*domain.Session, error)
+	// Touch prolunga una sessione non ancora scaduta, altrimenti restituisce domain.ErrSessionNotFound
+	Touch(ctx context.Context, token string, lastActivity, expiresAt time.Time) error
+	Delete(ctx context.Context, token string) error
+	DeleteByOperator(ctx context.Context, operatorID string) (int64, error)
+	FindByOperator(ctx context.Context, operatorID string) ([]*domain.Session, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*domain.Session)}
+}
+
+// le sessioni entrano ed escono come copie, così i chiamanti non condividono lo stato protetto da mu
+
+func (s *MemorySessionStore) Save(ctx context.Context, session *domain.Session) error {
+	stored := *session
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.Token] = &stored
+	return nil
+}
+
+func (s *MemorySessionStore) Find(ctx context.Context, token string) (*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+
+	found := *session
+	return &found, nil
+}
+
+func (s *MemorySessionStore) Touch(ctx context.Context, token string, lastActivity, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok || session.IsExpired(lastActivity) {
+		return domain.ErrSessionNotFound
+	}
+
+	session.LastActivity = lastActivity
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+	return nil
+}
+
+func (s *MemorySessionStore) DeleteByOperator(ctx context.Context, operatorID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for token, session := range s.sessions {
+		if session.OperatorID == operatorID {
+			delete(s.sessions, token)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemorySessionStore) FindByOperator(ctx context.Context, operatorID string) ([]*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []*domain.Session
+	for _, session := range s.sessions {
+		if session.OperatorID == operatorID {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemorySessionStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for token, session := range s.sessions {
+		if session.IsExpired(now) {
+			delete(s.sessions, token)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/boombuler/barcode"
 	"github.com/boombuler/barcode/code128"
 	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
 )
 
 var (
@@ -26,6 +27,7 @@ const (
 	FormatEAN13   BarcodeFormat = "EAN13"
 	FormatEAN8    BarcodeFormat = "EAN8"
 	FormatCode128 BarcodeFormat = "CODE128"
+	FormatQR      BarcodeFormat = "QR"
 )
 
 type BarcodeGenerator struct {
@@ -73,6 +75,9 @@ func (bg *BarcodeGenerator) Generate(data string, format BarcodeFormat) (barcode
 	case FormatCode128:
 		bc, err = code128.Encode(data)
 
+	case FormatQR:
+		bc, err = qr.Encode(data, qr.M, qr.Auto)
+
 	default:
 		return nil, ErrInvalidBarcodeFormat
 	}
@@ -81,7 +86,13 @@ func (bg *BarcodeGenerator) Generate(data string, format BarcodeFormat) (barcode
 		return nil, err
 	}
 
-	bc, err = barcode.Scale(bc, bg.width, bg.height)
+	// il QR deve restare quadrato
+	width, height := bg.width, bg.height
+	if format == FormatQR {
+		width = height
+	}
+
+	bc, err = barcode.Scale(bc, width, height)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +127,50 @@ func (bg *BarcodeGenerator) GenerateBase64(data string, format BarcodeFormat) (s
 	return base64.StdEncoding.EncodeToString(pngData), nil
 }
 
+// GenerateTerminalQR disegna il QR con caratteri a mezzo blocco, due righe di moduli per riga di testo.
+// I moduli chiari sono pieni, così su un terminale a sfondo scuro il codice appare scuro su chiaro
+func (bg *BarcodeGenerator) GenerateTerminalQR(data string) (string, error) {
+	bc, err := qr.Encode(strings.TrimSpace(data), qr.M, qr.Auto)
+	if err != nil {
+		return "", err
+	}
+
+	const quiet = 2
+	bounds := bc.Bounds()
+	size := bounds.Dx()
+	light := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		if x < 0 || y < 0 || x >= size || y >= size {
+			return true
+		}
+		r, _, _, _ := bc.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+		return r > 0x7fff
+	}
+
+	var sb strings.Builder
+	total := size + 2*quiet
+	for y := 0; y < total; y += 2 {
+		for x := 0; x < total; x++ {
+			top, bottom := light(x, y), y+1 < total && light(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteRune('█')
+			case top:
+				sb.WriteRune('▀')
+			case bottom:
+				sb.WriteRune('▄')
+			default:
+				sb.WriteRune(' ')
+			}
+		}
+		if y+2 < total {
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String(), nil
+}
+
 func calculateEAN13Checksum(data string) string {
 	if len(data) != 12 {
 		return "0"
@@ -253,6 +308,38 @@ func (lp *LabelPrinter) GenerateShelfLabel(articleCode, description, location st
 	return zpl.String()
 }
 
+// GenerateVoucherZPL stampa il buono a credito con il codice in chiaro e come Code128 o QR,
+// da leggere col lettore in cassa
+func (lp *LabelPrinter) GenerateVoucherZPL(code, customer string, amount float64, expiry string, format BarcodeFormat) string {
+	var zpl strings.Builder
+
+	zpl.WriteString("^XA\n")
+	zpl.WriteString("^FO20,20^A0N,35,35^FDBUONO A CREDITO^FS\n")
+
+	if len(customer) > 30 {
+		customer = customer[:30]
+	}
+	zpl.WriteString("^FO20,65^A0N,25,25^FD" + customer + "^FS\n")
+
+	amountStr := fmt.Sprintf("EUR %.2f", amount)
+	zpl.WriteString("^FO20,100^A0N,40,40^FD" + amountStr + "^FS\n")
+
+	if expiry != "" {
+		zpl.WriteString("^FO20,150^A0N,22,22^FDScadenza: " + expiry + "^FS\n")
+	}
+
+	if format == FormatQR {
+		zpl.WriteString("^FO20,180^BQN,2,6^FDQA," + code + "^FS\n")
+		zpl.WriteString("^FO220,230^A0N,30,30^FD" + code + "^FS\n")
+	} else {
+		zpl.WriteString("^FO20,190^BY2^BCN,80,Y,N,N^FD" + code + "^FS\n")
+	}
+
+	zpl.WriteString("^XZ\n")
+
+	return zpl.String()
+}
+
 type LabelData struct {
 	ArticleCode string
 	Description string
@@ -345,6 +432,9 @@ func (bv *BarcodeValidator) Validate(code string, format BarcodeFormat) (bool, e
 		}
 		return true, nil
 
+	case FormatQR:
+		return true, nil
+
 	default:
 		return false, ErrInvalidBarcodeFormat
 	}
@@ -0,0 +1,119 @@
+// pkg/export/csv.go
+
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Separatore ';' e virgola decimale: il formato che Excel in italiano apre senza import guidato
+const CSVSeparator = ';'
+
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+func NewTable(headers ...string) *Table {
+	return &Table{Headers: headers}
+}
+
+func (t *Table) AddRow(values ...string) {
+	t.Rows = append(t.Rows, values)
+}
+
+func WriteCSV(w io.Writer, table *Table) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = CSVSeparator
+
+	if err := writer.Write(table.Headers); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(table.Rows); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func SaveCSV(dir, name string, table *Table) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, Filename(name, "csv"))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := WriteCSV(file, table); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func Filename(name, ext string) string {
+	return fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102_150405"), ext)
+}
+
+func Amount(value float64) string {
+	return strings.Replace(strconv.FormatFloat(value, 'f', 2, 64), ".", ",", 1)
+}
+
+func Percent(value float64) string {
+	return strings.Replace(strconv.FormatFloat(value, 'f', 1, 64), ".", ",", 1)
+}
+
+func Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("02/01/2006")
+}
+
+// ParseAmount accetta sia la virgola sia il punto decimale, con o senza separatore delle migliaia
+func ParseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "€"))
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("02/01/2006", value, time.Local)
+}
+
+// ReadCSV legge un file col separatore di WriteCSV e restituisce intestazioni e righe
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = CSVSeparator
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return &Table{}, nil
+	}
+
+	return &Table{Headers: records[0], Rows: records[1:]}, nil
+}